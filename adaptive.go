@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Android adaptive icons are 108dp layers of which launchers show the
+// middle 72dp, cut to their own mask shape. safeZoneScale keeps the artwork
+// within the 66dp circle that survives every mask.
+const (
+	adaptiveLayerScale = 108.0 / 48
+
+	adaptiveIconXML    = "mipmap-anydpi-v26/ic_launcher.xml"
+	adaptiveRoundXML   = "mipmap-anydpi-v26/ic_launcher_round.xml"
+	adaptiveColorXML   = "values/ic_launcher_background.xml"
+	adaptiveForeground = "ic_launcher_foreground.png"
+	adaptiveMonochrome = "ic_launcher_monochrome.png"
+	roundLauncherIcon  = "ic_launcher_round.png"
+)
+
+// monochromeModes are the ways --monochrome extracts the themed icon's
+// silhouette from the artwork.
+var monochromeModes = map[string]func(img image.Image) image.Image{
+	// The artwork's transparency, for artwork on a transparent background
+	"alpha": func(img image.Image) image.Image { return tintImage(img, color.RGBA{255, 255, 255, 255}) },
+	// Its dark pixels, for opaque line art on a light background
+	"threshold": thresholdSilhouette,
+}
+
+// validateMonochrome checks a --monochrome mode.
+func validateMonochrome(config Config) error {
+	if _, ok := monochromeModes[config.Monochrome]; !ok {
+		return fmt.Errorf("unknown monochrome mode %q (expected alpha or threshold)", config.Monochrome)
+	}
+	if !hasPreset(config, "android") && !hasPreset(config, notificationPreset) && !hasPreset(config, templatePreset) {
+		return fmt.Errorf("--monochrome only applies to the android, %s and %s presets", notificationPreset, templatePreset)
+	}
+	return nil
+}
+
+// adaptiveSizes returns the foreground and monochrome layers of the android
+// preset, one per density.
+func adaptiveSizes(config Config) []IconSize {
+	var sizes []IconSize
+	for _, iconSize := range outputSizes(config) {
+		dir, name := filepath.Split(iconSize.Name)
+		if !strings.HasPrefix(dir, "mipmap-") || name != "ic_launcher.png" {
+			continue
+		}
+		layer := int(float64(iconSize.Size)*adaptiveLayerScale + 0.5)
+		sizes = append(sizes, IconSize{dir + adaptiveForeground, layer}, IconSize{dir + adaptiveMonochrome, layer})
+	}
+	return sizes
+}
+
+// isLauncherIcon reports whether iconSize is one of the android preset's
+// mipmap-*/ic_launcher.png icons.
+func isLauncherIcon(iconSize IconSize) bool {
+	dir, name := filepath.Split(iconSize.Name)
+	return strings.HasPrefix(dir, "mipmap-") && name == "ic_launcher.png"
+}
+
+// roundSizes returns the legacy round launcher icons of the android preset,
+// one per density, each the size of its ic_launcher.png.
+func roundSizes(config Config) []IconSize {
+	var sizes []IconSize
+	for _, iconSize := range outputSizes(config) {
+		if isLauncherIcon(iconSize) {
+			dir, _ := filepath.Split(iconSize.Name)
+			sizes = append(sizes, IconSize{dir + roundLauncherIcon, iconSize.Size})
+		}
+	}
+	return sizes
+}
+
+// roundTargets lists the round launcher icons the android preset of config
+// adds to its launcher icons.
+func roundTargets(config Config) []plannedOutput {
+	if !hasPreset(config, "android") {
+		return nil
+	}
+	var targets []plannedOutput
+	for _, iconSize := range roundSizes(config) {
+		targets = append(targets, iconTarget(artworkConfig(config), iconSize, iconSize.Name, "round"))
+	}
+	return targets
+}
+
+// adaptiveTargets lists the outputs the --monochrome adaptive icon of config
+// produces.
+func adaptiveTargets(config Config) []plannedOutput {
+	if config.Monochrome == "" || !hasPreset(config, "android") {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, iconSize := range adaptiveSizes(config) {
+		target := iconTarget(artworkConfig(config), iconSize, iconSize.Name, "adaptive")
+		if strings.HasSuffix(iconSize.Name, adaptiveMonochrome) {
+			target.Settings = map[string]string{"monochrome": config.Monochrome}
+		}
+		targets = append(targets, target)
+	}
+	targets = append(targets,
+		plannedOutput{Name: adaptiveIconXML, Format: "xml", Variant: "adaptive"},
+		plannedOutput{Name: adaptiveRoundXML, Format: "xml", Variant: "adaptive"},
+		plannedOutput{Name: adaptiveColorXML, Format: "xml", Variant: "adaptive"},
+	)
+	return targets
+}
+
+// generateAdaptive writes an Android adaptive launcher icon: a foreground
+// layer with the artwork and a monochrome layer with its silhouette for
+// Android 13 themed icons, at every density, over a background color, tied
+// together by mipmap-anydpi-v26/ic_launcher.xml and its round twin.
+func generateAdaptive(sourceImg image.Image, config Config, state *manifestState) error {
+	background := adaptiveBackground(config)
+	config = artworkConfig(config)
+	config.ForegroundScale = layerScale(config.ForegroundScale) * safeZoneScale(config, sourceImg) / 100
+	silhouette := monochromeModes[config.Monochrome](sourceImg)
+
+	for _, iconSize := range adaptiveSizes(config) {
+		iconSize := iconSize
+		label := fmt.Sprintf("%dx%d, adaptive foreground", iconSize.Size, iconSize.Size)
+		render := func() image.Image {
+			return prepareIcon(sourceImg, config, backgroundPattern{}, nil, nil, iconSize.Size)
+		}
+		if strings.HasSuffix(iconSize.Name, adaptiveMonochrome) {
+			label = fmt.Sprintf("%dx%d, monochrome", iconSize.Size, iconSize.Size)
+			// The silhouette leaves out overlays and text, which don't
+			// belong on a themed icon
+			render = func() image.Image {
+				return scaleForeground(silhouette, iconSize.Size, config.ForegroundScale, depthResizer(config))
+			}
+		}
+		if err := saveOutput(config, state, iconSize.Name, label, render); err != nil {
+			return err
+		}
+	}
+	adaptiveIcon := `<?xml version="1.0" encoding="utf-8"?>
+<adaptive-icon xmlns:android="http://schemas.android.com/apk/res/android">
+    <background android:drawable="@color/ic_launcher_background"/>
+    <foreground android:drawable="@mipmap/ic_launcher_foreground"/>
+    <monochrome android:drawable="@mipmap/ic_launcher_monochrome"/>
+</adaptive-icon>
+`
+	files := []struct {
+		name string
+		data string
+	}{
+		{adaptiveIconXML, adaptiveIcon},
+		{adaptiveRoundXML, adaptiveIcon},
+		{adaptiveColorXML, fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <color name="ic_launcher_background">%s</color>
+</resources>
+`, background)},
+	}
+	for _, file := range files {
+		fmt.Fprintf(logOutput(config), " - %s\n", file.name)
+		path := filepath.Join(config.OutputDir, file.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.name, err)
+		}
+		if err := os.WriteFile(path, []byte(file.data), 0644); err != nil {
+			return fmt.Errorf("failed to save %s: %w", file.name, err)
+		}
+		if err := state.record(file.name); err != nil {
+			return fmt.Errorf("failed to record %s: %w", file.name, err)
+		}
+	}
+	return nil
+}
+
+// generateRoundLaunchers writes ic_launcher_round.png at every density of
+// the android preset, for the launchers that read the round resource.
+func generateRoundLaunchers(sourceImg image.Image, config Config, state *manifestState) error {
+	render := roundLauncher(sourceImg, config)
+	for _, iconSize := range roundSizes(config) {
+		iconSize := iconSize
+		label := fmt.Sprintf("%dx%d, round", iconSize.Size, iconSize.Size)
+		err := saveOutput(config, state, iconSize.Name, label, func() image.Image {
+			return render(iconSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundLauncher returns a function rendering the round launcher icon
+// iconSize: the adaptive icon as a circle mask shows it, the middle 72dp of
+// the layer, with its artwork scaled into the safe zone over the background
+// color and cut to a circle.
+func roundLauncher(sourceImg image.Image, config Config) func(iconSize IconSize) image.Image {
+	fill := adaptiveBackgroundColor(config)
+	config = artworkConfig(config)
+	config.ForegroundScale = layerScale(config.ForegroundScale) * safeZoneScale(config, sourceImg) * 108 / 72 / 100
+
+	circle := func(img image.Image, size int) image.Image { return addCircleMask(img) }
+	return func(iconSize IconSize) image.Image {
+		artwork := prepareIcon(sourceImg, config, backgroundPattern{}, nil, nil, iconSize.Size)
+		icon := addBackground(artwork, solidBackground(fill, iconSize.Size))
+		return finishIcon(addCircleMask(icon), config, iconSize, circle)
+	}
+}
+
+// adaptiveBackground returns the background color of the adaptive icon in
+// Android's #AARRGGBB notation.
+func adaptiveBackground(config Config) string {
+	n := color.NRGBAModel.Convert(adaptiveBackgroundColor(config)).(color.NRGBA)
+	return fmt.Sprintf("#%02X%02X%02X%02X", n.A, n.R, n.G, n.B)
+}
+
+// adaptiveBackgroundColor returns the background color of the adaptive
+// icon: the --background color, or white when the background isn't a plain
+// color.
+func adaptiveBackgroundColor(config Config) color.RGBA {
+	c, err := parseHexColor(config.Background)
+	if config.Background == "" || backgroundIsImage(config) || err != nil {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	return c
+}
+
+// thresholdSilhouette returns the pixels of img darker than mid-gray as an
+// opaque white silhouette, leaving the rest transparent.
+func thresholdSilhouette(img image.Image) image.Image {
+	bounds := img.Bounds()
+	silhouette := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if c.A >= 128 && luma(c) < 128 {
+				silhouette.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			}
+		}
+	}
+
+	return silhouette
+}
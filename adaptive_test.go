@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateMonochrome(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"alpha", Config{Preset: "android", Monochrome: "alpha"}, false},
+		{"threshold", Config{Preset: "macos,android", Monochrome: "threshold"}, false},
+		{"unknown mode", Config{Preset: "android", Monochrome: "luma"}, true},
+		{"without android", Config{Preset: "web", Monochrome: "alpha"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMonochrome(tt.config)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestThresholdSilhouette(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{20, 20, 20, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{240, 240, 240, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{20, 20, 20, 40})
+
+	silhouette := thresholdSilhouette(img)
+	for x, expect := range []uint32{0xffff, 0, 0} {
+		if _, _, _, a := silhouette.At(x, 0).RGBA(); a != expect {
+			t.Errorf("Expected alpha %d at x=%d, got %d", expect>>8, x, a>>8)
+		}
+	}
+}
+
+func TestGenerateAdaptive(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImageWithSquare(64, 32, color.RGBA{255, 200, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "android", TrimPercent: 80, Background: "#3366CC", Monochrome: "alpha"}
+	if err := validateOptions(config); err != nil {
+		t.Fatalf("Expected valid options: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		size  int
+		color color.RGBA
+	}{
+		{"mipmap-mdpi/ic_launcher_foreground.png", 108, color.RGBA{255, 200, 0, 255}},
+		{"mipmap-xxxhdpi/ic_launcher_foreground.png", 432, color.RGBA{255, 200, 0, 255}},
+		{"mipmap-xxxhdpi/ic_launcher_monochrome.png", 432, color.RGBA{255, 255, 255, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := loadImage(filepath.Join(outputDir, tt.name))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", tt.name, err)
+			}
+			if img.Bounds().Dx() != tt.size {
+				t.Errorf("Expected %dpx, got %dpx", tt.size, img.Bounds().Dx())
+			}
+			// The background is left to the adaptive icon's background layer
+			if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+				t.Errorf("Expected a transparent corner, got alpha %d", a>>8)
+			}
+			if got := color.RGBAModel.Convert(img.At(tt.size/2, tt.size/2)).(color.RGBA); got != tt.color {
+				t.Errorf("Expected %v at the center, got %v", tt.color, got)
+			}
+		})
+	}
+
+	xml, err := os.ReadFile(filepath.Join(outputDir, adaptiveIconXML))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", adaptiveIconXML, err)
+	}
+	if !strings.Contains(string(xml), `<monochrome android:drawable="@mipmap/ic_launcher_monochrome"/>`) {
+		t.Errorf("Expected a monochrome element, got:\n%s", xml)
+	}
+
+	colors, err := os.ReadFile(filepath.Join(outputDir, adaptiveColorXML))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", adaptiveColorXML, err)
+	}
+	if !strings.Contains(string(colors), `<color name="ic_launcher_background">#FF3366CC</color>`) {
+		t.Errorf("Expected the background color, got:\n%s", colors)
+	}
+}
+
+func TestRoundLauncherIcons(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImageWithSquare(64, 32, color.RGBA{255, 200, 0, 255}))
+	outputDir := t.TempDir()
+
+	// Without --monochrome too, and with --radius-percent, which leaves the
+	// launcher icons without a rounded variant
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "android", TrimPercent: 80, Background: "#3366CC", RadiusPercent: 20}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range roundSizes(config) {
+		img, err := loadImage(filepath.Join(outputDir, iconSize.Name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", iconSize.Name, err)
+		}
+		size := img.Bounds().Dx()
+		if size != iconSize.Size {
+			t.Errorf("%s: expected %dpx, got %dpx", iconSize.Name, iconSize.Size, size)
+		}
+		if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("%s: expected a transparent corner outside the circle", iconSize.Name)
+		}
+		if got := color.RGBAModel.Convert(img.At(size/2, size/2)).(color.RGBA); got != (color.RGBA{255, 200, 0, 255}) {
+			t.Errorf("%s: expected the artwork at the center, got %v", iconSize.Name, got)
+		}
+		if got := color.RGBAModel.Convert(img.At(size/2, size/12)).(color.RGBA); got != (color.RGBA{0x33, 0x66, 0xCC, 255}) {
+			t.Errorf("%s: expected the background inside the circle, got %v", iconSize.Name, got)
+		}
+	}
+	if len(roundSizes(config)) != 5 {
+		t.Errorf("Expected a round icon per density, got %d", len(roundSizes(config)))
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "mipmap-mdpi/ic_launcher_rounded.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected no rounded variant of the launcher icons, got %v", err)
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(targets) != 2*len(roundSizes(config)) {
+		t.Errorf("Expected a launcher and a round icon per density, got %d targets", len(targets))
+	}
+
+	config.Monochrome = "alpha"
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	xml, err := os.ReadFile(filepath.Join(outputDir, adaptiveRoundXML))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", adaptiveRoundXML, err)
+	}
+	if !strings.Contains(string(xml), `<foreground android:drawable="@mipmap/ic_launcher_foreground"/>`) {
+		t.Errorf("Expected the round resource to be the adaptive icon, got:\n%s", xml)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// appearanceIconSize is the single-size iOS icon the dark and tinted
+// appearances are variants of.
+var appearanceIconSize = IconSize{"icon_1024x1024.png", 1024}
+
+// iconAppearances are the iOS 18 appearances written by --appearances, in
+// the luminosity values Contents.json names them by.
+var iconAppearances = []string{"dark", "tinted"}
+
+// appearanceIconName returns the file name of the appearance variant of name.
+func appearanceIconName(name, appearance string) string {
+	return strings.TrimSuffix(name, ".png") + "_" + appearance + ".png"
+}
+
+// appearanceTargets lists the outputs the --appearances variants of config
+// produce.
+func appearanceTargets(config Config) []plannedOutput {
+	if !config.Appearances {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, appearance := range iconAppearances {
+		target := iconTarget(artworkConfig(config), appearanceIconSize, appearanceIconName(appearanceIconSize.Name, appearance), "appearance")
+		target.Settings["appearance"] = appearance
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// artworkConfig returns config without the backgrounds, for outputs the
+// platform draws its own background behind, such as the iOS dark and tinted
+// appearances and Android adaptive icon layers.
+func artworkConfig(config Config) Config {
+	config.Background = ""
+	config.BackgroundGradient = ""
+	config.BackgroundPattern = ""
+	config.Fill = "none"
+	config.Layers = ""
+	return config
+}
+
+// generateAppearances writes the dark variant of the iOS icon, the artwork
+// on a transparent background, and the tinted variant, a grayscale copy of
+// the dark one that iOS colors with the user's tint.
+func generateAppearances(sourceImg image.Image, config Config, state *manifestState) error {
+	config = artworkConfig(config)
+
+	var dark image.Image
+	render := func() image.Image {
+		if dark == nil {
+			dark = finishIcon(prepareIcon(sourceImg, config, backgroundPattern{}, nil, nil, appearanceIconSize.Size), config, appearanceIconSize, nil)
+		}
+		return dark
+	}
+
+	for _, appearance := range iconAppearances {
+		appearance := appearance
+		name := appearanceIconName(appearanceIconSize.Name, appearance)
+		label := fmt.Sprintf("%dx%d, %s", appearanceIconSize.Size, appearanceIconSize.Size, appearance)
+
+		err := saveOutput(config, state, name, label, func() image.Image {
+			if appearance == "tinted" {
+				return grayscaleImage(render())
+			}
+			return render()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
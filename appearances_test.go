@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateAppearances(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImageWithSquare(64, 32, color.RGBA{255, 200, 0, 255}))
+	outputDir := filepath.Join(t.TempDir(), "AppIcon.appiconset")
+
+	config := Config{
+		InputPath:    inputPath,
+		OutputDir:    outputDir,
+		TrimPercent:  80,
+		Background:   "#3366CC",
+		ContentsJSON: true,
+		Appearances:  true,
+	}
+	if err := validateOptions(config); err != nil {
+		t.Fatalf("Expected valid options: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	icons := map[string]image.Image{}
+	for _, name := range []string{"icon_1024x1024.png", "icon_1024x1024_dark.png", "icon_1024x1024_tinted.png"} {
+		img, err := loadImage(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		icons[name] = img
+	}
+	regular, dark, tinted := icons["icon_1024x1024.png"], icons["icon_1024x1024_dark.png"], icons["icon_1024x1024_tinted.png"]
+
+	// The background is dropped from the appearances only
+	if _, _, _, a := regular.At(0, 0).RGBA(); a == 0 {
+		t.Errorf("Expected the regular icon to keep its background")
+	}
+	if _, _, _, a := dark.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("Expected a transparent corner in the dark icon, got alpha %d", a>>8)
+	}
+
+	center := color.NRGBAModel.Convert(tinted.At(512, 512)).(color.NRGBA)
+	if center.R != center.G || center.G != center.B || center.A != 255 {
+		t.Errorf("Expected an opaque gray center in the tinted icon, got %v", center)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, xcodeContentsName))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", xcodeContentsName, err)
+	}
+	var contents xcodeContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		t.Fatalf("Failed to parse %s: %v", xcodeContentsName, err)
+	}
+
+	found := map[string]string{}
+	for _, image := range contents.Images {
+		if len(image.Appearances) == 1 {
+			found[image.Filename] = image.Appearances[0].Appearance + "=" + image.Appearances[0].Value
+		}
+	}
+	for name, expect := range map[string]string{
+		"icon_1024x1024_dark.png":   "luminosity=dark",
+		"icon_1024x1024_tinted.png": "luminosity=tinted",
+	} {
+		if found[name] != expect {
+			t.Errorf("Expected %s for %s, got %q", expect, name, found[name])
+		}
+	}
+}
+
+func TestAppearancesRequireMacOS(t *testing.T) {
+	config := Config{Preset: "web", TrimPercent: 80, Appearances: true}
+	if err := validateOptions(config); err == nil {
+		t.Errorf("Expected an error for --appearances without the macos preset")
+	}
+}
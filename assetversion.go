@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// latestName is the pointer --versioned-dirs keeps next to the version
+// directories, naming the most recently generated one.
+const latestName = "latest"
+
+// validateAssetVersion checks that version can name a directory.
+func validateAssetVersion(version string) error {
+	for _, r := range version {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_+", r)) {
+			return fmt.Errorf("invalid asset version %q (use letters, digits and . - _ +)", version)
+		}
+	}
+	if version == "" || strings.Trim(version, ".") == "" {
+		return fmt.Errorf("invalid asset version %q", version)
+	}
+	return nil
+}
+
+// versionDirName returns the directory name of an asset version, e.g.
+// "v1.4.0" for both "1.4.0" and "v1.4.0".
+func versionDirName(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+// assetDir returns the directory config's outputs are written to: the output
+// directory, or its version directory with --versioned-dirs.
+func assetDir(config Config) string {
+	if config.VersionedDirs && config.AssetVersion != "" {
+		return filepath.Join(config.OutputDir, versionDirName(config.AssetVersion))
+	}
+	return config.OutputDir
+}
+
+// updateLatest points the latest entry of base at the version directory
+// dir: a relative symlink, or a text file holding the directory name where
+// symlinks aren't available.
+func updateLatest(base, dir string) error {
+	path := filepath.Join(base, latestName)
+	if info, err := os.Lstat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Symlink(dir, path); err == nil {
+		return nil
+	}
+	return os.WriteFile(path, []byte(dir+"\n"), 0644)
+}
+
+// addPNGText returns the PNG data with a text chunk holding keyword and text
+// inserted right after the IHDR chunk: tEXt for ASCII text, or else an
+// uncompressed iTXt, whose text is UTF-8 rather than Latin-1.
+func addPNGText(data []byte, keyword, text string) ([]byte, error) {
+	payload := append([]byte(keyword), 0)
+	if !isASCII(text) {
+		// No compression, language tag or translated keyword
+		payload = append(payload, 0, 0, 0, 0)
+		return addPNGChunk(data, "iTXt", append(payload, text...))
+	}
+	return addPNGChunk(data, "tEXt", append(payload, text...))
+}
+
+// isASCII reports whether s only holds ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// addPNGChunk returns the PNG data with a chunk of typ holding payload
+// inserted right after the IHDR chunk.
+func addPNGChunk(data []byte, typ string, payload []byte) ([]byte, error) {
+	ihdrEnd, err := pngHeaderEnd(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(payload)))
+	chunk.WriteString(typ)
+	chunk.Write(payload)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), payload...)))
+
+	out := make([]byte, 0, len(data)+chunk.Len())
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunk.Bytes()...)
+	return append(out, data[ihdrEnd:]...), nil
+}
+
+// pngHeaderEnd returns the offset in the PNG data right after the IHDR
+// chunk, where addPNGChunk inserts chunks.
+func pngHeaderEnd(data []byte) (int, error) {
+	const signatureLen = 8
+	if len(data) < signatureLen+8 || string(data[signatureLen+4:signatureLen+8]) != "IHDR" {
+		return 0, fmt.Errorf("not a PNG image")
+	}
+	ihdrEnd := signatureLen + 12 + int(binary.BigEndian.Uint32(data[signatureLen:]))
+	if ihdrEnd > len(data) {
+		return 0, fmt.Errorf("truncated PNG image")
+	}
+	return ihdrEnd, nil
+}
+
+// pngText returns the text of the first tEXt or uncompressed iTXt chunk
+// with keyword in the PNG data, if there is one.
+func pngText(data []byte, keyword string) (string, bool) {
+	for pos := 8; pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if pos+12+length > len(data) {
+			break
+		}
+		switch payload := data[pos+8 : pos+8+length]; string(data[pos+4 : pos+8]) {
+		case "tEXt":
+			if key, text, ok := bytes.Cut(payload, []byte{0}); ok && string(key) == keyword {
+				return string(text), true
+			}
+		case "iTXt":
+			if key, rest, ok := bytes.Cut(payload, []byte{0}); ok && string(key) == keyword && len(rest) >= 2 && rest[0] == 0 {
+				// Skip the language tag and translated keyword
+				_, rest, _ = bytes.Cut(rest[2:], []byte{0})
+				if _, text, ok := bytes.Cut(rest, []byte{0}); ok {
+					return string(text), true
+				}
+			}
+		}
+		pos += 12 + length
+	}
+	return "", false
+}
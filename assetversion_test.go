@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAssetVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{"1.4.0", false},
+		{"v2.0.0-beta.1+build5", false},
+		{"", true},
+		{"..", true},
+		{"1.4/0", true},
+		{"1 4", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			err := validateAssetVersion(tt.version)
+			if tt.wantErr && err == nil {
+				t.Errorf("Expected error for %q", tt.version)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error for %q, got: %v", tt.version, err)
+			}
+		})
+	}
+
+	if got := versionDirName("1.4.0"); got != "v1.4.0" {
+		t.Errorf("Expected v1.4.0, got %s", got)
+	}
+	if got := versionDirName("v1.4.0"); got != "v1.4.0" {
+		t.Errorf("Expected v1.4.0 not to get a second v, got %s", got)
+	}
+}
+
+func TestAddPNGText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, createTestImage(8, color.RGBA{255, 0, 0, 255})); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	data, err := addPNGText(buf.Bytes(), "Version", "1.4.0")
+	if err != nil {
+		t.Fatalf("Failed to add text: %v", err)
+	}
+	if text, ok := pngText(data, "Version"); !ok || text != "1.4.0" {
+		t.Errorf("Expected Version 1.4.0, got %q (%v)", text, ok)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("Expected PNG with text chunk to decode, got: %v", err)
+	}
+
+	if _, err := addPNGText([]byte("GIF89a"), "Version", "1"); err == nil {
+		t.Errorf("Expected error for non-PNG data")
+	}
+}
+
+func TestVersionedDirs(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 0, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		TrimPercent:   80,
+		AssetVersion:  "1.4.0",
+		VersionedDirs: true,
+	}
+
+	for _, version := range []string{"1.4.0", "1.5.0"} {
+		config.AssetVersion = version
+		if err := generateIcons(config); err != nil {
+			t.Fatalf("Failed to generate %s: %v", version, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(outputDir, "v"+version, "icon_16x16.png"))
+		if err != nil {
+			t.Fatalf("Expected icons in v%s: %v", version, err)
+		}
+		if text, _ := pngText(data, "Version"); text != version {
+			t.Errorf("Expected PNG metadata version %s, got %q", version, text)
+		}
+
+		m, err := readManifest(filepath.Join(outputDir, "v"+version))
+		if err != nil || m.AssetVersion != version {
+			t.Errorf("Expected manifest with version %s", version)
+		}
+	}
+
+	// latest follows the newest version, whether it's a symlink or a file
+	if target, err := os.Readlink(filepath.Join(outputDir, latestName)); err == nil {
+		if target != "v1.5.0" {
+			t.Errorf("Expected latest to point at v1.5.0, got %s", target)
+		}
+	} else if data, _ := os.ReadFile(filepath.Join(outputDir, latestName)); strings.TrimSpace(string(data)) != "v1.5.0" {
+		t.Errorf("Expected latest to name v1.5.0, got %q", data)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "v1.4.0", "icon_16x16.png")); err != nil {
+		t.Errorf("Expected earlier versions to be kept: %v", err)
+	}
+}
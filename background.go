@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// backgroundPattern describes a tiled pattern synthesized behind the artwork.
+type backgroundPattern struct {
+	Kind        string // dots, stripes or checker
+	CellPercent int    // size of one pattern cell as percentage of the icon size
+	Foreground  color.RGBA
+	Background  color.RGBA
+}
+
+// backgroundGradient describes a gradient synthesized behind the artwork,
+// with its color stops spread evenly from start to end.
+type backgroundGradient struct {
+	Stops  []color.RGBA
+	Radial bool    // from the center out to the corners
+	Angle  float64 // direction of a linear gradient in degrees, as in CSS
+	Dither string  // how it is quantized to 8 bits, as validateDither accepts
+}
+
+// parseHexColor parses #RGB, #RRGGBB or #RRGGBBAA into a premultiplied color.
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q (expected #RGB, #RRGGBB or #RRGGBBAA)", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q (expected #RGB, #RRGGBB or #RRGGBBAA)", s)
+	}
+
+	nrgba := color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+	return color.RGBAModel.Convert(nrgba).(color.RGBA), nil
+}
+
+// formatHexColor formats c as #RRGGBB, or #RRGGBBAA when not opaque.
+func formatHexColor(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 255 {
+		return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", n.R, n.G, n.B, n.A)
+}
+
+// parseBackgroundPattern parses a --background-pattern spec of the form
+// KIND[:key=value,...], e.g. "checker:size=12,fg=#DDDDDD,bg=#FFFFFF".
+func parseBackgroundPattern(spec string) (backgroundPattern, error) {
+	pattern := backgroundPattern{
+		CellPercent: 10,
+		Foreground:  color.RGBA{221, 221, 221, 255},
+		Background:  color.RGBA{255, 255, 255, 255},
+	}
+
+	kind, params, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "dots", "stripes", "checker":
+		pattern.Kind = kind
+	default:
+		return pattern, fmt.Errorf("unknown background pattern %q (expected dots, stripes or checker)", kind)
+	}
+
+	if params == "" {
+		return pattern, nil
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return pattern, fmt.Errorf("invalid background pattern parameter %q (expected key=value)", param)
+		}
+
+		var err error
+		switch key {
+		case "size":
+			pattern.CellPercent, err = strconv.Atoi(value)
+			if err == nil && (pattern.CellPercent < 1 || pattern.CellPercent > 100) {
+				err = fmt.Errorf("pattern size must be between 1 and 100 (got %d)", pattern.CellPercent)
+			}
+		case "fg":
+			pattern.Foreground, err = parseHexColor(value)
+		case "bg":
+			pattern.Background, err = parseHexColor(value)
+		default:
+			err = fmt.Errorf("unknown background pattern parameter %q", key)
+		}
+		if err != nil {
+			return pattern, err
+		}
+	}
+
+	return pattern, nil
+}
+
+// parseBackgroundGradient parses a --background-gradient spec of the form
+// COLOR..COLOR[..COLOR][@ANGLEdeg|@radial], e.g. "#4F46E5..#9333EA@45deg".
+// Angles follow CSS linear-gradient: 0deg runs bottom to top, 90deg left to
+// right, and the default 180deg top to bottom.
+func parseBackgroundGradient(spec string) (backgroundGradient, error) {
+	gradient := backgroundGradient{Angle: 180}
+
+	colors, shape, hasShape := strings.Cut(spec, "@")
+	if hasShape {
+		if shape == "radial" {
+			gradient.Radial = true
+		} else {
+			angle, err := strconv.ParseFloat(strings.TrimSuffix(shape, "deg"), 64)
+			if err != nil || !strings.HasSuffix(shape, "deg") {
+				return gradient, fmt.Errorf("invalid gradient shape %q (expected e.g. 45deg or radial)", shape)
+			}
+			gradient.Angle = angle
+		}
+	}
+
+	for _, s := range strings.Split(colors, "..") {
+		c, err := parseHexColor(s)
+		if err != nil {
+			return gradient, err
+		}
+		gradient.Stops = append(gradient.Stops, c)
+	}
+	if len(gradient.Stops) < 2 {
+		return gradient, fmt.Errorf("invalid gradient %q (expected at least two colors, e.g. #4F46E5..#9333EA)", spec)
+	}
+
+	return gradient, nil
+}
+
+// renderGradient synthesizes the gradient on a size x size canvas. As in
+// CSS, a linear gradient reaches its end colors exactly at the corners and a
+// radial one at the farthest corner. It is quantized to 8 bits with the
+// gradient's Dither, which keeps a gradient spread over a large icon from
+// banding.
+func renderGradient(gradient backgroundGradient, size int) *image.RGBA {
+	center := float64(size) / 2
+	rad := gradient.Angle * math.Pi / 180
+	dirX, dirY := math.Sin(rad), -math.Cos(rad)
+	length := float64(size) * (math.Abs(dirX) + math.Abs(dirY))
+	radius := center * math.Sqrt2
+
+	return ditherRGBA(size, gradient.Dither, func(x, y int) [4]float64 {
+		px := float64(x) + 0.5 - center
+		py := float64(y) + 0.5 - center
+
+		var t float64
+		if gradient.Radial {
+			t = math.Sqrt(px*px+py*py) / radius
+		} else {
+			t = (px*dirX+py*dirY)/length + 0.5
+		}
+		return gradientValue(gradient.Stops, t)
+	})
+}
+
+// gradientValue returns the channels of the color at t in [0, 1] along
+// evenly spread stops, before they are rounded to 8 bits.
+func gradientValue(stops []color.RGBA, t float64) [4]float64 {
+	t = math.Max(0, math.Min(1, t)) * float64(len(stops)-1)
+	i := int(t)
+	if i >= len(stops)-1 {
+		i, t = len(stops)-2, float64(len(stops)-1)
+	}
+	a, b := stops[i], stops[i+1]
+	mix := func(a, b uint8) float64 {
+		return float64(a)*(1-(t-float64(i))) + float64(b)*(t-float64(i))
+	}
+	return [4]float64{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// renderPattern synthesizes the pattern on a size x size canvas.
+func renderPattern(pattern backgroundPattern, size int) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	cell := size * pattern.CellPercent / 100
+	if cell < 2 {
+		cell = 2
+	}
+
+	fg := pattern.Foreground
+	bg := pattern.Background
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			switch pattern.Kind {
+			case "checker":
+				if (x/cell+y/cell)%2 == 1 {
+					canvas.SetRGBA(x, y, fg)
+				}
+			case "stripes":
+				if ((x+y)/cell)%2 == 1 {
+					canvas.SetRGBA(x, y, fg)
+				}
+			case "dots":
+				// One dot per cell, anti-aliased over a one pixel edge
+				cx := float64(x%cell) + 0.5 - float64(cell)/2
+				cy := float64(y%cell) + 0.5 - float64(cell)/2
+				coverage := float64(cell)*0.3 - math.Sqrt(cx*cx+cy*cy) + 0.5
+				if coverage > 0 {
+					canvas.SetRGBA(x, y, mixRGBA(bg, fg, math.Min(coverage, 1)))
+				}
+			}
+		}
+	}
+
+	return canvas
+}
+
+// mixRGBA linearly blends from a to b by t in [0, 1].
+func mixRGBA(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-t) + float64(b)*t))
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// solidBackground returns a size x size canvas filled with c.
+func solidBackground(c color.RGBA, size int) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	return canvas
+}
+
+// addBackground composites img over background, which must be at least as
+// large as img.
+func addBackground(img image.Image, background *image.RGBA) image.Image {
+	bounds := img.Bounds()
+	draw.Draw(background, background.Bounds(), img, bounds.Min, draw.Over)
+	return background
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  color.RGBA
+		expectErr bool
+	}{
+		{"#FF0000", color.RGBA{255, 0, 0, 255}, false},
+		{"00ff00", color.RGBA{0, 255, 0, 255}, false},
+		{"#fff", color.RGBA{255, 255, 255, 255}, false},
+		{"#0000FF80", color.RGBA{0, 0, 128, 128}, false},
+		{"#12345", color.RGBA{}, true},
+		{"#GGGGGG", color.RGBA{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := parseHexColor(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if !strings.Contains(err.Error(), "#RGB, #RRGGBB or #RRGGBBAA") {
+					t.Errorf("Expected the error to list every accepted form, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if c != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, c)
+			}
+		})
+	}
+}
+
+func TestParseBackgroundPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expectErr bool
+	}{
+		{"dots", "dots", false},
+		{"stripes with size", "stripes:size=5", false},
+		{"checker with colors", "checker:size=12,fg=#000000,bg=#FFFFFF", false},
+		{"unknown kind", "waves", true},
+		{"missing value", "dots:size", true},
+		{"unknown parameter", "dots:angle=45", true},
+		{"size out of range", "checker:size=0", true},
+		{"bad color", "checker:fg=red", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBackgroundPattern(tt.spec)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseBackgroundGradient(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		stops     int
+		radial    bool
+		angle     float64
+		expectErr bool
+	}{
+		{"default angle", "#000000..#FFFFFF", 2, false, 180, false},
+		{"angle", "#4F46E5..#9333EA@45deg", 2, false, 45, false},
+		{"three stops", "#F00..#0F0..#00F@90deg", 3, false, 90, false},
+		{"radial", "#FFFFFF..#4F46E5@radial", 2, true, 180, false},
+		{"one color", "#FFFFFF", 0, false, 0, true},
+		{"bad color", "#FFFFFF..blue", 0, false, 0, true},
+		{"angle without unit", "#000..#FFF@45", 0, false, 0, true},
+		{"unknown shape", "#000..#FFF@conic", 0, false, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gradient, err := parseBackgroundGradient(tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(gradient.Stops) != tt.stops || gradient.Radial != tt.radial || gradient.Angle != tt.angle {
+				t.Errorf("Expected %d stops, radial %v, angle %v, got %+v", tt.stops, tt.radial, tt.angle, gradient)
+			}
+		})
+	}
+}
+
+func TestRenderGradient(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+
+	// 90deg runs left to right, reaching the end colors at the edges
+	linear := renderGradient(backgroundGradient{Stops: []color.RGBA{black, white}, Angle: 90}, 100)
+	if left, right := linear.RGBAAt(0, 50), linear.RGBAAt(99, 50); left.R > 5 || right.R < 250 {
+		t.Errorf("Expected black on the left and white on the right, got %v and %v", left, right)
+	}
+	// Dithering only moves a column's pixels by a level
+	if top, bottom := linear.RGBAAt(50, 0), linear.RGBAAt(50, 99); math.Abs(float64(top.R)-float64(bottom.R)) > 1 {
+		t.Errorf("Expected columns of a horizontal gradient to be uniform, got %v and %v", top, bottom)
+	}
+
+	// 45deg ends exactly in the corners
+	diagonal := renderGradient(backgroundGradient{Stops: []color.RGBA{black, white}, Angle: 45}, 100)
+	if start, end := diagonal.RGBAAt(0, 99), diagonal.RGBAAt(99, 0); start.R > 5 || end.R < 250 {
+		t.Errorf("Expected black in the bottom left and white in the top right, got %v and %v", start, end)
+	}
+
+	radial := renderGradient(backgroundGradient{Stops: []color.RGBA{white, black}, Radial: true}, 100)
+	if center, corner := radial.RGBAAt(50, 50), radial.RGBAAt(0, 0); center.R < 250 || corner.R > 5 {
+		t.Errorf("Expected white in the center and black in the corner, got %v and %v", center, corner)
+	}
+}
+
+// longestFlatRun returns the length of the longest run of equal pixels on
+// row y of img.
+func longestFlatRun(img image.Image, y int) int {
+	longest, run := 1, 1
+	for x := img.Bounds().Min.X + 1; x < img.Bounds().Max.X; x++ {
+		if img.At(x, y) == img.At(x-1, y) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func TestGradientDithering(t *testing.T) {
+	// 16 levels over 1024px would band into 64px wide stripes if rounded
+	gradient := backgroundGradient{Stops: []color.RGBA{{0, 0, 0, 255}, {16, 16, 16, 255}}, Angle: 90, Dither: "ordered"}
+	const bandWidth = 1024 / 16
+
+	if longest := longestFlatRun(renderGradient(gradient, 1024), 512); longest > bandWidth/2 {
+		t.Errorf("Expected no flat runs near the %dpx band width, got %dpx", bandWidth, longest)
+	}
+}
+
+func TestGradientDitherDefault(t *testing.T) {
+	// The artwork is a small square in the middle, so the top rows are all
+	// gradient
+	source := createTestImageWithSquare(64, 8, color.RGBA{255, 0, 0, 255})
+	config := Config{TrimPercent: 80, BackgroundGradient: "#000000..#101010@90deg"}
+	const bandWidth = 1024 / 16
+
+	icon := prepareIcon(source, config, backgroundPattern{}, nil, nil, 1024)
+	if longest := longestFlatRun(icon, 16); longest > bandWidth/2 {
+		t.Errorf("Expected the gradient dithered by default, got a %dpx flat run", longest)
+	}
+
+	config.Dither = "none"
+	icon = prepareIcon(source, config, backgroundPattern{}, nil, nil, 1024)
+	if longest := longestFlatRun(icon, 16); longest < bandWidth-1 {
+		t.Errorf("Expected --dither=none to keep the %dpx bands, got at most %dpx", bandWidth, longest)
+	}
+}
+
+func TestBackgroundsExclusive(t *testing.T) {
+	for _, config := range []Config{
+		{Background: "#FFF", BackgroundGradient: "#000..#FFF"},
+		{BackgroundPattern: "dots", BackgroundGradient: "#000..#FFF"},
+	} {
+		config.TrimPercent = 80
+		if err := validateOptions(config); err == nil {
+			t.Errorf("Expected combined backgrounds %+v to fail", config)
+		}
+	}
+}
+
+func TestRenderPattern(t *testing.T) {
+	fg := color.RGBA{0, 0, 0, 255}
+	bg := color.RGBA{255, 255, 255, 255}
+
+	for _, kind := range []string{"dots", "stripes", "checker"} {
+		t.Run(kind, func(t *testing.T) {
+			pattern := backgroundPattern{Kind: kind, CellPercent: 10, Foreground: fg, Background: bg}
+			canvas := renderPattern(pattern, 100)
+
+			if canvas.Bounds().Dx() != 100 || canvas.Bounds().Dy() != 100 {
+				t.Fatalf("Expected 100x100 canvas, got %v", canvas.Bounds())
+			}
+
+			// Every pattern must use both colors and be fully opaque
+			var sawFg, sawBg bool
+			for y := 0; y < 100; y++ {
+				for x := 0; x < 100; x++ {
+					c := canvas.RGBAAt(x, y)
+					if c.A != 255 {
+						t.Fatalf("Expected opaque pattern, got alpha %d at (%d, %d)", c.A, x, y)
+					}
+					sawFg = sawFg || c == fg
+					sawBg = sawBg || c == bg
+				}
+			}
+			if !sawFg || !sawBg {
+				t.Errorf("Expected both pattern colors to appear (fg=%v, bg=%v)", sawFg, sawBg)
+			}
+		})
+	}
+}
+
+func TestSolidBackground(t *testing.T) {
+	artwork := createTestImageWithSquare(50, 10, color.RGBA{255, 0, 0, 255})
+	config := Config{Background: "#0000FF"}
+
+	result := prepareIcon(artwork, config, backgroundPattern{}, nil, nil, 50)
+
+	if c := color.RGBAModel.Convert(result.At(25, 25)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected artwork on top, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(result.At(0, 0)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the background color outside the artwork, got %v", c)
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, Background: "#FFF", BackgroundPattern: "checker"}); err == nil {
+		t.Errorf("Expected --background with --background-pattern to fail")
+	}
+	if err := validateOptions(Config{TrimPercent: 80, Background: "#blue"}); err == nil {
+		t.Errorf("Expected an invalid --background color to fail")
+	}
+}
+
+func TestAddBackgroundKeepsArtwork(t *testing.T) {
+	artwork := createTestImageWithSquare(50, 10, color.RGBA{255, 0, 0, 255})
+	pattern := backgroundPattern{Kind: "checker", CellPercent: 10, Foreground: color.RGBA{0, 0, 0, 255}, Background: color.RGBA{255, 255, 255, 255}}
+
+	result := addBackground(artwork, renderPattern(pattern, 50))
+
+	if c := color.RGBAModel.Convert(result.At(25, 25)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected artwork on top, got %v", c)
+	}
+	if _, _, _, a := result.At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("Expected opaque background outside the artwork, got alpha %#x", a)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// badgeMaxLength caps --badge labels so they fit across the ribbon.
+const badgeMaxLength = 12
+
+// badgeCorners maps every --badge-position to the start of the ribbon's
+// center line on a unit canvas and the direction the ribbon runs in, for a
+// center line cutting off cut of each edge. The label reads along it.
+var badgeCorners = map[string]func(cut float64) (start, dir [2]float64){
+	"top-left":     func(cut float64) ([2]float64, [2]float64) { return [2]float64{0, cut}, [2]float64{1, -1} },
+	"top-right":    func(cut float64) ([2]float64, [2]float64) { return [2]float64{1 - cut, 0}, [2]float64{1, 1} },
+	"bottom-left":  func(cut float64) ([2]float64, [2]float64) { return [2]float64{0, 1 - cut}, [2]float64{1, 1} },
+	"bottom-right": func(cut float64) ([2]float64, [2]float64) { return [2]float64{1 - cut, 1}, [2]float64{1, -1} },
+}
+
+// validateBadge checks the --badge label, color and position of config.
+func validateBadge(config Config) error {
+	if len(config.Badge) > badgeMaxLength {
+		return fmt.Errorf("badge %q is too long (at most %d characters)", config.Badge, badgeMaxLength)
+	}
+	if !supportedText(config.Badge) {
+		return fmt.Errorf("badge %q has characters the built-in font can't draw", config.Badge)
+	}
+	if _, err := parseHexColor(config.BadgeColor); err != nil {
+		return fmt.Errorf("invalid badge color: %w", err)
+	}
+	if _, ok := badgeCorners[config.BadgePosition]; !ok {
+		return fmt.Errorf("unknown badge position %q (expected top-left, top-right, bottom-left or bottom-right)", config.BadgePosition)
+	}
+	return nil
+}
+
+// addBadge lays the --badge ribbon across a corner of img. It runs edge to
+// edge, over transparent margins too, so it reads on any artwork.
+func addBadge(img image.Image, config Config, size int) image.Image {
+	if config.Badge == "" {
+		return img
+	}
+	ribbonColor, err := parseHexColor(config.BadgeColor)
+	if err != nil {
+		return img
+	}
+	corner, ok := badgeCorners[config.BadgePosition]
+	if !ok {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	ribbon := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	// The ribbon's center line cuts 30% off both edges; it is 12% thick
+	s := float64(size)
+	cut, thickness := 0.30, 0.12*s
+	start, dir := corner(cut)
+	start[0], start[1] = start[0]*s, start[1]*s
+	dir[0], dir[1] = dir[0]/math.Sqrt2, dir[1]/math.Sqrt2
+	// Across the ribbon, from its top edge to its bottom edge
+	across := [2]float64{-dir[1], dir[0]}
+	length := cut * s * math.Sqrt2
+
+	label := badgeLabel(config.Badge, thickness, length)
+	labelBounds := label.Bounds()
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			px, py := float64(x)+0.5-start[0], float64(y)+0.5-start[1]
+			along := px*dir[0] + py*dir[1]
+			offset := px*across[0] + py*across[1]
+
+			coverage := math.Max(0, math.Min(1, thickness/2-math.Abs(offset)+0.5))
+			if coverage == 0 {
+				continue
+			}
+
+			// Sample the label, centered on the ribbon
+			lx := along - length/2 + float64(labelBounds.Dx())/2
+			ly := offset + float64(labelBounds.Dy())/2
+			ink := sampleAlpha(label, lx-0.5, ly-0.5)
+
+			c := ribbonColor
+			mix := func(v uint8) uint8 { return uint8(math.Round(float64(v) + (255-float64(v))*ink)) }
+			c.R, c.G, c.B = mix(c.R), mix(c.G), mix(c.B)
+			ribbon.SetNRGBA(x, y, color.NRGBA{c.R, c.G, c.B, uint8(math.Round(float64(c.A) * coverage))})
+		}
+	}
+
+	badged := toRGBA(img)
+	draw.Draw(badged, badged.Bounds(), ribbon, image.Point{}, draw.Over)
+	return badged
+}
+
+// badgeLabel renders text in white on a transparent strip, as large as fits
+// within the ribbon's thickness and the length of its shorter edge.
+func badgeLabel(text string, thickness, length float64) *image.RGBA {
+	text = strings.ToUpper(text)
+	height := thickness * 0.55
+	// The label's top corners reach towards the ribbon's shorter edge
+	fits := (length - height*math.Sqrt2) * 0.85
+	if width := measureText(text, height); width > fits && width > 0 {
+		height *= fits / width
+	}
+	width := measureText(text, height)
+
+	label := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(width))+2, int(math.Ceil(height))+2))
+	drawText(label, text, 1, 1, height, color.RGBA{255, 255, 255, 255})
+	return label
+}
+
+// sampleAlpha returns the alpha of img at (x, y), bilinearly interpolated,
+// as a fraction. Outside img it is 0.
+func sampleAlpha(img *image.RGBA, x, y float64) float64 {
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	at := func(x, y int) float64 {
+		if !(image.Point{x, y}.In(img.Bounds())) {
+			return 0
+		}
+		return float64(img.RGBAAt(x, y).A) / 255
+	}
+	top := at(x0, y0)*(1-fx) + at(x0+1, y0)*fx
+	bottom := at(x0, y0+1)*(1-fx) + at(x0+1, y0+1)*fx
+	return top*(1-fy) + bottom*fy
+}
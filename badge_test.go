@@ -0,0 +1,65 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestValidateBadge(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"beta", Config{Badge: "BETA", BadgeColor: "#FF3B30", BadgePosition: "top-right"}, false},
+		{"lowercase", Config{Badge: "nightly", BadgeColor: "#5856D6", BadgePosition: "bottom-left"}, false},
+		{"too long", Config{Badge: "RELEASE CANDIDATE", BadgeColor: "#FF3B30", BadgePosition: "top-right"}, true},
+		{"unsupported character", Config{Badge: "β", BadgeColor: "#FF3B30", BadgePosition: "top-right"}, true},
+		{"bad color", Config{Badge: "DEV", BadgeColor: "red", BadgePosition: "top-right"}, true},
+		{"unknown position", Config{Badge: "DEV", BadgeColor: "#FF3B30", BadgePosition: "center"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBadge(tt.config)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddBadge(t *testing.T) {
+	icon := createTestImage(200, color.RGBA{0, 0, 255, 255})
+	config := Config{Badge: "DEV", BadgeColor: "#FF0000", BadgePosition: "top-right"}
+
+	badged := addBadge(icon, config, 200)
+
+	// The ribbon's center line cuts the top edge at 70%, where it has no label
+	if c := color.RGBAModel.Convert(badged.At(141, 1)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the ribbon color near the top edge, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(badged.At(10, 190)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the opposite corner untouched, got %v", c)
+	}
+
+	// The label is drawn in white across the middle of the ribbon
+	white := 0
+	for i := 0; i < 60; i++ {
+		if c := color.RGBAModel.Convert(badged.At(140+i, i)).(color.RGBA); c.G > 200 {
+			white++
+		}
+	}
+	if white == 0 {
+		t.Errorf("Expected the label along the ribbon")
+	}
+
+	// Transparent margins get the ribbon too
+	transparent := createTestImage(200, color.RGBA{})
+	if _, _, _, a := addBadge(transparent, config, 200).At(141, 1).RGBA(); a != 0xffff {
+		t.Errorf("Expected an opaque ribbon over a transparent corner, got alpha %#x", a)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// badgeCountMax is the largest --badge-count; larger numbers don't fit
+// a badge on the smallest icons.
+const badgeCountMax = 9999
+
+// badgeCountColor is the red of notification badges.
+var badgeCountColor = color.RGBA{255, 59, 48, 255}
+
+// validateBadgeCount checks a --badge-count.
+func validateBadgeCount(count int) error {
+	if count < 1 || count > badgeCountMax {
+		return fmt.Errorf("badge count must be between 1 and %d (got %d)", badgeCountMax, count)
+	}
+	return nil
+}
+
+// badgeCountIconName returns the file name of the copy of name with the
+// count badge.
+func badgeCountIconName(name string, count int) string {
+	return strings.TrimSuffix(name, ".png") + "_badge" + strconv.Itoa(count) + ".png"
+}
+
+// badgeCountTargets lists the outputs the --badge-count copies of config
+// produce.
+func badgeCountTargets(config Config) []plannedOutput {
+	if config.BadgeCount == 0 {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, iconSize := range outputSizes(config) {
+		target := iconTarget(config, iconSize, badgeCountIconName(iconSize.Name, config.BadgeCount), "badge-count")
+		target.Settings["badge-count"] = strconv.Itoa(config.BadgeCount)
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// generateBadgeCount writes a copy of every regular icon with a notification
+// badge showing the --badge-count in the top-right corner.
+func generateBadgeCount(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, state *manifestState) error {
+	for _, iconSize := range outputSizes(config) {
+		iconSize := iconSize
+		name := badgeCountIconName(iconSize.Name, config.BadgeCount)
+		desc := fmt.Sprintf("%dx%d, badge %d", iconSize.Size, iconSize.Size, config.BadgeCount)
+
+		err := saveOutput(config, state, name, desc, func() image.Image {
+			icon := finishIcon(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size), config, iconSize, nil)
+			return addBadgeCount(icon, config.BadgeCount)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addBadgeCount returns a copy of img with a red badge showing count in its
+// top-right corner. The badge is a circle 36% of the icon across, which
+// stretches into a pill for numbers of several digits. Below a legible
+// size the number is left out and only the dot remains.
+func addBadgeCount(img image.Image, count int) image.Image {
+	bounds := img.Bounds()
+	size := float64(bounds.Dx())
+	label := strconv.Itoa(count)
+
+	diameter := size * 0.36
+	textHeight := diameter * 0.5
+	textWidth := measureText(label, textHeight)
+	// The pill is as long as the number plus the round ends
+	length := math.Min(math.Max(diameter, textWidth+diameter*0.6), size)
+	radius := diameter / 2
+
+	// The centers of the pill's round ends, flush with the top-right corner
+	right := [2]float64{size - radius, radius}
+	left := [2]float64{size - length + radius, radius}
+
+	badged := toRGBA(img)
+	for y := 0; y < int(math.Ceil(diameter)); y++ {
+		for x := int(math.Floor(size - length)); x < bounds.Dx(); x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			// Distance to the pill's center line
+			cx := math.Max(left[0], math.Min(right[0], px))
+			distance := math.Hypot(px-cx, py-right[1])
+			coverage := math.Max(0, math.Min(1, radius-distance+0.5))
+			if coverage > 0 {
+				blendPixel(badged, x, y, badgeCountColor, coverage)
+			}
+		}
+	}
+
+	if textHeight >= glyphHeight/2 {
+		x := (left[0]+right[0])/2 - textWidth/2
+		y := radius - textHeight/2
+		drawText(badged, label, x, y, textHeight, color.RGBA{255, 255, 255, 255})
+	}
+	return badged
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateBadgeCount(t *testing.T) {
+	tests := []struct {
+		count     int
+		expectErr bool
+	}{
+		{1, false},
+		{99, false},
+		{badgeCountMax, false},
+		{-1, true},
+		{badgeCountMax + 1, true},
+	}
+
+	for _, tt := range tests {
+		err := validateBadgeCount(tt.count)
+		if tt.expectErr && err == nil {
+			t.Errorf("Expected error for %d but got none", tt.count)
+		}
+		if !tt.expectErr && err != nil {
+			t.Errorf("Expected no error for %d but got: %v", tt.count, err)
+		}
+	}
+}
+
+func TestAddBadgeCount(t *testing.T) {
+	icon := createTestImage(100, color.RGBA{0, 0, 255, 255})
+
+	// A single digit sits in a circle 36 pixels across in the corner
+	badged := addBadgeCount(icon, 3)
+	if c := color.RGBAModel.Convert(badged.At(70, 18)).(color.RGBA); c != badgeCountColor {
+		t.Errorf("Expected the badge color left of the number, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(badged.At(80, 10)).(color.RGBA); c.G < 200 {
+		t.Errorf("Expected the white number on the badge, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(badged.At(50, 18)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the artwork left of the circle, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(badged.At(65, 2)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the circle's rounded corner to show the artwork, got %v", c)
+	}
+
+	// More digits stretch the circle into a pill
+	if c := color.RGBAModel.Convert(addBadgeCount(icon, 1234).At(50, 18)).(color.RGBA); c != badgeCountColor {
+		t.Errorf("Expected a wider badge for four digits, got %v", c)
+	}
+}
+
+func TestBadgeCountOutputs(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 200, 80, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, BadgeCount: 3}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range iconSizes {
+		name := badgeCountIconName(iconSize.Name, 3)
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	if targets := badgeCountTargets(config); len(targets) != len(iconSizes) {
+		t.Errorf("Expected %d planned badge copies, got %d", len(iconSizes), len(targets))
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// stageTimes sums how long each stage of the pipeline takes during one
+// icongen bench run. Outputs are encoded in the background, so stages are
+// added to from several goroutines.
+type stageTimes struct {
+	mu    sync.Mutex
+	order []string
+	times map[string]time.Duration
+	calls map[string]int
+}
+
+// benchStages collects the stage timings of the current bench run, and is
+// nil outside of icongen bench, when timeStage does nothing.
+var benchStages *stageTimes
+
+func newStageTimes() *stageTimes {
+	return &stageTimes{times: make(map[string]time.Duration), calls: make(map[string]int)}
+}
+
+func (s *stageTimes) add(stage string, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.calls[stage]; !ok {
+		s.order = append(s.order, stage)
+	}
+	s.times[stage] += elapsed
+	s.calls[stage]++
+}
+
+// timeStage starts timing a stage of the pipeline for icongen bench and
+// returns the function that stops it.
+func timeStage(stage string) func() {
+	stages := benchStages
+	if stages == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { stages.add(stage, time.Since(start)) }
+}
+
+// benchStageOrder is the order the report lists stages in, that of the
+// pipeline, rather than the order they first finished in.
+var benchStageOrder = []string{"decode", "crop", "mipmaps", "resize", "mask", "encode"}
+
+// stageSummary is the timing of one stage over every bench run, per run.
+type stageSummary struct {
+	Stage  string  `json:"stage"`
+	Calls  int     `json:"calls"`
+	MinMS  float64 `json:"min_ms"`
+	MeanMS float64 `json:"mean_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// runBench implements "icongen bench": it generates the icons of the
+// source the given number of times into a temporary directory and reports
+// how long each stage took per run.
+func runBench(args []string) error {
+	var config Config
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	defineFlags(flags, &config)
+	var runs int
+	var asJSON bool
+	flags.IntVar(&runs, "runs", 5, "How many times to run the whole pipeline")
+	flags.BoolVar(&asJSON, "json", false, "Print the timings as JSON instead of a table")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s bench [--runs 5] [--json] [options] source.png\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Generate the icons of the source several times and report how long each stage takes per run:\n")
+		fmt.Fprintf(flags.Output(), "decoding, cropping, resizing to each size, masking and encoding. Generation options are passed through.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("bench requires a source image")
+	}
+	if runs < 1 {
+		return fmt.Errorf("runs must be at least 1 (got %d)", runs)
+	}
+	config.InputPath = flags.Arg(0)
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	applyMemoryLimit(config)
+
+	summaries, err := benchIcons(config, runs)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Runs   int            `json:"runs"`
+			Stages []stageSummary `json:"stages"`
+		}{runs, summaries})
+	}
+	return printStageSummaries(os.Stdout, summaries)
+}
+
+// benchIcons generates the icons of config runs times, each into a fresh
+// temporary directory without a manifest, and summarizes the stage timings
+// of the runs. The progress output and warnings of the runs are discarded.
+func benchIcons(config Config, runs int) ([]stageSummary, error) {
+	config.Stateless = true
+	config.Log = io.Discard
+	var results []*stageTimes
+	var totals []time.Duration
+	for i := 0; i < runs; i++ {
+		dir, err := os.MkdirTemp("", "icongen-bench-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bench directory: %w", err)
+		}
+		runConfig := config
+		runConfig.OutputDir = dir
+
+		stages := newStageTimes()
+		benchStages = stages
+		start := time.Now()
+		err = generateConfig(runConfig)
+		elapsed := time.Since(start)
+		benchStages = nil
+		os.RemoveAll(dir)
+		if err != nil {
+			return nil, fmt.Errorf("run %d: %w", i+1, err)
+		}
+		results = append(results, stages)
+		totals = append(totals, elapsed)
+	}
+
+	total := stageSummary{Stage: "total", Calls: 1}
+	summaries := append(summarizeStages(results), total)
+	setTimings(&summaries[len(summaries)-1], totals)
+	return summaries, nil
+}
+
+// summarizeStages returns the timing per run of every stage of results, in
+// benchStageOrder.
+func summarizeStages(results []*stageTimes) []stageSummary {
+	var order []string
+	seen := make(map[string]bool)
+	for _, stages := range results {
+		for _, stage := range stages.order {
+			if !seen[stage] {
+				seen[stage] = true
+				order = append(order, stage)
+			}
+		}
+	}
+
+	rank := func(stage string) int {
+		name := strings.Fields(stage)[0]
+		for i, s := range benchStageOrder {
+			if s == name {
+				return i
+			}
+		}
+		return len(benchStageOrder)
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rank(order[i]) < rank(order[j]) })
+
+	summaries := make([]stageSummary, len(order))
+	for i, stage := range order {
+		times := make([]time.Duration, len(results))
+		calls := 0
+		for j, stages := range results {
+			times[j] = stages.times[stage]
+			calls += stages.calls[stage]
+		}
+		summaries[i] = stageSummary{Stage: stage, Calls: calls / len(results)}
+		setTimings(&summaries[i], times)
+	}
+	return summaries
+}
+
+// setTimings sets the minimum, mean and maximum of times on summary.
+func setTimings(summary *stageSummary, times []time.Duration) {
+	lowest, highest, sum := times[0], times[0], time.Duration(0)
+	for _, t := range times {
+		if t < lowest {
+			lowest = t
+		}
+		if t > highest {
+			highest = t
+		}
+		sum += t
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	summary.MinMS, summary.MeanMS, summary.MaxMS = ms(lowest), ms(sum/time.Duration(len(times))), ms(highest)
+}
+
+// printStageSummaries writes summaries to w as a table.
+func printStageSummaries(w io.Writer, summaries []stageSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tCALLS\tMIN\tMEAN\tMAX")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%.1fms\t%.1fms\t%.1fms\n", s.Stage, s.Calls, s.MinMS, s.MeanMS, s.MaxMS)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchIcons(t *testing.T) {
+	config := Config{
+		InputPath:     createTempImageFile(t, createTestImage(256, color.RGBA{255, 128, 0, 255})),
+		TrimPercent:   80,
+		CropEnabled:   true,
+		RadiusPercent: 20,
+	}
+
+	summaries, err := benchIcons(config, 2)
+	if err != nil {
+		t.Fatalf("Bench failed: %v", err)
+	}
+	if benchStages != nil {
+		t.Errorf("Expected stage timing switched off after the bench")
+	}
+
+	stages := make(map[string]stageSummary)
+	var order []string
+	for _, s := range summaries {
+		stages[s.Stage] = s
+		order = append(order, strings.Fields(s.Stage)[0])
+		if s.MinMS > s.MeanMS || s.MeanMS > s.MaxMS {
+			t.Errorf("Expected min <= mean <= max for %s, got %+v", s.Stage, s)
+		}
+	}
+	for _, stage := range []string{"decode", "crop", "mask", "encode", "total"} {
+		if _, ok := stages[stage]; !ok {
+			t.Errorf("Expected a %s stage, got %v", stage, order)
+		}
+	}
+	if s, ok := stages["resize 1024px"]; !ok || s.Calls < 1 {
+		t.Errorf("Expected the 1024px resize timed once per run, got %+v", s)
+	}
+	if order[0] != "decode" || order[len(order)-1] != "total" {
+		t.Errorf("Expected stages in pipeline order, got %v", order)
+	}
+}
+
+func TestBenchIconsQuiet(t *testing.T) {
+	// A wide source makes every run warn, which must not reach the terminal
+	wide := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	config := Config{InputPath: createTempImageFile(t, wide), CropEnabled: true, TrimPercent: 80}
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, out
+	_, err = benchIcons(config, 1)
+	os.Stdout, os.Stderr = stdout, stderr
+	if err != nil {
+		t.Fatalf("Bench failed: %v", err)
+	}
+
+	if info, err := out.Stat(); err != nil || info.Size() != 0 {
+		data, _ := os.ReadFile(out.Name())
+		t.Errorf("Expected the runs to print nothing, got %q", data)
+	}
+}
+
+func TestSummarizeStages(t *testing.T) {
+	a, b := newStageTimes(), newStageTimes()
+	a.add("encode", 4*time.Millisecond)
+	a.add("encode", 2*time.Millisecond)
+	a.add("decode", time.Millisecond)
+	b.add("decode", 3*time.Millisecond)
+	b.add("encode", 2*time.Millisecond)
+
+	summaries := summarizeStages([]*stageTimes{a, b})
+	want := []stageSummary{
+		{Stage: "decode", Calls: 1, MinMS: 1, MeanMS: 2, MaxMS: 3},
+		{Stage: "encode", Calls: 1, MinMS: 2, MeanMS: 4, MaxMS: 6},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("Expected %d stages, got %+v", len(want), summaries)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], summaries[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := printStageSummaries(&buf, summaries); err != nil {
+		t.Fatalf("Failed to print stages: %v", err)
+	}
+	if !strings.Contains(buf.String(), "encode  1      2.0ms  4.0ms  6.0ms") {
+		t.Errorf("Expected an encode row in the table, got:\n%s", buf.String())
+	}
+}
+
+func TestRunBenchErrors(t *testing.T) {
+	input := createTempImageFile(t, createTestImage(64, color.RGBA{0, 0, 255, 255}))
+	if err := runBench([]string{"--runs", "0", input}); err == nil {
+		t.Errorf("Expected an error for --runs 0")
+	}
+	if err := runBench(nil); err == nil {
+		t.Errorf("Expected an error without a source image")
+	}
+}
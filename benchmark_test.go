@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
 	"testing"
 )
 
@@ -42,6 +44,46 @@ func BenchmarkResizeImage(b *testing.B) {
 	}
 }
 
+// BenchmarkResizePixelAccess compares the resize of the image types read
+// straight from their Pix slices with reading through At.
+func BenchmarkResizePixelAccess(b *testing.B) {
+	rgba := createTestImage(1024, color.RGBA{255, 128, 64, 255}).(*image.RGBA)
+	nrgba := image.NewNRGBA(rgba.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), rgba, image.Point{}, draw.Src)
+
+	sources := []struct {
+		name string
+		img  image.Image
+	}{
+		{"rgba", rgba},
+		{"nrgba", nrgba},
+		{"at", opaqueImage{rgba}},
+	}
+
+	for _, source := range sources {
+		b.Run("bilinear_1024_to_512_"+source.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = resizeImage(source.img, 512)
+			}
+		})
+		b.Run("catmullrom_1024_to_64_"+source.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = resizeFiltered(source.img, 64, catmullRomFilter)
+			}
+		})
+	}
+}
+
+func BenchmarkPad(b *testing.B) {
+	testImg := createTestImage(1024, color.RGBA{255, 128, 64, 255})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = addPadding(testImg, 10, 1024)
+	}
+}
+
 func BenchmarkAddRoundedCorners(b *testing.B) {
 	testImg := createTestImage(512, color.RGBA{255, 128, 64, 255})
 
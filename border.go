@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// borderWidth is a parsed --border-width: a width in pixels at every size,
+// or a percentage of the icon size.
+type borderWidth struct {
+	Value   float64
+	Percent bool
+}
+
+// parseBorderWidth parses a --border-width spec such as "1", "1.5px" or "2%".
+func parseBorderWidth(spec string) (borderWidth, error) {
+	var w borderWidth
+	text := strings.TrimSuffix(spec, "px")
+	if strings.HasSuffix(spec, "%") {
+		text = strings.TrimSuffix(spec, "%")
+		w.Percent = true
+	}
+
+	var err error
+	w.Value, err = strconv.ParseFloat(text, 64)
+	if err != nil || w.Value < 0 || math.IsInf(w.Value, 0) || (w.Percent && w.Value > 50) {
+		return borderWidth{}, fmt.Errorf("invalid border width %q (expected pixels such as 1.5px, or a percentage of the size up to 50%%)", spec)
+	}
+	return w, nil
+}
+
+// resolve returns the width in pixels at size.
+func (w borderWidth) resolve(size int) float64 {
+	if w.Percent {
+		return float64(size) * w.Value / 100
+	}
+	return w.Value
+}
+
+// addBorder strokes the inside of the icon's outline with the configured
+// --border-width and --border-color. The outline is that of shape applied to
+// a full square, so the stroke follows rounded, circle and image masks; a nil
+// shape strokes the square edge. The inner edge of the stroke is anti-aliased
+// by its distance from the outline, the outer edge by the shape's own alpha.
+func addBorder(img image.Image, config Config, size int, shape func(img image.Image, size int) image.Image) image.Image {
+	if config.BorderWidth == "" {
+		return img
+	}
+	spec, err := parseBorderWidth(config.BorderWidth)
+	if err != nil {
+		return img
+	}
+	width := spec.resolve(size)
+	if width <= 0 {
+		return img
+	}
+	borderColor, err := parseHexColor(config.BorderColor)
+	if err != nil {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	var outline image.Image = image.NewUniform(color.Opaque)
+	if shape != nil {
+		square := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(square, square.Bounds(), image.NewUniform(color.Opaque), image.Point{}, draw.Src)
+		outline = shape(square, size)
+	}
+
+	// Distances are measured on a grid with a ring of outside pixels, so the
+	// image edge counts as part of the outline
+	inside := make([]bool, (w+2)*(h+2))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, a := outline.At(x, y).RGBA()
+			inside[(y+1)*(w+2)+x+1] = a >= 0x8000
+		}
+	}
+	dist := distanceToOutside(inside, w+2, h+2)
+
+	bordered := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(bordered, bordered.Bounds(), img, bounds.Min, draw.Src)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, shapeAlpha := outline.At(x, y).RGBA()
+			if shapeAlpha == 0 {
+				continue
+			}
+
+			coverage := 1.0
+			if d := dist[(y+1)*(w+2)+x+1]; d > 0 {
+				// The outline runs half a pixel in from the nearest outside
+				// pixel's centre
+				coverage = math.Max(0, math.Min(1, width-d+1))
+			}
+			coverage *= float64(shapeAlpha) / 0xffff
+			if coverage == 0 {
+				continue
+			}
+
+			// Composite the stroke over the artwork
+			dst := bordered.RGBAAt(x, y)
+			srcA := float64(borderColor.A) * coverage
+			blend := func(src, dst uint8) uint8 {
+				return uint8(math.Round(float64(src)*coverage + float64(dst)*(1-srcA/255)))
+			}
+			bordered.SetRGBA(x, y, color.RGBA{
+				R: blend(borderColor.R, dst.R),
+				G: blend(borderColor.G, dst.G),
+				B: blend(borderColor.B, dst.B),
+				A: blend(borderColor.A, dst.A),
+			})
+		}
+	}
+
+	return bordered
+}
+
+// distanceToOutside returns, for every cell of a w×h grid, the Euclidean
+// distance from its centre to the centre of the nearest cell that isn't
+// inside, or 0 for cells outside. It uses the linear-time separable
+// transform of Felzenszwalb and Huttenlocher on squared distances.
+func distanceToOutside(inside []bool, w, h int) []float64 {
+	const inf = 1e20
+
+	f := make([]float64, w*h)
+	for i, in := range inside {
+		if in {
+			f[i] = inf
+		}
+	}
+
+	n := w
+	if h > n {
+		n = h
+	}
+	line := make([]float64, n)
+	out := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+
+	// Columns, then rows
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			line[y] = f[y*w+x]
+		}
+		distanceTransform1D(line[:h], out[:h], v, z)
+		for y := 0; y < h; y++ {
+			f[y*w+x] = out[y]
+		}
+	}
+	for y := 0; y < h; y++ {
+		copy(line[:w], f[y*w:(y+1)*w])
+		distanceTransform1D(line[:w], out[:w], v, z)
+		for x := 0; x < w; x++ {
+			f[y*w+x] = math.Sqrt(out[x])
+		}
+	}
+	return f
+}
+
+// distanceTransform1D computes the squared distance transform of f into d,
+// using v and z as scratch space.
+func distanceTransform1D(f, d []float64, v []int, z []float64) {
+	n := len(f)
+	k := 0
+	v[0] = 0
+	z[0] = math.Inf(-1)
+	z[1] = math.Inf(1)
+	parabola := func(q, p int) float64 {
+		return ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+	}
+	for q := 1; q < n; q++ {
+		s := parabola(q, v[k])
+		for s <= z[k] {
+			k--
+			s = parabola(q, v[k])
+		}
+		k++
+		v[k] = q
+		z[k] = s
+		z[k+1] = math.Inf(1)
+	}
+
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := v[k]
+		d[q] = float64((q-p)*(q-p)) + f[p]
+	}
+}
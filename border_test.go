@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestParseBorderWidth(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    float64
+		percent bool
+		wantErr bool
+	}{
+		{"1", 1, false, false},
+		{"1.5px", 1.5, false, false},
+		{"2%", 2, true, false},
+		{"0", 0, false, false},
+		{"-1", 0, false, true},
+		{"60%", 0, false, true},
+		{"thick", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			w, err := parseBorderWidth(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if w.Value != tt.want || w.Percent != tt.percent {
+				t.Errorf("Expected %g (percent %v), got %g (percent %v)", tt.want, tt.percent, w.Value, w.Percent)
+			}
+		})
+	}
+
+	if got := (borderWidth{Value: 2, Percent: true}).resolve(512); got != 10.24 {
+		t.Errorf("Expected 2%% of 512 to be 10.24 pixels, got %g", got)
+	}
+}
+
+func TestDistanceToOutside(t *testing.T) {
+	// A 7x7 grid with a 5x5 inside block
+	const n = 7
+	inside := make([]bool, n*n)
+	for y := 1; y < n-1; y++ {
+		for x := 1; x < n-1; x++ {
+			inside[y*n+x] = true
+		}
+	}
+	dist := distanceToOutside(inside, n, n)
+
+	tests := []struct {
+		x, y int
+		want float64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{1, 3, 1},
+		{2, 2, 2},
+		{3, 3, 3},
+	}
+	for _, tt := range tests {
+		if got := dist[tt.y*n+tt.x]; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Expected distance %g at (%d,%d), got %g", tt.want, tt.x, tt.y, got)
+		}
+	}
+}
+
+func TestAddBorder(t *testing.T) {
+	img := createTestImage(64, color.RGBA{0, 0, 255, 255})
+	red := color.RGBA{255, 0, 0, 255}
+
+	tests := []struct {
+		name   string
+		width  string
+		x, y   int
+		want   color.RGBA
+		square bool
+	}{
+		{"edge", "2px", 0, 32, red, true},
+		{"inside the stroke", "2px", 1, 32, red, true},
+		{"past the stroke", "2px", 2, 32, color.RGBA{0, 0, 255, 255}, true},
+		{"half pixel", "1.5px", 1, 32, color.RGBA{128, 0, 128, 255}, true},
+		{"circle edge", "2px", 32, 1, red, false},
+		{"circle corner", "2px", 2, 2, color.RGBA{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{BorderWidth: tt.width, BorderColor: "#FF0000"}
+			bordered := addBorder(img, config, 64, nil)
+			if !tt.square {
+				shape := func(img image.Image, size int) image.Image { return addCircleMask(img) }
+				bordered = addBorder(addCircleMask(img), config, 64, shape)
+			}
+
+			got := color.RGBAModel.Convert(bordered.At(tt.x, tt.y)).(color.RGBA)
+			if got != tt.want {
+				t.Errorf("Expected %v at (%d,%d), got %v", tt.want, tt.x, tt.y, got)
+			}
+		})
+	}
+
+	if got := addBorder(img, Config{}, 64, nil); got != img {
+		t.Errorf("Expected no border without --border-width")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// budgetCalibrationSize is the icon size a --budget run renders once to
+// estimate the cost of the whole run.
+const budgetCalibrationSize = 256
+
+// budgetEffects are the optional effects a --budget run drops, costliest
+// first, until the estimate fits the budget. Everything else, such as masks,
+// backgrounds and text, is content and always kept.
+var budgetEffects = []struct {
+	flag   string
+	active func(config Config, source image.Image) bool
+	drop   func(config *Config)
+}{
+	{
+		"--shadow",
+		func(config Config, source image.Image) bool { return config.Shadow },
+		func(config *Config) { config.Shadow = false },
+	},
+	{
+		"--effects",
+		func(config Config, source image.Image) bool { return config.Effects != "" },
+		func(config *Config) { config.Effects = "" },
+	},
+	{
+		"--long-shadow",
+		func(config Config, source image.Image) bool { return longShadowLength(config) > 0 },
+		func(config *Config) { config.LongShadow, config.LongShadowLength = false, 0 },
+	},
+	{
+		"--fill=blur",
+		func(config Config, source image.Image) bool { return blurFillActive(config, source) },
+		func(config *Config) { config.Fill = "none" },
+	},
+}
+
+// applyBudget returns config without the optional effects a run can't
+// afford within its --budget, warning about each one dropped. The outputs of
+// the reduced options hash differently, so a later run without --budget
+// regenerates them at full quality.
+func applyBudget(config Config) (Config, error) {
+	sourceImg, err := loadSource(config)
+	if err != nil {
+		return config, err
+	}
+	estimate := func(c Config) time.Duration {
+		return estimateRunTime(c, sourceImg)
+	}
+
+	reduced, dropped, estimated := budgetConfig(config, sourceImg, estimate)
+	for _, flag := range dropped {
+		emitWarning(config, warning{Code: "W005", Message: fmt.Sprintf("skipped %s to stay within the %s budget", flag, config.Budget)})
+	}
+	if estimated > config.Budget {
+		fmt.Fprintf(logOutput(config), "Estimated %s, over the %s budget even without optional effects\n", estimated.Round(time.Millisecond), config.Budget)
+	}
+	return reduced, nil
+}
+
+// budgetConfig drops the optional effects of config, costliest first, until
+// estimate fits its --budget. It returns the reduced config, the flags it
+// dropped and the final estimate.
+func budgetConfig(config Config, sourceImg image.Image, estimate func(Config) time.Duration) (Config, []string, time.Duration) {
+	var dropped []string
+	estimated := estimate(config)
+	for _, effect := range budgetEffects {
+		if estimated <= config.Budget {
+			break
+		}
+		if !effect.active(config, sourceImg) {
+			continue
+		}
+		effect.drop(&config)
+		dropped = append(dropped, effect.flag)
+		estimated = estimate(config)
+	}
+	return config, dropped, estimated
+}
+
+// estimateRunTime estimates how long rendering the outputs of config takes
+// by timing the regular icon at budgetCalibrationSize and scaling that by
+// the pixels of every planned image.
+func estimateRunTime(config Config, sourceImg image.Image) time.Duration {
+	var pattern backgroundPattern
+	if config.BackgroundPattern != "" {
+		pattern, _ = parseBackgroundPattern(config.BackgroundPattern)
+	}
+	backgroundImg, _ := loadBackgroundImage(config)
+	layers, _ := loadLayers(config)
+
+	iconSize := IconSize{Name: "calibration", Size: budgetCalibrationSize}
+	start := time.Now()
+	finishIcon(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size), config, iconSize, nil)
+	elapsed := time.Since(start)
+
+	var pixels int64
+	for _, target := range planTargets(config) {
+		if target.Format == "png" {
+			pixels += int64(target.Width) * int64(target.Height)
+		}
+	}
+	return time.Duration(float64(elapsed) * float64(pixels) / (budgetCalibrationSize * budgetCalibrationSize))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBudgetConfig(t *testing.T) {
+	// Each effect costs a fixed amount on top of a 100ms base
+	estimate := func(config Config) time.Duration {
+		cost := 100 * time.Millisecond
+		if config.Shadow {
+			cost += 400 * time.Millisecond
+		}
+		if config.Effects != "" {
+			cost += 200 * time.Millisecond
+		}
+		if config.LongShadow {
+			cost += 100 * time.Millisecond
+		}
+		return cost
+	}
+	full := Config{Shadow: true, Effects: "gloss", LongShadow: true, Fill: "none"}
+
+	tests := []struct {
+		name          string
+		config        Config
+		budget        time.Duration
+		expectDropped []string
+		expectTime    time.Duration
+	}{
+		{"within budget", full, time.Second, nil, 800 * time.Millisecond},
+		{"drops the shadow first", full, 500 * time.Millisecond, []string{"--shadow"}, 400 * time.Millisecond},
+		{"drops until it fits", full, 250 * time.Millisecond, []string{"--shadow", "--effects"}, 200 * time.Millisecond},
+		{"never fits", full, 50 * time.Millisecond, []string{"--shadow", "--effects", "--long-shadow"}, 100 * time.Millisecond},
+		{"skips inactive effects", Config{Effects: "gloss", LongShadow: true, Fill: "none"}, 250 * time.Millisecond, []string{"--effects"}, 200 * time.Millisecond},
+	}
+
+	source := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Budget = tt.budget
+			reduced, dropped, estimated := budgetConfig(config, source, estimate)
+			if !reflect.DeepEqual(dropped, tt.expectDropped) {
+				t.Errorf("Expected dropped %v, got %v", tt.expectDropped, dropped)
+			}
+			if estimated != tt.expectTime {
+				t.Errorf("Expected estimate %s, got %s", tt.expectTime, estimated)
+			}
+			if got := estimate(reduced); got != estimated {
+				t.Errorf("Expected the reduced config to cost %s, got %s", estimated, got)
+			}
+		})
+	}
+}
+
+func TestEstimateRunTime(t *testing.T) {
+	config := Config{Preset: "ios", TrimPercent: 80, OutputDir: t.TempDir()}
+	source := image.NewRGBA(image.Rect(0, 0, 300, 300))
+
+	if estimated := estimateRunTime(config, source); estimated <= 0 {
+		t.Errorf("Expected a positive estimate, got %s", estimated)
+	}
+}
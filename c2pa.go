@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+	"time"
+)
+
+// C2PA content credentials (https://c2pa.org) travel in a caBX chunk of the
+// PNG: a JUMBF manifest store holding one manifest, which holds the
+// assertions, the claim listing their hashes, and the COSE signature of the
+// claim by the certificate of --c2pa-cert.
+const c2paChunkType = "caBX"
+
+// The COSE algorithms content credentials are signed with, by key type.
+const (
+	coseES256 = -7
+	coseES384 = -35
+	coseES512 = -36
+	coseEdDSA = -8
+	cosePS256 = -37
+)
+
+// c2paSourceAssertion is the label of the assertion recording the hash of
+// the source image, in icongen's own namespace.
+const c2paSourceAssertion = "com.github.nayuta.icongen.source"
+
+// c2paSigner signs content credentials with a private key and the
+// certificate chain that vouches for it.
+type c2paSigner struct {
+	key crypto.Signer
+	// alg is the COSE algorithm, and hash the digest ECDSA signs
+	alg  int64
+	hash crypto.Hash
+	// chain holds the DER certificates, the signer's first
+	chain [][]byte
+}
+
+// validateC2PA checks that --c2pa-cert and --c2pa-key come together.
+func validateC2PA(config Config) error {
+	if (config.C2PACert == "") != (config.C2PAKey == "") {
+		return fmt.Errorf("c2pa-cert and c2pa-key must be given together")
+	}
+	return nil
+}
+
+// loadC2PASigner reads the --c2pa-cert chain and the --c2pa-key of config
+// and checks that they belong together. With --reproducible only Ed25519
+// keys are accepted, as ECDSA and RSA-PSS signatures differ on every run.
+func loadC2PASigner(config Config) (*c2paSigner, error) {
+	certPEM, err := os.ReadFile(config.C2PACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read C2PA certificate: %w", err)
+	}
+	var chain [][]byte
+	for rest := certPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificate in %s", config.C2PACert)
+	}
+	cert, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid C2PA certificate %s: %w", config.C2PACert, err)
+	}
+
+	keyPEM, err := os.ReadFile(config.C2PAKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read C2PA key: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key in %s", config.C2PAKey)
+	}
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid C2PA key %s: %w", config.C2PAKey, err)
+	}
+	if public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !public.Equal(cert.PublicKey) {
+		return nil, fmt.Errorf("C2PA key %s doesn't match the certificate %s", config.C2PAKey, config.C2PACert)
+	}
+
+	signer := &c2paSigner{key: key, chain: chain}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			signer.alg, signer.hash = coseES256, crypto.SHA256
+		case elliptic.P384():
+			signer.alg, signer.hash = coseES384, crypto.SHA384
+		case elliptic.P521():
+			signer.alg, signer.hash = coseES512, crypto.SHA512
+		default:
+			return nil, fmt.Errorf("C2PA key %s is on an unsupported curve (expected P-256, P-384 or P-521)", config.C2PAKey)
+		}
+	case ed25519.PrivateKey:
+		signer.alg = coseEdDSA
+	case *rsa.PrivateKey:
+		signer.alg, signer.hash = cosePS256, crypto.SHA256
+	}
+	if config.Reproducible && signer.alg != coseEdDSA {
+		return nil, fmt.Errorf("--reproducible needs an Ed25519 C2PA key, as other signatures differ on every run")
+	}
+	return signer, nil
+}
+
+// parsePrivateKey parses a PKCS #8, SEC 1 EC or PKCS #1 RSA private key.
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey, ed25519.PrivateKey, *rsa.PrivateKey:
+		return key.(crypto.Signer), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T (expected ECDSA, Ed25519 or RSA)", key)
+}
+
+// sign returns the signature of message in its COSE form; ECDSA signatures
+// are the two fixed-length integers r and s.
+func (s *c2paSigner) sign(message []byte) ([]byte, error) {
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, message), nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		return rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case *ecdsa.PrivateKey:
+		h := s.hash.New()
+		h.Write(message)
+		r, sig, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		out := make([]byte, 2*size)
+		r.FillBytes(out[:size])
+		sig.FillBytes(out[size:])
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", s.key)
+}
+
+// coseSign1 returns the tagged COSE_Sign1 structure signing payload, which
+// is detached: the claim it signs sits in a box of its own. The certificate
+// chain goes into the protected header as x5chain.
+func (s *c2paSigner) coseSign1(payload []byte) ([]byte, error) {
+	var x5chain interface{} = s.chain[0]
+	if len(s.chain) > 1 {
+		certs := make([]interface{}, len(s.chain))
+		for i, cert := range s.chain {
+			certs[i] = cert
+		}
+		x5chain = certs
+	}
+	protected := appendCBOR(nil, cborMap{{1, s.alg}, {33, x5chain}})
+
+	toBeSigned := appendCBOR(nil, []interface{}{"Signature1", protected, []byte{}, payload})
+	signature, err := s.sign(toBeSigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign C2PA claim: %w", err)
+	}
+	return appendCBOR(nil, cborTag{18, []interface{}{protected, cborMap{}, nil, signature}}), nil
+}
+
+// saveSignedImage saves img as a PNG at path like saveTextImage, with
+// content credentials added by addContentCredentials.
+func saveSignedImage(img image.Image, path string, chunks []pngChunk, text []pngTextChunk, config Config, state *manifestState) error {
+	data, err := encodeTextImage(img, chunks, text)
+	if err != nil {
+		return err
+	}
+	if data, err = addContentCredentials(data, config, state); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// addContentCredentials inserts a signed C2PA manifest after the IHDR chunk
+// of the PNG data. It records that icongen created the image, when, with
+// --provenance's timing, and from which source, and binds itself to the
+// image with a hash of every byte outside its own chunk.
+func addContentCredentials(data []byte, config Config, state *manifestState) ([]byte, error) {
+	start, err := pngHeaderEnd(data)
+	if err != nil {
+		return nil, err
+	}
+	// The chunk goes where the hash excludes it, so the hash is of data
+	sum := sha256.Sum256(data)
+
+	// The excluded length is part of what the chunk holds, so build it
+	// until the length it records is its own
+	length := 0
+	for {
+		store, err := c2paManifestStore(config, state, sum, start, length)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(store) + 12; n != length {
+			length = n
+			continue
+		}
+		return addPNGChunk(data, c2paChunkType, store)
+	}
+}
+
+// c2paManifestStore returns the JUMBF manifest store of a PNG whose bytes,
+// apart from the length bytes of its caBX chunk at start, hash to sum.
+func c2paManifestStore(config Config, state *manifestState, sum [32]byte, start, length int) ([]byte, error) {
+	generator := strings.Replace(software(), " ", "/", 1)
+	id := c2paUUID(sum)
+
+	action := cborMap{{"action", "c2pa.created"}, {"softwareAgent", generator}}
+	if created, ok := creationTime(config.Reproducible); ok {
+		action = append(action, cborEntry{"when", created.Format(time.RFC3339)})
+	}
+	sourceHash, err := hex.DecodeString(state.sourceHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source hash: %w", err)
+	}
+	exclusion := cborMap{{"start", start}, {"length", length}}
+
+	assertions := []struct {
+		label string
+		data  cborMap
+	}{
+		{"c2pa.actions", cborMap{{"actions", []interface{}{action}}}},
+		{c2paSourceAssertion, cborMap{{"alg", "sha256"}, {"hash", sourceHash}}},
+		{"c2pa.hash.data", cborMap{
+			{"exclusions", []interface{}{exclusion}},
+			{"name", "jumbf manifest"},
+			{"alg", "sha256"},
+			{"hash", sum[:]},
+			{"pad", []byte{}},
+		}},
+	}
+	var boxes [][]byte
+	var refs []interface{}
+	for _, assertion := range assertions {
+		box := jumbfSuperbox("cbor", assertion.label, jumbfBox("cbor", appendCBOR(nil, assertion.data)))
+		// Hashes of boxes cover their description and content, not the
+		// superbox header
+		hash := sha256.Sum256(box[8:])
+		refs = append(refs, cborMap{{"url", "self#jumbf=c2pa.assertions/" + assertion.label}, {"hash", hash[:]}})
+		boxes = append(boxes, box)
+	}
+
+	claim := appendCBOR(nil, cborMap{
+		{"claim_generator", generator},
+		{"signature", "self#jumbf=c2pa.signature"},
+		{"assertions", refs},
+		{"dc:format", "image/png"},
+		{"instanceID", "xmp:iid:" + id},
+		{"alg", "sha256"},
+	})
+	signature, err := state.credentials.coseSign1(claim)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := jumbfSuperbox("c2ma", "urn:uuid:"+id,
+		jumbfSuperbox("c2as", "c2pa.assertions", boxes...),
+		jumbfSuperbox("c2cl", "c2pa.claim", jumbfBox("cbor", claim)),
+		jumbfSuperbox("c2cs", "c2pa.signature", jumbfBox("cbor", signature)),
+	)
+	return jumbfSuperbox("c2pa", "c2pa", manifest), nil
+}
+
+// c2paUUID derives the identifier of a manifest and its image from the hash
+// of the image, as a version 8 UUID, so the same bytes get the same one.
+func c2paUUID(sum [32]byte) string {
+	u := sum[:16]
+	u[6] = u[6]&0x0f | 0x80
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// jumbfBox returns an ISO 19566-5 box of type typ holding payload.
+func jumbfBox(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	box := binary.BigEndian.AppendUint32(make([]byte, 0, size), uint32(size))
+	box = append(box, typ...)
+	for _, p := range payload {
+		box = append(box, p...)
+	}
+	return box
+}
+
+// jumbfSuperbox returns a JUMBF superbox of boxes, described as requestable
+// by label and of the C2PA content type named by the four characters of
+// contentType.
+func jumbfSuperbox(contentType, label string, boxes ...[]byte) []byte {
+	description := append([]byte(contentType), 0x00, 0x11, 0x00, 0x10, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71)
+	// Toggles: requestable, with a label
+	description = append(append(append(description, 0x03), label...), 0)
+	return jumbfBox("jumb", append([][]byte{jumbfBox("jumd", description)}, boxes...)...)
+}
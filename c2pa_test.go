@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"image/color"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeC2PAKey writes a self-signed certificate for key and key itself as
+// PEM files, and returns their paths.
+func writeC2PAKey(t *testing.T, key crypto.Signer) (certPath, keyPath string) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "icongen test"},
+		NotBefore:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+// jumbfSuperboxes adds the payload of every superbox in data, nested ones
+// included, to boxes by the label of its description box.
+func jumbfSuperboxes(t *testing.T, data []byte, boxes map[string][]byte) {
+	t.Helper()
+	for len(data) > 0 {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			t.Fatalf("Invalid JUMBF box size %d", size)
+		}
+		if string(data[4:8]) == "jumb" {
+			payload := data[8:size]
+			descSize := int(binary.BigEndian.Uint32(payload))
+			label := payload[8+17 : descSize-1]
+			boxes[string(label)] = payload
+			jumbfSuperboxes(t, payload[descSize:], boxes)
+		}
+		data = data[size:]
+	}
+}
+
+// jumbfContent returns the payload of the content box following the
+// description box in a superbox payload.
+func jumbfContent(payload []byte) []byte {
+	content := payload[binary.BigEndian.Uint32(payload):]
+	return content[8:binary.BigEndian.Uint32(content)]
+}
+
+// cborBytes splits the CBOR byte string at the start of b from the rest.
+func cborBytes(t *testing.T, b []byte) ([]byte, []byte) {
+	t.Helper()
+	if b[0]>>5 != 2 {
+		t.Fatalf("Expected a CBOR byte string, got %#x", b[0])
+	}
+	n, head := int(b[0]&0x1f), 1
+	switch n {
+	case 24:
+		n, head = int(b[1]), 2
+	case 25:
+		n, head = int(binary.BigEndian.Uint16(b[1:])), 3
+	}
+	return b[head : head+n], b[head+n:]
+}
+
+func TestContentCredentials(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := writeC2PAKey(t, key)
+
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, C2PACert: certPath, C2PAKey: keyPath}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	if _, err := loadImage(filepath.Join(outputDir, "icon_32x32.png")); err != nil {
+		t.Fatalf("Signed icon doesn't decode: %v", err)
+	}
+	store, err := findPNGChunk(bytes.NewReader(data), c2paChunkType)
+	if err != nil || store == nil {
+		t.Fatalf("Expected a %s chunk, got %v", c2paChunkType, err)
+	}
+
+	boxes := make(map[string][]byte)
+	jumbfSuperboxes(t, store, boxes)
+	for _, label := range []string{"c2pa", "c2pa.assertions", "c2pa.claim", "c2pa.signature", "c2pa.actions", "c2pa.hash.data", c2paSourceAssertion} {
+		if boxes[label] == nil {
+			t.Fatalf("Expected a %s box, got %d boxes", label, len(boxes))
+		}
+	}
+
+	// The data hash covers every byte but the chunk, which it excludes
+	start := bytes.Index(data, store) - 8
+	length := len(store) + 12
+	without := append(append([]byte{}, data[:start]...), data[start+length:]...)
+	sum := sha256.Sum256(without)
+	hashData := jumbfContent(boxes["c2pa.hash.data"])
+	if !bytes.Contains(hashData, sum[:]) {
+		t.Errorf("Expected the data hash of the image without the manifest")
+	}
+	if exclusion := appendCBOR(nil, cborMap{{"start", start}, {"length", length}}); !bytes.Contains(hashData, exclusion) {
+		t.Errorf("Expected an exclusion of %d bytes at %d", length, start)
+	}
+
+	sourceHash, _ := hashFile(inputPath)
+	sourceSum, _ := hex.DecodeString(sourceHash)
+	if !bytes.Contains(jumbfContent(boxes[c2paSourceAssertion]), sourceSum) {
+		t.Errorf("Expected the source hash in the %s assertion", c2paSourceAssertion)
+	}
+	if !bytes.Contains(jumbfContent(boxes["c2pa.actions"]), []byte("c2pa.created")) {
+		t.Errorf("Expected a c2pa.created action")
+	}
+
+	claim := jumbfContent(boxes["c2pa.claim"])
+	for _, label := range []string{"c2pa.actions", "c2pa.hash.data", c2paSourceAssertion} {
+		hash := sha256.Sum256(boxes[label])
+		if !bytes.Contains(claim, hash[:]) {
+			t.Errorf("Expected the claim to hold the hash of %s", label)
+		}
+	}
+
+	// The signature is a tagged COSE_Sign1 with a detached payload
+	cose := jumbfContent(boxes["c2pa.signature"])
+	if cose[0] != 0xd2 || cose[1] != 0x84 {
+		t.Fatalf("Expected a tagged COSE_Sign1, got %x", cose[:2])
+	}
+	protected, rest := cborBytes(t, cose[2:])
+	if rest[0] != 0xa0 || rest[1] != 0xf6 {
+		t.Fatalf("Expected an empty unprotected header and no payload, got %x", rest[:2])
+	}
+	signature, _ := cborBytes(t, rest[2:])
+	digest := sha256.Sum256(appendCBOR(nil, []interface{}{"Signature1", protected, []byte{}, claim}))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Errorf("Claim signature doesn't verify")
+	}
+}
+
+func TestReproducibleContentCredentials(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := writeC2PAKey(t, key)
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 128, 255, 255}))
+
+	var icons [][]byte
+	for i := 0; i < 2; i++ {
+		outputDir := t.TempDir()
+		config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Reproducible: true, C2PACert: certPath, C2PAKey: keyPath}
+		if err := generateIcons(config); err != nil {
+			t.Fatalf("Failed to generate icons: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		icons = append(icons, data)
+	}
+	if !bytes.Equal(icons[0], icons[1]) {
+		t.Errorf("Expected byte-identical signed icons with --reproducible")
+	}
+}
+
+func TestC2PASignerErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := writeC2PAKey(t, ecKey)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKeyPath := writeC2PAKey(t, otherKey)
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"cert without key", Config{C2PACert: certPath}, "given together"},
+		{"mismatched key", Config{C2PACert: certPath, C2PAKey: otherKeyPath}, "doesn't match"},
+		{"reproducible ECDSA", Config{C2PACert: certPath, C2PAKey: keyPath, Reproducible: true}, "Ed25519"},
+		{"key as certificate", Config{C2PACert: keyPath, C2PAKey: keyPath}, "no certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.InputPath, config.OutputDir, config.TrimPercent = inputPath, t.TempDir(), 80
+			err := validateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
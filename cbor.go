@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// cborMap is a CBOR map that keeps its entries in the order they were
+// written, so C2PA manifests encode the same way on every run. Keys are
+// strings, or integers for COSE headers.
+type cborMap []cborEntry
+
+type cborEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// cborTag is a CBOR tagged value, such as the COSE_Sign1 tag 18.
+type cborTag struct {
+	Number uint64
+	Value  interface{}
+}
+
+// appendCBOR appends the CBOR encoding (RFC 8949) of v to b. v is nil, a
+// bool, an int, an int64, a uint64, a string, a []byte, an []interface{}, a
+// cborMap or a cborTag; anything else is a programming error.
+func appendCBOR(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xf6)
+	case bool:
+		if v {
+			return append(b, 0xf5)
+		}
+		return append(b, 0xf4)
+	case int:
+		return appendCBOR(b, int64(v))
+	case int64:
+		if v < 0 {
+			return appendCBORHead(b, 1, uint64(-1-v))
+		}
+		return appendCBORHead(b, 0, uint64(v))
+	case uint64:
+		return appendCBORHead(b, 0, v)
+	case []byte:
+		return append(appendCBORHead(b, 2, uint64(len(v))), v...)
+	case string:
+		return append(appendCBORHead(b, 3, uint64(len(v))), v...)
+	case []interface{}:
+		b = appendCBORHead(b, 4, uint64(len(v)))
+		for _, item := range v {
+			b = appendCBOR(b, item)
+		}
+		return b
+	case cborMap:
+		b = appendCBORHead(b, 5, uint64(len(v)))
+		for _, entry := range v {
+			b = appendCBOR(appendCBOR(b, entry.Key), entry.Value)
+		}
+		return b
+	case cborTag:
+		return appendCBOR(appendCBORHead(b, 6, v.Number), v.Value)
+	}
+	panic(fmt.Sprintf("cbor: unsupported type %T", v))
+}
+
+// appendCBORHead appends the initial bytes of a CBOR data item of major
+// type major with argument n, in the shortest form.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= 0xff:
+		return append(b, major|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestAppendCBOR(t *testing.T) {
+	// Examples from RFC 8949, appendix A
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{uint64(1000000000000), "1b000000e8d4a51000"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{nil, "f6"},
+		{true, "f5"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{[]interface{}{1, []interface{}{2, 3}}, "8201820203"},
+		{cborMap{{"a", 1}, {"b", []interface{}{2, 3}}}, "a26161016162820203"},
+		{cborTag{1, 1363896240}, "c11a514b67b0"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(appendCBOR(nil, tt.value)); got != tt.want {
+			t.Errorf("appendCBOR(%v) = %s, expected %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// removeBackgroundTolerance is the --remove-background tolerance when the
+// spec gives none.
+const removeBackgroundTolerance = 10
+
+// parseRemoveBackground parses a --remove-background spec of the form
+// #RRGGBB[:tolerance], the tolerance a percentage as for --map-color.
+func parseRemoveBackground(spec string) (color.NRGBA, int, error) {
+	hex, toleranceSpec, hasTolerance := strings.Cut(spec, ":")
+	c, err := parseHexColor(hex)
+	if err != nil {
+		return color.NRGBA{}, 0, err
+	}
+
+	tolerance := removeBackgroundTolerance
+	if hasTolerance {
+		tolerance, err = strconv.Atoi(toleranceSpec)
+		if err != nil || tolerance < 0 || tolerance > 100 {
+			return color.NRGBA{}, 0, fmt.Errorf("background removal tolerance must be between 0 and 100 (got %q)", toleranceSpec)
+		}
+	}
+	return color.NRGBAModel.Convert(c).(color.NRGBA), tolerance, nil
+}
+
+// removeBackground returns a copy of img with the background of color bg
+// keyed out to transparency. Only the background connected to the image's
+// edges is removed, so white lettering inside a logo on white stays.
+// Pixels within tolerance of bg become transparent; up to twice that far
+// they fade in, with bg unmixed from their color, so anti-aliased edges
+// don't keep a light fringe.
+func removeBackground(img image.Image, bg color.NRGBA, tolerance int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	keyed := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			keyed.SetNRGBA(x, y, color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA))
+		}
+	}
+
+	inner, outer := float64(tolerance), float64(2*tolerance)
+	matches := func(c color.NRGBA) bool {
+		return c.A > 0 && colorDistance(c, bg) <= outer
+	}
+
+	// Flood fill from every edge pixel that matches the background
+	visited := make([]bool, width*height)
+	var queue []image.Point
+	push := func(x, y int) {
+		if x < 0 || y < 0 || x >= width || y >= height || visited[y*width+x] {
+			return
+		}
+		visited[y*width+x] = true
+		if matches(keyed.NRGBAAt(x, y)) {
+			queue = append(queue, image.Pt(x, y))
+		}
+	}
+	for x := 0; x < width; x++ {
+		push(x, 0)
+		push(x, height-1)
+	}
+	for y := 0; y < height; y++ {
+		push(0, y)
+		push(width-1, y)
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		c := keyed.NRGBAAt(p.X, p.Y)
+		distance := colorDistance(c, bg)
+		if distance <= inner {
+			keyed.SetNRGBA(p.X, p.Y, color.NRGBA{})
+		} else {
+			// Unmix bg from the edge pixel: c = a*fg + (1-a)*bg
+			a := (distance - inner) / (outer - inner)
+			unmix := func(v, b uint8) uint8 {
+				return uint8(math.Round(math.Max(0, math.Min(255, (float64(v)-(1-a)*float64(b))/a))))
+			}
+			keyed.SetNRGBA(p.X, p.Y, color.NRGBA{unmix(c.R, bg.R), unmix(c.G, bg.G), unmix(c.B, bg.B), uint8(math.Round(a * float64(c.A)))})
+			// The fill stops at the edge of the artwork
+			continue
+		}
+
+		push(p.X+1, p.Y)
+		push(p.X-1, p.Y)
+		push(p.X, p.Y+1)
+		push(p.X, p.Y-1)
+	}
+
+	return keyed
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseRemoveBackground(t *testing.T) {
+	tests := []struct {
+		spec          string
+		wantColor     color.NRGBA
+		wantTolerance int
+		wantErr       bool
+	}{
+		{"#FFFFFF", color.NRGBA{255, 255, 255, 255}, removeBackgroundTolerance, false},
+		{"#00FF00:15", color.NRGBA{0, 255, 0, 255}, 15, false},
+		{"#FFFFFF:0", color.NRGBA{255, 255, 255, 255}, 0, false},
+		{"#FFFFFF:101", color.NRGBA{}, 0, true},
+		{"#FFFFFF:-1", color.NRGBA{}, 0, true},
+		{"#FFFFFF:soft", color.NRGBA{}, 0, true},
+		{"white", color.NRGBA{}, 0, true},
+	}
+
+	for _, tt := range tests {
+		c, tolerance, err := parseRemoveBackground(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRemoveBackground(%q): expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && (c != tt.wantColor || tolerance != tt.wantTolerance) {
+			t.Errorf("parseRemoveBackground(%q): expected %v and %d, got %v and %d", tt.spec, tt.wantColor, tt.wantTolerance, c, tolerance)
+		}
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, RemoveBackground: "#FFFFFF:200"}); err == nil {
+		t.Errorf("Expected an out-of-range --remove-background tolerance to fail")
+	}
+}
+
+// createBoxedRing returns a 7x7 image of a black ring on white around a white
+// center pixel.
+func createBoxedRing(background color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 7, 7))
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 7; x++ {
+			c := background
+			if x >= 1 && x <= 5 && y >= 1 && y <= 5 && (x != 3 || y != 3) {
+				c = color.NRGBA{0, 0, 0, 255}
+			}
+			if x == 3 && y == 3 {
+				c = color.NRGBA{255, 255, 255, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestRemoveBackground(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	tests := []struct {
+		name       string
+		background color.NRGBA
+		tolerance  int
+		cornerA    uint8
+	}{
+		{"exact white", white, 10, 0},
+		{"off-white within tolerance", color.NRGBA{250, 250, 250, 255}, 10, 0},
+		{"off-white with zero tolerance", color.NRGBA{250, 250, 250, 255}, 0, 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyed := removeBackground(createBoxedRing(tt.background), white, tt.tolerance)
+			if a := color.NRGBAModel.Convert(keyed.At(0, 0)).(color.NRGBA).A; a != tt.cornerA {
+				t.Errorf("Expected corner alpha %d, got %d", tt.cornerA, a)
+			}
+			if c := color.NRGBAModel.Convert(keyed.At(1, 1)).(color.NRGBA); c != (color.NRGBA{0, 0, 0, 255}) {
+				t.Errorf("Expected the ring to stay black, got %v", c)
+			}
+			if c := color.NRGBAModel.Convert(keyed.At(3, 3)).(color.NRGBA); c != white {
+				t.Errorf("Expected the enclosed center to stay white, got %v", c)
+			}
+		})
+	}
+}
+
+func TestRemoveBackgroundSoftEdge(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, white)
+	img.SetNRGBA(1, 0, color.NRGBA{215, 215, 215, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{0, 0, 0, 255})
+
+	keyed := removeBackground(img, white, 10)
+	edge := color.NRGBAModel.Convert(keyed.At(1, 0)).(color.NRGBA)
+	if edge.A == 0 || edge.A == 255 {
+		t.Errorf("Expected the anti-aliased edge to be partly transparent, got alpha %d", edge.A)
+	}
+	if edge.R >= 215 {
+		t.Errorf("Expected white to be unmixed from the edge, got %v", edge)
+	}
+	if c := color.NRGBAModel.Convert(keyed.At(2, 0)).(color.NRGBA); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("Expected the artwork to stay opaque, got %v", c)
+	}
+}
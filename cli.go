@@ -0,0 +1,97 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// subcommands maps the first argument to commands other than generation.
+var subcommands = map[string]func(args []string) error{
+	"bench":         runBench,
+	"compare":       runCompare,
+	"diff":          runDiff,
+	"formats":       runFormats,
+	"rpc":           runRPC,
+	"settings-from": runSettingsFrom,
+	"watermark":     runWatermark,
+	"gradle-task":   runGradleTask,
+	"hook":          runHook,
+	"init":          runInit,
+	"xcode-phase":   runXcodePhase,
+}
+
+func main() {
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	config := parseFlags()
+
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.DryRun {
+		if err := printPlan(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	applyMemoryLimit(config)
+
+	stopProfiling, err := startProfiling(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	err = generateConfig(config)
+	if err := stopProfiling(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating icons: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.Check {
+		return
+	}
+	if skipped := skippedOutputs(config); len(skipped) > 0 && config.FailOnSkipped {
+		fmt.Fprintf(os.Stderr, "Error: %d requested outputs were skipped (--fail-on-skipped)\n", len(skipped))
+		os.Exit(exitSkipped)
+	}
+	fmt.Printf("✅ Done. Generated icons in %s.\n", strings.Join(outputDirs(config), ", "))
+}
+
+// outputDirs lists the directories a run of config writes its icons into:
+// the root of the mirrored tree in recursive mode, and the directory of
+// every flavor with --flavors.
+func outputDirs(config Config) []string {
+	if config.Recursive {
+		return []string{config.OutputDir}
+	}
+	if config.Flavors != "" {
+		configs, err := flavorConfigs(config)
+		if err == nil {
+			var dirs []string
+			for _, c := range configs {
+				dirs = append(dirs, assetDir(c))
+			}
+			return dirs
+		}
+	}
+	return []string{assetDir(config)}
+}
@@ -0,0 +1,29 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOutputDirs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{"single run", Config{OutputDir: "out"}, []string{"out"}},
+		{"versioned", Config{OutputDir: "out", AssetVersion: "1.4.0", VersionedDirs: true}, []string{filepath.Join("out", "v1.4.0")}},
+		{"recursive", Config{OutputDir: "out", Recursive: true}, []string{"out"}},
+		{"flavors", Config{OutputDir: "out", Flavors: `[{"name":"acme"},{"name":"globex","output":"elsewhere"}]`}, []string{filepath.Join("out", "acme"), "elsewhere"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outputDirs(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
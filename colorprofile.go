@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// pngChunk is a chunk other than text written into a saved PNG, such as the
+// color space information --color-profile asks for or the --dpi density.
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// validateColorProfile checks the --color-profile value.
+func validateColorProfile(profile string) error {
+	switch profile {
+	case "", "srgb", "source", "none":
+		return nil
+	}
+	return fmt.Errorf("unknown color profile %q (expected srgb, source or none)", profile)
+}
+
+// colorChunks returns the chunks that tell color-managed viewers what color
+// space the icons of config are in. icongen works on the source's samples
+// as they are, in sRGB unless the source says otherwise, so:
+//   - srgb, the default, marks them sRGB with an sRGB chunk, and the gAMA
+//     and cHRM chunks the PNG specification recommends alongside it for
+//     viewers that don't read sRGB
+//   - source copies the ICC profile of a PNG source, and marks the icons
+//     sRGB if it has none
+//   - none writes no color space information
+//
+// sourceChunk reads a chunk of the source, as readPNGChunk does.
+func colorChunks(config Config, sourceChunk func(typ string) ([]byte, error)) ([]pngChunk, error) {
+	switch config.ColorProfile {
+	case "none":
+		return nil, nil
+	case "source":
+		profile, err := sourceChunk("iCCP")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the source's color profile: %w", err)
+		}
+		if profile != nil {
+			return []pngChunk{{"iCCP", profile}}, nil
+		}
+	}
+	return srgbChunks(), nil
+}
+
+// srgbChunks returns the sRGB chunk with the perceptual rendering intent,
+// and the gAMA and cHRM chunks that match it.
+func srgbChunks() []pngChunk {
+	gamma := make([]byte, 4)
+	binary.BigEndian.PutUint32(gamma, 45455)
+
+	// White point, then red, green and blue, in 1/100000ths
+	chromaticities := []uint32{31270, 32900, 64000, 33000, 30000, 60000, 15000, 6000}
+	chrm := make([]byte, 4*len(chromaticities))
+	for i, v := range chromaticities {
+		binary.BigEndian.PutUint32(chrm[4*i:], v)
+	}
+
+	return []pngChunk{{"sRGB", []byte{0}}, {"gAMA", gamma}, {"cHRM", chrm}}
+}
+
+// readPNGChunk returns the payload of the first chunk of typ before the
+// image data of the PNG at path, or nil if it isn't a PNG or has none, such
+// as the iCCP chunk that holds the name and compressed data of an ICC
+// profile.
+func readPNGChunk(path, typ string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return findPNGChunk(file, typ)
+}
+
+// findPNGChunk is readPNGChunk for a PNG read from r.
+func findPNGChunk(r io.Reader, typ string) ([]byte, error) {
+	c := pngChunks{bufio.NewReader(r)}
+	var signature [8]byte
+	if _, err := io.ReadFull(c.r, signature[:]); err != nil || string(signature[:]) != pngSignature {
+		return nil, nil
+	}
+	for {
+		length, chunkType, err := c.next()
+		if err != nil {
+			return nil, fmt.Errorf("invalid PNG: %w", err)
+		}
+		switch chunkType {
+		case typ:
+			return c.data(typ, length)
+		case "IDAT", "IEND":
+			return nil, nil
+		}
+		if _, err := c.r.Discard(int(length) + 4); err != nil {
+			return nil, fmt.Errorf("invalid PNG: %w", err)
+		}
+	}
+}
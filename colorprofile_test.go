@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pngChunkData returns the payload of the first chunk of typ in the PNG
+// data, if there is one.
+func pngChunkData(data []byte, typ string) ([]byte, bool) {
+	for pos := 8; pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if pos+12+length > len(data) {
+			break
+		}
+		if string(data[pos+4:pos+8]) == typ {
+			return data[pos+8 : pos+8+length], true
+		}
+		pos += 12 + length
+	}
+	return nil, false
+}
+
+func TestColorProfile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, createTestImage(64, color.RGBA{255, 0, 0, 255})); err != nil {
+		t.Fatalf("Failed to encode source: %v", err)
+	}
+	profile := []byte("Display P3\x00\x00fake compressed profile")
+	data, err := addPNGChunk(buf.Bytes(), "iCCP", profile)
+	if err != nil {
+		t.Fatalf("Failed to add an ICC profile: %v", err)
+	}
+	withICC := filepath.Join(t.TempDir(), "p3.png")
+	if err := os.WriteFile(withICC, data, 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	plain := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+
+	tests := []struct {
+		name    string
+		input   string
+		profile string
+		want    string
+	}{
+		{"default", plain, "", "sRGB"},
+		{"srgb", withICC, "srgb", "sRGB"},
+		{"source", withICC, "source", "iCCP"},
+		{"source without a profile", plain, "source", "sRGB"},
+		{"none", withICC, "none", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			config := Config{InputPath: tt.input, OutputDir: outputDir, TrimPercent: 80, ColorProfile: tt.profile}
+			if err := generateIcons(config); err != nil {
+				t.Fatalf("Failed to generate icons: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+			if err != nil {
+				t.Fatalf("Failed to read icon: %v", err)
+			}
+			if _, err := loadImage(filepath.Join(outputDir, "icon_32x32.png")); err != nil {
+				t.Errorf("Expected the icon to stay a valid PNG: %v", err)
+			}
+
+			srgb, hasSRGB := pngChunkData(data, "sRGB")
+			icc, hasICC := pngChunkData(data, "iCCP")
+			_, hasGamma := pngChunkData(data, "gAMA")
+			switch tt.want {
+			case "sRGB":
+				if !hasSRGB || !bytes.Equal(srgb, []byte{0}) || !hasGamma || hasICC {
+					t.Errorf("Expected sRGB, gAMA and no iCCP, got sRGB %v, gAMA %v, iCCP %v", hasSRGB, hasGamma, hasICC)
+				}
+			case "iCCP":
+				if !bytes.Equal(icc, profile) || hasSRGB {
+					t.Errorf("Expected the source's ICC profile and no sRGB, got %q, sRGB %v", icc, hasSRGB)
+				}
+			default:
+				if hasSRGB || hasICC || hasGamma {
+					t.Errorf("Expected no color space chunks, got sRGB %v, gAMA %v, iCCP %v", hasSRGB, hasGamma, hasICC)
+				}
+			}
+		})
+	}
+
+	if err := validateColorProfile("adobe-rgb"); err == nil {
+		t.Errorf("Expected an error for an unknown color profile")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// compareIconName returns the file name of the side-by-side comparison of
+// the icon called name.
+func compareIconName(name string) string {
+	dir, file := path.Split(name)
+	return dir + "compare_" + file
+}
+
+// runCompare implements "icongen compare": it renders every icon size with
+// two configurations in memory and writes one composite per size showing
+// them side by side, without touching either configuration's outputs.
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	var pathA, pathB, outputDir string
+	fs.StringVar(&pathA, "config-a", "", "Configuration file for the left column (.json, .yaml or .yml)")
+	fs.StringVar(&pathB, "config-b", "", "Configuration file for the right column (.json, .yaml or .yml)")
+	fs.StringVar(&outputDir, "output", "", "Output directory for compare_*.png (defaults to the input image directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare --config-a a.yaml --config-b b.yaml [input-image] [output-dir]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Render every icon size with two configurations and write side-by-side compare_*.png composites.\n")
+		fmt.Fprintf(fs.Output(), "A positional input image overrides the input of both configurations.\n\n")
+		fmt.Fprintf(fs.Output(), "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if pathA == "" || pathB == "" {
+		return fmt.Errorf("compare requires both --config-a and --config-b")
+	}
+
+	configA, err := loadCompareConfig(pathA)
+	if err != nil {
+		return err
+	}
+	configB, err := loadCompareConfig(pathB)
+	if err != nil {
+		return err
+	}
+
+	// Handle positional arguments
+	if fs.NArg() > 0 {
+		configA.InputPath = fs.Arg(0)
+		configB.InputPath = fs.Arg(0)
+	}
+	if fs.NArg() > 1 {
+		outputDir = fs.Arg(1)
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(configA.InputPath)
+	}
+
+	for _, config := range []Config{configA, configB} {
+		if err := validateConfig(config); err != nil {
+			return err
+		}
+	}
+	if configA.Preset != configB.Preset {
+		return fmt.Errorf("both configurations must use the same preset (got %s and %s)", configA.Preset, configB.Preset)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	columns := []struct {
+		title      string
+		config     Config
+		source     image.Image
+		pattern    backgroundPattern
+		background image.Image
+		layers     []stackLayer
+		standalone standaloneIcons
+	}{
+		{title: "A: " + filepath.Base(pathA), config: configA},
+		{title: "B: " + filepath.Base(pathB), config: configB},
+	}
+	for i := range columns {
+		column := &columns[i]
+		column.source, err = loadSource(column.config)
+		if err != nil {
+			return err
+		}
+		column.standalone = newStandaloneIcons(column.source, column.config)
+		column.background, err = loadBackgroundImage(column.config)
+		if err != nil {
+			return err
+		}
+		column.layers, err = loadLayers(column.config)
+		if err != nil {
+			return err
+		}
+		if column.config.BackgroundPattern != "" {
+			column.pattern, err = parseBackgroundPattern(column.config.BackgroundPattern)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("Comparing %s with %s in: %s\n", pathA, pathB, outputDir)
+	for _, iconSize := range outputSizes(configA) {
+		var renders [2][]image.Image
+		for i, column := range columns {
+			for _, icon := range renderIcons(column.source, column.config, column.pattern, column.background, column.layers, column.standalone, iconSize) {
+				renders[i] = append(renders[i], icon.Image)
+			}
+		}
+
+		sheet := compareSheet(iconSize, columns[0].title, columns[1].title, renders[0], renders[1])
+		name := compareIconName(iconSize.Name)
+		fmt.Printf(" - %s (%dx%d)\n", name, iconSize.Size, iconSize.Size)
+		sheetPath := filepath.Join(outputDir, name)
+		if err := os.MkdirAll(filepath.Dir(sheetPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := saveImage(sheet, sheetPath); err != nil {
+			return fmt.Errorf("failed to save %s: %w", name, err)
+		}
+	}
+
+	fmt.Println("✅ Done. Generated compare_* PNGs.")
+	return nil
+}
+
+// loadCompareConfig builds a Config from the defaults overridden by the
+// options in the configuration file at path.
+func loadCompareConfig(path string) (Config, error) {
+	var config Config
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &config)
+
+	if err := loadConfigFile(fs, path); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// renderedIcon is an output of an icon size rendered in memory.
+type renderedIcon struct {
+	Name  string
+	Image image.Image
+}
+
+// renderIcons renders the outputs of iconSize exactly as generateIcons
+// would save them, before they are encoded: a standalone icon on its own,
+// or the regular icon followed by its rounded and masked variants.
+func renderIcons(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, standalone standaloneIcons, iconSize IconSize) []renderedIcon {
+	prepared := func() image.Image {
+		return prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size)
+	}
+	artwork := func() image.Image { return finishIcon(prepared(), config, iconSize, nil) }
+	if _, render, ok := standalone.render(iconSize, artwork); ok {
+		return []renderedIcon{{iconSize.Name, render()}}
+	}
+
+	img := prepared()
+	icons := []renderedIcon{{iconSize.Name, finishIcon(img, config, iconSize, nil)}}
+	for _, variant := range variantsFor(iconVariants(config), iconSize) {
+		name := variantIconName(iconSize.Name, variant.Name)
+		icons = append(icons, renderedIcon{name, finishIcon(variant.mask(img, iconSize.Size), config, iconSize, variant.mask)})
+	}
+	return icons
+}
+
+// compareSheet lays out the renders of two configurations in two labelled
+// columns on a checkerboard, so transparent areas are easy to tell apart.
+func compareSheet(iconSize IconSize, titleA, titleB string, a, b []image.Image) *image.RGBA {
+	const (
+		gap        = 16
+		labelSize  = 10.0
+		lineHeight = 16
+	)
+
+	columnWidth := iconSize.Size
+	for _, title := range []string{titleA, titleB, iconSize.Name} {
+		if w := int(measureText(title, labelSize)) + 1; w > columnWidth {
+			columnWidth = w
+		}
+	}
+
+	rows := len(a)
+	if len(b) > rows {
+		rows = len(b)
+	}
+
+	top := gap + 2*lineHeight
+	width := 3*gap + 2*columnWidth
+	height := top + rows*(iconSize.Size+gap)
+
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{color.RGBA{255, 255, 255, 255}}, image.Point{}, draw.Src)
+
+	ink := color.RGBA{34, 34, 34, 255}
+	drawText(sheet, iconSize.Name, gap, gap, labelSize, ink)
+
+	checker := renderPattern(backgroundPattern{
+		Kind:        "checker",
+		CellPercent: 6,
+		Foreground:  color.RGBA{208, 208, 208, 255},
+		Background:  color.RGBA{255, 255, 255, 255},
+	}, iconSize.Size)
+
+	for i, column := range [][]image.Image{a, b} {
+		x := gap + i*(columnWidth+gap)
+		title := titleA
+		if i == 1 {
+			title = titleB
+		}
+		drawText(sheet, title, float64(x), gap+lineHeight, labelSize, ink)
+
+		for row, icon := range column {
+			rect := image.Rect(x, top+row*(iconSize.Size+gap), x+iconSize.Size, top+row*(iconSize.Size+gap)+iconSize.Size)
+			draw.Draw(sheet, rect, checker, image.Point{}, draw.Src)
+			draw.Draw(sheet, rect, icon, icon.Bounds().Min, draw.Over)
+		}
+	}
+
+	return sheet
+}
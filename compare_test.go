@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCompare(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := createTempImageFile(t, createTestImage(128, color.RGBA{255, 0, 0, 255}))
+	outputDir := filepath.Join(tempDir, "compare")
+
+	configA := filepath.Join(tempDir, "a.yaml")
+	configB := filepath.Join(tempDir, "b.json")
+	if err := os.WriteFile(configA, []byte("radius-percent: 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config A: %v", err)
+	}
+	if err := os.WriteFile(configB, []byte(`{"radius-percent": 25, "padding-percent": 10}`), 0644); err != nil {
+		t.Fatalf("Failed to write config B: %v", err)
+	}
+
+	err := runCompare([]string{"--config-a", configA, "--config-b", configB, inputPath, outputDir})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	for _, iconSize := range iconSizes {
+		path := filepath.Join(outputDir, compareIconName(iconSize.Name))
+		img, err := loadImage(path)
+		if err != nil {
+			t.Errorf("Expected composite %s: %v", path, err)
+			continue
+		}
+
+		// Config B has a rounded variant, so there are two rows
+		if img.Bounds().Dy() < 2*iconSize.Size {
+			t.Errorf("Expected %s to hold two rows of %dpx icons, got height %d",
+				filepath.Base(path), iconSize.Size, img.Bounds().Dy())
+		}
+		if img.Bounds().Dx() < 2*iconSize.Size {
+			t.Errorf("Expected %s to hold two columns of %dpx icons, got width %d",
+				filepath.Base(path), iconSize.Size, img.Bounds().Dx())
+		}
+	}
+
+	// Generation outputs must not be written
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_16x16.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected compare to leave icon outputs alone")
+	}
+}
+
+func TestRunCompareRequiresBothConfigs(t *testing.T) {
+	if err := runCompare([]string{"--config-a", "a.yaml"}); err == nil {
+		t.Errorf("Expected error when --config-b is missing")
+	}
+}
+
+func TestRenderIconsStandalone(t *testing.T) {
+	// A notification icon is compared as the silhouette generateIcons saves,
+	// without the rounded variant the regular icons get
+	source := createTestImageWithSquare(128, 80, color.RGBA{255, 0, 0, 255})
+	config := Config{Preset: notificationPreset, TrimPercent: 80, RadiusPercent: 20}
+	iconSize := notificationSizes[0]
+
+	icons := renderIcons(source, config, backgroundPattern{}, nil, nil, newStandaloneIcons(source, config), iconSize)
+	if len(icons) != 1 || icons[0].Name != iconSize.Name {
+		t.Fatalf("Expected only %s, got %d icons", iconSize.Name, len(icons))
+	}
+	center := iconSize.Size / 2
+	if got := color.RGBAModel.Convert(icons[0].Image.At(center, center)).(color.RGBA); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected a white silhouette, got %v", got)
+	}
+}
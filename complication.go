@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Apple Watch complications are drawn from an asset catalog complication
+// set: one image set per family, each with an image per watch case. The
+// legacy families are template images watchOS tints from their alpha
+// channel; the graphic ones show in full color.
+const (
+	complicationPreset = "watch-complication"
+	complicationSet    = "Complication.complicationset"
+)
+
+// complicationCases are the watch cases complication images are sized for,
+// with the screen width Xcode tells them apart by. Later cases pick the
+// images of the nearest one: 41mm those of 40mm, 45mm and 49mm those of 44mm.
+var complicationCases = []struct {
+	Name        string
+	ScreenWidth string
+}{
+	{"38mm", "<=145"},
+	{"40mm", ">161"},
+	{"42mm", ">145"},
+	{"44mm", ">183"},
+}
+
+// complicationFamily is one image set of the complication set.
+type complicationFamily struct {
+	Name    string // of the image set, as Xcode names it
+	Role    string
+	Graphic bool   // full color rather than a template image
+	Sizes   [4]int // in pixels per complicationCases entry, 0 where the case lacks the family
+}
+
+// complicationFamilies are the image sets the watch-complication preset
+// writes, at the @2x pixel sizes of Apple's guidelines.
+var complicationFamilies = []complicationFamily{
+	{Name: "Circular", Role: "circular", Sizes: [4]int{32, 36, 36, 40}},
+	{Name: "Modular", Role: "modular", Sizes: [4]int{52, 58, 58, 64}},
+	{Name: "Utilitarian", Role: "utilitarian", Sizes: [4]int{40, 44, 44, 50}},
+	{Name: "Graphic Corner", Role: "graphic-corner", Graphic: true, Sizes: [4]int{0, 40, 0, 44}},
+	{Name: "Graphic Bezel", Role: "graphic-bezel", Graphic: true, Sizes: [4]int{0, 84, 0, 94}},
+	{Name: "Graphic Circular", Role: "graphic-circular", Graphic: true, Sizes: [4]int{0, 84, 0, 94}},
+}
+
+// complicationSizes lists the outputs of the watch-complication preset, e.g.
+// Complication.complicationset/Circular.imageset/circular_38mm.png.
+func complicationSizes() []IconSize {
+	var sizes []IconSize
+	for _, family := range complicationFamilies {
+		for i, watchCase := range complicationCases {
+			if family.Sizes[i] != 0 {
+				sizes = append(sizes, IconSize{complicationImageName(family, watchCase.Name), family.Sizes[i]})
+			}
+		}
+	}
+	return sizes
+}
+
+// complicationImageSet returns the directory of the image set of family.
+func complicationImageSet(family complicationFamily) string {
+	return path.Join(complicationSet, family.Name+".imageset")
+}
+
+// complicationImageName returns the output name of the image of family for
+// the watch case called watchCase.
+func complicationImageName(family complicationFamily, watchCase string) string {
+	stem := strings.ToLower(strings.ReplaceAll(family.Name, " ", "_"))
+	return path.Join(complicationImageSet(family), fmt.Sprintf("%s_%s.png", stem, watchCase))
+}
+
+// complicationFamilyOf returns the family whose image set holds the output
+// iconSize, and whether it is a complication image at all. Those are
+// generated on their own rather than as regular icons with variants.
+func complicationFamilyOf(iconSize IconSize) (complicationFamily, bool) {
+	dir := path.Dir(iconSize.Name)
+	for _, family := range complicationFamilies {
+		if dir == complicationImageSet(family) {
+			return family, true
+		}
+	}
+	return complicationFamily{}, false
+}
+
+// isComplicationImage reports whether iconSize is one of the
+// watch-complication preset's images.
+func isComplicationImage(iconSize IconSize) bool {
+	_, ok := complicationFamilyOf(iconSize)
+	return ok
+}
+
+// complicationTarget describes the output of the complication image
+// iconSize.
+func complicationTarget(iconSize IconSize) plannedOutput {
+	return plannedOutput{
+		Name:    iconSize.Name,
+		Width:   iconSize.Size,
+		Height:  iconSize.Size,
+		Format:  "png",
+		Variant: "complication",
+	}
+}
+
+// complicationContentsTargets lists the Contents.json files the
+// watch-complication preset adds to a run.
+func complicationContentsTargets(config Config) []plannedOutput {
+	if !hasPreset(config, complicationPreset) {
+		return nil
+	}
+	targets := []plannedOutput{{Name: path.Join(complicationSet, xcodeContentsName), Format: "json", Variant: "complication"}}
+	for _, family := range complicationFamilies {
+		targets = append(targets, plannedOutput{Name: path.Join(complicationImageSet(family), xcodeContentsName), Format: "json", Variant: "complication"})
+	}
+	return targets
+}
+
+// renderComplicationImage returns the label and renderer of the
+// complication image iconSize: the finished artwork for a graphic family,
+// and for the others the silhouette watchOS tints, scaled to fill it.
+func renderComplicationImage(artwork, silhouette func() image.Image, config Config, iconSize IconSize) (string, func() image.Image) {
+	family, _ := complicationFamilyOf(iconSize)
+	if family.Graphic {
+		return fmt.Sprintf("%dx%d, complication", iconSize.Size, iconSize.Size), artwork
+	}
+	label := fmt.Sprintf("%dx%d, complication template", iconSize.Size, iconSize.Size)
+	return label, func() image.Image {
+		return scaleForeground(silhouette(), iconSize.Size, 100, depthResizer(config))
+	}
+}
+
+// complicationContents is the Contents.json of a complication set.
+type complicationContents struct {
+	Assets []complicationAsset `json:"assets"`
+	Info   xcodeInfo           `json:"info"`
+}
+
+type complicationAsset struct {
+	Filename string `json:"filename"`
+	Idiom    string `json:"idiom"`
+	Role     string `json:"role"`
+}
+
+// complicationImageContents is the Contents.json of one of its image sets.
+type complicationImageContents struct {
+	Images []complicationImage `json:"images"`
+	Info   xcodeInfo           `json:"info"`
+}
+
+type complicationImage struct {
+	Filename    string `json:"filename,omitempty"`
+	Idiom       string `json:"idiom"`
+	Scale       string `json:"scale"`
+	ScreenWidth string `json:"screen-width"`
+}
+
+// writeComplicationContents writes the Contents.json files that make the
+// complication images an asset catalog complication set. The slots of
+// images --no-upscale skipped are left without a file.
+func writeComplicationContents(config Config, state *manifestState) error {
+	generated := make(map[string]bool)
+	for _, iconSize := range outputSizes(config) {
+		generated[iconSize.Name] = true
+	}
+
+	info := xcodeInfo{Author: "icongen", Version: 1}
+	set := complicationContents{Info: info}
+	// The complication set's Contents.json first, filled in once its image
+	// sets are
+	files := []struct {
+		name     string
+		contents interface{}
+	}{{name: path.Join(complicationSet, xcodeContentsName)}}
+	for _, family := range complicationFamilies {
+		set.Assets = append(set.Assets, complicationAsset{
+			Filename: path.Base(complicationImageSet(family)),
+			Idiom:    "watch",
+			Role:     family.Role,
+		})
+
+		contents := complicationImageContents{Info: info}
+		for i, watchCase := range complicationCases {
+			if family.Sizes[i] == 0 {
+				continue
+			}
+			slot := complicationImage{Idiom: "watch", Scale: "2x", ScreenWidth: watchCase.ScreenWidth}
+			if name := complicationImageName(family, watchCase.Name); generated[name] {
+				slot.Filename = path.Base(name)
+			}
+			contents.Images = append(contents.Images, slot)
+		}
+		files = append(files, struct {
+			name     string
+			contents interface{}
+		}{path.Join(complicationImageSet(family), xcodeContentsName), contents})
+	}
+	files[0].contents = set
+
+	for _, file := range files {
+		name := file.name
+		data, err := json.MarshalIndent(file.contents, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(logOutput(config), " - %s\n", name)
+		out := filepath.Join(config.OutputDir, name)
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(out, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to save %s: %w", name, err)
+		}
+		if err := state.record(name); err != nil {
+			return fmt.Errorf("failed to record %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComplicationPreset(t *testing.T) {
+	// A red square on transparency
+	inputPath := createTempImageFile(t, createTestImageWithSquare(200, 120, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: complicationPreset, TrimPercent: 80, RadiusPercent: 20}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	sizes := complicationSizes()
+	if len(sizes) != 18 {
+		t.Fatalf("Expected 18 complication images, got %d", len(sizes))
+	}
+	for _, iconSize := range sizes {
+		img, err := loadImage(filepath.Join(outputDir, iconSize.Name))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", iconSize.Name, err)
+		}
+		if img.Bounds().Dx() != iconSize.Size {
+			t.Errorf("%s: expected %dpx, got %dpx", iconSize.Name, iconSize.Size, img.Bounds().Dx())
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, roundedIconName(iconSize.Name))); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no rounded variant, got %v", iconSize.Name, err)
+		}
+	}
+
+	// Template families hold the white silhouette, graphic ones the artwork
+	template, err := loadImage(filepath.Join(outputDir, "Complication.complicationset/Modular.imageset/modular_44mm.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(template.At(32, 32)).(color.NRGBA); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected a white template image, got %v", c)
+	}
+	graphic, err := loadImage(filepath.Join(outputDir, "Complication.complicationset/Graphic Circular.imageset/graphic_circular_40mm.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(graphic.At(42, 42)).(color.NRGBA); c.R != 255 || c.G != 0 {
+		t.Errorf("Expected the red artwork in a graphic image, got %v", c)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, complicationSet, xcodeContentsName))
+	if err != nil {
+		t.Fatalf("Failed to read the complication set's Contents.json: %v", err)
+	}
+	var set complicationContents
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Assets) != len(complicationFamilies) || set.Assets[3].Filename != "Graphic Corner.imageset" || set.Assets[3].Role != "graphic-corner" {
+		t.Errorf("Unexpected assets %+v", set.Assets)
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, complicationSet, "Graphic Corner.imageset", xcodeContentsName))
+	if err != nil {
+		t.Fatalf("Failed to read the image set's Contents.json: %v", err)
+	}
+	var imageSet complicationImageContents
+	if err := json.Unmarshal(data, &imageSet); err != nil {
+		t.Fatal(err)
+	}
+	want := []complicationImage{
+		{Filename: "graphic_corner_40mm.png", Idiom: "watch", Scale: "2x", ScreenWidth: ">161"},
+		{Filename: "graphic_corner_44mm.png", Idiom: "watch", Scale: "2x", ScreenWidth: ">183"},
+	}
+	if len(imageSet.Images) != len(want) || imageSet.Images[0] != want[0] || imageSet.Images[1] != want[1] {
+		t.Errorf("Expected images %+v, got %+v", want, imageSet.Images)
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if want := len(sizes) + 1 + len(complicationFamilies); len(targets) != want {
+		t.Errorf("Expected %d targets, got %d", want, len(targets))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// loadConfigFile applies the options in a configuration file to fs. Keys are
+// flag names, so "trim-percent: 75" in a file is the same as --trim-percent=75
+// on the command line. JSON files must hold a single object; arrays and
+// objects in it reach their option as JSON, which is how --layers is set.
+// YAML files are read as flat "key: value" lines, which covers everything a
+// flag can express without pulling in a YAML library.
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		values, err = parseJSONConfig(data)
+	case ".yaml", ".yml":
+		values, err = parseYAMLConfig(data)
+	default:
+		return fmt.Errorf("unsupported config file type %q (use .json, .yaml or .yml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := applyOptions(fs, values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyOptions sets the flags named by the keys of values on fs.
+func applyOptions(fs *flag.FlagSet, values map[string]string) error {
+	// Apply in a stable order so errors are reproducible
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("unknown option %q", key)
+		}
+		if err := fs.Set(key, values[key]); err != nil {
+			return fmt.Errorf("invalid value for %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func parseJSONConfig(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return optionValues(raw)
+}
+
+// optionValues converts decoded JSON option values to their command-line
+// spelling.
+func optionValues(raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		case float64:
+			values[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case []interface{}, map[string]interface{}:
+			// Structured options such as layers take their value as JSON
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = string(data)
+		default:
+			return nil, fmt.Errorf("option %q must not be null", key)
+		}
+	}
+	return values, nil
+}
+
+func parseYAMLConfig(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		// Quoted values are taken literally; otherwise a " #" starts a comment
+		if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+			if value[0] == '"' {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				value = unquoted
+			} else {
+				value = strings.ReplaceAll(value[1:n-1], "''", "'")
+			}
+		} else if j := strings.Index(value, " #"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("line %d: option %q has no value (nested YAML is not supported)", i+1, key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected func(Config) bool
+		wantErr  string
+	}{
+		{
+			name:    "yaml",
+			file:    "a.yaml",
+			content: "# design A\ntrim-percent: 75\nradius-percent: 10  # smaller corners\nno-crop: true\nseries-color: \"#FF0000\"\n",
+			expected: func(c Config) bool {
+				return c.TrimPercent == 75 && c.RadiusPercent == 10 && !c.CropEnabled && c.SeriesColor == "#FF0000"
+			},
+		},
+		{
+			name:    "json",
+			file:    "b.json",
+			content: `{"padding-percent": 12, "padding-ios-mode": true, "background-pattern": "dots"}`,
+			expected: func(c Config) bool {
+				return c.PaddingPercent == 12 && c.PaddingIOSMode && c.BackgroundPattern == "dots" && c.TrimPercent == 80
+			},
+		},
+		{
+			name:    "json layers",
+			file:    "layers.json",
+			content: `{"layers": [{"type": "fill", "color": "#FFFFFF"}, {"type": "source", "scale": 80}]}`,
+			expected: func(c Config) bool {
+				return c.Layers == `[{"color":"#FFFFFF","type":"fill"},{"scale":80,"type":"source"}]`
+			},
+		},
+		{
+			name:    "unknown option",
+			file:    "c.yaml",
+			content: "trim-percentage: 75\n",
+			wantErr: "unknown option",
+		},
+		{
+			name:    "invalid value",
+			file:    "d.json",
+			content: `{"trim-percent": "most"}`,
+			wantErr: "invalid value",
+		},
+		{
+			name:    "nested yaml",
+			file:    "e.yaml",
+			content: "padding:\n  percent: 10\n",
+			wantErr: "nested YAML is not supported",
+		},
+		{
+			name:    "unsupported type",
+			file:    "f.toml",
+			content: "trim-percent = 75\n",
+			wantErr: "unsupported config file type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			var config Config
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			defineFlags(fs, &config)
+
+			err := loadConfigFile(fs, path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load config file: %v", err)
+			}
+			if !tt.expected(config) {
+				t.Errorf("Config file options not applied, got %+v", config)
+			}
+		})
+	}
+}
+
+func TestNegatedBoolFlag(t *testing.T) {
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, &config)
+
+	if err := fs.Parse([]string{"--no-incremental"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if config.Incremental {
+		t.Errorf("Expected --no-incremental to disable incremental mode")
+	}
+	if !config.CropEnabled {
+		t.Errorf("Expected cropping to stay enabled by default")
+	}
+}
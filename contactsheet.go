@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+)
+
+const contactSheetName = "contact_sheet.png"
+
+// contactSheetTargets lists the outputs --contact-sheet adds to a run. The
+// sheet's dimensions depend on the icons, so they aren't known up front.
+func contactSheetTargets(config Config) []plannedOutput {
+	if !config.ContactSheet {
+		return nil
+	}
+	return []plannedOutput{{Name: contactSheetName, Format: "png", Variant: "contact-sheet"}}
+}
+
+// iconFiles returns the images recorded so far in state, leaving out
+// non-image outputs and the contact sheet itself.
+func iconFiles(state *manifestState) []manifestFile {
+	var icons []manifestFile
+	for _, file := range state.files {
+		if file.Width > 0 && file.Name != contactSheetName {
+			icons = append(icons, file)
+		}
+	}
+	return icons
+}
+
+// writeContactSheet composites every image recorded so far in state into a
+// single labelled PNG, for attaching to pull requests and release notes.
+// Icons are shown at actual size, capped at 256px, and wrap into rows.
+func writeContactSheet(config Config, state *manifestState) error {
+	const (
+		maxIcon    = 256
+		maxWidth   = 1600
+		gap        = 16
+		labelSize  = 10.0
+		lineHeight = 16
+	)
+
+	icons := iconFiles(state)
+
+	type cell struct {
+		file   manifestFile
+		img    image.Image
+		rect   image.Rectangle
+		labelY int
+	}
+
+	// Lay cells out left to right, wrapping into rows
+	var cells []cell
+	x, y, rowHeight, sheetWidth := gap, gap, 0, 0
+	for _, file := range icons {
+		img, err := loadImage(filepath.Join(config.OutputDir, file.Name))
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", file.Name, err)
+		}
+
+		w, h := file.Width, file.Height
+		if w > maxIcon || h > maxIcon {
+			img = resizeImage(img, maxIcon)
+			w, h = maxIcon, maxIcon
+		}
+
+		cellWidth := w
+		if labelWidth := int(measureText(file.Name, labelSize)) + 1; labelWidth > cellWidth {
+			cellWidth = labelWidth
+		}
+		cellHeight := h + gap/2 + 2*lineHeight
+
+		if x > gap && x+cellWidth+gap > maxWidth {
+			x = gap
+			y += rowHeight + gap
+			rowHeight = 0
+		}
+
+		cells = append(cells, cell{
+			file:   file,
+			img:    img,
+			rect:   image.Rect(x, y, x+w, y+h),
+			labelY: y + h + gap/2,
+		})
+
+		x += cellWidth + gap
+		if x > sheetWidth {
+			sheetWidth = x
+		}
+		if cellHeight > rowHeight {
+			rowHeight = cellHeight
+		}
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, sheetWidth, y+rowHeight+gap))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{color.RGBA{255, 255, 255, 255}}, image.Point{}, draw.Src)
+
+	ink := color.RGBA{34, 34, 34, 255}
+	subtle := color.RGBA{120, 120, 120, 255}
+	for _, c := range cells {
+		draw.Draw(sheet, c.rect, c.img, c.img.Bounds().Min, draw.Over)
+		drawText(sheet, c.file.Name, float64(c.rect.Min.X), float64(c.labelY), labelSize, ink)
+		drawText(sheet, fmt.Sprintf("%dx%d", c.file.Width, c.file.Height),
+			float64(c.rect.Min.X), float64(c.labelY+lineHeight), labelSize, subtle)
+	}
+
+	fmt.Fprintf(logOutput(config), " - %s (%d icons)\n", contactSheetName, len(cells))
+	if err := saveImage(sheet, filepath.Join(config.OutputDir, contactSheetName)); err != nil {
+		return fmt.Errorf("failed to save %s: %w", contactSheetName, err)
+	}
+
+	if err := state.record(contactSheetName); err != nil {
+		return fmt.Errorf("failed to record %s: %w", contactSheetName, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteContactSheet(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{255, 0, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 0,
+		ContactSheet:  true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	sheet, err := loadImage(filepath.Join(outputDir, contactSheetName))
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", contactSheetName, err)
+	}
+
+	bounds := sheet.Bounds()
+	if bounds.Dx() > 1600 {
+		t.Errorf("Expected sheet to wrap at 1600px, got width %d", bounds.Dx())
+	}
+	if bounds.Dy() < 256 {
+		t.Errorf("Expected sheet tall enough for the capped 256px icons, got height %d", bounds.Dy())
+	}
+
+	// The first icon (16x16, solid red) sits at the top-left margin
+	if c := color.RGBAModel.Convert(sheet.At(16+8, 16+8)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected first icon at the top-left, got %v", c)
+	}
+
+}
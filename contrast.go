@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// autoContrastClip is the fraction of the darkest and of the lightest pixels
+// --auto-contrast lets clip, so a few stray pixels don't hold the stretch back.
+const autoContrastClip = 0.005
+
+// autoContrastModes are the histograms --auto-contrast can stretch.
+var autoContrastModes = map[string]bool{
+	// One stretch of the lightness for all channels, keeping the hues
+	"luminance": true,
+	// A stretch of each channel on its own, also removing a color cast
+	"channels": true,
+}
+
+// validateAutoContrast checks an --auto-contrast mode.
+func validateAutoContrast(config Config) error {
+	if !autoContrastModes[config.AutoContrast] {
+		return fmt.Errorf("unknown auto contrast mode %q (expected luminance or channels)", config.AutoContrast)
+	}
+	return nil
+}
+
+// autoContrast returns a copy of img with its histogram stretched to the full
+// range, as an editor's auto levels would, to rescue washed-out exports.
+// Transparent pixels don't count towards the histogram and alpha is kept.
+func autoContrast(img image.Image, mode string) image.Image {
+	bounds := img.Bounds()
+	stretched := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	// Histograms of R, G, B and luma
+	var hist [4][256]int
+	total := 0
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			stretched.SetNRGBA(x, y, c)
+			if c.A == 0 {
+				continue
+			}
+			hist[0][c.R]++
+			hist[1][c.G]++
+			hist[2][c.B]++
+			hist[3][luma(c)]++
+			total++
+		}
+	}
+	if total == 0 {
+		return stretched
+	}
+
+	var levels [3][256]uint8
+	if mode == "channels" {
+		for i := range levels {
+			levels[i] = stretchLevels(hist[i], total)
+		}
+	} else {
+		l := stretchLevels(hist[3], total)
+		levels = [3][256]uint8{l, l, l}
+	}
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := stretched.NRGBAAt(x, y)
+			stretched.SetNRGBA(x, y, color.NRGBA{levels[0][c.R], levels[1][c.G], levels[2][c.B], c.A})
+		}
+	}
+
+	return stretched
+}
+
+// stretchLevels returns the lookup table mapping the levels of hist, less
+// autoContrastClip at each end, to 0-255. A histogram of a single level is
+// left as it is.
+func stretchLevels(hist [256]int, total int) [256]uint8 {
+	clip := int(float64(total) * autoContrastClip)
+
+	lo, count := 0, 0
+	for ; lo < 255; lo++ {
+		if count += hist[lo]; count > clip {
+			break
+		}
+	}
+	hi, count := 255, 0
+	for ; hi > 0; hi-- {
+		if count += hist[hi]; count > clip {
+			break
+		}
+	}
+
+	var table [256]uint8
+	for v := range table {
+		if hi <= lo {
+			table[v] = uint8(v)
+			continue
+		}
+		stretched := math.Round(float64(v-lo) * 255 / float64(hi-lo))
+		table[v] = uint8(math.Max(0, math.Min(255, stretched)))
+	}
+	return table
+}
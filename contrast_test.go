@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// createRamp returns a 128x1 image stepping from lo to hi, one pixel per
+// step, with a transparent white pixel first that must not count.
+func createRamp(lo, hi color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 128, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 255, 255, 0})
+	mix := func(a, b uint8, x int) uint8 {
+		return uint8(int(a) + (int(b)-int(a))*(x-1)/126)
+	}
+	for x := 1; x < 128; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{mix(lo.R, hi.R, x), mix(lo.G, hi.G, x), mix(lo.B, hi.B, x), 255})
+	}
+	return img
+}
+
+func TestAutoContrast(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		lo, hi   color.NRGBA
+		expectLo color.NRGBA
+		expectHi color.NRGBA
+	}{
+		{"washed-out gray", "luminance", color.NRGBA{64, 64, 64, 255}, color.NRGBA{191, 191, 191, 255}, color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}},
+		{"full range unchanged", "luminance", color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}, color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}},
+		{"luminance keeps a cast", "luminance", color.NRGBA{100, 50, 50, 255}, color.NRGBA{200, 150, 150, 255}, color.NRGBA{89, 0, 0, 255}, color.NRGBA{255, 217, 217, 255}},
+		{"channels remove a cast", "channels", color.NRGBA{100, 50, 50, 255}, color.NRGBA{200, 150, 150, 255}, color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stretched := autoContrast(createRamp(tt.lo, tt.hi), tt.mode)
+			if got := color.NRGBAModel.Convert(stretched.At(1, 0)).(color.NRGBA); !closeColor(got, tt.expectLo) {
+				t.Errorf("Expected darkest pixel %v, got %v", tt.expectLo, got)
+			}
+			if got := color.NRGBAModel.Convert(stretched.At(127, 0)).(color.NRGBA); !closeColor(got, tt.expectHi) {
+				t.Errorf("Expected lightest pixel %v, got %v", tt.expectHi, got)
+			}
+			if got := color.NRGBAModel.Convert(stretched.At(0, 0)).(color.NRGBA); got.A != 0 {
+				t.Errorf("Expected transparency to be kept, got %v", got)
+			}
+		})
+	}
+}
+
+func TestAutoContrastFlat(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	if got := color.NRGBAModel.Convert(autoContrast(img, "channels").At(2, 2)).(color.NRGBA); got != (color.NRGBA{200, 200, 200, 200}) {
+		t.Errorf("Expected a flat image to stay as it is, got %v", got)
+	}
+}
+
+func TestValidateAutoContrast(t *testing.T) {
+	for _, mode := range []string{"luminance", "channels"} {
+		if err := validateOptions(Config{TrimPercent: 80, AutoContrast: mode}); err != nil {
+			t.Errorf("Expected --auto-contrast=%s to be valid, got %v", mode, err)
+		}
+	}
+	if err := validateOptions(Config{TrimPercent: 80, AutoContrast: "levels"}); err == nil {
+		t.Errorf("Expected an unknown --auto-contrast mode to fail")
+	}
+}
+
+// closeColor reports whether a and b differ by at most 2 in every channel.
+func closeColor(a, b color.NRGBA) bool {
+	near := func(x, y uint8) bool { return int(x)-int(y) <= 2 && int(y)-int(x) <= 2 }
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && a.A == b.A
+}
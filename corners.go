@@ -0,0 +1,151 @@
+package main
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// smoothCornerProfile is the outline of one smoothed corner, following
+// Figma's corner smoothing: a circular arc of the corner radius, shortened
+// as smoothing grows, eased into both edges by cubic Bézier curves that
+// together start (1+smoothing)*radius from the corner.
+//
+// Points are in corner coordinates: u is the distance from the side edge and
+// v the distance from the top edge, so the outline runs from (p, 0) to (0, p)
+// with v increasing and u decreasing.
+type smoothCornerProfile struct {
+	p      float64
+	points [][2]float64
+}
+
+// newSmoothCornerProfile computes the corner outline for radius and
+// smoothing (0-1) on an icon whose shorter side is size pixels. As in Figma,
+// smoothing is reduced where the extended corner wouldn't fit.
+func newSmoothCornerProfile(radius, smoothing, size float64) smoothCornerProfile {
+	budget := size / 2
+	if radius > budget {
+		radius = budget
+	}
+	if radius <= 0 {
+		return smoothCornerProfile{}
+	}
+
+	p := (1 + smoothing) * radius
+	if p > budget {
+		smoothing = math.Min(smoothing, budget/radius-1)
+		p = budget
+	}
+
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	arcMeasure := 90 * (1 - smoothing)
+	arcSection := math.Sin(rad(arcMeasure/2)) * radius * math.Sqrt2
+	angleAlpha := (90 - arcMeasure) / 2
+	p3ToP4 := radius * math.Tan(rad(angleAlpha/2))
+	angleBeta := 45 * smoothing
+	c := p3ToP4 * math.Cos(rad(angleBeta))
+	d := c * math.Tan(rad(angleBeta))
+	b := (p - arcSection - c - d) / 3
+	a := 2 * b
+
+	const steps = 64
+	points := [][2]float64{{p, 0}}
+
+	cubic := func(p0, p1, p2, p3 [2]float64) {
+		for i := 1; i <= steps; i++ {
+			t := float64(i) / steps
+			mt := 1 - t
+			var pt [2]float64
+			for k := 0; k < 2; k++ {
+				pt[k] = mt*mt*mt*p0[k] + 3*mt*mt*t*p1[k] + 3*mt*t*t*p2[k] + t*t*t*p3[k]
+			}
+			points = append(points, pt)
+		}
+	}
+
+	// Ease out of the top edge
+	start := [2]float64{p, 0}
+	arcStart := [2]float64{p - a - b - c, d}
+	cubic(start, [2]float64{p - a, 0}, [2]float64{p - a - b, 0}, arcStart)
+
+	// Circular arc centred on the 45° diagonal
+	for i := 1; i <= steps; i++ {
+		theta := rad(-45 - arcMeasure/2 + arcMeasure*float64(i)/steps)
+		points = append(points, [2]float64{radius - radius*math.Cos(theta), radius + radius*math.Sin(theta)})
+	}
+
+	// Ease into the side edge
+	arcEnd := points[len(points)-1]
+	cubic(arcEnd,
+		[2]float64{arcEnd[0] - d, arcEnd[1] + c},
+		[2]float64{arcEnd[0] - d, arcEnd[1] + b + c},
+		[2]float64{0, p})
+
+	return smoothCornerProfile{p: p, points: points}
+}
+
+// edge returns the distance from the side edge where the outline crosses
+// distance v from the top edge, for 0 <= v <= p.
+func (c smoothCornerProfile) edge(v float64) float64 {
+	i := sort.Search(len(c.points), func(i int) bool { return c.points[i][1] >= v })
+	if i == 0 {
+		return c.points[0][0]
+	}
+	if i == len(c.points) {
+		return 0
+	}
+	p0, p1 := c.points[i-1], c.points[i]
+	if p1[1] == p0[1] {
+		return math.Min(p0[0], p1[0])
+	}
+	t := (v - p0[1]) / (p1[1] - p0[1])
+	return p0[0] + t*(p1[0]-p0[0])
+}
+
+// addSmoothCorners returns img with Figma-style smoothed corners of the
+// given radius and smoothing (0-1), anti-aliased.
+func addSmoothCorners(img image.Image, radius int, smoothing float64) image.Image {
+	const samples = 4
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	masked, scale := maskedCopy(img)
+
+	profile := newSmoothCornerProfile(float64(radius), smoothing, math.Min(float64(width), float64(height)))
+	if profile.p == 0 {
+		return masked
+	}
+
+	corner := int(math.Ceil(profile.p))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Only the corner boxes are affected
+			inX := x < corner || x >= width-corner
+			inY := y < corner || y >= height-corner
+			if !inX || !inY {
+				continue
+			}
+
+			inside := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := float64(x) + (float64(sx)+0.5)/samples
+					py := float64(y) + (float64(sy)+0.5)/samples
+					u := math.Min(px, float64(width)-px)
+					v := math.Min(py, float64(height)-py)
+					if u >= profile.p || v >= profile.p || u >= profile.edge(v) {
+						inside++
+					}
+				}
+			}
+			if inside == samples*samples {
+				continue
+			}
+
+			scale(x, y, float64(inside)/(samples*samples))
+		}
+	}
+
+	return masked
+}
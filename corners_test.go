@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSmoothCornerProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		radius    float64
+		smoothing float64
+		size      float64
+		wantP     float64
+	}{
+		{"no smoothing", 20, 0, 100, 20},
+		{"iOS smoothing", 20, 0.6, 100, 32},
+		{"full smoothing", 20, 1, 100, 40},
+		{"capped by size", 40, 1, 100, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := newSmoothCornerProfile(tt.radius, tt.smoothing, tt.size)
+			if math.Abs(profile.p-tt.wantP) > 1e-9 {
+				t.Errorf("Expected corner to extend %g pixels, got %g", tt.wantP, profile.p)
+			}
+
+			// The outline runs from the top edge to the side edge
+			first, last := profile.points[0], profile.points[len(profile.points)-1]
+			if math.Abs(first[0]-profile.p) > 1e-6 || math.Abs(first[1]) > 1e-6 {
+				t.Errorf("Expected outline to start at (%g, 0), got %v", profile.p, first)
+			}
+			if math.Abs(last[0]) > 1e-6 || math.Abs(last[1]-profile.p) > 1e-6 {
+				t.Errorf("Expected outline to end at (0, %g), got %v", profile.p, last)
+			}
+		})
+	}
+
+	// Without smoothing the corner is a circular arc of the radius
+	profile := newSmoothCornerProfile(20, 0, 100)
+	for _, v := range []float64{2, 10, 18} {
+		want := 20 - math.Sqrt(20*20-(20-v)*(20-v))
+		if got := profile.edge(v); math.Abs(got-want) > 0.05 {
+			t.Errorf("Expected circular edge at v=%g to be %.3f, got %.3f", v, want, got)
+		}
+	}
+}
+
+func TestAddSmoothCorners(t *testing.T) {
+	img := createTestImage(100, color.RGBA{0, 0, 255, 255})
+	circular := addSmoothCorners(img, 20, 0)
+	smooth := addSmoothCorners(img, 20, 0.6)
+
+	alpha := func(x, y int, smoothing float64) uint32 {
+		m := circular
+		if smoothing > 0 {
+			m = smooth
+		}
+		_, _, _, a := m.At(x, y).RGBA()
+		return a
+	}
+
+	for _, s := range []float64{0, 0.6} {
+		if a := alpha(0, 0, s); a != 0 {
+			t.Errorf("Expected transparent corner with smoothing %g, got alpha %d", s, a)
+		}
+		if a := alpha(50, 50, s); a != 0xffff {
+			t.Errorf("Expected opaque center with smoothing %g, got alpha %d", s, a)
+		}
+	}
+
+	// Smoothing starts the curve further along the edge, so the smoothed
+	// corner cuts away more near the edge than the circular one
+	var circularRow, smoothRow uint32
+	for x := 0; x < 40; x++ {
+		circularRow += alpha(x, 0, 0)
+		smoothRow += alpha(x, 0, 0.6)
+	}
+	if alpha(21, 0, 0) != 0xffff || alpha(21, 0, 0.6) == 0xffff || smoothRow >= circularRow {
+		t.Errorf("Expected smoothed corner to ease in further along the edge")
+	}
+}
+
+func TestCornerSmoothingValidation(t *testing.T) {
+	for _, smoothing := range []float64{-0.1, 1.5} {
+		config := Config{TrimPercent: 80, RadiusPercent: 20, CornerSmoothing: smoothing}
+		if err := validateOptions(config); err == nil {
+			t.Errorf("Expected error for corner smoothing %g", smoothing)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseCropAnchor parses a --crop-anchor: one of the --overlay-anchor
+// positions such as top or bottom-left, or x%,y% for the share of the
+// cropped-away margin that goes to the left and to the top. No anchor is
+// the center.
+func parseCropAnchor(spec string) ([2]float64, error) {
+	if spec == "" {
+		spec = "center"
+	}
+	if anchor, ok := overlayAnchors[spec]; ok {
+		return anchor, nil
+	}
+
+	x, y, ok := strings.Cut(spec, ",")
+	if !ok {
+		return [2]float64{}, fmt.Errorf("unknown crop anchor %q (expected center, top, bottom, left, right, a corner such as top-left, or x%%,y%% such as 50%%,30%%)", spec)
+	}
+	var anchor [2]float64
+	for i, part := range []string{x, y} {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(part), "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return [2]float64{}, fmt.Errorf("crop anchor %q must give two percentages between 0%% and 100%%", spec)
+		}
+		anchor[i] = percent / 100
+	}
+	return anchor, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestParseCropAnchor(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    [2]float64
+		wantErr bool
+	}{
+		{"", [2]float64{0.5, 0.5}, false},
+		{"center", [2]float64{0.5, 0.5}, false},
+		{"top", [2]float64{0.5, 0}, false},
+		{"bottom-left", [2]float64{0, 1}, false},
+		{"50%,30%", [2]float64{0.5, 0.3}, false},
+		{"0,100", [2]float64{0, 1}, false},
+		{"middle", [2]float64{}, true},
+		{"50%", [2]float64{}, true},
+		{"120%,0%", [2]float64{}, true},
+		{"a%,b%", [2]float64{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCropAnchor(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCropAnchor(%q): expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCropAnchor(%q): expected %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, CropEnabled: true, SmartCrop: true, CropAnchor: "top"}); err == nil {
+		t.Errorf("Expected --smart-crop with --crop-anchor to fail")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// darkSourceAuto is the --dark-source that derives the dark artwork from the
+// source instead of reading it from a file.
+const darkSourceAuto = "auto"
+
+// darkIconName returns the file name of the dark-mode copy of name.
+func darkIconName(name string) string {
+	return strings.TrimSuffix(name, ".png") + "_dark.png"
+}
+
+// darkTargets lists the outputs the --dark-source copies of config produce.
+func darkTargets(config Config) []plannedOutput {
+	if config.DarkSource == "" {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, iconSize := range outputSizes(config) {
+		if isStandaloneIcon(iconSize) {
+			continue
+		}
+		target := iconTarget(config, iconSize, darkIconName(iconSize.Name), "dark")
+		target.Settings["dark-source"] = config.DarkSource
+		targets = append(targets, target)
+
+		for _, variant := range variantsFor(iconVariants(config), iconSize) {
+			target := iconTarget(config, iconSize, darkIconName(variantIconName(iconSize.Name, variant.Name)), "dark")
+			for key, value := range variant.describe(iconSize.Size) {
+				target.Settings[key] = value
+			}
+			target.Settings["dark-source"] = config.DarkSource
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// loadDarkSource returns the dark artwork: the --dark-source image, cropped
+// like the source, or with "auto" the source with its lightness inverted.
+func loadDarkSource(sourceImg image.Image, config Config) (image.Image, error) {
+	if config.DarkSource == darkSourceAuto {
+		return invertLightness(sourceImg), nil
+	}
+
+	config.InputPath = config.DarkSource
+	darkImg, err := loadSource(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dark source: %w", err)
+	}
+	return darkImg, nil
+}
+
+// generateDark writes a _dark copy of every regular icon and rounded or
+// masked variant, rendered from the dark artwork with the same options.
+// Standalone icons have none.
+func generateDark(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, state *manifestState) error {
+	darkImg, err := loadDarkSource(sourceImg, config)
+	if err != nil {
+		return err
+	}
+
+	variants := iconVariants(config)
+	for _, iconSize := range outputSizes(config) {
+		iconSize := iconSize
+		if isStandaloneIcon(iconSize) {
+			continue
+		}
+
+		// Resize lazily, once per size, only if one of its outputs is stale
+		var resized image.Image
+		prepared := func() image.Image {
+			if resized == nil {
+				resized = prepareIcon(darkImg, config, pattern, backgroundImg, layers, iconSize.Size)
+			}
+			return resized
+		}
+
+		label := fmt.Sprintf("%dx%d, dark", iconSize.Size, iconSize.Size)
+		err := saveOutput(config, state, darkIconName(iconSize.Name), label, func() image.Image {
+			return finishIcon(prepared(), config, iconSize, nil)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, variant := range variantsFor(variants, iconSize) {
+			variant := variant
+			name := darkIconName(variantIconName(iconSize.Name, variant.Name))
+			label := fmt.Sprintf("%dx%d, %s, dark", iconSize.Size, iconSize.Size, variant.label(iconSize.Size))
+			err := saveOutput(config, state, name, label, func() image.Image {
+				return finishIcon(variant.mask(prepared(), iconSize.Size), config, iconSize, variant.mask)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// invertLightness returns a copy of img with the lightness of every pixel
+// inverted and its hue and saturation kept, so black line art turns white
+// while brand colors stay recognizable.
+func invertLightness(img image.Image) image.Image {
+	bounds := img.Bounds()
+	inverted := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+
+			// In HSL, inverting the lightness keeps the hue and saturation
+			// and maps every channel v to v + 1 - max - min
+			shift := 1 - math.Max(r, math.Max(g, b)) - math.Min(r, math.Min(g, b))
+			inverted.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Round((r + shift) * 255)),
+				G: uint8(math.Round((g + shift) * 255)),
+				B: uint8(math.Round((b + shift) * 255)),
+				A: c.A,
+			})
+		}
+	}
+
+	return inverted
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInvertLightness(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  color.NRGBA
+		expect color.NRGBA
+	}{
+		{"black turns white", color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}},
+		{"white turns black", color.NRGBA{255, 255, 255, 255}, color.NRGBA{0, 0, 0, 255}},
+		{"pure hue stays", color.NRGBA{255, 0, 0, 255}, color.NRGBA{255, 0, 0, 255}},
+		{"dark blue turns light blue", color.NRGBA{0, 0, 128, 255}, color.NRGBA{127, 127, 255, 255}},
+		{"alpha kept", color.NRGBA{0, 0, 0, 64}, color.NRGBA{255, 255, 255, 64}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.SetNRGBA(0, 0, tt.input)
+			got := color.NRGBAModel.Convert(invertLightness(img).At(0, 0)).(color.NRGBA)
+			if got != tt.expect {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestGenerateDark(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 255, 255, 255}))
+	darkPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 0, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "web", TrimPercent: 80, RadiusPercent: 20, DarkSource: darkPath}
+	if err := validateConfig(config); err != nil {
+		t.Fatalf("Expected valid config: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		expect color.RGBA
+	}{
+		{"favicon-32x32.png", color.RGBA{255, 255, 255, 255}},
+		{"favicon-32x32_dark.png", color.RGBA{0, 0, 255, 255}},
+		{"favicon-32x32_rounded_dark.png", color.RGBA{0, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := loadImage(filepath.Join(outputDir, tt.name))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", tt.name, err)
+			}
+			got := color.RGBAModel.Convert(img.At(16, 16)).(color.RGBA)
+			if got != tt.expect {
+				t.Errorf("Expected %v at the center, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	head, err := os.ReadFile(filepath.Join(outputDir, webHeadName))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", webHeadName, err)
+	}
+	for _, want := range []string{
+		`<link rel="icon" type="image/png" sizes="32x32" media="(prefers-color-scheme: light)" href="/favicon-32x32.png">`,
+		`<link rel="icon" type="image/png" sizes="32x32" media="(prefers-color-scheme: dark)" href="/favicon-32x32_dark.png">`,
+	} {
+		if !strings.Contains(string(head), want) {
+			t.Errorf("Expected head snippet to contain %q, got:\n%s", want, head)
+		}
+	}
+}
+
+func TestGenerateDarkSkipsStandalone(t *testing.T) {
+	// Notification icons are tinted silhouettes, with no dark copy to write
+	inputPath := createTempImageFile(t, createTestImageWithSquare(64, 40, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: notificationPreset, TrimPercent: 80, DarkSource: darkSourceAuto}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "drawable-mdpi", notificationIcon)); err != nil {
+		t.Errorf("Expected the notification icon: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "drawable-mdpi", darkIconName(notificationIcon))); err == nil {
+		t.Errorf("Expected no dark copy of the notification icon")
+	}
+	for _, target := range darkTargets(config) {
+		if strings.HasSuffix(target.Name, darkIconName(notificationIcon)) {
+			t.Errorf("Expected no dark target for %s", target.Name)
+		}
+	}
+}
+
+func TestDarkSourceValidation(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 255, 255, 255}))
+
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"auto", Config{InputPath: inputPath, TrimPercent: 80, DarkSource: "auto"}, false},
+		{"missing file", Config{InputPath: inputPath, TrimPercent: 80, DarkSource: "missing-dark.png"}, true},
+		{"with appearances", Config{InputPath: inputPath, TrimPercent: 80, DarkSource: "auto", Appearances: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(tt.config)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderIconSetDarkAuto(t *testing.T) {
+	source := pngData(t, createTestImage(64, color.RGBA{0, 0, 0, 255}))
+	config := Config{TrimPercent: 80, DarkSource: darkSourceAuto}
+
+	files, err := renderIconSet(source, config)
+	if err != nil {
+		t.Fatalf("Failed to render icon set: %v", err)
+	}
+	if len(files) != 2*len(iconSizes) {
+		t.Fatalf("Expected %d files, got %d", 2*len(iconSizes), len(files))
+	}
+	if last := files[len(files)-1].Name; last != darkIconName(iconSizes[len(iconSizes)-1].Name) {
+		t.Errorf("Expected the dark copies last, got %s", last)
+	}
+}
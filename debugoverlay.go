@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// Colors of the --debug-overlay guides: keylines, which artwork usually
+// lines up with, and safe zones, which it has to stay within.
+var (
+	keylineColor  = color.NRGBA{0xFF, 0x2D, 0x55, 0xE0}
+	safeZoneColor = color.NRGBA{0x34, 0xC7, 0x59, 0xE0}
+)
+
+// guideLine is one line of a --debug-overlay: dist returns how far the
+// point (x, y) of a size x size icon is from it, in pixels.
+type guideLine struct {
+	color color.NRGBA
+	dist  func(x, y, size float64) float64
+}
+
+// circleGuide is a circle around the center, with a diameter of diameter
+// of the icon size.
+func circleGuide(c color.NRGBA, diameter float64) guideLine {
+	return guideLine{c, func(x, y, size float64) float64 {
+		return math.Abs(math.Hypot(x-size/2, y-size/2) - size*diameter/2)
+	}}
+}
+
+// rectGuide is a centered rectangle of width by height with corners of
+// radius, all fractions of the icon size.
+func rectGuide(c color.NRGBA, width, height, radius float64) guideLine {
+	return guideLine{c, func(x, y, size float64) float64 {
+		r := size * radius
+		qx := math.Abs(x-size/2) - size*width/2 + r
+		qy := math.Abs(y-size/2) - size*height/2 + r
+		outside := math.Hypot(math.Max(qx, 0), math.Max(qy, 0))
+		return math.Abs(outside + math.Min(math.Max(qx, qy), 0) - r)
+	}}
+}
+
+// segmentGuide is a line from (x0, y0) to (x1, y1), fractions of the icon
+// size.
+func segmentGuide(c color.NRGBA, x0, y0, x1, y1 float64) guideLine {
+	return guideLine{c, func(x, y, size float64) float64 {
+		ax, ay := x0*size, y0*size
+		dx, dy := (x1-x0)*size, (y1-y0)*size
+		t := math.Max(0, math.Min(1, ((x-ax)*dx+(y-ay)*dy)/(dx*dx+dy*dy)))
+		return math.Hypot(x-ax-t*dx, y-ay-t*dy)
+	}}
+}
+
+// presetGuides are the --debug-overlay guides drawn over the icons of each
+// preset.
+var presetGuides = map[string]func(config Config) []guideLine{
+	// The Big Sur icon grid: the 824px body on the 1024px canvas, the
+	// circle it inscribes and the smaller circle of the grid, with the
+	// center lines and diagonals of the body
+	"macos": func(config Config) []guideLine {
+		inset, body := 100.0/1024, 824.0/1024
+		return []guideLine{
+			rectGuide(keylineColor, body, body, 185.4/1024),
+			circleGuide(keylineColor, body),
+			circleGuide(keylineColor, body/math.Phi),
+			segmentGuide(keylineColor, 0.5, inset, 0.5, 1-inset),
+			segmentGuide(keylineColor, inset, 0.5, 1-inset, 0.5),
+			segmentGuide(keylineColor, inset, inset, 1-inset, 1-inset),
+			segmentGuide(keylineColor, inset, 1-inset, 1-inset, inset),
+		}
+	},
+	// The Material keylines of a 48dp launcher icon: the 44dp circle, the
+	// 38dp square and the 32x44dp portrait and landscape rectangles
+	"android": func(config Config) []guideLine {
+		return []guideLine{
+			circleGuide(keylineColor, 44.0/48),
+			rectGuide(keylineColor, 38.0/48, 38.0/48, 2.0/48),
+			rectGuide(keylineColor, 32.0/48, 44.0/48, 2.0/48),
+			rectGuide(keylineColor, 44.0/48, 32.0/48, 2.0/48),
+		}
+	},
+	// The 22dp live area of a 24dp notification icon
+	notificationPreset: func(config Config) []guideLine {
+		return []guideLine{rectGuide(safeZoneColor, 22.0/24, 22.0/24, 0)}
+	},
+	// Maskable web app icons keep a circle of 40% radius
+	"web": func(config Config) []guideLine {
+		return []guideLine{circleGuide(safeZoneColor, 0.8)}
+	},
+}
+
+// adaptiveGuides are the --debug-overlay guides of an adaptive icon
+// foreground: the 72dp a launcher mask shows of the 108dp layer, and the
+// --safe-zone circle.
+func adaptiveGuides(config Config) []guideLine {
+	return []guideLine{
+		rectGuide(keylineColor, 72.0/108, 72.0/108, 0),
+		circleGuide(safeZoneColor, float64(safeZone(config))/100),
+	}
+}
+
+// debugOutput is an output --debug-overlay draws guides over.
+type debugOutput struct {
+	IconSize
+	guides []guideLine
+}
+
+// debugOutputs lists the outputs of config --debug-overlay draws guides
+// over: the regular icons of every preset that has guides, and the adaptive
+// icon foregrounds.
+func debugOutputs(config Config) []debugOutput {
+	if !config.DebugOverlay {
+		return nil
+	}
+
+	var outputs []debugOutput
+	selected, _ := parsePresets(config.Preset)
+	for _, p := range selected {
+		guides, ok := presetGuides[p.Name]
+		if !ok {
+			continue
+		}
+		// The sizes of this preset alone, named as the run names them
+		c := config
+		c.Preset, c.First = p.Name, ""
+		for _, iconSize := range outputSizes(c) {
+			outputs = append(outputs, debugOutput{iconSize, guides(config)})
+		}
+	}
+	for _, target := range adaptiveTargets(config) {
+		if strings.HasSuffix(target.Name, adaptiveForeground) {
+			outputs = append(outputs, debugOutput{IconSize{target.Name, target.Width}, adaptiveGuides(config)})
+		}
+	}
+	return outputs
+}
+
+// debugIconName returns the file name of the --debug-overlay copy of name.
+func debugIconName(name string) string {
+	return variantIconName(name, "debug")
+}
+
+// debugTargets lists the outputs --debug-overlay adds to a run.
+func debugTargets(config Config) []plannedOutput {
+	var targets []plannedOutput
+	for _, output := range debugOutputs(config) {
+		targets = append(targets, plannedOutput{
+			Name:    debugIconName(output.Name),
+			Width:   output.Size,
+			Height:  output.Size,
+			Format:  "png",
+			Variant: "debug",
+		})
+	}
+	return targets
+}
+
+// writeDebugOverlays writes a _debug copy of every output debugOutputs
+// lists, with its platform's keylines and safe zones drawn on top, so
+// designers can check where the artwork sits. It reads the outputs back as
+// written.
+func writeDebugOverlays(config Config, state *manifestState) error {
+	for _, output := range debugOutputs(config) {
+		img, err := loadImage(filepath.Join(config.OutputDir, output.Name))
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", output.Name, err)
+		}
+		output := output
+		label := fmt.Sprintf("%dx%d, debug overlay", output.Size, output.Size)
+		err = saveOutput(config, state, debugIconName(output.Name), label, func() image.Image {
+			return drawGuides(img, output.guides)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return state.flush()
+}
+
+// drawGuides returns a copy of img with guides stroked over it, anti-aliased
+// and about 1px wide per 256px of size, at least 1px.
+func drawGuides(img image.Image, guides []guideLine) *image.RGBA {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Src)
+
+	size := float64(bounds.Dx())
+	width := math.Max(1, size/256)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			for _, guide := range guides {
+				coverage := width/2 + 0.5 - guide.dist(float64(x)+0.5, float64(y)+0.5, size)
+				if coverage <= 0 {
+					continue
+				}
+				c := guide.color
+				c.A = uint8(float64(c.A) * math.Min(1, coverage))
+				draw.Draw(canvas, image.Rect(x, y, x+1, y+1), &image.Uniform{c}, image.Point{}, draw.Over)
+			}
+		}
+	}
+	return canvas
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestDebugOverlay(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(256, color.RGBA{255, 255, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "macos,android", TrimPercent: 80, Monochrome: "alpha", DebugOverlay: true}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		x, y  int
+		guide color.NRGBA
+		plain bool
+	}{
+		// The center lines of the Apple grid cross in the middle
+		{"icon_256x256.png", 128, 64, keylineColor, false},
+		// The 44dp keyline circle of a 192px launcher icon passes 8px in
+		{"mipmap-xxxhdpi/ic_launcher.png", 96, 8, keylineColor, false},
+		// The safe zone circle of a 432px foreground layer
+		{"mipmap-xxxhdpi/ic_launcher_foreground.png", 216, 216 - 216*defaultSafeZone/100, safeZoneColor, false},
+		// Away from every guide the icon shows through
+		{"mipmap-xxxhdpi/ic_launcher.png", 60, 96, color.NRGBA{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := loadImage(filepath.Join(outputDir, debugIconName(tt.name)))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", debugIconName(tt.name), err)
+			}
+			original, err := loadImage(filepath.Join(outputDir, tt.name))
+			if err != nil {
+				t.Fatalf("Failed to load %s: %v", tt.name, err)
+			}
+			got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)).(color.NRGBA)
+			want := color.NRGBAModel.Convert(original.At(tt.x, tt.y)).(color.NRGBA)
+			if tt.plain {
+				if got != want {
+					t.Errorf("Expected the icon unchanged at (%d, %d), got %v instead of %v", tt.x, tt.y, got, want)
+				}
+				return
+			}
+			if got == want || (got.G > got.R) != (tt.guide.G > tt.guide.R) {
+				t.Errorf("Expected a guide in %v at (%d, %d), got %v", tt.guide, tt.x, tt.y, got)
+			}
+		})
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	debug := 0
+	for _, target := range targets {
+		if target.Variant == "debug" {
+			debug++
+		}
+	}
+	if want := len(iconSizes) + 5 + 5; debug != want {
+		t.Errorf("Expected %d debug targets, got %d", want, debug)
+	}
+}
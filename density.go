@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// maxDPI is the highest --dpi accepted, well past any screen or printer.
+const maxDPI = 10000
+
+// metersPerInch converts --dpi to the pixels per meter of a pHYs chunk.
+const metersPerInch = 0.0254
+
+// densityChunks returns the pHYs chunk recording the pixel density of the
+// icons of config: the --dpi, or else that of a PNG source, copied as is.
+// Without either the icons have no density, which viewers take as 72 DPI.
+//
+// sourceChunk reads a chunk of the source, as readPNGChunk does.
+func densityChunks(config Config, sourceChunk func(typ string) ([]byte, error)) ([]pngChunk, error) {
+	if config.DPI > 0 {
+		return []pngChunk{{"pHYs", physChunk(config.DPI)}}, nil
+	}
+	phys, err := sourceChunk("pHYs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the source's pixel density: %w", err)
+	}
+	if len(phys) != 9 {
+		return nil, nil
+	}
+	return []pngChunk{{"pHYs", phys}}, nil
+}
+
+// physChunk returns the payload of a pHYs chunk for square pixels at dpi.
+func physChunk(dpi int) []byte {
+	perMeter := uint32(math.Round(float64(dpi) / metersPerInch))
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data[0:], perMeter)
+	binary.BigEndian.PutUint32(data[4:], perMeter)
+	data[8] = 1 // the unit is the meter
+	return data
+}
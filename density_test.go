@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPhysChunk(t *testing.T) {
+	tests := []struct {
+		dpi      int
+		perMeter []byte
+	}{
+		{72, []byte{0, 0, 0x0b, 0x13}},  // 2835
+		{144, []byte{0, 0, 0x16, 0x25}}, // 5669
+	}
+	for _, tt := range tests {
+		data := physChunk(tt.dpi)
+		want := append(append(append([]byte{}, tt.perMeter...), tt.perMeter...), 1)
+		if !bytes.Equal(data, want) {
+			t.Errorf("Expected pHYs %v for %d DPI, got %v", want, tt.dpi, data)
+		}
+	}
+}
+
+func TestDensity(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, createTestImage(64, color.RGBA{0, 255, 0, 255})); err != nil {
+		t.Fatalf("Failed to encode source: %v", err)
+	}
+	data, err := addPNGChunk(buf.Bytes(), "pHYs", physChunk(300))
+	if err != nil {
+		t.Fatalf("Failed to add a density: %v", err)
+	}
+	dense := filepath.Join(t.TempDir(), "300dpi.png")
+	if err := os.WriteFile(dense, data, 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	plain := createTempImageFile(t, createTestImage(64, color.RGBA{0, 255, 0, 255}))
+
+	tests := []struct {
+		name  string
+		input string
+		dpi   int
+		want  []byte
+	}{
+		{"no density", plain, 0, nil},
+		{"source density", dense, 0, physChunk(300)},
+		{"--dpi", plain, 144, physChunk(144)},
+		{"--dpi over the source's", dense, 72, physChunk(72)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			config := Config{InputPath: tt.input, OutputDir: outputDir, TrimPercent: 80, DPI: tt.dpi}
+			if err := generateIcons(config); err != nil {
+				t.Fatalf("Failed to generate icons: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+			if err != nil {
+				t.Fatalf("Failed to read icon: %v", err)
+			}
+			phys, ok := pngChunkData(data, "pHYs")
+			if tt.want == nil && ok {
+				t.Errorf("Expected no pHYs chunk, got %v", phys)
+			}
+			if tt.want != nil && !bytes.Equal(phys, tt.want) {
+				t.Errorf("Expected pHYs %v, got %v", tt.want, phys)
+			}
+		})
+	}
+}
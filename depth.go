@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// A 16-bit source, and any source with --bit-depth=16, is processed at 16
+// bits per channel as far as the stages allow: cropping, resizing, the
+// padding and the rounded and circle masks keep *image.RGBA64 images
+// 16-bit. Every other stage works in 8 bits and returns an *image.RGBA, so
+// an output that goes through one has no more precision than an 8-bit icon.
+
+// validateBitDepth checks the --bit-depth value.
+func validateBitDepth(depth int) error {
+	if depth != 0 && depth != 8 && depth != 16 {
+		return fmt.Errorf("bit depth must be 8 or 16 (got %d)", depth)
+	}
+	return nil
+}
+
+// deepImage reports whether img holds more than 8 bits per channel.
+func deepImage(img image.Image) bool {
+	switch img := img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		return true
+	case *mipmapImage:
+		return deepImage(img.Image)
+	}
+	return false
+}
+
+// toRGBA64 copies img into a new *image.RGBA64 starting at the origin.
+func toRGBA64(img image.Image) *image.RGBA64 {
+	bounds := img.Bounds()
+	deep := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(deep, deep.Bounds(), img, bounds.Min, draw.Src)
+	return deep
+}
+
+// cropDeep returns the area of img cropAnchor keeps as an *image.RGBA64.
+func cropDeep(img image.Image, percent int, anchor [2]float64) *image.RGBA64 {
+	r := anchoredCrop(img.Bounds(), percent, anchor)
+	cropped := image.NewRGBA64(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, r.Min, draw.Src)
+	return cropped
+}
+
+// newCanvasLike returns a transparent canvas of r as deep as img.
+func newCanvasLike(img image.Image, r image.Rectangle) draw.Image {
+	if deepImage(img) {
+		return image.NewRGBA64(r)
+	}
+	return image.NewRGBA(r)
+}
+
+// maskedCopy copies img to a new image starting at the origin, as deep as
+// img, and returns it with a function scaling the pixel at (x, y) by
+// coverage, 0-1, which the masks clear and anti-alias their edges with.
+func maskedCopy(img image.Image) (image.Image, func(x, y int, coverage float64)) {
+	bounds := img.Bounds()
+	if deepImage(img) {
+		masked := toRGBA64(img)
+		return masked, func(x, y int, coverage float64) {
+			c := masked.RGBA64At(x, y)
+			scale := func(v uint16) uint16 { return uint16(math.Round(float64(v) * coverage)) }
+			masked.SetRGBA64(x, y, color.RGBA64{scale(c.R), scale(c.G), scale(c.B), scale(c.A)})
+		}
+	}
+
+	masked := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(masked, masked.Bounds(), img, bounds.Min, draw.Src)
+	return masked, func(x, y int, coverage float64) {
+		c := masked.RGBAAt(x, y)
+		scale := func(v uint8) uint8 { return uint8(math.Round(float64(v) * coverage)) }
+		masked.SetRGBA(x, y, color.RGBA{scale(c.R), scale(c.G), scale(c.B), scale(c.A)})
+	}
+}
+
+// roundRGBA copies img into a new *image.RGBA starting at the origin, with
+// its channels rounded to 8 bits rather than truncated as draw.Draw does.
+func roundRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	at := pixelReader(img)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := at(bounds.Min.X+x, bounds.Min.Y+y)
+			setPixel(rgba, x, y, r, g, b, a)
+		}
+	}
+	return rgba
+}
+
+// outputDepth converts a rendered output to the --bit-depth: 16-bit images
+// are rounded to 8 bits unless it is 16, and 8-bit ones widened if it is.
+// It also reports whether the output lost precision on the way to a 16-bit
+// file, having gone through a stage that only works in 8 bits.
+func outputDepth(img image.Image, config Config) (image.Image, bool) {
+	deep := deepImage(img)
+	if config.BitDepth != 16 {
+		if deep {
+			return roundRGBA(img), false
+		}
+		return img, false
+	}
+	if deep {
+		return img, false
+	}
+	return toRGBA64(img), true
+}
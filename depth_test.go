@@ -0,0 +1,92 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+// gradient16 returns a size x size 16-bit gradient with more steps across
+// than 8 bits can hold.
+func gradient16(size int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := uint16(x * 0xffff / (size - 1))
+			img.SetNRGBA64(x, y, color.NRGBA64{v, v / 2, 0xffff - v, 0xffff})
+		}
+	}
+	return img
+}
+
+// distinctReds counts the different 16-bit red values along row y of img.
+func distinctReds(img image.Image, y int) int {
+	seen := make(map[uint32]bool)
+	bounds := img.Bounds()
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		r, _, _, _ := img.At(x, y).RGBA()
+		seen[r] = true
+	}
+	return len(seen)
+}
+
+func TestBitDepth(t *testing.T) {
+	inputPath := createTempImageFile(t, gradient16(1200))
+
+	tests := []struct {
+		name   string
+		config Config
+		deep   bool
+		steps  bool
+	}{
+		{"16-bit", Config{BitDepth: 16, RadiusPercent: 20, PaddingPercent: 5}, true, true},
+		{"16-bit with a foreground scale", Config{BitDepth: 16, ForegroundScale: 80}, true, true},
+		{"8-bit from a 16-bit source", Config{RadiusPercent: 20}, false, false},
+		{"16-bit through an 8-bit effect", Config{BitDepth: 16, Effects: "gloss"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.InputPath, config.OutputDir = inputPath, t.TempDir()
+			config.TrimPercent, config.CropEnabled = 90, true
+			if err := generateIcons(config); err != nil {
+				t.Fatalf("Failed to generate icons: %v", err)
+			}
+
+			for _, name := range []string{"icon_1024x1024.png", "icon_1024x1024_rounded.png"} {
+				if name != "icon_1024x1024.png" && config.RadiusPercent == 0 {
+					continue
+				}
+				img, err := loadImage(filepath.Join(config.OutputDir, name))
+				if err != nil {
+					t.Fatalf("Failed to load %s: %v", name, err)
+				}
+				if deepImage(img) != tt.deep {
+					t.Errorf("Expected %s to be 16-bit: %v, got %T", name, tt.deep, img)
+				}
+				if steps := distinctReds(img, 512); (steps > 256) != tt.steps {
+					t.Errorf("Expected %s to keep the 16-bit steps of the gradient: %v, got %d values", name, tt.steps, steps)
+				}
+			}
+		})
+	}
+}
+
+func TestFitFilterDeep(t *testing.T) {
+	source := gradient16(90)
+	deep := fitFilterDeep(source, 40, catmullRomFilter, false)
+	shallow := resizeFiltered(source, 40, catmullRomFilter)
+
+	// The same resize, rounded to 8 bits
+	if got := roundRGBA(deep); string(got.Pix) != string(shallow.Pix) {
+		t.Errorf("Expected the 16-bit resize to round to the 8-bit one")
+	}
+	if steps := distinctReds(fitFilterDeep(source, 400, catmullRomFilter, true), 200); steps <= 256 {
+		t.Errorf("Expected a 16-bit upscale to keep more than 256 steps, got %d", steps)
+	}
+
+	if err := validateBitDepth(12); err == nil {
+		t.Errorf("Expected an error for --bit-depth=12")
+	}
+}
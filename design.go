@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	designSVGName = "icon_grid.svg"
+	// designSize is the canvas of the design file, the App Store icon size
+	designSize = 1024
+)
+
+// safeZones are the platform guides the design file draws for every preset
+// of a run, as SVG elements on a size x size canvas.
+var safeZones = map[string]func(size float64) string{
+	// The Big Sur icon grid: an 824px body on the 1024px canvas
+	"macos": func(size float64) string {
+		inset := size * 100 / 1024
+		return fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" rx="%s"/>`,
+			svgNumber(inset), svgNumber(inset), svgNumber(size-2*inset), svgNumber(size-2*inset), svgNumber(size*185.4/1024))
+	},
+	// Adaptive icons keep a 66dp circle of the 108dp layer clear of masks
+	"android": func(size float64) string {
+		return fmt.Sprintf(`<circle cx="%s" cy="%s" r="%s"/>`, svgNumber(size/2), svgNumber(size/2), svgNumber(size*33/108))
+	},
+	// Maskable web app icons keep a circle of 40% radius
+	"web": func(size float64) string {
+		return fmt.Sprintf(`<circle cx="%s" cy="%s" r="%s"/>`, svgNumber(size/2), svgNumber(size/2), svgNumber(size*0.4))
+	},
+}
+
+// designSVGTargets lists the outputs --design-svg adds to a run.
+func designSVGTargets(config Config) []plannedOutput {
+	if !config.DesignSVG {
+		return nil
+	}
+	return []plannedOutput{{Name: designSVGName, Width: designSize, Height: designSize, Format: "svg", Variant: "design"}}
+}
+
+// writeDesignSVG writes a layered SVG of the composed icon for design tools:
+// the artwork as an embedded PNG, then the variant masks, the padding and
+// the platform safe zone as separate vector layers on top. Figma, Sketch,
+// Illustrator and Inkscape all import the groups as layers.
+func writeDesignSVG(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, state *manifestState) error {
+	artwork := applyEffects(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, designSize), config, designSize)
+	svg, err := designSVG(artwork, config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(logOutput(config), " - %s (%dx%d)\n", designSVGName, designSize, designSize)
+	if err := os.WriteFile(filepath.Join(config.OutputDir, designSVGName), svg, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", designSVGName, err)
+	}
+
+	if err := state.record(designSVGName); err != nil {
+		return fmt.Errorf("failed to record %s: %w", designSVGName, err)
+	}
+	return nil
+}
+
+// designSVG returns the design file for artwork rendered at designSize.
+func designSVG(artwork image.Image, config Config) ([]byte, error) {
+	size := float64(designSize)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		designSize, designSize, designSize, designSize)
+
+	artworkURI, err := pngDataURI(artwork)
+	if err != nil {
+		return nil, err
+	}
+	designLayer(&b, "Artwork", "", []string{
+		fmt.Sprintf(`<image width="%d" height="%d" xlink:href="%s"/>`, designSize, designSize, artworkURI),
+	})
+
+	var masks []string
+	if hasRoundedVariant(config) {
+		radius := float64(cornerRadius(config, designSize))
+		masks = append(masks, fmt.Sprintf(`<path id="mask-rounded" d="%s"/>`, roundedSquarePath(size, radius, config.CornerSmoothing)))
+	}
+	if config.Mask != "" {
+		names, _ := parseMasks(config.Mask)
+		for _, name := range names {
+			if name == "circle" {
+				masks = append(masks, fmt.Sprintf(`<circle id="mask-circle" cx="%s" cy="%s" r="%s"/>`, svgNumber(size/2), svgNumber(size/2), svgNumber(size/2)))
+			}
+		}
+	}
+	if config.MaskImage != "" {
+		if mask, err := loadImage(config.MaskImage); err == nil {
+			maskURI, err := pngDataURI(resizeImage(mask, designSize))
+			if err != nil {
+				return nil, err
+			}
+			masks = append(masks, fmt.Sprintf(`<image id="mask-image" width="%d" height="%d" opacity="0.5" xlink:href="%s"/>`, designSize, designSize, maskURI))
+		}
+	}
+	designLayer(&b, "Masks", `fill="none" stroke="#FF2D55" stroke-width="2"`, masks)
+
+	if padding := paddingPercent(config, designSize); padding > 0 {
+		// addPadding shrinks the artwork into the middle of a larger canvas
+		inset := size * padding / (100 + 2*padding)
+		designLayer(&b, "Padding", `fill="none" stroke="#0A84FF" stroke-width="2" stroke-dasharray="8 8"`, []string{
+			fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s"/>`, svgNumber(inset), svgNumber(inset), svgNumber(size-2*inset), svgNumber(size-2*inset)),
+		})
+	}
+
+	var zones []string
+	selected, _ := parsePresets(config.Preset)
+	for _, p := range selected {
+		if zone, ok := safeZones[p.Name]; ok {
+			zones = append(zones, zone(size))
+		}
+	}
+	designLayer(&b, "Safe Zone", `fill="none" stroke="#34C759" stroke-width="2" stroke-dasharray="16 8"`, zones)
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String()), nil
+}
+
+// designLayer writes a named group that design tools import as a layer,
+// leaving it out if it has no elements.
+func designLayer(b *strings.Builder, name, attrs string, elements []string) {
+	if len(elements) == 0 {
+		return
+	}
+	id := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+	if attrs != "" {
+		attrs = " " + attrs
+	}
+	fmt.Fprintf(b, `  <g id="%s" inkscape:label="%s" inkscape:groupmode="layer"%s>`+"\n", id, name, attrs)
+	for _, element := range elements {
+		b.WriteString("    " + element + "\n")
+	}
+	b.WriteString("  </g>\n")
+}
+
+// roundedSquarePath returns the SVG path of the rounded variant's outline on
+// a size x size canvas: circular corners of radius, or Figma-style smoothed
+// corners traced as a polygon.
+func roundedSquarePath(size, radius, smoothing float64) string {
+	if radius <= 0 {
+		return fmt.Sprintf("M0 0H%[1]sV%[1]sH0Z", svgNumber(size))
+	}
+	if smoothing == 0 {
+		radius = math.Min(radius, size/2)
+		r, far := svgNumber(radius), svgNumber(size-radius)
+		return fmt.Sprintf("M%s 0H%sA%s %s 0 0 1 %s %sV%sA%s %s 0 0 1 %s %sH%sA%s %s 0 0 1 0 %sV%sA%s %s 0 0 1 %s 0Z",
+			r, far, r, r, svgNumber(size), r, far, r, r, far, svgNumber(size), r, r, r, far, r, r, r, r)
+	}
+
+	profile := newSmoothCornerProfile(radius, smoothing, size)
+	n := len(profile.points)
+	var points [][2]float64
+	// Clockwise from the top-left corner, mirroring the profile into each
+	// corner; the straight edges join them
+	for i := n - 1; i >= 0; i-- {
+		u, v := profile.points[i][0], profile.points[i][1]
+		points = append(points, [2]float64{u, v})
+	}
+	for i := 0; i < n; i++ {
+		u, v := profile.points[i][0], profile.points[i][1]
+		points = append(points, [2]float64{size - u, v})
+	}
+	for i := n - 1; i >= 0; i-- {
+		u, v := profile.points[i][0], profile.points[i][1]
+		points = append(points, [2]float64{size - u, size - v})
+	}
+	for i := 0; i < n; i++ {
+		u, v := profile.points[i][0], profile.points[i][1]
+		points = append(points, [2]float64{u, size - v})
+	}
+
+	var d strings.Builder
+	for i, p := range points {
+		command := "L"
+		if i == 0 {
+			command = "M"
+		}
+		fmt.Fprintf(&d, "%s%s %s", command, svgNumber(p[0]), svgNumber(p[1]))
+	}
+	d.WriteString("Z")
+	return d.String()
+}
+
+// svgNumber formats v with at most two decimals.
+func svgNumber(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// pngDataURI encodes img as a data: URI for embedding.
+func pngDataURI(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/xml"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDesignSVG(t *testing.T) {
+	artwork := createTestImage(designSize, color.RGBA{255, 0, 0, 255})
+
+	tests := []struct {
+		name   string
+		config Config
+		layers []string
+	}{
+		{"macos", Config{RadiusPercent: 20}, []string{"artwork", "masks", "safe-zone"}},
+		{"padded circle", Config{Mask: "circle", PaddingPercent: 10}, []string{"artwork", "masks", "padding", "safe-zone"}},
+		{"plain android", Config{Preset: "android"}, []string{"artwork", "safe-zone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := designSVG(artwork, tt.config)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			var svg struct {
+				Width  int `xml:"width,attr"`
+				Groups []struct {
+					ID string `xml:"id,attr"`
+				} `xml:"g"`
+			}
+			if err := xml.Unmarshal(data, &svg); err != nil {
+				t.Fatalf("Expected well-formed SVG, got: %v", err)
+			}
+			if svg.Width != designSize {
+				t.Errorf("Expected width %d, got %d", designSize, svg.Width)
+			}
+
+			var ids []string
+			for _, g := range svg.Groups {
+				ids = append(ids, g.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.layers, ",") {
+				t.Errorf("Expected layers %v, got %v", tt.layers, ids)
+			}
+		})
+	}
+}
+
+func TestRoundedSquarePath(t *testing.T) {
+	if got := roundedSquarePath(100, 0, 0); got != "M0 0H100V100H0Z" {
+		t.Errorf("Expected a plain square, got %q", got)
+	}
+	if got := roundedSquarePath(100, 20, 0); !strings.HasPrefix(got, "M20 0H80A20 20 0 0 1 100 20") {
+		t.Errorf("Expected circular corners of radius 20, got %q", got)
+	}
+
+	// Smoothed corners start further from the corner and trace back to it
+	smooth := roundedSquarePath(100, 20, 0.6)
+	if !strings.HasPrefix(smooth, "M0 32L") || !strings.HasSuffix(smooth, "Z") {
+		t.Errorf("Expected a closed outline starting 32px down the left edge, got %q...", smooth[:20])
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// imageDiff summarizes how far two images of the same size are apart.
+type imageDiff struct {
+	SSIM      float64 // mean structural similarity over RGBA, 1 for identical images
+	MeanDelta float64 // mean absolute channel difference, 0-1
+	Changed   int     // number of pixels that differ at all
+}
+
+// runDiff implements "icongen diff": it compares two icon sets file by file,
+// reporting files only present on one side and a perceptual score for every
+// image present on both, and optionally writes images highlighting changes.
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var outputDir string
+	var failUnder float64
+	flags.StringVar(&outputDir, "output", "", "Write an image highlighting the changed pixels of every changed icon to this directory")
+	flags.Float64Var(&failUnder, "fail-under", 0, "Exit with an error if any file is missing, extra, or scores an SSIM below this (0-1, 0 disables)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s diff [options] old-dir new-dir\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Compare two icon sets, reporting missing and extra files and an SSIM score per changed image.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("diff requires an old and a new directory")
+	}
+	if failUnder < 0 || failUnder > 1 {
+		return fmt.Errorf("fail-under must be between 0 and 1 (got %g)", failUnder)
+	}
+
+	oldDir, newDir := flags.Arg(0), flags.Arg(1)
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", oldDir, err)
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", newDir, err)
+	}
+
+	var missing, extra, failing []string
+	for name := range oldFiles {
+		if !newFiles[name] {
+			missing = append(missing, name)
+		}
+	}
+	for name := range newFiles {
+		if !oldFiles[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+
+	var common []string
+	for name := range oldFiles {
+		if newFiles[name] {
+			common = append(common, name)
+		}
+	}
+	sort.Strings(common)
+
+	fmt.Printf("Comparing %s with %s\n", oldDir, newDir)
+	changed := 0
+	for _, name := range common {
+		oldPath := filepath.Join(oldDir, filepath.FromSlash(name))
+		newPath := filepath.Join(newDir, filepath.FromSlash(name))
+
+		oldData, err := os.ReadFile(oldPath)
+		if err != nil {
+			return err
+		}
+		newData, err := os.ReadFile(newPath)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(oldData, newData) {
+			continue
+		}
+		changed++
+
+		oldImg, _, oldErr := image.Decode(bytes.NewReader(oldData))
+		newImg, _, newErr := image.Decode(bytes.NewReader(newData))
+		if oldErr != nil || newErr != nil {
+			fmt.Printf(" - %s: changed\n", name)
+			if failUnder > 0 {
+				failing = append(failing, name)
+			}
+			continue
+		}
+
+		oldSize, newSize := oldImg.Bounds().Size(), newImg.Bounds().Size()
+		if oldSize != newSize {
+			fmt.Printf(" - %s: dimensions changed %dx%d -> %dx%d\n", name, oldSize.X, oldSize.Y, newSize.X, newSize.Y)
+			if failUnder > 0 {
+				failing = append(failing, name)
+			}
+			continue
+		}
+
+		d := compareImages(oldImg, newImg)
+		fmt.Printf(" - %s: SSIM %.4f, mean delta %.2f%%, %d pixels changed\n", name, d.SSIM, d.MeanDelta*100, d.Changed)
+		if d.SSIM < failUnder {
+			failing = append(failing, name)
+		}
+
+		if outputDir != "" && d.Changed > 0 {
+			path := filepath.Join(outputDir, strings.TrimSuffix(filepath.FromSlash(name), filepath.Ext(name))+".png")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := saveImage(diffImage(oldImg, newImg), path); err != nil {
+				return fmt.Errorf("failed to save diff image: %w", err)
+			}
+		}
+	}
+
+	for _, name := range missing {
+		fmt.Printf(" - %s: missing from %s\n", name, newDir)
+	}
+	for _, name := range extra {
+		fmt.Printf(" - %s: only in %s\n", name, newDir)
+	}
+	fmt.Printf("%d changed, %d unchanged, %d missing, %d extra\n",
+		changed, len(common)-changed, len(missing), len(extra))
+
+	if failUnder > 0 {
+		failing = append(failing, missing...)
+		failing = append(failing, extra...)
+		if len(failing) > 0 {
+			return fmt.Errorf("%d files differ beyond --fail-under=%g: %s", len(failing), failUnder, strings.Join(failing, ", "))
+		}
+	}
+	return nil
+}
+
+// listFiles returns the slash-separated paths of the regular files under
+// dir, leaving out icongen's own manifest.
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() == manifestName {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// compareImages scores two images of the same size. SSIM is computed per
+// premultiplied RGBA channel over 8x8 windows and averaged, so changes to
+// fully transparent pixels don't count.
+func compareImages(a, b image.Image) imageDiff {
+	const window = 8
+	const c1, c2 = 0.01 * 0.01, 0.03 * 0.03
+
+	ab, bb := a.Bounds(), b.Bounds()
+	width, height := ab.Dx(), ab.Dy()
+	if width == 0 || height == 0 {
+		return imageDiff{SSIM: 1}
+	}
+
+	channels := func(img image.Image, bounds image.Rectangle, x, y int) [4]float64 {
+		r, g, bl, al := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return [4]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(bl) / 0xffff, float64(al) / 0xffff}
+	}
+
+	var d imageDiff
+	var ssimSum float64
+	windows := 0
+	var deltaSum float64
+
+	for wy := 0; wy < height; wy += window {
+		for wx := 0; wx < width; wx += window {
+			var sumA, sumB, sumAA, sumBB, sumAB [4]float64
+			n := 0.0
+
+			for y := wy; y < wy+window && y < height; y++ {
+				for x := wx; x < wx+window && x < width; x++ {
+					pa := channels(a, ab, x, y)
+					pb := channels(b, bb, x, y)
+					differs := false
+					for c := 0; c < 4; c++ {
+						sumA[c] += pa[c]
+						sumB[c] += pb[c]
+						sumAA[c] += pa[c] * pa[c]
+						sumBB[c] += pb[c] * pb[c]
+						sumAB[c] += pa[c] * pb[c]
+						deltaSum += math.Abs(pa[c] - pb[c])
+						if pa[c] != pb[c] {
+							differs = true
+						}
+					}
+					if differs {
+						d.Changed++
+					}
+					n++
+				}
+			}
+
+			for c := 0; c < 4; c++ {
+				meanA, meanB := sumA[c]/n, sumB[c]/n
+				varA := sumAA[c]/n - meanA*meanA
+				varB := sumBB[c]/n - meanB*meanB
+				cov := sumAB[c]/n - meanA*meanB
+				ssimSum += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+					((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			}
+			windows++
+		}
+	}
+
+	d.SSIM = ssimSum / float64(4*windows)
+	d.MeanDelta = deltaSum / float64(4*width*height)
+	return d
+}
+
+// diffImage renders the new image faded over white with every changed pixel
+// painted red, brighter the larger the change.
+func diffImage(a, b image.Image) *image.RGBA {
+	ab, bb := a.Bounds(), b.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, ab.Dx(), ab.Dy()))
+
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			r1, g1, b1, a1 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+
+			delta := math.Max(math.Max(absDiff(r1, r2), absDiff(g1, g2)), math.Max(absDiff(b1, b2), absDiff(a1, a2))) / 0xffff
+			if delta > 0 {
+				// Small changes still need to be visible
+				strength := 0.35 + 0.65*delta
+				out.SetRGBA(x, y, color.RGBA{255, uint8(255 * (1 - strength)), uint8(255 * (1 - strength)), 255})
+				continue
+			}
+
+			// Unchanged: the new pixel's luma at 25% over white
+			luma := (0.299*float64(r2) + 0.587*float64(g2) + 0.114*float64(b2)) / 0xffff
+			v := uint8(math.Round(255 * (1 - 0.25*(float64(a2)/0xffff-luma))))
+			out.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return out
+}
+
+func absDiff(a, b uint32) float64 {
+	return math.Abs(float64(a) - float64(b))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareImages(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	base := createTestImage(32, red)
+
+	onePixel := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			onePixel.SetRGBA(x, y, red)
+		}
+	}
+	onePixel.SetRGBA(5, 5, color.RGBA{0, 0, 255, 255})
+
+	tests := []struct {
+		name        string
+		other       image.Image
+		wantChanged int
+		minSSIM     float64
+		maxSSIM     float64
+	}{
+		{"identical", createTestImage(32, red), 0, 1, 1},
+		{"one pixel", onePixel, 1, 0.9, 0.9999},
+		{"recolored", createTestImage(32, color.RGBA{0, 0, 255, 255}), 32 * 32, -1, 0.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := compareImages(base, tt.other)
+			if d.Changed != tt.wantChanged {
+				t.Errorf("Expected %d changed pixels, got %d", tt.wantChanged, d.Changed)
+			}
+			if d.SSIM < tt.minSSIM-1e-9 || d.SSIM > tt.maxSSIM+1e-9 {
+				t.Errorf("Expected SSIM between %.4f and %.4f, got %.4f", tt.minSSIM, tt.maxSSIM, d.SSIM)
+			}
+		})
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir := filepath.Join(tempDir, "old")
+	newDir := filepath.Join(tempDir, "new")
+	diffDir := filepath.Join(tempDir, "diff")
+
+	write := func(dir, name string, c color.RGBA) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if err := saveImage(createTestImage(16, c), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Failed to save %s: %v", name, err)
+		}
+	}
+
+	write(oldDir, "icon_16x16.png", color.RGBA{255, 0, 0, 255})
+	write(newDir, "icon_16x16.png", color.RGBA{255, 0, 0, 255})
+	write(oldDir, "icon_32x32.png", color.RGBA{255, 0, 0, 255})
+	write(newDir, "icon_32x32.png", color.RGBA{0, 0, 255, 255})
+	write(oldDir, "icon_old.png", color.RGBA{255, 0, 0, 255})
+
+	if err := runDiff([]string{"--output", diffDir, oldDir, newDir}); err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(diffDir, "icon_32x32.png")); err != nil {
+		t.Errorf("Expected diff image for changed icon: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(diffDir, "icon_16x16.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected no diff image for unchanged icon")
+	}
+
+	err := runDiff([]string{"--fail-under", "0.99", oldDir, newDir})
+	if err == nil {
+		t.Fatalf("Expected error with --fail-under")
+	}
+	for _, name := range []string{"icon_32x32.png", "icon_old.png"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error to mention %s, got %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "icon_16x16.png") {
+		t.Errorf("Expected unchanged icon to pass, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"unicode/utf16"
+)
+
+// marketingIcon is the App Store icon, the one output --color-space=p3
+// converts to Display P3.
+const marketingIcon = "icon_1024x1024.png"
+
+// srgbToP3 converts linear sRGB to linear Display P3. Both share the D65
+// white point and differ only in their primaries.
+var srgbToP3 = [3][3]float64{
+	{0.8224621, 0.1775380, 0},
+	{0.0331941, 0.9668058, 0},
+	{0.0170827, 0.0723974, 0.9105199},
+}
+
+// validateColorSpace checks the --color-space of config.
+func validateColorSpace(config Config) error {
+	switch config.ColorSpace {
+	case "", "srgb":
+		return nil
+	case "p3":
+	default:
+		return fmt.Errorf("unknown color space %q (expected srgb or p3)", config.ColorSpace)
+	}
+
+	if config.ColorProfile == "source" || config.ColorProfile == "none" {
+		return fmt.Errorf("--color-space=p3 converts from sRGB and tags the result; it can't be combined with --color-profile=%s", config.ColorProfile)
+	}
+	for _, iconSize := range outputSizes(config) {
+		if iconSize.Name == marketingIcon {
+			return nil
+		}
+	}
+	return fmt.Errorf("--color-space=p3 converts %s, which --preset=%s doesn't generate", marketingIcon, config.Preset)
+}
+
+// p3Output reports whether the output called name is converted to Display
+// P3.
+func p3Output(config Config, name string) bool {
+	return config.ColorSpace == "p3" && name == marketingIcon
+}
+
+// outputChunks returns the color space and density chunks saveOutput writes
+// into the PNG called name: the Display P3 profile for a p3Output, or the
+// --color-profile chunks.
+func outputChunks(config Config, state *manifestState, name string) []pngChunk {
+	color := state.color
+	if p3Output(config, name) {
+		color = []pngChunk{{"iCCP", displayP3ICCP()}}
+	}
+	return append(append([]pngChunk{}, color...), state.density...)
+}
+
+// toDisplayP3 returns the sRGB img converted to Display P3: the same colors
+// as P3 values, which look alike on a wide-gamut display once the result is
+// tagged with the Display P3 profile. A 16-bit img stays 16-bit.
+func toDisplayP3(img image.Image) image.Image {
+	bounds := img.Bounds()
+	deep := deepImage(img)
+	var converted *image.NRGBA64
+	var shallow *image.NRGBA
+	if deep {
+		converted = image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	} else {
+		shallow = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	}
+
+	decode := srgbDecodeTable()
+	at := pixelReader(img)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := at(bounds.Min.X+x, bounds.Min.Y+y)
+			if a == 0 {
+				continue
+			}
+			unpremultiply := func(v uint32) float64 { return decode[v*0xffff/a] }
+			linear := [3]float64{unpremultiply(r), unpremultiply(g), unpremultiply(b)}
+
+			var p3 [3]uint16
+			for c, row := range srgbToP3 {
+				v := row[0]*linear[0] + row[1]*linear[1] + row[2]*linear[2]
+				p3[c] = uint16(math.Round(linearToSRGB(math.Max(0, math.Min(1, v))) * 0xffff))
+			}
+			if deep {
+				converted.SetNRGBA64(x, y, color.NRGBA64{p3[0], p3[1], p3[2], uint16(a)})
+			} else {
+				shallow.SetNRGBA(x, y, color.NRGBA{to8(uint32(p3[0])), to8(uint32(p3[1])), to8(uint32(p3[2])), to8(a)})
+			}
+		}
+	}
+	if deep {
+		return converted
+	}
+	return shallow
+}
+
+// displayP3ICCP returns the payload of an iCCP chunk holding the Display P3
+// profile.
+func displayP3ICCP() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Display P3\x00")
+	buf.WriteByte(0) // zlib
+	w := zlib.NewWriter(&buf)
+	w.Write(displayP3Profile())
+	w.Close()
+	return buf.Bytes()
+}
+
+// displayP3Profile builds an ICC v4 display profile for Display P3: the P3
+// primaries with the D65 white point and the sRGB transfer function, as in
+// the profile macOS ships. Values are adapted to the D50 connection space.
+func displayP3Profile() []byte {
+	s15 := func(v float64) uint32 { return uint32(int32(math.Round(v * 65536))) }
+	xyz := func(x, y, z float64) []byte {
+		data := []byte("XYZ \x00\x00\x00\x00")
+		return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(data, s15(x)), s15(y)), s15(z))
+	}
+	mluc := func(text string) []byte {
+		data := []byte("mluc\x00\x00\x00\x00")
+		data = binary.BigEndian.AppendUint32(data, 1)  // records
+		data = binary.BigEndian.AppendUint32(data, 12) // record size
+		data = append(data, "enUS"...)
+		units := utf16.Encode([]rune(text))
+		data = binary.BigEndian.AppendUint32(data, uint32(2*len(units)))
+		data = binary.BigEndian.AppendUint32(data, 28)
+		for _, u := range units {
+			data = binary.BigEndian.AppendUint16(data, u)
+		}
+		return data
+	}
+	// The sRGB curve: (a*x+b)^g above d, c*x below
+	trc := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		trc = binary.BigEndian.AppendUint32(trc, s15(v))
+	}
+	// Bradford adaptation from D65 to D50
+	chad := []byte("sf32\x00\x00\x00\x00")
+	for _, v := range []float64{1.047882, 0.022919, -0.050201, 0.029587, 0.990479, -0.017059, -0.009232, 0.015076, 0.751678} {
+		chad = binary.BigEndian.AppendUint32(chad, s15(v))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", mluc("Display P3")},
+		{"cprt", mluc("No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.515102, 0.241196, -0.001053)},
+		{"gXYZ", xyz(0.291965, 0.692236, 0.041885)},
+		{"bXYZ", xyz(0.157153, 0.066567, 0.784385)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+		{"chad", chad},
+	}
+
+	// The header, the tag table, then the tags, each 4-byte aligned; the
+	// color channels share one curve
+	offset := 128 + 4 + 12*len(tags)
+	var table, data []byte
+	table = binary.BigEndian.AppendUint32(table, uint32(len(tags)))
+	offsets := make(map[string]int)
+	for _, tag := range tags {
+		at, shared := offsets[string(tag.data)]
+		if !shared {
+			at = offset + len(data)
+			offsets[string(tag.data)] = at
+			data = append(data, tag.data...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		table = append(table, tag.sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(at))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(offset+len(data)))
+	binary.BigEndian.PutUint32(header[8:], 0x04300000) // version 4.3
+	copy(header[12:], "mntrRGB XYZ ")
+	// A fixed creation date, so outputs stay reproducible
+	for i, v := range []uint16{2026, 1, 1, 0, 0, 0} {
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[68:], s15(0.9642))
+	binary.BigEndian.PutUint32(header[72:], s15(1))
+	binary.BigEndian.PutUint32(header[76:], s15(0.8249))
+
+	return append(append(header, table...), data...)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToDisplayP3(t *testing.T) {
+	tests := []struct {
+		name string
+		in   color.RGBA
+		want color.NRGBA
+	}{
+		{"red", color.RGBA{255, 0, 0, 255}, color.NRGBA{234, 51, 35, 255}},
+		{"white", color.RGBA{255, 255, 255, 255}, color.NRGBA{255, 255, 255, 255}},
+		{"translucent green", color.RGBA{0, 128, 0, 128}, color.NRGBA{117, 251, 76, 128}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := color.NRGBAModel.Convert(toDisplayP3(createTestImage(4, tt.in)).At(1, 1)).(color.NRGBA)
+			near := func(a, b uint8) bool { return int(a)-int(b) <= 1 && int(b)-int(a) <= 1 }
+			if !near(got.R, tt.want.R) || !near(got.G, tt.want.G) || !near(got.B, tt.want.B) || got.A != tt.want.A {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDisplayP3Output(t *testing.T) {
+	outputDir := t.TempDir()
+	input := createTempImageFile(t, createTestImage(1024, color.RGBA{255, 0, 0, 255}))
+	config := Config{InputPath: input, OutputDir: outputDir, TrimPercent: 80, ColorSpace: "p3"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, marketingIcon))
+	if err != nil {
+		t.Fatalf("Failed to read the marketing icon: %v", err)
+	}
+	icc, ok := pngChunkData(data, "iCCP")
+	if !ok || !bytes.HasPrefix(icc, []byte("Display P3\x00\x00")) {
+		t.Fatalf("Expected a Display P3 iCCP chunk")
+	}
+	if _, ok := pngChunkData(data, "sRGB"); ok {
+		t.Errorf("Expected no sRGB chunk alongside the Display P3 profile")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(icc[len("Display P3\x00\x00"):]))
+	if err != nil {
+		t.Fatalf("Failed to decompress the profile: %v", err)
+	}
+	profile, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress the profile: %v", err)
+	}
+	if len(profile) < 128 || string(profile[36:40]) != "acsp" || int(profile[3])|int(profile[2])<<8 != len(profile) {
+		t.Errorf("Expected a valid ICC profile header")
+	}
+
+	img, err := loadImage(filepath.Join(outputDir, marketingIcon))
+	if err != nil {
+		t.Fatalf("Failed to load the marketing icon: %v", err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(512, 512)).(color.NRGBA); c.G < 40 || c.R > 240 {
+		t.Errorf("Expected sRGB red converted to P3 values, got %v", c)
+	}
+
+	small, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	if _, ok := pngChunkData(small, "sRGB"); !ok {
+		t.Errorf("Expected the other icons to stay sRGB")
+	}
+}
+
+func TestValidateColorSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"default", Config{}, ""},
+		{"p3", Config{ColorSpace: "p3"}, ""},
+		{"unknown", Config{ColorSpace: "adobe-rgb"}, "unknown color space"},
+		{"source profile", Config{ColorSpace: "p3", ColorProfile: "source"}, "--color-profile=source"},
+		{"no profile", Config{ColorSpace: "p3", ColorProfile: "none"}, "--color-profile=none"},
+		{"no marketing icon", Config{ColorSpace: "p3", Preset: "web"}, "doesn't generate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateColorSpace(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// bayer4 is the 4x4 Bayer threshold matrix ordered dithering adds to each
+// pixel, in sixteenths of a quantization step.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// validateDither checks the --dither value.
+func validateDither(dither string) error {
+	switch dither {
+	case "", "none", "ordered", "floyd-steinberg":
+		return nil
+	}
+	return fmt.Errorf("unknown dither %q (expected none, ordered or floyd-steinberg)", dither)
+}
+
+// gradientDither returns the dithering --background-gradient is quantized
+// with: the --dither, or ordered by default.
+func gradientDither(config Config) string {
+	if config.Dither == "" {
+		return "ordered"
+	}
+	return config.Dither
+}
+
+// bayerOffset returns the ordered dithering offset of the pixel at (x, y),
+// from -0.5 to 0.5 of a quantization step.
+func bayerOffset(x, y int) float64 {
+	return (bayer4[y%4][x%4]+0.5)/16 - 0.5
+}
+
+// ditherRGBA quantizes a size x size canvas of premultiplied 0-255 channel
+// values, given row by row by at, to 8 bits with dither: rounding each
+// value with none, adding bayerOffset with ordered, and spreading the
+// rounding error to the pixels not yet quantized with floyd-steinberg.
+func ditherRGBA(size int, dither string, at func(x, y int) [4]float64) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	// The errors carried to this row and the next, one pixel of margin on
+	// either side
+	errs := [2][][4]float64{make([][4]float64, size+2), make([][4]float64, size+2)}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := at(x, y)
+			var q [4]uint8
+			for c := 3; c >= 0; c-- {
+				want := v[c]
+				switch dither {
+				case "ordered":
+					want += bayerOffset(x, y)
+				case "floyd-steinberg":
+					want += errs[0][x+1][c]
+				}
+				rounded := math.Max(0, math.Min(255, math.Round(want)))
+				if c < 3 {
+					// Keep the channels premultiplied
+					rounded = math.Min(rounded, float64(q[3]))
+				}
+				q[c] = uint8(rounded)
+
+				if dither == "floyd-steinberg" {
+					e := want - rounded
+					errs[0][x+2][c] += e * 7 / 16
+					errs[1][x][c] += e * 3 / 16
+					errs[1][x+1][c] += e * 5 / 16
+					errs[1][x+2][c] += e * 1 / 16
+				}
+			}
+			canvas.SetRGBA(x, y, color.RGBA{q[0], q[1], q[2], q[3]})
+		}
+		errs[0], errs[1] = errs[1], make([][4]float64, size+2)
+	}
+	return canvas
+}
+
+// ditherPaletted converts img to an image of pal with ordered dithering,
+// offsetting each pixel by bayerOffset of step, the distance between the
+// palette's levels.
+func ditherPaletted(img *image.RGBA, pal color.Palette, step float64) *image.Paletted {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, pal)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			offset := func(v uint8) uint8 {
+				return uint8(math.Max(0, math.Min(float64(c.A), math.Round(float64(v)+bayerOffset(x, y)*step))))
+			}
+			paletted.SetColorIndex(x, y, uint8(pal.Index(color.RGBA{offset(c.R), offset(c.G), offset(c.B), c.A})))
+		}
+	}
+	return paletted
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDitherRGBA(t *testing.T) {
+	// A flat value halfway between two 8-bit levels
+	at := func(x, y int) [4]float64 { return [4]float64{100.5, 100.5, 100.5, 255} }
+
+	tests := []struct {
+		dither string
+		mixed  bool
+	}{
+		{"none", false},
+		{"ordered", true},
+		{"floyd-steinberg", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dither, func(t *testing.T) {
+			canvas := ditherRGBA(16, tt.dither, at)
+			sum := 0.0
+			levels := map[uint8]bool{}
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 16; x++ {
+					c := canvas.RGBAAt(x, y)
+					sum += float64(c.R)
+					levels[c.R] = true
+				}
+			}
+			if mixed := len(levels) > 1; mixed != tt.mixed {
+				t.Errorf("Expected mixed levels %v, got %d levels", tt.mixed, len(levels))
+			}
+			if tt.mixed && math.Abs(sum/256-100.5) > 0.05 {
+				t.Errorf("Expected an average level of 100.5, got %.3f", sum/256)
+			}
+		})
+	}
+}
+
+func TestDitherGradient(t *testing.T) {
+	// A gradient too shallow for 8 bits: 64 rows over 8 levels
+	stops := []color.RGBA{{0, 0, 0, 255}, {8, 8, 8, 255}}
+
+	for _, dither := range []string{"none", "ordered", "floyd-steinberg"} {
+		t.Run(dither, func(t *testing.T) {
+			canvas := renderGradient(backgroundGradient{Stops: stops, Angle: 180, Dither: dither}, 64)
+
+			mixed := 0
+			for y := 0; y < 64; y++ {
+				sum := 0.0
+				seen := map[uint8]bool{}
+				for x := 0; x < 64; x++ {
+					c := canvas.RGBAAt(x, y)
+					if c.A != 255 || c.R != c.G || c.G != c.B {
+						t.Fatalf("Expected opaque gray, got %v", c)
+					}
+					sum += float64(c.R)
+					seen[c.R] = true
+				}
+				if len(seen) > 1 {
+					mixed++
+				}
+				want := gradientValue(stops, (float64(y)+0.5)/64)[0]
+				if got := sum / 64; math.Abs(got-want) > 0.5 {
+					t.Errorf("Row %d: expected an average level of %.2f, got %.2f", y, want, got)
+				}
+			}
+
+			if dither == "none" && mixed != 0 {
+				t.Errorf("Expected flat bands without dithering, got %d mixed rows", mixed)
+			}
+			if dither != "none" && mixed < 32 {
+				t.Errorf("Expected most rows to mix two levels, got %d", mixed)
+			}
+		})
+	}
+}
+
+func TestDitherKeepsPremultiplied(t *testing.T) {
+	stops := []color.RGBA{{0, 0, 0, 0}, {100, 50, 0, 100}}
+	for _, dither := range []string{"ordered", "floyd-steinberg"} {
+		canvas := renderGradient(backgroundGradient{Stops: stops, Radial: true, Dither: dither}, 32)
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				if c := canvas.RGBAAt(x, y); c.R > c.A || c.G > c.A {
+					t.Fatalf("%s: expected premultiplied colors, got %v at (%d, %d)", dither, c, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateDither(t *testing.T) {
+	for _, dither := range []string{"", "none", "ordered", "floyd-steinberg"} {
+		if err := validateDither(dither); err != nil {
+			t.Errorf("%q: unexpected error: %v", dither, err)
+		}
+	}
+	if err := validateDither("atkinson"); err == nil {
+		t.Errorf("Expected an error for an unknown dither")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// defaultLongShadowLength is the length --long-shadow uses, as a percentage
+// of the icon size, when --long-shadow-length isn't set.
+const defaultLongShadowLength = 50
+
+// longShadowLength returns the long shadow length of config as a percentage
+// of the icon size, 0 when there is no long shadow.
+func longShadowLength(config Config) int {
+	if config.LongShadow && config.LongShadowLength == 0 {
+		return defaultLongShadowLength
+	}
+	return config.LongShadowLength
+}
+
+// addLongShadow casts a flat-design long shadow from the artwork's silhouette
+// onto the transparent area behind it. The shadow runs lengthPx pixels in the
+// direction given by angle (degrees clockwise from +x, so 45 points to the
+// bottom right) and is drawn in black at opacityPercent.
+func addLongShadow(img image.Image, angle float64, lengthPx int, opacityPercent int) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	shadowed := image.NewRGBA(image.Rect(0, 0, width, height))
+	if lengthPx <= 0 || opacityPercent <= 0 {
+		draw.Draw(shadowed, shadowed.Bounds(), img, bounds.Min, draw.Src)
+		return shadowed
+	}
+
+	// Step one pixel at a time along the dominant axis of the shadow direction,
+	// carrying the fractional offset on the minor axis. Every pixel then has a
+	// single predecessor on its ray, so distances can be swept in one pass.
+	rad := angle * math.Pi / 180
+	dirX := math.Cos(rad)
+	dirY := math.Sin(rad)
+	xMajor := math.Abs(dirX) >= math.Abs(dirY)
+
+	var steps int
+	var slope float64
+	var sign int
+	if xMajor {
+		steps = int(math.Round(float64(lengthPx) * math.Abs(dirX)))
+		slope = dirY / dirX
+		sign = 1
+		if dirX < 0 {
+			sign = -1
+		}
+	} else {
+		steps = int(math.Round(float64(lengthPx) * math.Abs(dirY)))
+		slope = dirX / dirY
+		sign = 1
+		if dirY < 0 {
+			sign = -1
+		}
+	}
+
+	// distance[i] is the number of steps back along the ray to the nearest
+	// opaque artwork pixel, capped at steps+1 (meaning "out of reach").
+	unreachable := int32(steps + 1)
+	distance := make([]int32, width*height)
+
+	isCaster := func(x, y int) bool {
+		_, _, _, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return a >= 0x8000
+	}
+
+	visit := func(x, y, px, py int) {
+		i := y*width + x
+		if isCaster(x, y) {
+			distance[i] = 0
+			return
+		}
+		if px < 0 || px >= width || py < 0 || py >= height {
+			distance[i] = unreachable
+			return
+		}
+		d := distance[py*width+px] + 1
+		if d > unreachable {
+			d = unreachable
+		}
+		distance[i] = d
+	}
+
+	if xMajor {
+		for n := 0; n < width; n++ {
+			x := n
+			if sign < 0 {
+				x = width - 1 - n
+			}
+			px := x - sign
+			shift := int(math.Round(float64(x)*slope)) - int(math.Round(float64(px)*slope))
+			for y := 0; y < height; y++ {
+				visit(x, y, px, y-shift)
+			}
+		}
+	} else {
+		for n := 0; n < height; n++ {
+			y := n
+			if sign < 0 {
+				y = height - 1 - n
+			}
+			py := y - sign
+			shift := int(math.Round(float64(y)*slope)) - int(math.Round(float64(py)*slope))
+			for x := 0; x < width; x++ {
+				visit(x, y, x-shift, py)
+			}
+		}
+	}
+
+	shadow := color.RGBA{0, 0, 0, uint8(255 * opacityPercent / 100)}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			d := distance[y*width+x]
+			if d > 0 && d < unreachable {
+				shadowed.SetRGBA(x, y, shadow)
+			}
+		}
+	}
+
+	// Composite the artwork back on top of its shadow
+	draw.Draw(shadowed, shadowed.Bounds(), img, bounds.Min, draw.Over)
+
+	return shadowed
+}
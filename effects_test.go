@@ -0,0 +1,112 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// Helper function to create a transparent image with an opaque square in the middle
+func createTestImageWithSquare(size, squareSize int, fillColor color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	offset := (size - squareSize) / 2
+	for y := offset; y < offset+squareSize; y++ {
+		for x := offset; x < offset+squareSize; x++ {
+			img.Set(x, y, fillColor)
+		}
+	}
+
+	return img
+}
+
+func TestAddLongShadow(t *testing.T) {
+	testImg := createTestImageWithSquare(100, 20, color.RGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		name        string
+		angle       float64
+		shadowedAt  image.Point
+		clearAt     image.Point
+		expectAlpha uint32
+	}{
+		{"bottom-right", 45, image.Point{70, 70}, image.Point{30, 30}, 0x7f7f},
+		{"right", 0, image.Point{75, 50}, image.Point{25, 50}, 0x7f7f},
+		{"up", 270, image.Point{50, 20}, image.Point{50, 80}, 0x7f7f},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadowed := addLongShadow(testImg, tt.angle, 40, 50)
+
+			if shadowed.Bounds() != testImg.Bounds() {
+				t.Fatalf("Expected bounds %v, got %v", testImg.Bounds(), shadowed.Bounds())
+			}
+
+			_, _, _, a := shadowed.At(tt.shadowedAt.X, tt.shadowedAt.Y).RGBA()
+			if a != tt.expectAlpha {
+				t.Errorf("Expected shadow alpha %#x at %v, got %#x", tt.expectAlpha, tt.shadowedAt, a)
+			}
+
+			_, _, _, a = shadowed.At(tt.clearAt.X, tt.clearAt.Y).RGBA()
+			if a != 0 {
+				t.Errorf("Expected no shadow at %v, got alpha %#x", tt.clearAt, a)
+			}
+
+			// The artwork itself must be untouched
+			r, g, b, a := shadowed.At(50, 50).RGBA()
+			if r != 0xffff || g != 0xffff || b != 0xffff || a != 0xffff {
+				t.Errorf("Expected artwork to stay white, got RGBA(%d, %d, %d, %d)", r>>8, g>>8, b>>8, a>>8)
+			}
+		})
+	}
+}
+
+func TestAddLongShadowLength(t *testing.T) {
+	testImg := createTestImageWithSquare(100, 20, color.RGBA{255, 255, 255, 255})
+
+	// A 10px shadow cast to the right must stop 10px past the square's edge
+	shadowed := addLongShadow(testImg, 0, 10, 100)
+
+	if _, _, _, a := shadowed.At(65, 50).RGBA(); a == 0 {
+		t.Errorf("Expected shadow inside the cast length")
+	}
+	if _, _, _, a := shadowed.At(75, 50).RGBA(); a != 0 {
+		t.Errorf("Expected no shadow beyond the cast length, got alpha %#x", a)
+	}
+}
+
+func TestAddLongShadowDisabled(t *testing.T) {
+	testImg := createTestImageWithSquare(50, 10, color.RGBA{255, 0, 0, 255})
+
+	shadowed := addLongShadow(testImg, 45, 0, 50)
+
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			if shadowed.At(x, y) != testImg.At(x, y) {
+				t.Fatalf("Expected unchanged pixel at (%d, %d)", x, y)
+			}
+		}
+	}
+}
+
+func TestLongShadowLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   int
+	}{
+		{"disabled", Config{}, 0},
+		{"length only", Config{LongShadowLength: 30}, 30},
+		{"switch uses default", Config{LongShadow: true}, defaultLongShadowLength},
+		{"switch with length", Config{LongShadow: true, LongShadowLength: 70}, 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := longShadowLength(tt.config); got != tt.want {
+				t.Errorf("Expected length %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// fillModes are the --fill modes for the bars a non-square source leaves.
+var fillModes = map[string]bool{"none": true, "blur": true}
+
+func validateFill(fill string) error {
+	if fill != "" && !fillModes[fill] {
+		return fmt.Errorf("unknown fill mode %q (expected none or blur)", fill)
+	}
+	return nil
+}
+
+// fitModes are the --fit modes for bringing a non-square source into the
+// square icon.
+var fitModes = map[string]bool{
+	// Scale it to fit, leaving bars for --fill
+	"contain": true,
+	// Crop it to a square at the --crop-anchor, filling the icon
+	"cover": true,
+	// Scale it to a square, distorting it, filling the icon
+	"stretch": true,
+}
+
+// nonSquarePolicies maps every --non-square policy to the --fit mode it
+// stands for; error has none and rejects non-square sources instead.
+var nonSquarePolicies = map[string]string{
+	"pad":     "contain",
+	"crop":    "cover",
+	"stretch": "stretch",
+	"error":   "contain",
+}
+
+// fitMode returns the --fit mode of config, as set directly or by its
+// --non-square policy.
+func fitMode(config Config) string {
+	if mode, ok := nonSquarePolicies[config.NonSquare]; ok {
+		return mode
+	}
+	if config.FitMode == "" {
+		return "contain"
+	}
+	return config.FitMode
+}
+
+func validateFitMode(config Config) error {
+	if config.FitMode != "" && !fitModes[config.FitMode] {
+		return fmt.Errorf("unknown fit mode %q (expected contain, cover or stretch)", config.FitMode)
+	}
+	if config.NonSquare != "" {
+		if _, ok := nonSquarePolicies[config.NonSquare]; !ok {
+			return fmt.Errorf("unknown non-square policy %q (expected pad, crop, stretch or error)", config.NonSquare)
+		}
+		if config.FitMode != "" && config.FitMode != "contain" {
+			return fmt.Errorf("--fit and --non-square both decide how a non-square source fills the icons; use one")
+		}
+	}
+	if mode := fitMode(config); mode != "contain" && config.Fill == "blur" {
+		return fmt.Errorf("--fit=%s leaves no bars for --fill=blur to fill", mode)
+	}
+	return nil
+}
+
+// checkSquare returns an error for a non-square source under
+// --non-square=error, giving its size and aspect ratio, so teams can insist
+// on square artwork rather than letterboxed icons.
+func checkSquare(img image.Image, config Config) error {
+	bounds := img.Bounds()
+	if config.NonSquare != "error" || bounds.Dx() == bounds.Dy() {
+		return nil
+	}
+	stage := "after cropping"
+	if !config.CropEnabled {
+		stage = "uncropped"
+	}
+	return fmt.Errorf("source is %dx%d %s, an aspect ratio of %s, and --non-square=error requires a square source",
+		bounds.Dx(), bounds.Dy(), stage, aspectRatio(bounds.Dx(), bounds.Dy()))
+}
+
+// aspectRatio formats width:height in lowest terms, such as 16:9, or as a
+// decimal ratio such as 1.02:1 when those terms are unwieldy.
+func aspectRatio(width, height int) string {
+	a, b := width, height
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if w, h := width/a, height/a; w <= 32 && h <= 32 {
+		return fmt.Sprintf("%d:%d", w, h)
+	}
+	return fmt.Sprintf("%.2f:1", float64(width)/float64(height))
+}
+
+// fitSource brings a non-square source into a square for --fit=cover or
+// --fit=stretch, so the icons have no bars. With --fit=contain, the default,
+// it's left as it is and every resize fits it into the square.
+func fitSource(img image.Image, config Config) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == bounds.Dy() {
+		return img
+	}
+
+	switch fitMode(config) {
+	case "cover":
+		anchor, _ := parseCropAnchor(config.CropAnchor)
+		return squareCrop(img, anchor)
+	case "stretch":
+		side := bounds.Dx()
+		if bounds.Dy() > side {
+			side = bounds.Dy()
+		}
+		return stretchImage(img, side, side)
+	}
+	return img
+}
+
+// stretchImage scales img to width x height with bilinear interpolation,
+// without keeping its aspect ratio.
+func stretchImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	stretched := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+
+	// Clamped to the edges, so the last row and column aren't lost
+	at := func(x, y int) [4]float64 {
+		if x > bounds.Dx()-1 {
+			x = bounds.Dx() - 1
+		}
+		if y > bounds.Dy()-1 {
+			y = bounds.Dy() - 1
+		}
+		r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := math.Max(0, (float64(x)+0.5)*scaleX-0.5)
+			srcY := math.Max(0, (float64(y)+0.5)*scaleY-0.5)
+			x0, y0 := int(srcX), int(srcY)
+			fracX, fracY := srcX-float64(x0), srcY-float64(y0)
+
+			c00, c10, c01, c11 := at(x0, y0), at(x0+1, y0), at(x0, y0+1), at(x0+1, y0+1)
+			var v [4]uint8
+			for i := range v {
+				v[i] = uint8(bilinearInterpolate(c00[i], c10[i], c01[i], c11[i], fracX, fracY) / 257)
+			}
+			stretched.SetRGBA(x, y, color.RGBA{v[0], v[1], v[2], v[3]})
+		}
+	}
+	return stretched
+}
+
+// blurFillActive reports whether the --fill=blur backdrop applies to source.
+func blurFillActive(config Config, source image.Image) bool {
+	bounds := source.Bounds()
+	return config.Fill == "blur" && bounds.Dx() != bounds.Dy()
+}
+
+// blurFillResolution is the largest size the blurred fill is computed at.
+// Nothing that fine survives the blur, so larger sizes are scaled up from it.
+const blurFillResolution = 128
+
+// blurredFill scales source to cover a size x size canvas, cropping the
+// overhang, and blurs it heavily, as the backdrop for the fitted artwork.
+// The blur runs on a canvas extended past the edges so the backdrop doesn't
+// fade out towards the border.
+func blurredFill(source image.Image, size int) *image.RGBA {
+	if size > blurFillResolution {
+		return toRGBA(resizeImage(blurredFill(source, blurFillResolution), size))
+	}
+
+	sigma := float64(size) * 0.08
+	pad := int(math.Ceil(3 * sigma))
+	extended := size + 2*pad
+
+	cover := coverImage(source, extended)
+	channels := make([][]float64, 4)
+	for c := range channels {
+		channels[c] = make([]float64, extended*extended)
+	}
+	for y := 0; y < extended; y++ {
+		for x := 0; x < extended; x++ {
+			p := cover.RGBAAt(x, y)
+			i := y*extended + x
+			channels[0][i], channels[1][i], channels[2][i], channels[3][i] = float64(p.R), float64(p.G), float64(p.B), float64(p.A)
+		}
+	}
+	for c := range channels {
+		channels[c] = gaussianBlur(channels[c], extended, extended, sigma)
+	}
+
+	fill := image.NewRGBA(image.Rect(0, 0, size, size))
+	clamp := func(v float64) uint8 { return uint8(math.Round(math.Max(0, math.Min(255, v)))) }
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			i := (y+pad)*extended + x + pad
+			fill.SetRGBA(x, y, color.RGBA{clamp(channels[0][i]), clamp(channels[1][i]), clamp(channels[2][i]), clamp(channels[3][i])})
+		}
+	}
+	return fill
+}
+
+// coverImage scales img so it covers a size x size canvas, centered, cropping
+// whatever overhangs on the longer side.
+func coverImage(img image.Image, size int) *image.RGBA {
+	return toRGBA(resizeImage(squareCrop(img, overlayAnchors["center"]), size))
+}
+
+// squareCrop crops the longer side of img to a square, placed by anchor as
+// for cropAnchor.
+func squareCrop(img image.Image, anchor [2]float64) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	offset := image.Pt(
+		bounds.Min.X+int(float64(bounds.Dx()-side)*anchor[0]),
+		bounds.Min.Y+int(float64(bounds.Dy()-side)*anchor[1]),
+	)
+	draw.Draw(square, square.Bounds(), img, offset, draw.Src)
+
+	return square
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func TestValidateFill(t *testing.T) {
+	for _, fill := range []string{"", "none", "blur"} {
+		if err := validateFill(fill); err != nil {
+			t.Errorf("Expected fill %q to be valid, got %v", fill, err)
+		}
+	}
+	if err := validateFill("stretch"); err == nil {
+		t.Errorf("Expected unknown fill mode to fail")
+	}
+	if err := validateOptions(Config{TrimPercent: 80, Fill: "blur", Background: "#FFFFFF"}); err == nil {
+		t.Errorf("Expected --fill=blur with --background to fail")
+	}
+}
+
+func TestBlurredFillCoversBars(t *testing.T) {
+	// A wide source leaves transparent bars above and below the artwork
+	source := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(source, source.Bounds(), &image.Uniform{color.RGBA{0, 128, 255, 255}}, image.Point{}, draw.Src)
+
+	plain := prepareIcon(source, Config{}, backgroundPattern{}, nil, nil, 64)
+	if _, _, _, a := plain.At(32, 2).RGBA(); a != 0 {
+		t.Fatalf("Expected a transparent bar without --fill, got alpha %#x", a)
+	}
+
+	for _, size := range []int{64, 256} {
+		filled := prepareIcon(source, Config{Fill: "blur"}, backgroundPattern{}, nil, nil, size)
+		for _, p := range []image.Point{{size / 2, 1}, {1, 1}, {size - 2, size - 2}} {
+			c := color.RGBAModel.Convert(filled.At(p.X, p.Y)).(color.RGBA)
+			if c.A < 250 || c.B < 240 {
+				t.Errorf("Expected the blurred source in the bar at %v of %dpx, got %v", p, size, c)
+			}
+		}
+	}
+}
+
+func TestBlurFillSquareSource(t *testing.T) {
+	source := createTestImageWithSquare(100, 20, color.RGBA{255, 0, 0, 255})
+	if blurFillActive(Config{Fill: "blur"}, source) {
+		t.Errorf("Expected no blurred fill for a square source")
+	}
+
+	filled := prepareIcon(source, Config{Fill: "blur"}, backgroundPattern{}, nil, nil, 50)
+	if _, _, _, a := filled.At(1, 1).RGBA(); a != 0 {
+		t.Errorf("Expected a square source's transparency to be kept, got alpha %#x", a)
+	}
+}
+
+func TestFitSource(t *testing.T) {
+	// A wide source, red on the left half and blue on the right
+	source := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(source, image.Rect(0, 0, 100, 100), &image.Uniform{color.RGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
+	draw.Draw(source, image.Rect(100, 0, 200, 100), &image.Uniform{color.RGBA{0, 0, 255, 255}}, image.Point{}, draw.Src)
+
+	tests := []struct {
+		name     string
+		config   Config
+		size     int
+		topLeft  color.RGBA
+		topRight color.RGBA
+	}{
+		{"contain", Config{FitMode: "contain"}, 200, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}},
+		{"cover", Config{FitMode: "cover"}, 100, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}},
+		{"cover at the left", Config{FitMode: "cover", CropAnchor: "left"}, 100, color.RGBA{255, 0, 0, 255}, color.RGBA{255, 0, 0, 255}},
+		{"stretch", Config{FitMode: "stretch"}, 200, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted := fitSource(source, tt.config)
+			bounds := fitted.Bounds()
+			if tt.config.FitMode == "contain" {
+				if bounds != source.Bounds() {
+					t.Fatalf("Expected contain to leave the source as it is, got %v", bounds)
+				}
+				return
+			}
+			if bounds.Dx() != tt.size || bounds.Dy() != tt.size {
+				t.Fatalf("Expected a %dpx square, got %v", tt.size, bounds)
+			}
+			if got := color.RGBAModel.Convert(fitted.At(0, 0)).(color.RGBA); got != tt.topLeft {
+				t.Errorf("Expected %v at the top left, got %v", tt.topLeft, got)
+			}
+			if got := color.RGBAModel.Convert(fitted.At(tt.size-1, 0)).(color.RGBA); got != tt.topRight {
+				t.Errorf("Expected %v at the top right, got %v", tt.topRight, got)
+			}
+		})
+	}
+
+	// Filled icons have no bars
+	icon := prepareIcon(fitSource(source, Config{FitMode: "cover"}), Config{}, backgroundPattern{}, nil, nil, 64)
+	if _, _, _, a := icon.At(32, 1).RGBA(); a == 0 {
+		t.Errorf("Expected --fit=cover to leave no transparent bar")
+	}
+}
+
+func TestValidateFitMode(t *testing.T) {
+	for _, mode := range []string{"", "contain", "cover", "stretch"} {
+		if err := validateOptions(Config{TrimPercent: 80, FitMode: mode}); err != nil {
+			t.Errorf("Expected fit mode %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := validateOptions(Config{TrimPercent: 80, FitMode: "fill"}); err == nil {
+		t.Errorf("Expected an unknown fit mode to fail")
+	}
+	if err := validateOptions(Config{TrimPercent: 80, FitMode: "cover", Fill: "blur"}); err == nil {
+		t.Errorf("Expected --fit=cover with --fill=blur to fail")
+	}
+}
+
+func TestNonSquarePolicy(t *testing.T) {
+	for policy, mode := range map[string]string{"pad": "contain", "crop": "cover", "stretch": "stretch", "error": "contain"} {
+		if got := fitMode(Config{NonSquare: policy}); got != mode {
+			t.Errorf("Expected --non-square=%s to fit with %s, got %s", policy, mode, got)
+		}
+	}
+
+	wide := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	config := Config{InputPath: createTempImageFile(t, wide), OutputDir: t.TempDir(), CropEnabled: true, TrimPercent: 80, NonSquare: "error"}
+	err := generateIcons(config)
+	if err == nil || !strings.Contains(err.Error(), "480x240") || !strings.Contains(err.Error(), "2:1") {
+		t.Errorf("Expected the size and aspect ratio of a non-square source in the error, got %v", err)
+	}
+
+	config.NonSquare = "crop"
+	if err := generateIcons(config); err != nil {
+		t.Errorf("Expected --non-square=crop to accept a non-square source, got %v", err)
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, NonSquare: "letterbox"}); err == nil {
+		t.Errorf("Expected an unknown non-square policy to fail")
+	}
+	if err := validateOptions(Config{TrimPercent: 80, NonSquare: "error", FitMode: "cover"}); err == nil {
+		t.Errorf("Expected --non-square with --fit=cover to fail")
+	}
+}
+
+func TestAspectRatio(t *testing.T) {
+	tests := []struct {
+		width, height int
+		expect        string
+	}{
+		{480, 240, "2:1"},
+		{1920, 1080, "16:9"},
+		{240, 480, "1:2"},
+		{1023, 1000, "1.02:1"},
+	}
+
+	for _, tt := range tests {
+		if got := aspectRatio(tt.width, tt.height); got != tt.expect {
+			t.Errorf("aspectRatio(%d, %d): expected %s, got %s", tt.width, tt.height, tt.expect, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// validateFlattenMarketing checks the --flatten-marketing and --flatten-color
+// values.
+func validateFlattenMarketing(config Config) error {
+	switch config.Flatten {
+	case "", "auto", "on", "off":
+	default:
+		return fmt.Errorf("unknown --flatten-marketing value %q (expected auto, on or off)", config.Flatten)
+	}
+	c, err := parseHexColor(flattenColor(config))
+	if err != nil {
+		return fmt.Errorf("invalid --flatten-color: %w", err)
+	}
+	if c.A != 255 {
+		return fmt.Errorf("--flatten-color must be opaque (got %s)", flattenColor(config))
+	}
+	return nil
+}
+
+// flattenColor returns the --flatten-color, white unless set.
+func flattenColor(config Config) string {
+	if config.FlattenColor == "" {
+		return "#FFFFFF"
+	}
+	return config.FlattenColor
+}
+
+// flattenOutput reports whether the output called name is flattened onto the
+// --flatten-color. App Store Connect rejects a marketing icon with an alpha
+// channel, so auto flattens it for the iOS outputs: with --padding-ios-mode
+// or --contents-json.
+func flattenOutput(config Config, name string) bool {
+	if name != marketingIcon {
+		return false
+	}
+	switch config.Flatten {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	return config.PaddingIOSMode || config.ContentsJSON
+}
+
+// flattenOpaque composites img over a canvas of the opaque color c as deep
+// as img, so the PNG encoder saves it without an alpha channel. It also reports whether
+// img had any transparency to discard.
+func flattenOpaque(img image.Image, c string) (image.Image, bool) {
+	bg, err := parseHexColor(c)
+	if err != nil {
+		return img, false
+	}
+
+	bounds := img.Bounds()
+	at := pixelReader(img)
+	discarded := false
+	for y := bounds.Min.Y; y < bounds.Max.Y && !discarded; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := at(x, y); a != 0xffff {
+				discarded = true
+				break
+			}
+		}
+	}
+	if !discarded {
+		return img, false
+	}
+
+	canvas := newCanvasLike(img, image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Over)
+	return canvas, true
+}
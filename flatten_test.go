@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlattenOpaque(t *testing.T) {
+	translucent := createTestImage(8, color.RGBA{0, 0, 128, 128})
+	flattened, discarded := flattenOpaque(translucent, "#FF0000")
+	if !discarded {
+		t.Errorf("Expected the transparency to be reported discarded")
+	}
+	if c := color.RGBAModel.Convert(flattened.At(4, 4)).(color.RGBA); c != (color.RGBA{127, 0, 128, 255}) {
+		t.Errorf("Expected blue over red, got %v", c)
+	}
+
+	opaque := createTestImage(8, color.RGBA{0, 0, 255, 255})
+	if same, discarded := flattenOpaque(opaque, "#FF0000"); discarded || same != opaque {
+		t.Errorf("Expected an opaque image to be left as it is")
+	}
+
+	deep, _ := flattenOpaque(toRGBA64(translucent), "#FF0000")
+	if !deepImage(deep) {
+		t.Errorf("Expected a 16-bit image to stay 16-bit")
+	}
+}
+
+func TestFlattenMarketingIcon(t *testing.T) {
+	input := createTempImageFile(t, createTestImageWithBorder(1024, color.RGBA{255, 0, 0, 255}, color.RGBA{}, 100))
+
+	tests := []struct {
+		name   string
+		config Config
+		want   bool
+	}{
+		{"default", Config{}, false},
+		{"contents json", Config{ContentsJSON: true}, true},
+		{"padding ios mode", Config{PaddingIOSMode: true}, true},
+		{"on", Config{Flatten: "on"}, true},
+		{"off", Config{ContentsJSON: true, Flatten: "off"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.InputPath, config.OutputDir, config.TrimPercent = input, t.TempDir(), 80
+			if err := generateIcons(config); err != nil {
+				t.Fatalf("Failed to generate icons: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(config.OutputDir, marketingIcon))
+			if err != nil {
+				t.Fatalf("Failed to read the marketing icon: %v", err)
+			}
+			ihdr, _ := pngChunkData(data, "IHDR")
+			// Color type 2 is truecolor without alpha, 6 with it
+			if opaque := ihdr[9] == 2; opaque != tt.want {
+				t.Errorf("Expected an opaque PNG %v, got color type %d", tt.want, ihdr[9])
+			}
+
+			small, err := loadImage(filepath.Join(config.OutputDir, "icon_16x16.png"))
+			if err != nil {
+				t.Fatalf("Failed to load icon: %v", err)
+			}
+			if _, _, _, a := small.At(0, 0).RGBA(); a != 0 {
+				t.Errorf("Expected the other icons to keep their transparency")
+			}
+		})
+	}
+}
+
+func TestValidateFlattenMarketing(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"on with a color", Config{Flatten: "on", FlattenColor: "#000"}, false},
+		{"unknown value", Config{Flatten: "always"}, true},
+		{"invalid color", Config{FlattenColor: "white"}, true},
+		{"translucent color", Config{FlattenColor: "#FFFFFF80"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateFlattenMarketing(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// flavor is one entry of --flavors: a named white-label build whose options
+// override the shared ones.
+type flavor struct {
+	Name    string
+	Input   string
+	Output  string
+	Options map[string]string
+}
+
+// parseFlavors parses a --flavors spec, a JSON array of objects. Each object
+// needs a name and may give an input and output; its other keys are option
+// names, as in a configuration file.
+func parseFlavors(spec string) ([]flavor, error) {
+	var raw []map[string]interface{}
+	if err := json.Unmarshal([]byte(spec), &raw); err != nil {
+		return nil, fmt.Errorf("invalid flavors (expected a JSON array of objects): %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no flavors defined")
+	}
+
+	var flavors []flavor
+	seen := make(map[string]bool)
+	for i, entry := range raw {
+		var f flavor
+		for key, field := range map[string]*string{"name": &f.Name, "input": &f.Input, "output": &f.Output} {
+			value, ok := entry[key]
+			if !ok {
+				continue
+			}
+			s, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("flavor %d: %s must be a string", i+1, key)
+			}
+			*field = s
+			delete(entry, key)
+		}
+
+		if f.Name == "" {
+			return nil, fmt.Errorf("flavor %d has no name", i+1)
+		}
+		if f.Name == "." || f.Name == ".." || strings.ContainsAny(f.Name, `/\`) {
+			return nil, fmt.Errorf("invalid flavor name %q (it names the flavor's output directory)", f.Name)
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("flavor %q is defined twice", f.Name)
+		}
+		seen[f.Name] = true
+
+		if _, nested := entry["flavors"]; nested {
+			return nil, fmt.Errorf("flavor %q: flavors can't be nested", f.Name)
+		}
+		options, err := optionValues(entry)
+		if err != nil {
+			return nil, fmt.Errorf("flavor %q: %w", f.Name, err)
+		}
+		f.Options = options
+		flavors = append(flavors, f)
+	}
+	return flavors, nil
+}
+
+// flavorConfigs expands a config with --flavors into one config per flavor.
+// Each starts from the shared options of config and writes into a directory
+// named after the flavor inside the output directory, unless it sets its own.
+func flavorConfigs(config Config) ([]Config, error) {
+	flavors, err := parseFlavors(config.Flavors)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []Config
+	outputs := make(map[string]string)
+	for _, f := range flavors {
+		var flavorConfig Config
+		fs := flag.NewFlagSet(f.Name, flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		defineFlags(fs, &flavorConfig)
+
+		// The flags stay bound to the fields, so the flavor's options
+		// override the shared ones rather than the defaults
+		flavorConfig = config
+		flavorConfig.Flavors = ""
+		flavorConfig.ManifestPath = ""
+		if err := applyOptions(fs, f.Options); err != nil {
+			return nil, fmt.Errorf("flavor %q: %w", f.Name, err)
+		}
+
+		if f.Input != "" {
+			flavorConfig.InputPath = f.Input
+		}
+		flavorConfig.OutputDir = filepath.Join(config.OutputDir, f.Name)
+		if f.Output != "" {
+			flavorConfig.OutputDir = f.Output
+		}
+		if other, ok := outputs[filepath.Clean(flavorConfig.OutputDir)]; ok {
+			return nil, fmt.Errorf("flavors %q and %q both write to %s", other, f.Name, flavorConfig.OutputDir)
+		}
+		outputs[filepath.Clean(flavorConfig.OutputDir)] = f.Name
+
+		configs = append(configs, flavorConfig)
+	}
+	return configs, nil
+}
+
+// validateFlavors checks every flavor of config as a configuration of its own.
+func validateFlavors(config Config) error {
+	if config.Recursive {
+		return fmt.Errorf("--flavors can't be combined with --recursive")
+	}
+	if config.FlavorJobs < 0 {
+		return fmt.Errorf("flavor jobs must not be negative (got %d)", config.FlavorJobs)
+	}
+
+	configs, err := flavorConfigs(config)
+	if err != nil {
+		return err
+	}
+	flavors, _ := parseFlavors(config.Flavors)
+	for i, flavorConfig := range configs {
+		if err := validateConfig(flavorConfig); err != nil {
+			return fmt.Errorf("flavor %q: %w", flavors[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// generateFlavors generates the icon sets of every flavor of config, up to
+// --flavor-jobs of them at a time. A failing flavor doesn't stop the others;
+// a combined report of all of them follows, and with --manifest a single
+// generation report covers every flavor's outputs.
+func generateFlavors(config Config) error {
+	flavors, err := parseFlavors(config.Flavors)
+	if err != nil {
+		return err
+	}
+	configs, err := flavorConfigs(config)
+	if err != nil {
+		return err
+	}
+
+	jobs := config.FlavorJobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	if config.MaxMemory != "" {
+		limit, err := parseByteSize(config.MaxMemory)
+		if err != nil {
+			return err
+		}
+		jobs = flavorJobsWithin(configs, jobs, limit)
+	}
+
+	errs := make([]error, len(configs))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := range configs {
+		i := i
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[i] = generateIcons(configs[i])
+		}()
+	}
+	wg.Wait()
+
+	var outputDirs []string
+	failed := 0
+	fmt.Fprintf(logOutput(config), "Flavors:\n")
+	for i, flavorConfig := range configs {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(logOutput(config), " ❌ %s: %v\n", flavors[i].Name, errs[i])
+			continue
+		}
+		fmt.Fprintf(logOutput(config), " ✅ %s: %d outputs in %s\n", flavors[i].Name, len(planTargets(flavorConfig)), assetDir(flavorConfig))
+		outputDirs = append(outputDirs, assetDir(flavorConfig))
+	}
+
+	if config.ManifestPath != "" {
+		if err := writeReport(config.ManifestPath, outputDirs); err != nil {
+			return fmt.Errorf("failed to write %s: %w", config.ManifestPath, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d flavors failed", failed, len(configs))
+	}
+	return nil
+}
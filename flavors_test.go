@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFlavors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    int
+		wantErr string
+	}{
+		{"two flavors", `[{"name":"acme","tint":"#FF0000"},{"name":"globex","badge":"BETA","output":"out/globex"}]`, 2, ""},
+		{"not an array", `{"name":"acme"}`, 0, "JSON array"},
+		{"empty", `[]`, 0, "no flavors"},
+		{"no name", `[{"tint":"#FF0000"}]`, 0, "no name"},
+		{"path as name", `[{"name":"../acme"}]`, 0, "invalid flavor name"},
+		{"duplicate", `[{"name":"acme"},{"name":"acme"}]`, 0, "defined twice"},
+		{"nested", `[{"name":"acme","flavors":[]}]`, 0, "nested"},
+		{"output not a string", `[{"name":"acme","output":1}]`, 0, "must be a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flavors, err := parseFlavors(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse flavors: %v", err)
+			}
+			if len(flavors) != tt.want {
+				t.Errorf("Expected %d flavors, got %d", tt.want, len(flavors))
+			}
+		})
+	}
+}
+
+func TestFlavorConfigs(t *testing.T) {
+	config := Config{
+		InputPath:   "logo.png",
+		OutputDir:   "build",
+		TrimPercent: 80,
+		Badge:       "DEV",
+		Flavors:     `[{"name":"acme","tint":"#FF0000"},{"name":"globex","badge":"BETA","trim-percent":70,"output":"out/globex","input":"globex.png"}]`,
+	}
+
+	configs, err := flavorConfigs(config)
+	if err != nil {
+		t.Fatalf("Failed to expand flavors: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(configs))
+	}
+
+	acme, globex := configs[0], configs[1]
+	if acme.Tint != "#FF0000" || acme.Badge != "DEV" || acme.TrimPercent != 80 {
+		t.Errorf("Expected acme to override the tint and keep the shared options, got tint %q, badge %q, trim %d", acme.Tint, acme.Badge, acme.TrimPercent)
+	}
+	if acme.OutputDir != filepath.Join("build", "acme") || acme.InputPath != "logo.png" {
+		t.Errorf("Expected acme to read logo.png into build/acme, got %s into %s", acme.InputPath, acme.OutputDir)
+	}
+	if globex.Badge != "BETA" || globex.TrimPercent != 70 || globex.Tint != "" {
+		t.Errorf("Expected globex's own badge and trim, got badge %q, trim %d, tint %q", globex.Badge, globex.TrimPercent, globex.Tint)
+	}
+	if globex.OutputDir != "out/globex" || globex.InputPath != "globex.png" {
+		t.Errorf("Expected globex to read globex.png into out/globex, got %s into %s", globex.InputPath, globex.OutputDir)
+	}
+	if acme.Flavors != "" || globex.Flavors != "" {
+		t.Errorf("Expected flavor configs not to define flavors themselves")
+	}
+
+	config.Flavors = `[{"name":"acme","no-such-option":1}]`
+	if _, err := flavorConfigs(config); err == nil {
+		t.Errorf("Expected an unknown option in a flavor to fail")
+	}
+	config.Flavors = `[{"name":"acme","output":"out"},{"name":"globex","output":"out/"}]`
+	if _, err := flavorConfigs(config); err == nil {
+		t.Errorf("Expected two flavors writing to the same directory to fail")
+	}
+}
+
+func TestGenerateFlavors(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+	reportPath := filepath.Join(outputDir, "report.json")
+
+	config := Config{
+		InputPath:    inputPath,
+		OutputDir:    outputDir,
+		Preset:       "macos",
+		TrimPercent:  80,
+		ManifestPath: reportPath,
+		FlavorJobs:   2,
+		Flavors:      `[{"name":"acme","tint":"#FF0000"},{"name":"globex","preset":"web"},{"name":"initech","hue-shift":90}]`,
+	}
+	if err := validateConfig(config); err != nil {
+		t.Fatalf("Expected valid flavors, got %v", err)
+	}
+	if err := generateConfig(config); err != nil {
+		t.Fatalf("Failed to generate flavors: %v", err)
+	}
+
+	for _, want := range []string{"acme/icon_16x16.png", "globex/favicon-32x32.png", "initech/icon_1024x1024.png"} {
+		if _, err := os.Stat(filepath.Join(outputDir, want)); err != nil {
+			t.Errorf("Expected %s: %v", want, err)
+		}
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Expected a combined report: %v", err)
+	}
+	var report generationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse the report: %v", err)
+	}
+	flavorsSeen := map[string]bool{}
+	for _, output := range report.Outputs {
+		rel, _ := filepath.Rel(outputDir, filepath.FromSlash(output.Path))
+		flavorsSeen[strings.Split(filepath.ToSlash(rel), "/")[0]] = true
+	}
+	if len(flavorsSeen) != 3 {
+		t.Errorf("Expected the report to cover all 3 flavors, got %v", flavorsSeen)
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan flavors: %v", err)
+	}
+	if !strings.HasPrefix(targets[0].Path, filepath.Join(outputDir, "acme")) {
+		t.Errorf("Expected the plan to start with acme's outputs, got %s", targets[0].Path)
+	}
+}
+
+func TestValidateFlavors(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 128, 0, 255}))
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"invalid option value", Config{InputPath: inputPath, TrimPercent: 80, Flavors: `[{"name":"acme","trim-percent":0}]`}},
+		{"missing input", Config{InputPath: inputPath, TrimPercent: 80, Flavors: `[{"name":"acme","input":"missing.png"}]`}},
+		{"recursive", Config{InputPath: inputPath, TrimPercent: 80, Recursive: true, Flavors: `[{"name":"acme"}]`}},
+		{"negative jobs", Config{InputPath: inputPath, TrimPercent: 80, FlavorJobs: -1, Flavors: `[{"name":"acme"}]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateConfig(tt.config); err == nil {
+				t.Errorf("Expected validation to fail")
+			}
+		})
+	}
+}
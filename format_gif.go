@@ -0,0 +1,67 @@
+//go:build !icongen_minimal
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+)
+
+func init() {
+	registerFormat(formatBackend{Name: "gif", Extensions: []string{".gif"}, Decode: true, Encode: true})
+}
+
+// saveGIF assembles frames into a looping animated GIF, reduced to the
+// web-safe palette with Floyd-Steinberg dithering unless dither asks for
+// ordered or none. GIF only supports on/off transparency, so pixels less
+// than half opaque become transparent.
+func saveGIF(frames []*image.RGBA, path, dither string) error {
+	delay := 100 / len(frames)
+	if delay < 2 {
+		delay = 2
+	}
+
+	// Reserve index 0 of the web-safe palette for transparency
+	pal := append(color.Palette{color.RGBA{0, 0, 0, 0}}, palette.WebSafe...)
+
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		var paletted *image.Paletted
+		switch dither {
+		case "ordered":
+			// The web-safe palette has six levels per channel, 51 apart
+			paletted = ditherPaletted(frame, pal, 51)
+		case "none":
+			paletted = image.NewPaletted(bounds, pal)
+			draw.Draw(paletted, bounds, frame, bounds.Min, draw.Src)
+		default:
+			paletted = image.NewPaletted(bounds, pal)
+			draw.FloydSteinberg.Draw(paletted, bounds, frame, bounds.Min)
+		}
+
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if frame.RGBAAt(x, y).A < 128 {
+					paletted.SetColorIndex(x, y, 0)
+				}
+			}
+		}
+
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gif.EncodeAll(file, anim)
+}
@@ -0,0 +1,14 @@
+//go:build icongen_minimal
+
+package main
+
+import (
+	"errors"
+	"image"
+)
+
+// saveGIF is unavailable in minimal builds; skippedOutputs reports
+// --spinner-gif instead of it being reached.
+func saveGIF(frames []*image.RGBA, path, dither string) error {
+	return errors.New("GIF encoding is not available in this build")
+}
@@ -0,0 +1,75 @@
+//go:build !icongen_minimal
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpinnerGIF(t *testing.T) {
+	testImg := createTestImageWithSquare(100, 60, color.RGBA{0, 200, 100, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		TrimPercent:   100,
+		SpinnerFrames: 8,
+		SpinnerSize:   32,
+		SpinnerGIF:    true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(outputDir, spinnerGIFName))
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", spinnerGIFName, err)
+	}
+	defer file.Close()
+
+	anim, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", spinnerGIFName, err)
+	}
+	if len(anim.Image) != config.SpinnerFrames {
+		t.Errorf("Expected %d GIF frames, got %d", config.SpinnerFrames, len(anim.Image))
+	}
+}
+
+func TestSaveGIFDither(t *testing.T) {
+	frame := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			frame.SetRGBA(x, y, color.RGBA{uint8(x * 16), 100, 180, 255})
+		}
+	}
+
+	for _, dither := range []string{"", "none", "ordered", "floyd-steinberg"} {
+		t.Run(dither, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spinner.gif")
+			if err := saveGIF([]*image.RGBA{frame}, path, dither); err != nil {
+				t.Fatalf("Failed to save GIF: %v", err)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Failed to open GIF: %v", err)
+			}
+			defer file.Close()
+			anim, err := gif.DecodeAll(file)
+			if err != nil {
+				t.Fatalf("Failed to decode GIF: %v", err)
+			}
+			if _, _, _, a := anim.Image[0].At(8, 8).RGBA(); a != 0xffff {
+				t.Errorf("Expected opaque pixels to stay opaque")
+			}
+		})
+	}
+}
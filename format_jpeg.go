@@ -0,0 +1,11 @@
+//go:build !icongen_minimal
+
+package main
+
+import (
+	_ "image/jpeg"
+)
+
+func init() {
+	registerFormat(formatBackend{Name: "jpeg", Extensions: []string{".jpg", ".jpeg"}, Decode: true})
+}
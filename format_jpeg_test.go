@@ -0,0 +1,31 @@
+//go:build !icongen_minimal
+
+package main
+
+import (
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadJPEGSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "source.jpg")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create JPEG: %v", err)
+	}
+	if err := jpeg.Encode(file, createTestImage(64, color.RGBA{0, 128, 255, 255}), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	file.Close()
+
+	img, err := loadImage(path)
+	if err != nil {
+		t.Fatalf("Failed to load JPEG source: %v", err)
+	}
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 {
+		t.Errorf("Expected 64x64 image, got %v", img.Bounds())
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+// PNG is the output format of every icon, so it's part of every build.
+func init() {
+	registerFormat(formatBackend{Name: "png", Extensions: []string{".png"}, Decode: true, Encode: true})
+}
@@ -0,0 +1,13 @@
+//go:build icongen_webp
+
+package main
+
+import (
+	_ "golang.org/x/image/webp"
+)
+
+// WebP sources need golang.org/x/image, so the backend is opt-in: build
+// with -tags icongen_webp. It only decodes; there is no WebP encoder.
+func init() {
+	registerFormat(formatBackend{Name: "webp", Extensions: []string{".webp"}, Decode: true})
+}
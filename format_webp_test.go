@@ -0,0 +1,35 @@
+//go:build icongen_webp
+
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A 1x1 lossless WebP
+const testWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestWebPSource(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(testWebP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputPath := filepath.Join(t.TempDir(), "logo.webp")
+	if err := os.WriteFile(inputPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !decodableExtension(".webp") {
+		t.Errorf("Expected .webp sources to be decodable")
+	}
+	img, err := loadImage(inputPath)
+	if err != nil {
+		t.Fatalf("Failed to decode WebP: %v", err)
+	}
+	if img.Bounds().Dx() != 1 || img.Bounds().Dy() != 1 {
+		t.Errorf("Expected a 1x1 image, got %v", img.Bounds())
+	}
+}
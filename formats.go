@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// formatBackend describes an image format compiled into this build. Optional
+// backends live in their own format_*.go files behind build tags and register
+// themselves from init, so building with -tags icongen_minimal leaves out
+// everything but the PNG core and the PDF writer.
+type formatBackend struct {
+	Name       string
+	Extensions []string // lowercase, with leading dot
+	Decode     bool     // source images in this format can be read
+	Encode     bool     // outputs can be written in this format
+}
+
+var formatBackends = map[string]formatBackend{}
+
+// optionalFormats are the formats whose backends some builds leave out,
+// with the build tag that includes them.
+var optionalFormats = map[string]string{
+	"gif":  "without -tags icongen_minimal",
+	"jpeg": "without -tags icongen_minimal",
+	"webp": "with -tags icongen_webp",
+}
+
+// unsupportedFormats have no backend in any build: they need codecs that
+// neither Go's standard library nor golang.org/x/image provide.
+var unsupportedFormats = []string{"avif", "heic", "svg"}
+
+func registerFormat(backend formatBackend) {
+	formatBackends[backend.Name] = backend
+}
+
+// hasEncoder reports whether this build can write the named format.
+func hasEncoder(name string) bool {
+	return formatBackends[name].Encode
+}
+
+// decodableExtension reports whether this build can read source images with
+// the file extension ext.
+func decodableExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, backend := range formatBackends {
+		if !backend.Decode {
+			continue
+		}
+		for _, e := range backend.Extensions {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runFormats implements "icongen formats": it reports which formats this
+// build can read and write.
+func runFormats(args []string) error {
+	flags := flag.NewFlagSet("formats", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s formats\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "List the image formats this build can read and write.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(formatBackends))
+	for name := range formatBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FORMAT\tREAD\tWRITE\tEXTENSIONS")
+	for _, name := range names {
+		backend := formatBackends[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, yesNo(backend.Decode), yesNo(backend.Encode), strings.Join(backend.Extensions, " "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for name := range optionalFormats {
+		if _, ok := formatBackends[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		fmt.Println()
+	}
+	for _, name := range missing {
+		fmt.Printf("%s: not in this build, build %s\n", name, optionalFormats[name])
+	}
+	fmt.Printf("\nNot supported: %s (no codec in Go's standard library or golang.org/x/image)\n", strings.Join(unsupportedFormats, ", "))
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormatRegistry(t *testing.T) {
+	tests := []struct {
+		ext      string
+		expected bool
+	}{
+		{".png", true},
+		{".PNG", true},
+		{".pdf", false},
+		{".txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			if got := decodableExtension(tt.ext); got != tt.expected {
+				t.Errorf("Expected decodableExtension(%q) = %v, got %v", tt.ext, tt.expected, got)
+			}
+		})
+	}
+
+	if !hasEncoder("png") || !hasEncoder("pdf") {
+		t.Errorf("Expected PNG and PDF encoders in every build")
+	}
+	if hasEncoder("webp") {
+		t.Errorf("Expected no encoder for unregistered formats")
+	}
+}
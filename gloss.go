@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// surfaceEffects are the effects --effects accepts, applied in the order
+// given on top of the masked icon. Each keeps within the icon's alpha.
+var surfaceEffects = map[string]func(img image.Image, size int) image.Image{
+	"gloss":        addGloss,
+	"inner-shadow": addInnerShadow,
+}
+
+// parseEffects parses a comma-separated --effects spec such as
+// "inner-shadow,gloss".
+func parseEffects(spec string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		if _, ok := surfaceEffects[name]; !ok {
+			return nil, fmt.Errorf("unknown effect %q (expected gloss or inner-shadow)", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate effect %q", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// applyEffects applies the configured --effects to img in order.
+func applyEffects(img image.Image, config Config, size int) image.Image {
+	if config.Effects == "" {
+		return img
+	}
+	names, err := parseEffects(config.Effects)
+	if err != nil {
+		return img
+	}
+	for _, name := range names {
+		img = surfaceEffects[name](img, size)
+	}
+	return img
+}
+
+// addGloss lays the classic glossy highlight over the top of the icon: a
+// white ellipse reaching halfway down, fading out towards its lower edge.
+func addGloss(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	glossy := toRGBA(img)
+
+	cx, rx, ry := float64(width)/2, float64(width)*0.9, float64(height)*0.5
+	for y := 0; y < height; y++ {
+		py := float64(y) + 0.5
+		for x := 0; x < width; x++ {
+			px := float64(x) + 0.5
+			dx, dy := (px-cx)/rx, py/ry
+			// Approximate distance in pixels to the ellipse's edge, for an
+			// anti-aliased lower rim
+			coverage := math.Max(0, math.Min(1, (1-math.Sqrt(dx*dx+dy*dy))*ry+0.5))
+			if coverage == 0 {
+				continue
+			}
+			strength := (0.55 - 0.4*math.Min(1, py/ry)) * coverage
+			glossy.SetRGBA(x, y, atop(glossy.RGBAAt(x, y), color.RGBA{255, 255, 255, 255}, strength))
+		}
+	}
+	return glossy
+}
+
+// addInnerShadow darkens the inside of the icon's edges, strongest along the
+// top, as if the icon were recessed.
+func addInnerShadow(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	shadowed := toRGBA(img)
+
+	// Blurring the alpha and inverting it gives the shadow cast inwards by
+	// the outside, which counts as fully transparent
+	offset := int(math.Round(float64(size) * 0.02))
+	alpha := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if sy := y - offset; sy >= 0 {
+				alpha[y*width+x] = float64(shadowed.RGBAAt(x, sy).A) / 255
+			}
+		}
+	}
+	alpha = gaussianBlur(alpha, width, height, float64(size)*0.015)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			strength := (1 - alpha[y*width+x]) * 0.5
+			if strength > 0 {
+				shadowed.SetRGBA(x, y, atop(shadowed.RGBAAt(x, y), color.RGBA{0, 0, 0, 255}, strength))
+			}
+		}
+	}
+	return shadowed
+}
+
+// atop composites c at opacity over dst, clipped to dst's alpha.
+func atop(dst, c color.RGBA, opacity float64) color.RGBA {
+	a := opacity * float64(c.A) / 255 * float64(dst.A) / 255
+	mix := func(src, d uint8) uint8 {
+		return uint8(math.Round(float64(src)*float64(dst.A)/255*opacity*float64(c.A)/255 + float64(d)*(1-a)))
+	}
+	return color.RGBA{mix(c.R, dst.R), mix(c.G, dst.G), mix(c.B, dst.B), dst.A}
+}
+
+// toRGBA returns a copy of img as an RGBA image anchored at the origin.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestParseEffects(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"gloss", []string{"gloss"}, false},
+		{"inner-shadow,gloss", []string{"inner-shadow", "gloss"}, false},
+		{"gloss,gloss", nil, true},
+		{"bevel", nil, true},
+		{"gloss,", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEffects(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEffects(%q): expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseEffects(%q): expected %v, got %v", tt.spec, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseEffects(%q): expected %v, got %v", tt.spec, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestGlossLightensTopOnly(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0, 0, 200, 255}}, image.Point{}, draw.Src)
+
+	glossy := addGloss(img, 100).(*image.RGBA)
+	if top := glossy.RGBAAt(50, 5); top.R < 100 {
+		t.Errorf("Expected the top to be lightened, got %v", top)
+	}
+	if bottom := glossy.RGBAAt(50, 80); bottom != (color.RGBA{0, 0, 200, 255}) {
+		t.Errorf("Expected the bottom to be untouched, got %v", bottom)
+	}
+}
+
+func TestEffectsKeepAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, image.Rect(16, 16, 48, 48), &image.Uniform{color.RGBA{200, 200, 200, 255}}, image.Point{}, draw.Src)
+
+	config := Config{Effects: "inner-shadow,gloss"}
+	result := applyEffects(img, config, 64).(*image.RGBA)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if result.RGBAAt(x, y).A != img.RGBAAt(x, y).A {
+				t.Fatalf("Expected alpha at (%d,%d) to be %d, got %d", x, y, img.RGBAAt(x, y).A, result.RGBAAt(x, y).A)
+			}
+			if img.RGBAAt(x, y).A == 0 && result.RGBAAt(x, y) != (color.RGBA{}) {
+				t.Fatalf("Expected transparent pixel at (%d,%d) to stay clear, got %v", x, y, result.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestInnerShadowDarkensEdges(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{200, 200, 200, 255}}, image.Point{}, draw.Src)
+
+	shadowed := addInnerShadow(img, 100).(*image.RGBA)
+	if edge, center := shadowed.RGBAAt(50, 0), shadowed.RGBAAt(50, 50); edge.R >= center.R {
+		t.Errorf("Expected the top edge (%v) to be darker than the center (%v)", edge, center)
+	}
+	if center := shadowed.RGBAAt(50, 50); center.R != 200 {
+		t.Errorf("Expected the center to be untouched, got %v", center)
+	}
+}
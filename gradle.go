@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runGradleTask implements "icongen gradle-task": it prints a Gradle task
+// that generates the Android launcher icons from a source image into a
+// generated res directory, with its inputs and outputs declared so Gradle's
+// up-to-date checks and build cache decide when icongen runs.
+func runGradleTask(args []string) error {
+	var config Config
+	flags := flag.NewFlagSet("gradle-task", flag.ContinueOnError)
+	defineFlags(flags, &config)
+	var dsl, taskName string
+	flags.StringVar(&dsl, "dsl", "kotlin", "Build script language: kotlin (build.gradle.kts) or groovy (build.gradle)")
+	flags.StringVar(&taskName, "task", "generateLauncherIcons", "Name of the Gradle task")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s gradle-task [options] source.png\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Print a Gradle task that generates the Android launcher icons from the source.\n")
+		fmt.Fprintf(flags.Output(), "The source path is relative to the module directory. Generation options are passed through.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("gradle-task requires a source image")
+	}
+	if dsl != "kotlin" && dsl != "groovy" {
+		return fmt.Errorf("unknown dsl %q (use kotlin or groovy)", dsl)
+	}
+	if !gradleIdentifier(taskName) {
+		return fmt.Errorf("invalid task name %q (use letters, digits and underscores)", taskName)
+	}
+
+	// Gradle tracks the outputs, so icongen keeps no state of its own
+	options := []string{"--preset=android", "--stateless", "--force"}
+	options = append(options, regularIconsOnly(flags, passThroughOptions(flags, "dsl", "task", "preset", "stateless", "force",
+		"incremental", "no-incremental", "clean", "clean-all", "manifest", "dry-run"))...)
+
+	fmt.Print(gradleTask(dsl, taskName, flags.Arg(0), options))
+	return nil
+}
+
+// gradleTask returns the Gradle snippet in the given dsl. The task writes to
+// its own directory under build/, which is added as a res source directory,
+// so it never overlaps other outputs and can be restored from the build cache.
+func gradleTask(dsl, taskName, source string, options []string) string {
+	var b strings.Builder
+	quoted := make([]string, len(options))
+	for i, option := range options {
+		quoted[i] = gradleString(option)
+	}
+	args := strings.Join(quoted, ", ")
+
+	b.WriteString("// Generates the Android launcher icons from their source with icongen.\n")
+	b.WriteString("// Generated by icongen gradle-task.\n")
+	if dsl == "kotlin" {
+		fmt.Fprintf(&b, "val icongenSource = file(%s)\n", gradleString(source))
+		b.WriteString("val icongenRes = layout.buildDirectory.dir(\"generated/icongen/res\")\n\n")
+		fmt.Fprintf(&b, "val %s by tasks.registering(Exec::class) {\n", taskName)
+		b.WriteString("    description = \"Generates the launcher icons with icongen\"\n")
+		b.WriteString("    inputs.file(icongenSource).withPathSensitivity(PathSensitivity.RELATIVE)\n")
+		fmt.Fprintf(&b, "    inputs.property(\"icongenOptions\", listOf(%s))\n", args)
+		b.WriteString("    outputs.dir(icongenRes)\n")
+		b.WriteString("    outputs.cacheIf { true }\n")
+		b.WriteString("    doFirst { delete(icongenRes) }\n")
+		fmt.Fprintf(&b, "    commandLine(listOf(\"icongen\", %s, icongenSource.path, icongenRes.get().asFile.path))\n", args)
+		b.WriteString("}\n\n")
+		b.WriteString("android.sourceSets[\"main\"].res.srcDir(icongenRes)\n")
+		fmt.Fprintf(&b, "tasks.named(\"preBuild\") { dependsOn(%s) }\n", taskName)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "def icongenSource = file(%s)\n", gradleString(source))
+	b.WriteString("def icongenRes = layout.buildDirectory.dir(\"generated/icongen/res\")\n\n")
+	fmt.Fprintf(&b, "def %s = tasks.register(%s, Exec) {\n", taskName, gradleString(taskName))
+	b.WriteString("    description = \"Generates the launcher icons with icongen\"\n")
+	b.WriteString("    inputs.file(icongenSource).withPathSensitivity(PathSensitivity.RELATIVE)\n")
+	fmt.Fprintf(&b, "    inputs.property(\"icongenOptions\", [%s])\n", args)
+	b.WriteString("    outputs.dir(icongenRes)\n")
+	b.WriteString("    outputs.cacheIf { true }\n")
+	b.WriteString("    doFirst { delete(icongenRes) }\n")
+	fmt.Fprintf(&b, "    commandLine([\"icongen\", %s, icongenSource.path, icongenRes.get().asFile.path])\n", args)
+	b.WriteString("}\n\n")
+	b.WriteString("android.sourceSets.main.res.srcDir(icongenRes)\n")
+	fmt.Fprintf(&b, "tasks.named(\"preBuild\") { dependsOn(%s) }\n", taskName)
+	return b.String()
+}
+
+// gradleIdentifier reports whether name can be used as a variable name in
+// both Kotlin and Groovy.
+func gradleIdentifier(name string) bool {
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// gradleString quotes s as a string literal valid in both Kotlin and Groovy
+// build scripts.
+func gradleString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
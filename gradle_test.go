@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGradleTask(t *testing.T) {
+	options := []string{"--preset=android", "--stateless", "--force", "--radius-percent=0"}
+
+	tests := []struct {
+		dsl  string
+		want []string
+	}{
+		{"kotlin", []string{
+			`val icongenSource = file("src/main/icon \$1.png")`,
+			"val generateIcons by tasks.registering(Exec::class)",
+			`inputs.property("icongenOptions", listOf("--preset=android", "--stateless", "--force", "--radius-percent=0"))`,
+			"outputs.dir(icongenRes)",
+			`android.sourceSets["main"].res.srcDir(icongenRes)`,
+			"dependsOn(generateIcons)",
+		}},
+		{"groovy", []string{
+			`def icongenSource = file("src/main/icon \$1.png")`,
+			`def generateIcons = tasks.register("generateIcons", Exec)`,
+			`inputs.property("icongenOptions", ["--preset=android", "--stateless", "--force", "--radius-percent=0"])`,
+			"outputs.dir(icongenRes)",
+			"android.sourceSets.main.res.srcDir(icongenRes)",
+			"dependsOn(generateIcons)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsl, func(t *testing.T) {
+			task := gradleTask(tt.dsl, "generateIcons", "src/main/icon $1.png", options)
+			for _, want := range tt.want {
+				if !strings.Contains(task, want) {
+					t.Errorf("Expected task to contain %q, got:\n%s", want, task)
+				}
+			}
+		})
+	}
+}
+
+func TestGradleIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"generateLauncherIcons", true},
+		{"_icons2", true},
+		{"2icons", false},
+		{"generate-icons", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := gradleIdentifier(tt.name); got != tt.want {
+			t.Errorf("Expected gradleIdentifier(%q) to be %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
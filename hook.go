@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runHook implements "icongen hook install": it installs a git pre-commit
+// hook that regenerates the icons, or with --verify checks they're up to
+// date, whenever a commit touches their source image or configuration.
+func runHook(args []string) error {
+	if len(args) == 0 || args[0] != "install" {
+		fmt.Fprintf(os.Stderr, "Usage: %s hook install [options] source.png output-dir\n", os.Args[0])
+		return fmt.Errorf("unknown hook command (expected install)")
+	}
+
+	var config Config
+	flags := flag.NewFlagSet("hook install", flag.ContinueOnError)
+	defineFlags(flags, &config)
+	var verify bool
+	var configPath string
+	flags.BoolVar(&verify, "verify", false, "Reject the commit if the icons are out of date instead of regenerating and staging them")
+	flags.StringVar(&configPath, "config", "", "Project configuration file the hook passes to icongen; committing it also triggers the hook")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s hook install [options] source.png output-dir\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Install a git pre-commit hook that keeps the committed icons in sync with their source.\n")
+		fmt.Fprintf(flags.Output(), "Generation options are passed through; --force replaces an existing pre-commit hook.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("hook install requires a source image and an output directory")
+	}
+	if config.Stateless {
+		return fmt.Errorf("the hook relies on the output manifest, which --stateless doesn't write")
+	}
+
+	top, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	hooksDir, err := gitOutput("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return fmt.Errorf("failed to find the hooks directory: %w", err)
+	}
+
+	// The hook runs from the top of the work tree
+	var paths []string
+	for _, path := range []string{flags.Arg(0), flags.Arg(1), configPath} {
+		if path == "" {
+			paths = append(paths, "")
+			continue
+		}
+		rel, err := repoPath(top, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+	}
+
+	options := passThroughOptions(flags, "verify", "config", "force", "dry-run", "check", "clean", "clean-all")
+	if paths[2] != "" {
+		options = append([]string{"--config=" + paths[2]}, options...)
+	}
+	script := preCommitHook(paths[0], paths[1], paths[2], options, verify)
+
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+	if _, err := os.Stat(hookPath); err == nil && !config.Force {
+		return fmt.Errorf("%s already exists (use --force to replace it)", hookPath)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", hooksDir, err)
+	}
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hookPath, err)
+	}
+
+	fmt.Printf("Installed %s\n", hookPath)
+	return nil
+}
+
+// preCommitHook returns the pre-commit hook script. It only acts when the
+// source image or configuration file is part of the commit, and looks at the
+// work tree rather than the index, like icongen itself.
+func preCommitHook(source, outputDir, configPath string, options []string, verify bool) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Keeps the committed icons in sync with their source.\n")
+	b.WriteString("# Generated by icongen hook install.\n\n")
+
+	b.WriteString("changed=$(git diff --cached --name-only --diff-filter=ACMR)\n")
+	fmt.Fprintf(&b, "printf '%%s\\n' \"$changed\" | grep -qxF -e %s", shellQuote(source))
+	if configPath != "" {
+		fmt.Fprintf(&b, " -e %s", shellQuote(configPath))
+	}
+	b.WriteString(" || exit 0\n\n")
+
+	b.WriteString("if ! command -v icongen >/dev/null 2>&1; then\n")
+	b.WriteString("  echo \"warning: icongen not installed, icons not checked\" >&2\n")
+	b.WriteString("  exit 0\n")
+	b.WriteString("fi\n\n")
+
+	args := ""
+	for _, option := range options {
+		args += " " + shellQuote(option)
+	}
+	args += " " + shellQuote(source) + " " + shellQuote(outputDir)
+
+	if verify {
+		fmt.Fprintf(&b, "if ! icongen --check%s >&2; then\n", args)
+		fmt.Fprintf(&b, "  echo \"error: icons in %s are out of date; regenerate them with:\" >&2\n", shellEscape(outputDir))
+		fmt.Fprintf(&b, "  echo \"  icongen%s\" >&2\n", shellEscape(args))
+		b.WriteString("  exit 1\n")
+		b.WriteString("fi\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "icongen%s >&2 || exit 1\n", args)
+	fmt.Fprintf(&b, "git add -A -- %s\n", shellQuote(outputDir))
+	return b.String()
+}
+
+// repoPath returns path relative to the work tree top, failing for paths
+// outside it.
+func repoPath(top, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// Resolve symlinks on both sides, e.g. macOS's /tmp
+	if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+	if resolved, err := filepath.EvalSymlinks(top); err == nil {
+		top = resolved
+	}
+
+	rel, err := filepath.Rel(top, abs)
+	if err != nil || !localName(rel) {
+		return "", fmt.Errorf("%s is outside the repository", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// gitOutput runs git with args and returns its trimmed output.
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreCommitHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify bool
+		want   []string
+	}{
+		{"regenerate", false, []string{
+			"grep -qxF -e 'design/app icon.png' -e icongen.yaml || exit 0",
+			"icongen --config=icongen.yaml 'design/app icon.png' assets/icons >&2 || exit 1",
+			"git add -A -- assets/icons",
+		}},
+		{"verify", true, []string{
+			"if ! icongen --check --config=icongen.yaml 'design/app icon.png' assets/icons >&2; then",
+			"exit 1",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := preCommitHook("design/app icon.png", "assets/icons", "icongen.yaml", []string{"--config=icongen.yaml"}, tt.verify)
+			for _, want := range tt.want {
+				if !strings.Contains(script, want) {
+					t.Errorf("Expected hook to contain %q, got:\n%s", want, script)
+				}
+			}
+			if tt.verify && strings.Contains(script, "git add") {
+				t.Errorf("Expected --verify hook not to stage anything")
+			}
+		})
+	}
+}
+
+func TestRepoPath(t *testing.T) {
+	top := t.TempDir()
+
+	if got, err := repoPath(top, filepath.Join(top, "design", "icon.png")); err != nil || got != "design/icon.png" {
+		t.Errorf("Expected design/icon.png, got %q (%v)", got, err)
+	}
+	if _, err := repoPath(top, filepath.Join(filepath.Dir(top), "icon.png")); err == nil {
+		t.Errorf("Expected error for a path outside the repository")
+	}
+}
+
+func TestCheckIcons(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Check: true}
+	if err := generateIcons(config); err == nil {
+		t.Errorf("Expected --check to fail before the icons exist")
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Errorf("Expected --check not to write anything, found %d files", len(entries))
+	}
+
+	config.Check = false
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	config.Check = true
+	if err := generateIcons(config); err != nil {
+		t.Errorf("Expected --check to pass after generating, got: %v", err)
+	}
+
+	config.TrimPercent = 60
+	if err := generateIcons(config); err == nil {
+		t.Errorf("Expected --check to fail after the options changed")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// initOptions are the flags of "icongen init" on top of the generation
+// options.
+type initOptions struct {
+	build  string
+	config string
+	file   string
+}
+
+func newInitFlags(config *Config, opts *initOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	defineFlags(flags, config)
+	flags.StringVar(&opts.build, "build", "make", "Build tool to write rules for: make or just")
+	flags.StringVar(&opts.config, "config", "", "Project configuration file the rules pass to icongen and depend on")
+	flags.StringVar(&opts.file, "file", "", "File to write the rules to (default icons.mk or icons.just)")
+	return flags
+}
+
+// runInit implements "icongen init": it writes build rules that regenerate
+// the icons of a source image with the project's configuration file and
+// generation options, so icon generation plugs into make or just.
+func runInit(args []string) error {
+	var config Config
+	var opts initOptions
+	flags := newInitFlags(&config, &opts)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s init --build=make|just [options] source.png output-dir\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Write build rules that regenerate the icons whenever the source or configuration changes.\n")
+		fmt.Fprintf(flags.Output(), "Generation options are written into the rules; --force overwrites an existing rules file.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("init requires a source image and an output directory")
+	}
+	if opts.build != "make" && opts.build != "just" {
+		return fmt.Errorf("unknown build tool %q (use make or just)", opts.build)
+	}
+	if config.Recursive {
+		return fmt.Errorf("init writes rules for a single source image; run it once per source")
+	}
+	if config.Stateless {
+		return fmt.Errorf("the rules track the output manifest, which --stateless doesn't write")
+	}
+	if opts.file == "" {
+		opts.file = "icons.mk"
+		if opts.build == "just" {
+			opts.file = "icons.just"
+		}
+	}
+
+	// Plan with the configuration file applied underneath the command line,
+	// the same way icongen --config reads it
+	var planConfig Config
+	var planOpts initOptions
+	planFlags := newInitFlags(&planConfig, &planOpts)
+	planFlags.SetOutput(io.Discard)
+	if opts.config != "" {
+		if err := loadConfigFile(planFlags, opts.config); err != nil {
+			return err
+		}
+	}
+	if err := planFlags.Parse(args); err != nil {
+		return err
+	}
+	planConfig.InputPath, planConfig.OutputDir = flags.Arg(0), flags.Arg(1)
+	targets, err := planOutputs(planConfig)
+	if err != nil {
+		return err
+	}
+
+	command := []string{}
+	if opts.config != "" {
+		command = append(command, "--config", opts.config)
+	}
+	command = append(command, passThroughOptions(flags, "build", "config", "file", "force", "dry-run")...)
+	command = append(command, flags.Arg(0), flags.Arg(1))
+
+	var rules string
+	if opts.build == "make" {
+		rules, err = makeRules(flags.Arg(0), flags.Arg(1), opts.config, command, targets)
+		if err != nil {
+			return err
+		}
+	} else {
+		rules = justRecipes(command, targets)
+	}
+
+	if _, err := os.Stat(opts.file); err == nil && !config.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", opts.file)
+	}
+	if err := os.WriteFile(opts.file, []byte(rules), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", opts.file, err)
+	}
+
+	fmt.Printf("Wrote %s\n", opts.file)
+	if opts.build == "make" {
+		fmt.Printf("Add \"include %s\" to your Makefile and run \"make icons\".\n", opts.file)
+	} else {
+		fmt.Printf("Add \"import '%s'\" to your justfile and run \"just icons\".\n", opts.file)
+	}
+	return nil
+}
+
+// makeRules returns make rules generating the icons. The output manifest
+// stands in for every output, since icongen writes them all in one go; it
+// depends on the source and configuration file, and each icon depends on it.
+func makeRules(source, outputDir, configPath string, command []string, targets []plannedOutput) (string, error) {
+	paths := []string{source, outputDir, configPath}
+	for _, target := range targets {
+		paths = append(paths, target.Path)
+	}
+	for _, path := range paths {
+		if strings.ContainsAny(path, " \t\n:%") {
+			return "", fmt.Errorf("make can't handle the path %q; avoid spaces, colons and %%", path)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Regenerates the icons whenever the source or configuration changes.\n")
+	b.WriteString("# Generated by icongen init.\n")
+	b.WriteString("ICONGEN ?= icongen\n")
+	fmt.Fprintf(&b, "ICON_MANIFEST := %s\n", makeEscape(filepath.ToSlash(filepath.Join(outputDir, manifestName))))
+	b.WriteString("ICONS :=")
+	for _, target := range targets {
+		fmt.Fprintf(&b, " \\\n\t%s", makeEscape(filepath.ToSlash(target.Path)))
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(".PHONY: icons clean-icons\n")
+	b.WriteString("icons: $(ICONS)\n\n")
+
+	fmt.Fprintf(&b, "$(ICON_MANIFEST): %s", makeEscape(source))
+	if configPath != "" {
+		fmt.Fprintf(&b, " %s", makeEscape(configPath))
+	}
+	b.WriteString("\n")
+	b.WriteString("\t$(ICONGEN)")
+	for _, arg := range command {
+		b.WriteString(" " + makeEscape(shellQuote(arg)))
+	}
+	b.WriteString("\n")
+	// icongen leaves the manifest alone when everything is up to date
+	b.WriteString("\t@touch $@\n\n")
+
+	b.WriteString("$(ICONS): $(ICON_MANIFEST) ;\n\n")
+
+	b.WriteString("clean-icons:\n")
+	b.WriteString("\trm -f $(ICONS) $(ICON_MANIFEST)\n")
+	return b.String(), nil
+}
+
+// justRecipes returns just recipes generating the icons. just has no file
+// dependencies, so the recipe relies on icongen's incremental mode to skip
+// the work unless the source or options changed.
+func justRecipes(command []string, targets []plannedOutput) string {
+	var b strings.Builder
+	b.WriteString("# Generated by icongen init.\n")
+	b.WriteString("icongen := env_var_or_default(\"ICONGEN\", \"icongen\")\n\n")
+
+	b.WriteString("# Regenerate the icons; icongen skips the work unless the source or configuration changed\n")
+	b.WriteString("icons:\n")
+	b.WriteString("    {{icongen}}")
+	for _, arg := range command {
+		b.WriteString(" " + justEscape(shellQuote(arg)))
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString("# Remove the generated icons\n")
+	b.WriteString("clean-icons:\n")
+	b.WriteString("    rm -f")
+	for _, target := range targets {
+		b.WriteString(" " + justEscape(shellQuote(filepath.ToSlash(target.Path))))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// makeEscape escapes the characters make would otherwise expand.
+func makeEscape(s string) string {
+	return strings.NewReplacer("$", "$$", "#", `\#`).Replace(s)
+}
+
+// justEscape escapes just's interpolation braces.
+func justEscape(s string) string {
+	return strings.ReplaceAll(s, "{{", "{{{{")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakeRules(t *testing.T) {
+	targets := []plannedOutput{{Path: "build/icons/icon_16x16.png"}, {Path: "build/icons/icon_32x32.png"}}
+	command := []string{"--config", "icongen.yaml", "--series=A, B", "logo.png", "build/icons"}
+
+	rules, err := makeRules("logo.png", "build/icons", "icongen.yaml", command, targets)
+	if err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	for _, want := range []string{
+		"ICON_MANIFEST := build/icons/" + manifestName,
+		"\tbuild/icons/icon_32x32.png\n",
+		"$(ICON_MANIFEST): logo.png icongen.yaml\n",
+		"\t$(ICONGEN) --config icongen.yaml '--series=A, B' logo.png build/icons\n",
+		"$(ICONS): $(ICON_MANIFEST) ;",
+	} {
+		if !strings.Contains(rules, want) {
+			t.Errorf("Expected rules to contain %q, got:\n%s", want, rules)
+		}
+	}
+
+	if _, err := makeRules("my logo.png", "build/icons", "", nil, nil); err == nil {
+		t.Errorf("Expected error for a path with spaces")
+	}
+}
+
+func TestJustRecipes(t *testing.T) {
+	recipes := justRecipes([]string{"--series={{x}}", "logo.png", "out"}, []plannedOutput{{Path: "out/icon_16x16.png"}})
+
+	for _, want := range []string{
+		"icons:\n    {{icongen}} '--series={{{{x}}' logo.png out\n",
+		"clean-icons:\n    rm -f out/icon_16x16.png\n",
+	} {
+		if !strings.Contains(recipes, want) {
+			t.Errorf("Expected recipes to contain %q, got:\n%s", want, recipes)
+		}
+	}
+}
+
+func TestRunInit(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "logo.png")
+	if err := saveImage(createTestImage(64, color.RGBA{255, 0, 0, 255}), source); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	configPath := filepath.Join(dir, "icongen.yaml")
+	if err := os.WriteFile(configPath, []byte("mask: circle\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	rulesPath := filepath.Join(dir, "icons.mk")
+	outputDir := filepath.Join(dir, "icons")
+
+	args := []string{"--config", configPath, "--file", rulesPath, "--trim-percent=75", source, outputDir}
+	if err := runInit(args); err != nil {
+		t.Fatalf("Failed to run init: %v", err)
+	}
+
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		t.Fatalf("Expected rules file: %v", err)
+	}
+	rules := string(data)
+
+	// Outputs come from the config file, options from the command line
+	if !strings.Contains(rules, filepath.ToSlash(filepath.Join(outputDir, "icon_16x16_circle.png"))) {
+		t.Errorf("Expected the config's circle variants among the outputs")
+	}
+	if !strings.Contains(rules, "--trim-percent=75") || strings.Contains(rules, "--mask=") {
+		t.Errorf("Expected only command-line options inline, got:\n%s", rules)
+	}
+
+	if err := runInit(args); err == nil {
+		t.Errorf("Expected error when the rules file exists")
+	}
+	if err := runInit(append([]string{"--force"}, args...)); err != nil {
+		t.Errorf("Expected --force to overwrite the rules file, got: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errCanceled is returned by runs whose job was canceled.
+var errCanceled = errors.New("run canceled")
+
+// runProgress counts the outputs of a run as they are recorded, for the
+// background jobs of "icongen rpc", and lets the run be canceled between
+// outputs. A nil *runProgress tracks nothing, as for CLI runs.
+type runProgress struct {
+	mu       sync.Mutex
+	total    int
+	done     int
+	current  string
+	started  time.Time
+	finished time.Time
+	cancel   bool
+}
+
+// step counts the output name as done.
+func (p *runProgress) step(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.current = name
+}
+
+// canceled reports whether the run should stop.
+func (p *runProgress) canceled() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cancel
+}
+
+// rpcJob is a generate run started by the "submit" method.
+type rpcJob struct {
+	ID string
+	// Dir is the absolute directory the job writes its icons to
+	Dir      string
+	progress *runProgress
+	// err is the outcome once done is closed
+	err  error
+	done chan struct{}
+}
+
+// jobStatus is the result of the "progress" and "cancel" methods.
+type jobStatus struct {
+	Job     string `json:"job"`
+	State   string `json:"state"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Current string `json:"current,omitempty"`
+	// Elapsed and ETA are in seconds; ETA is left out until the first
+	// output is done
+	Elapsed float64  `json:"elapsed"`
+	ETA     *float64 `json:"eta,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// status reports the progress of the job: "running", "canceling",
+// "done", "canceled" or "failed".
+func (j *rpcJob) status() jobStatus {
+	p := j.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := jobStatus{Job: j.ID, State: "running", Done: p.done, Total: p.total, Current: p.current}
+	select {
+	case <-j.done:
+		status.Elapsed = p.finished.Sub(p.started).Seconds()
+		switch {
+		case j.err == nil:
+			status.State = "done"
+		case errors.Is(j.err, errCanceled):
+			status.State = "canceled"
+		default:
+			status.State = "failed"
+			status.Error = j.err.Error()
+		}
+		return status
+	default:
+	}
+
+	elapsed := time.Since(p.started)
+	status.Elapsed = elapsed.Seconds()
+	if p.cancel {
+		status.State = "canceling"
+	}
+	if p.done > 0 && p.done < p.total {
+		eta := elapsed.Seconds() / float64(p.done) * float64(p.total-p.done)
+		status.ETA = &eta
+	}
+	return status
+}
+
+// jobRegistry holds the jobs of an "icongen rpc" session.
+type jobRegistry struct {
+	mu      sync.Mutex
+	jobs    map[string]*rpcJob
+	next    int
+	running sync.WaitGroup
+}
+
+var rpcJobs = &jobRegistry{jobs: make(map[string]*rpcJob)}
+
+// start runs config in the background as a new job. Jobs writing to the
+// same directory would overwrite each other's manifest, so only one runs at
+// a time per directory, however its path is spelled.
+func (r *jobRegistry) start(config Config, total int) (*rpcJob, error) {
+	dir, err := jobDir(config)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.jobs {
+		select {
+		case <-other.done:
+			continue
+		default:
+		}
+		if other.Dir == dir {
+			return nil, fmt.Errorf("job %s is still writing to %s", other.ID, dir)
+		}
+	}
+
+	r.next++
+	job := &rpcJob{
+		ID:       strconv.Itoa(r.next),
+		Dir:      dir,
+		progress: &runProgress{total: total, started: time.Now()},
+		done:     make(chan struct{}),
+	}
+	r.jobs[job.ID] = job
+	config.Progress = job.progress
+
+	r.running.Add(1)
+	go func() {
+		defer r.running.Done()
+		err := generateConfig(config)
+
+		job.progress.mu.Lock()
+		job.progress.finished = time.Now()
+		if err == nil {
+			// Outputs such as the web snippets aren't counted one by one
+			job.progress.done = job.progress.total
+		}
+		job.err = err
+		job.progress.mu.Unlock()
+		close(job.done)
+	}()
+	return job, nil
+}
+
+// jobDir returns the directory a run with config writes its icons to, the
+// version directory with --versioned-dirs, as a clean absolute path.
+func jobDir(config Config) (string, error) {
+	dir, err := filepath.Abs(assetDir(config))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// find returns the job with the id in params, an object {"job": "1"}.
+func (r *jobRegistry) find(params json.RawMessage) (*rpcJob, error) {
+	var request struct {
+		Job string `json:"job"`
+	}
+	if err := json.Unmarshal(params, &request); err != nil || request.Job == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: `params must be an object with the job id, e.g. {"job": "1"}`}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[request.Job]
+	if !ok {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown job %q", request.Job)}
+	}
+	return job, nil
+}
+
+// wait blocks until every job has finished.
+func (r *jobRegistry) wait() {
+	r.running.Wait()
+}
+
+func rpcSubmit(params json.RawMessage, log io.Writer) (interface{}, error) {
+	config, err := rpcConfig(params, log)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := rpcJobs.start(config, len(targets))
+	if err != nil {
+		return nil, err
+	}
+	return job.status(), nil
+}
+
+func rpcProgress(params json.RawMessage, log io.Writer) (interface{}, error) {
+	job, err := rpcJobs.find(params)
+	if err != nil {
+		return nil, err
+	}
+	return job.status(), nil
+}
+
+func rpcCancel(params json.RawMessage, log io.Writer) (interface{}, error) {
+	job, err := rpcJobs.find(params)
+	if err != nil {
+		return nil, err
+	}
+
+	job.progress.mu.Lock()
+	job.progress.cancel = true
+	job.progress.mu.Unlock()
+	return job.status(), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRPCJobs(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 128, 255, 255}))
+	outputDir := t.TempDir()
+	options := fmt.Sprintf(`{"input": %q, "output": %q, "radius-percent": 0}`, inputPath, outputDir)
+
+	call := func(method, params string) (jobStatus, *rpcError) {
+		t.Helper()
+		response := handleRPC(json.RawMessage(`{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`), io.Discard)
+		if response.Error != nil {
+			return jobStatus{}, response.Error
+		}
+		return response.Result.(jobStatus), nil
+	}
+
+	submitted, rpcErr := call("submit", options)
+	if rpcErr != nil {
+		t.Fatalf("Expected submit to succeed, got %v", rpcErr)
+	}
+	if submitted.Total != len(iconSizes) {
+		t.Errorf("Expected %d outputs to do, got %d", len(iconSizes), submitted.Total)
+	}
+
+	rpcJobs.wait()
+	status, rpcErr := call("progress", `{"job": "`+submitted.Job+`"}`)
+	if rpcErr != nil {
+		t.Fatalf("Expected progress to succeed, got %v", rpcErr)
+	}
+	if status.State != "done" || status.Done != status.Total || status.ETA != nil {
+		t.Errorf("Expected a finished job, got %+v", status)
+	}
+
+	if _, rpcErr := call("cancel", `{"job": "none"}`); rpcErr == nil || rpcErr.Code != rpcInvalidParams {
+		t.Errorf("Expected an invalid params error for an unknown job, got %+v", rpcErr)
+	}
+	if _, rpcErr := call("progress", `[]`); rpcErr == nil || rpcErr.Code != rpcInvalidParams {
+		t.Errorf("Expected an invalid params error without a job id, got %+v", rpcErr)
+	}
+}
+
+func TestJobsShareNoOutputDir(t *testing.T) {
+	dir, err := jobDir(Config{OutputDir: "out"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := &jobRegistry{jobs: map[string]*rpcJob{
+		"1": {ID: "1", Dir: dir, progress: &runProgress{}, done: make(chan struct{})},
+	}}
+
+	// The same directory, however it is spelled
+	for _, outputDir := range []string{"out", "./out/", "out/../out", dir} {
+		if _, err := registry.start(Config{OutputDir: outputDir}, 1); err == nil {
+			t.Errorf("Expected a second job writing to %s to be refused", outputDir)
+		}
+	}
+
+	// --versioned-dirs writes to a directory of its own
+	versioned, err := jobDir(Config{OutputDir: "out", VersionedDirs: true, AssetVersion: "2.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versioned == dir || filepath.Dir(versioned) != dir {
+		t.Errorf("Expected the version directory under %s, got %s", dir, versioned)
+	}
+}
+
+func TestJobStatusETA(t *testing.T) {
+	job := &rpcJob{
+		ID:       "1",
+		progress: &runProgress{total: 10, done: 4, started: time.Now().Add(-4 * time.Second)},
+		done:     make(chan struct{}),
+	}
+
+	status := job.status()
+	if status.State != "running" || status.ETA == nil || *status.ETA < 5.9 || *status.ETA > 6.5 {
+		t.Errorf("Expected about 6 seconds to go, got %+v", status)
+	}
+
+	job.progress.cancel = true
+	if status := job.status(); status.State != "canceling" {
+		t.Errorf("Expected a canceling job, got %s", status.State)
+	}
+
+	job.err = fmt.Errorf("a.png: %w", errCanceled)
+	close(job.done)
+	if status := job.status(); status.State != "canceled" {
+		t.Errorf("Expected a canceled job, got %s", status.State)
+	}
+}
+
+func TestCanceledRunResumes(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 200, 80, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Incremental: true}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	// A run with new options canceled before its first output keeps the
+	// previous outputs as icongen's
+	config.TrimPercent = 70
+	config.Progress = &runProgress{cancel: true}
+	if err := generateIcons(config); !errors.Is(err, errCanceled) {
+		t.Fatalf("Expected the run to be canceled, got %v", err)
+	}
+	m, err := readManifest(outputDir)
+	if err != nil {
+		t.Fatalf("Expected a partial manifest: %v", err)
+	}
+	outputs := len(planTargets(config))
+	if len(m.Files) != 0 || len(m.Pending) != outputs {
+		t.Errorf("Expected every previous output pending, got %d files and %d pending", len(m.Files), len(m.Pending))
+	}
+
+	// Resuming overwrites them without --force
+	config.Progress = &runProgress{}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Expected the resumed run to succeed, got %v", err)
+	}
+	if config.Progress.done != outputs {
+		t.Errorf("Expected every output counted, got %d", config.Progress.done)
+	}
+	if m, _ := readManifest(outputDir); len(m.Pending) != 0 {
+		t.Errorf("Expected nothing pending after the run, got %v", m.Pending)
+	}
+}
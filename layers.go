@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// backgroundIsImage reports whether --background names a background image
+// rather than a #RRGGBB[AA] color.
+func backgroundIsImage(config Config) bool {
+	return config.Background != "" && !strings.HasPrefix(config.Background, "#")
+}
+
+// loadBackgroundImage loads the --background image, or returns nil when
+// --background isn't an image.
+func loadBackgroundImage(config Config) (image.Image, error) {
+	if !backgroundIsImage(config) {
+		return nil, nil
+	}
+	img, err := loadImage(config.Background)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load background image: %w", err)
+	}
+	return img, nil
+}
+
+// validateLayerScale checks a --foreground-scale or --background-scale.
+func validateLayerScale(name string, scale int) error {
+	if scale < 1 || scale > 200 {
+		return fmt.Errorf("%s must be between 1 and 200 (got %d)", name, scale)
+	}
+	return nil
+}
+
+// layerScale returns a layer's scale percentage, treating 0 as the default
+// of 100 so zero Configs render unscaled.
+func layerScale(scale int) int {
+	if scale == 0 {
+		return 100
+	}
+	return scale
+}
+
+// scaleForeground fits img into a square of scale percent of size, centered
+// on a size x size canvas, resized with fit. Above 100% the artwork overhangs
+// and is clipped.
+func scaleForeground(img image.Image, size, scale int, fit func(image.Image, int) image.Image) image.Image {
+	if scale == 100 {
+		return fit(img, size)
+	}
+	scaled := fit(img, size*scale/100)
+	canvas := newCanvasLike(scaled, image.Rect(0, 0, size, size))
+	drawCentered(canvas, scaled)
+	return canvas
+}
+
+// scaleBackground scales img to cover a square of scale percent of size,
+// cropping its overhang, centered on a size x size canvas.
+func scaleBackground(img image.Image, size, scale int) *image.RGBA {
+	return centerLayer(coverImage(img, size*scale/100), size)
+}
+
+// centerLayer centers layer on a transparent size x size canvas, clipping
+// whatever doesn't fit.
+func centerLayer(layer image.Image, size int) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	drawCentered(canvas, layer)
+	return canvas
+}
+
+// drawCentered copies layer into the middle of canvas.
+func drawCentered(canvas draw.Image, layer image.Image) {
+	size := canvas.Bounds().Dx()
+	bounds := layer.Bounds()
+	offset := image.Pt((size-bounds.Dx())/2, (size-bounds.Dy())/2)
+	draw.Draw(canvas, bounds.Sub(bounds.Min).Add(offset), layer, bounds.Min, draw.Src)
+}
+
+// stackLayer is one entry of a --layers stack: an ordered list of layers
+// composited bottom to top in place of the foreground and background.
+// Scale and offsets are percentages of the icon size and place every layer
+// but effects, which apply to the whole stack below them.
+type stackLayer struct {
+	Type     string `json:"type"`
+	Path     string `json:"path,omitempty"`
+	Color    string `json:"color,omitempty"`
+	Gradient string `json:"gradient,omitempty"`
+	Shape    string `json:"shape,omitempty"`
+	Effect   string `json:"effect,omitempty"`
+	Scale    *int   `json:"scale,omitempty"`
+	OffsetX  int    `json:"offset-x,omitempty"`
+	OffsetY  int    `json:"offset-y,omitempty"`
+	Opacity  *int   `json:"opacity,omitempty"`
+
+	// img is the decoded image of image and mask layers with a path
+	img image.Image
+}
+
+// stackLayerTypes are the layer types --layers accepts.
+var stackLayerTypes = []string{"source", "image", "fill", "gradient", "mask", "effect"}
+
+// parseLayers parses a --layers spec, a JSON array of layer objects.
+func parseLayers(spec string) ([]stackLayer, error) {
+	decoder := json.NewDecoder(strings.NewReader(spec))
+	decoder.DisallowUnknownFields()
+	var layers []stackLayer
+	if err := decoder.Decode(&layers); err != nil {
+		return nil, fmt.Errorf("invalid layers: %w", err)
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("invalid layers: the stack is empty")
+	}
+
+	for i, layer := range layers {
+		if err := layer.validate(); err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i+1, layer.Type, err)
+		}
+	}
+	return layers, nil
+}
+
+// validate checks that the layer has what its type needs and that its
+// placement is in range.
+func (l stackLayer) validate() error {
+	switch l.Type {
+	case "source":
+	case "image":
+		if l.Path == "" {
+			return fmt.Errorf("image layers need a path")
+		}
+	case "fill":
+		if _, err := parseHexColor(l.Color); err != nil {
+			return err
+		}
+	case "gradient":
+		if _, err := parseBackgroundGradient(l.Gradient); err != nil {
+			return err
+		}
+	case "mask":
+		if (l.Path == "") == (l.Shape == "") {
+			return fmt.Errorf("mask layers need either a path or a shape")
+		}
+		if _, ok := maskShapes[l.Shape]; l.Shape != "" && !ok {
+			return fmt.Errorf("unknown mask shape %q (expected circle)", l.Shape)
+		}
+	case "effect":
+		if _, ok := surfaceEffects[l.Effect]; !ok {
+			return fmt.Errorf("unknown effect %q (expected gloss or inner-shadow)", l.Effect)
+		}
+		if l.Scale != nil || l.OffsetX != 0 || l.OffsetY != 0 {
+			return fmt.Errorf("effects apply to the whole stack below them, so they take no scale or offsets")
+		}
+	default:
+		return fmt.Errorf("unknown layer type %q (expected %s)", l.Type, strings.Join(stackLayerTypes, ", "))
+	}
+
+	if err := validateLayerScale("scale", l.scale()); err != nil {
+		return err
+	}
+	if l.OffsetX < -100 || l.OffsetX > 100 || l.OffsetY < -100 || l.OffsetY > 100 {
+		return fmt.Errorf("offsets must be between -100 and 100 (got %d, %d)", l.OffsetX, l.OffsetY)
+	}
+	if opacity := l.opacity(); opacity < 0 || opacity > 100 {
+		return fmt.Errorf("opacity must be between 0 and 100 (got %d)", opacity)
+	}
+	return nil
+}
+
+// scale returns the layer's scale percentage, 100 unless set.
+func (l stackLayer) scale() int {
+	if l.Scale == nil {
+		return 100
+	}
+	return *l.Scale
+}
+
+// opacity returns the layer's opacity percentage, 100 unless set.
+func (l stackLayer) opacity() int {
+	if l.Opacity == nil {
+		return 100
+	}
+	return *l.Opacity
+}
+
+// loadLayers parses the --layers stack of config and loads the images its
+// layers name, or returns nil without a stack.
+func loadLayers(config Config) ([]stackLayer, error) {
+	if config.Layers == "" {
+		return nil, nil
+	}
+	layers, err := parseLayers(config.Layers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range layers {
+		if layers[i].Path == "" {
+			continue
+		}
+		if layers[i].img, err = loadImage(layers[i].Path); err != nil {
+			return nil, fmt.Errorf("failed to load layer image: %w", err)
+		}
+	}
+	return layers, nil
+}
+
+// layerPaths returns the files the --layers stack of config reads, in order.
+func layerPaths(config Config) []string {
+	if config.Layers == "" {
+		return nil
+	}
+	layers, err := parseLayers(config.Layers)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, layer := range layers {
+		if layer.Path != "" {
+			paths = append(paths, layer.Path)
+		}
+	}
+	return paths
+}
+
+// layerSummary lists the layer types of the --layers stack of config,
+// bottom to top, for plans.
+func layerSummary(config Config) string {
+	layers, err := parseLayers(config.Layers)
+	if err != nil {
+		return ""
+	}
+	types := make([]string, len(layers))
+	for i, layer := range layers {
+		types[i] = layer.Type
+		if layer.Type == "effect" {
+			types[i] = layer.Effect
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+// renderLayers composites layers bottom to top onto a transparent
+// size x size canvas. Source layers draw sourceImg.
+func renderLayers(sourceImg image.Image, layers []stackLayer, size int) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	for _, layer := range layers {
+		opacity := &image.Uniform{color.Alpha{uint8(layer.opacity() * 255 / 100)}}
+
+		switch layer.Type {
+		case "effect":
+			// Blend the affected stack over the original by the opacity
+			affected := surfaceEffects[layer.Effect](canvas, size)
+			draw.DrawMask(canvas, canvas.Bounds(), affected, affected.Bounds().Min, opacity, image.Point{}, draw.Src)
+		case "mask":
+			canvas = clipLayer(canvas, layer.place(sourceImg, size), layer.opacity())
+		default:
+			draw.DrawMask(canvas, canvas.Bounds(), layer.place(sourceImg, size), image.Point{}, opacity, image.Point{}, draw.Over)
+		}
+	}
+	return canvas
+}
+
+// place renders the content of the layer at its scale and centers it on a
+// transparent size x size canvas, moved by its offsets.
+func (l stackLayer) place(sourceImg image.Image, size int) *image.RGBA {
+	scaled := size * l.scale() / 100
+
+	var content image.Image
+	switch l.Type {
+	case "source":
+		content = resizeImage(sourceImg, scaled)
+	case "fill":
+		c, _ := parseHexColor(l.Color)
+		content = solidBackground(c, scaled)
+	case "gradient":
+		gradient, _ := parseBackgroundGradient(l.Gradient)
+		content = renderGradient(gradient, scaled)
+	case "mask":
+		if l.Shape != "" {
+			content = maskShapes[l.Shape](solidBackground(color.RGBA{255, 255, 255, 255}, scaled))
+		} else {
+			content = resizeImage(l.img, scaled)
+		}
+	default:
+		content = resizeImage(l.img, scaled)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-scaled)/2+size*l.OffsetX/100, (size-scaled)/2+size*l.OffsetY/100)
+	draw.Draw(canvas, image.Rect(0, 0, scaled, scaled).Add(offset), content, content.Bounds().Min, draw.Src)
+	return canvas
+}
+
+// clipLayer cuts img to the alpha of mask. At lower opacity the mask only
+// fades what it would clear.
+func clipLayer(img, mask *image.RGBA, opacity int) *image.RGBA {
+	clipped := image.NewRGBA(img.Bounds())
+	strength := float64(opacity) / 100
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			keep := 1 - strength*(1-float64(mask.RGBAAt(x, y).A)/255)
+			c := img.RGBAAt(x, y)
+			scale := func(v uint8) uint8 { return uint8(math.Round(float64(v) * keep)) }
+			clipped.SetRGBA(x, y, color.RGBA{scale(c.R), scale(c.G), scale(c.B), scale(c.A)})
+		}
+	}
+	return clipped
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestBackgroundIsImage(t *testing.T) {
+	tests := []struct {
+		background string
+		want       bool
+	}{
+		{"", false},
+		{"#FFFFFF", false},
+		{"#0000FF80", false},
+		{"bg.png", true},
+		{"art/background.png", true},
+	}
+
+	for _, tt := range tests {
+		if got := backgroundIsImage(Config{Background: tt.background}); got != tt.want {
+			t.Errorf("backgroundIsImage(%q): expected %v, got %v", tt.background, tt.want, got)
+		}
+	}
+}
+
+func TestLayeredIcon(t *testing.T) {
+	foreground := createTestImage(100, color.RGBA{255, 255, 255, 255})
+	background := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	draw.Draw(background, background.Bounds(), &image.Uniform{color.RGBA{0, 0, 255, 255}}, image.Point{}, draw.Src)
+
+	config := Config{ForegroundScale: 50, Background: "bg.png"}
+	icon := prepareIcon(foreground, config, backgroundPattern{}, background, nil, 100)
+
+	if c := color.RGBAModel.Convert(icon.At(50, 50)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("Expected the foreground in the center, got %v", c)
+	}
+	// The wide background covers the canvas, bars included
+	for _, p := range []image.Point{{2, 2}, {97, 97}, {10, 50}} {
+		if c := color.RGBAModel.Convert(icon.At(p.X, p.Y)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+			t.Errorf("Expected the background at %v, got %v", p, c)
+		}
+	}
+}
+
+func TestLayerScales(t *testing.T) {
+	foreground := createTestImage(100, color.RGBA{255, 0, 0, 255})
+
+	// 50% leaves a quarter of the canvas on each side
+	scaled := scaleForeground(foreground, 100, 50, func(img image.Image, size int) image.Image { return resizeImage(img, size) })
+	if _, _, _, a := scaled.At(20, 50).RGBA(); a != 0 {
+		t.Errorf("Expected a transparent margin around a 50%% foreground, got alpha %#x", a)
+	}
+	if _, _, _, a := scaled.At(30, 50).RGBA(); a == 0 {
+		t.Errorf("Expected the 50%% foreground to start at 25px")
+	}
+
+	background := createTestImage(100, color.RGBA{0, 255, 0, 255})
+	if _, _, _, a := scaleBackground(background, 100, 80).At(5, 5).RGBA(); a != 0 {
+		t.Errorf("Expected an 80%% background to leave the corner clear, got alpha %#x", a)
+	}
+	if _, _, _, a := scaleBackground(background, 100, 150).At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("Expected a 150%% background to cover the canvas, got alpha %#x", a)
+	}
+
+	for _, scale := range []int{-1, 201} {
+		if err := validateOptions(Config{TrimPercent: 80, ForegroundScale: scale}); err == nil {
+			t.Errorf("Expected foreground scale %d to fail", scale)
+		}
+	}
+}
+
+func TestParseLayers(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expectErr bool
+	}{
+		{"stack", `[{"type":"gradient","gradient":"#000..#FFF"},{"type":"source","scale":80,"offset-y":-5},{"type":"mask","shape":"circle"},{"type":"effect","effect":"gloss","opacity":50}]`, false},
+		{"image", `[{"type":"fill","color":"#FFFFFF"},{"type":"image","path":"logo.png","opacity":60}]`, false},
+		{"empty", `[]`, true},
+		{"not an array", `{"type":"source"}`, true},
+		{"unknown type", `[{"type":"text"}]`, true},
+		{"unknown field", `[{"type":"source","blend":"multiply"}]`, true},
+		{"image without path", `[{"type":"image"}]`, true},
+		{"bad color", `[{"type":"fill","color":"white"}]`, true},
+		{"mask with path and shape", `[{"type":"mask","path":"m.png","shape":"circle"}]`, true},
+		{"scaled effect", `[{"type":"effect","effect":"gloss","scale":50}]`, true},
+		{"scale out of range", `[{"type":"source","scale":0}]`, true},
+		{"offset out of range", `[{"type":"source","offset-x":150}]`, true},
+		{"opacity out of range", `[{"type":"source","opacity":101}]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseLayers(tt.spec)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderLayers(t *testing.T) {
+	source := createTestImage(100, color.RGBA{255, 0, 0, 255})
+	layers, err := parseLayers(`[
+		{"type":"fill","color":"#0000FF"},
+		{"type":"source","scale":50,"offset-x":25},
+		{"type":"fill","color":"#FFFFFF","scale":10,"opacity":50},
+		{"type":"mask","shape":"circle"}
+	]`)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	icon := renderLayers(source, layers, 100)
+
+	// The source is half the size, moved a quarter to the right
+	if c := color.RGBAModel.Convert(icon.At(70, 50)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the moved source right of center, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(icon.At(30, 50)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the fill left of center, got %v", c)
+	}
+	// The half-transparent white square sits over the center
+	if c := color.RGBAModel.Convert(icon.At(50, 50)).(color.RGBA); c.G < 120 || c.G > 135 || c.A != 255 {
+		t.Errorf("Expected white blended at half opacity in the center, got %v", c)
+	}
+	// The circle mask clears the corners
+	if _, _, _, a := icon.At(1, 1).RGBA(); a != 0 {
+		t.Errorf("Expected the mask to clear the corner, got alpha %#x", a)
+	}
+}
+
+func TestLayersReplaceBackgrounds(t *testing.T) {
+	stack := `[{"type":"source"}]`
+	for _, config := range []Config{
+		{Layers: stack, Background: "#FFFFFF"},
+		{Layers: stack, Fill: "blur"},
+		{Layers: stack, ForegroundScale: 50},
+		{Layers: stack, LongShadow: true},
+	} {
+		config.TrimPercent = 80
+		if err := validateOptions(config); err == nil {
+			t.Errorf("Expected --layers with %+v to fail", config)
+		}
+	}
+	if err := validateOptions(Config{TrimPercent: 80, Layers: stack}); err != nil {
+		t.Errorf("Expected --layers alone to pass, got: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// workingBuffers is how many full-size RGBA buffers of the largest output a
+// run holds at once: the resized icon, its background, mask, border and
+// shadow layers, and the padded result.
+const workingBuffers = 8
+
+// byteUnits are the suffixes --max-memory accepts, longest first so "MiB"
+// isn't read as "B".
+var byteUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseByteSize parses a --max-memory value such as "512MiB", "2G" or a
+// plain number of bytes.
+func parseByteSize(s string) (int64, error) {
+	number, factor := s, int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			number, factor = strings.TrimSuffix(s, unit.suffix), unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory size %q (expected e.g. 512MiB or 2G)", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// applyMemoryLimit makes the garbage collector keep the heap under
+// --max-memory, so a run close to the limit collects more often instead of
+// growing past it.
+func applyMemoryLimit(config Config) {
+	if config.MaxMemory == "" {
+		return
+	}
+	if limit, err := parseByteSize(config.MaxMemory); err == nil {
+		debug.SetMemoryLimit(limit)
+	}
+}
+
+// largestOutputSize returns the size of the largest icon config generates.
+func largestOutputSize(config Config) int {
+	largest := 0
+	for _, iconSize := range outputSizes(config) {
+		if iconSize.Size > largest {
+			largest = iconSize.Size
+		}
+	}
+	return largest
+}
+
+// queuedOutputs returns how many rendered outputs the output queue of a run
+// with config holds while they are written: one per --jobs, none when
+// outputs are written as soon as they are rendered.
+func queuedOutputs(config Config) int {
+	jobs := config.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs == 1 {
+		return 0
+	}
+	return jobs
+}
+
+// estimateMemory returns a rough upper bound of the bytes a run with config
+// holds at once for a width x height source: the decoded source, the working
+// buffers of the largest output, the outputs queued for writing and the
+// spinner frames kept for the GIF. Pixels take 8 bytes with --bit-depth=16.
+func estimateMemory(config Config, width, height int) int64 {
+	largest := largestOutputSize(config)
+	if config.SpinnerFrames > 0 && config.SpinnerSize > largest {
+		largest = config.SpinnerSize
+	}
+	pixelBytes := int64(4)
+	if config.BitDepth == 16 {
+		pixelBytes = 8
+	}
+
+	total := int64(width) * int64(height) * pixelBytes
+	total += int64(largest) * int64(largest) * pixelBytes * int64(workingBuffers+queuedOutputs(config))
+	if config.SpinnerFrames > 0 && config.SpinnerGIF {
+		total += int64(config.SpinnerFrames) * int64(config.SpinnerSize) * int64(config.SpinnerSize) * pixelBytes
+	}
+	return total
+}
+
+// flavorJobsWithin caps jobs, the flavors generated at once, so that the
+// runs of the most demanding of configs fit in limit bytes together. Each
+// flavor is still checked against the whole limit on its own, so at least
+// one runs at a time.
+func flavorJobsWithin(configs []Config, jobs int, limit int64) int {
+	needs := make([]int64, 0, len(configs))
+	for _, config := range configs {
+		width, height, err := imageDimensions(config.InputPath)
+		if err != nil {
+			// The flavor reports the error when it loads its source
+			continue
+		}
+		needs = append(needs, estimateMemory(config, width, height))
+	}
+	sort.Slice(needs, func(i, j int) bool { return needs[i] > needs[j] })
+
+	var total int64
+	for n, need := range needs {
+		if n == jobs {
+			break
+		}
+		if total += need; total > limit {
+			if n == 0 {
+				return 1
+			}
+			return n
+		}
+	}
+	return jobs
+}
+
+// checkSourceLimits reads the dimensions of the source image from its header
+// and refuses to decode it if it exceeds --max-pixels, or if the run would
+// need more than --max-memory. A source over --max-memory that is a
+// non-interlaced PNG is let through if decoding it at a fraction of its size
+// brings the run under the limit: checkSourceLimits returns that reduction
+// factor, or 1 to decode the source as is.
+func checkSourceLimits(config Config) (int, error) {
+	if config.MaxPixels == 0 && config.MaxMemory == "" {
+		return 1, nil
+	}
+
+	width, height, err := imageDimensions(config.InputPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source image dimensions: %w", err)
+	}
+
+	if pixels := int64(width) * int64(height); config.MaxPixels > 0 && pixels > int64(config.MaxPixels) {
+		return 0, fmt.Errorf("source image is %dx%d (%d pixels), over --max-pixels=%d", width, height, pixels, config.MaxPixels)
+	}
+
+	if config.MaxMemory != "" {
+		limit, err := parseByteSize(config.MaxMemory)
+		if err != nil {
+			return 0, err
+		}
+		if need := estimateMemory(config, width, height); need > limit {
+			factor := sourceReduction(config, width, height, limit)
+			if factor > 1 && pngStreamable(config.InputPath) {
+				return factor, nil
+			}
+			err := fmt.Errorf("generating from a %dx%d source needs about %d MiB, over --max-memory=%s", width, height, need>>20, config.MaxMemory)
+			if factor > 1 {
+				err = fmt.Errorf("%w; only non-interlaced PNG sources can be downscaled while decoding", err)
+			}
+			return 0, err
+		}
+	}
+	return 1, nil
+}
+
+// sourceReduction returns the smallest factor decoding a width x height
+// source at 1/factor of its size needs for the run to fit in limit bytes,
+// counting the full-resolution rows decodePNGReduced keeps, or 0 if the
+// source would have to shrink below the largest output (after --crop) to
+// fit.
+func sourceReduction(config Config, width, height int, limit int64) int {
+	largest := largestOutputSize(config)
+	for factor := 2; ; factor++ {
+		w, h := (width+factor-1)/factor, (height+factor-1)/factor
+		side := h
+		if w < h {
+			side = w
+		}
+		if config.CropEnabled {
+			side = side * config.TrimPercent / 100
+		}
+		if side < largest {
+			return 0
+		}
+
+		rows := int64(width)*8*2 + int64(w)*32
+		if estimateMemory(config, w, h)+rows <= limit {
+			return factor
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1 << 20, false},
+		{"512MiB", 512 << 20, false},
+		{"512M", 512 << 20, false},
+		{"2GB", 2000000000, false},
+		{"1.5G", 3 << 29, false},
+		{"64KB", 64000, false},
+		{"0", 0, true},
+		{"-1M", 0, true},
+		{"lots", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseByteSize(%q): expected error %v, got %v", tt.input, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseByteSize(%q): expected %d, got %d", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestCheckSourceLimits(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(100, color.RGBA{255, 0, 0, 255}))
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"no limits", Config{}, ""},
+		{"within pixels", Config{MaxPixels: 10000}, ""},
+		{"over pixels", Config{MaxPixels: 9999}, "--max-pixels"},
+		{"within memory", Config{MaxMemory: "1G"}, ""},
+		{"over memory", Config{MaxMemory: "1MiB"}, "--max-memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.InputPath = inputPath
+			_, err := checkSourceLimits(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error mentioning %s, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEstimateMemory(t *testing.T) {
+	base := estimateMemory(Config{Jobs: 1}, 100, 100)
+	if want := int64(100*100*4 + 1024*1024*4*workingBuffers); base != want {
+		t.Errorf("Expected %d bytes for the macOS set, got %d", want, base)
+	}
+
+	spinner := estimateMemory(Config{Jobs: 1, SpinnerFrames: 10, SpinnerSize: 64, SpinnerGIF: true}, 100, 100)
+	if want := base + 10*64*64*4; spinner != want {
+		t.Errorf("Expected the GIF frames to add to the estimate (%d), got %d", want, spinner)
+	}
+
+	queued := estimateMemory(Config{Jobs: 4}, 100, 100)
+	if want := base + 4*1024*1024*4; queued != want {
+		t.Errorf("Expected the outputs queued by --jobs to add to the estimate (%d), got %d", want, queued)
+	}
+
+	deep := estimateMemory(Config{Jobs: 1, BitDepth: 16}, 100, 100)
+	if want := 2 * base; deep != want {
+		t.Errorf("Expected 16-bit pixels to double the estimate (%d), got %d", want, deep)
+	}
+}
+
+func TestFlavorJobsWithin(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(100, color.RGBA{255, 0, 0, 255}))
+	configs := make([]Config, 4)
+	for i := range configs {
+		configs[i] = Config{InputPath: inputPath, Jobs: 1}
+	}
+	need := estimateMemory(configs[0], 100, 100)
+
+	tests := []struct {
+		jobs  int
+		limit int64
+		want  int
+	}{
+		{4, 4 * need, 4},
+		{4, 3*need + need/2, 3},
+		{2, 4 * need, 2},
+		{4, need / 2, 1},
+	}
+	for _, tt := range tests {
+		if got := flavorJobsWithin(configs, tt.jobs, tt.limit); got != tt.want {
+			t.Errorf("flavorJobsWithin(%d jobs, %d bytes) = %d, expected %d", tt.jobs, tt.limit, got, tt.want)
+		}
+	}
+}
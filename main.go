@@ -4,29 +4,162 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Config holds the generation options. Fields tagged json:"-" don't affect
+// the generated pixels and are left out of the manifest's options hash.
 type Config struct {
-	InputPath        string
-	OutputDir        string
-	Clean            bool
-	CropEnabled      bool
-	TrimPercent      int
-	RadiusPercent    int
-	PaddingPercent   int
-	PaddingIOSMode   bool
+	InputPath       string `json:"-"`
+	OutputDir       string `json:"-"`
+	Clean           bool   `json:"-"`
+	CleanAll        bool   `json:"-"`
+	Preset          string
+	AssetVersion    string
+	Watermark       string
+	Provenance      bool
+	Reproducible    bool
+	C2PACert        string `json:"-"`
+	C2PAKey         string `json:"-"`
+	ColorProfile    string
+	ColorSpace      string
+	Flatten         string
+	FlattenColor    string
+	DPI             int
+	Meta            string
+	BitDepth        int
+	VersionedDirs   bool `json:"-"`
+	CropEnabled     bool
+	TrimPercent     int
+	TrimAlpha       bool
+	SmartCrop       bool
+	CropAnchor      string
+	FitMode         string
+	NonSquare       string
+	NoUpscale       string
+	PixelArt        string
+	Filter          string
+	LinearLight     bool
+	Sharpen         int
+	Cache           bool
+	HueShift        int
+	Tint            string
+	MapColor        string
+	MapTolerance    int
+	RadiusPercent   int
+	RadiusPx        int
+	RadiusSizes     string
+	CornerSmoothing float64
+	Mask            string
+	MaskImage       string `json:"-"`
+	MaskChannel     string
+	Effects         string
+	Badge           string
+	BadgeColor      string
+	BadgePosition   string
+	Text            string
+	TextPosition    string
+	TextColor       string
+	TextFont        string `json:"-"`
+	Overlay         string `json:"-"`
+	OverlayScale    string
+	OverlayAnchor   string
+	OverlayOpacity  float64
+	BorderWidth     string
+	BorderColor     string
+	PaddingPercent  int
+	Padding         string
+	PaddingIOSMode  bool
+	Recursive       bool   `json:"-"`
+	SourcePattern   string `json:"-"`
+	Flavors         string `json:"-"`
+	FlavorJobs      int    `json:"-"`
+	Jobs            int    `json:"-"`
+	Incremental     bool   `json:"-"`
+	First           string `json:"-"`
+	Force           bool   `json:"-"`
+	Stateless       bool   `json:"-"`
+	ManifestPath    string `json:"-"`
+	DryRun          bool   `json:"-"`
+	Check           bool   `json:"-"`
+	FailOnSkipped   bool   `json:"-"`
+	Suppress        string `json:"-"`
+	MaxMemory       string `json:"-"`
+	MaxPixels       int    `json:"-"`
+	CPUProfile      string `json:"-"`
+	MemProfile      string `json:"-"`
+	Trace           string `json:"-"`
+	// Budget is left out of the hash; the effects it drops count instead
+	Budget time.Duration `json:"-"`
+	// Log receives the progress messages and warnings of a run, stdout and
+	// stderr when nil
+	Log io.Writer `json:"-"`
+
+	LongShadow        bool
+	LongShadowLength  int
+	LongShadowAngle   int
+	LongShadowOpacity int
+
+	Shadow        bool
+	ShadowBlur    int
+	ShadowOffsetX int
+	ShadowOffsetY int
+	ShadowOpacity int
+	ShadowColor   string
+
+	Fill               string
+	Foreground         string `json:"-"`
+	ForegroundScale    int
+	BackgroundScale    int
+	Background         string
+	BackgroundPattern  string
+	BackgroundGradient string
+	Dither             string
+	Layers             string
+	RemoveBackground   string
+	AutoContrast       string
+
+	SpinnerFrames int
+	SpinnerSize   int
+	SpinnerGIF    bool
+
+	States string
+
+	Series      string
+	SeriesColor string
+
+	BadgeCount int
+
+	Variant string
+
+	DarkSource string `json:"-"`
+
+	Monochrome string
+	SafeZone   int
+
+	HashNames    bool   `json:"-"`
+	Precompress  string `json:"-"`
+	ContentsJSON bool   `json:"-"`
+	Appearances  bool   `json:"-"`
+	PreviewHTML  bool   `json:"-"`
+	ReportPDF    bool   `json:"-"`
+	ContactSheet bool   `json:"-"`
+	DesignSVG    bool   `json:"-"`
+	DebugOverlay bool   `json:"-"`
+
+	// Progress tracks the run for the background jobs of icongen rpc
+	Progress *runProgress `json:"-"`
 }
 
 type IconSize struct {
-	Name string
-	Size int
+	Name string `json:"name"`
+	Size int    `json:"size"`
 }
 
 var iconSizes = []IconSize{
@@ -43,39 +176,23 @@ var iconSizes = []IconSize{
 	{"icon_1024x1024.png", 1024},
 }
 
-func main() {
-	config := parseFlags()
-
-	if err := validateConfig(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := generateIcons(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating icons: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Println("✅ Done. Generated icon_* PNGs alongside source.")
-}
-
 func parseFlags() Config {
 	var config Config
-
-	flag.StringVar(&config.InputPath, "input", "images/TranslateCat.png", "Input image path")
-	flag.StringVar(&config.OutputDir, "output", "", "Output directory (defaults to input image directory)")
-	flag.BoolVar(&config.Clean, "clean", false, "Remove existing icon_*.png files before generating")
-	flag.BoolVar(&config.CropEnabled, "crop", true, "Enable center cropping")
-	flag.IntVar(&config.TrimPercent, "trim-percent", 80, "Percentage of image to keep when cropping (1-100)")
-	flag.IntVar(&config.RadiusPercent, "radius-percent", 20, "Corner radius as percentage of size for rounded variants")
-	flag.IntVar(&config.PaddingPercent, "padding-percent", 0, "Padding as percentage of image size (0-50)")
-	flag.BoolVar(&config.PaddingIOSMode, "padding-ios-mode", false, "iOS-compliant padding: exclude base icon_1024x1024.png from padding")
-
-	// Handle --no-crop flag
-	noCrop := flag.Bool("no-crop", false, "Disable center cropping")
+	defineFlags(flag.CommandLine, &config)
+	configPath := flag.String("config", "", "Read options from a .json or .yaml configuration file; command-line flags override it")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [input-image] [output-dir]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [input-image] [output-dir]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare --config-a a.yaml --config-b b.yaml [input-image] [output-dir]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff [--output dir] [--fail-under 0.99] old-dir new-dir\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s xcode-phase [options] source.png AppIcon.appiconset\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s gradle-task [options] source.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s init --build=make|just [options] source.png output-dir\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s hook install [--verify] [options] source.png output-dir\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s watermark icon.png...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s settings-from icon_1024x1024.png [input-image [output-dir]]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s bench [--runs 5] [--json] [options] source.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s formats | rpc\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Generate app icon PNGs from a single source image.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
@@ -85,12 +202,34 @@ func parseFlags() Config {
 		fmt.Fprintf(os.Stderr, "  %s --no-crop logo.png\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --padding-percent=15 --padding-ios-mode source.png  # iOS: base 1024x1024 stays full size\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --padding-percent=10 source.png  # All sizes get padding\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --recursive --pattern='AppIcon*.png' apps/ build/icons/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --background-pattern=checker:size=12,fg=#DDDDDD logo.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --spinner-frames=12 --spinner-size=32 --spinner-gif logo.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --states=ok:#34c759,warn:#ff9f0a,error:#ff3b30 tray.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --series=1-9 workspace.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --long-shadow --long-shadow-opacity=25 logo.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --dry-run --series=1-3 logo.png\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s diff --output review/ icons-v1/ icons-v2/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s compare --config-a current.yaml --config-b proposed.yaml AppIcon.png review/\n", os.Args[0])
 	}
 
 	flag.Parse()
 
+	// Apply the configuration file, then the command line again so it wins
+	if *configPath != "" {
+		if err := loadConfigFile(flag.CommandLine, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		flag.Parse()
+	}
+
 	// Handle positional arguments
 	args := flag.Args()
+	if config.Foreground != "" {
+		// The foreground is the input, so a lone positional argument is the output
+		args = append([]string{config.Foreground}, args...)
+	}
 	if len(args) > 0 {
 		config.InputPath = args[0]
 	}
@@ -98,312 +237,1243 @@ func parseFlags() Config {
 		config.OutputDir = args[1]
 	}
 
-	// Handle special flags
-	if *noCrop {
-		config.CropEnabled = false
-	}
+	setDefaultOutputDir(&config)
+	return config
+}
 
-	// Set default output directory
+// setDefaultOutputDir writes icons next to the source image, or into the
+// input directory itself in recursive mode, unless an output was given.
+func setDefaultOutputDir(config *Config) {
 	if config.OutputDir == "" {
-		config.OutputDir = filepath.Dir(config.InputPath)
+		if config.Recursive {
+			config.OutputDir = config.InputPath
+		} else {
+			config.OutputDir = filepath.Dir(config.InputPath)
+		}
 	}
+}
 
-	return config
+// defineFlags registers every generation option on fs, bound to config. The
+// same definitions back the command line and configuration files.
+func defineFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.InputPath, "input", "images/TranslateCat.png", "Input image path")
+	fs.StringVar(&config.OutputDir, "output", "", "Output directory (defaults to input image directory)")
+	fs.BoolVar(&config.Clean, "clean", false, "Remove the files the previous run wrote before generating")
+	fs.BoolVar(&config.CleanAll, "clean-all", false, "Remove every icon_*.png in the output directory before generating, including files icongen didn't create")
+	fs.BoolVar(&config.CropEnabled, "crop", true, "Enable center cropping")
+	fs.IntVar(&config.TrimPercent, "trim-percent", 80, "Percentage of image to keep when cropping (1-100)")
+	fs.StringVar(&config.CropAnchor, "crop-anchor", "center", "Where the crop sits in the source: center, top, bottom, left, right, a corner such as top-left, or x%,y% such as 50%,30%")
+	fs.StringVar(&config.FitMode, "fit", "contain", "How a non-square source fills the square icons: contain (scale to fit, leaving bars), cover (crop to a square at --crop-anchor) or stretch")
+	fs.StringVar(&config.NonSquare, "non-square", "", "Policy for sources that aren't square after cropping: pad, crop or stretch as for --fit=contain, cover or stretch, or error to fail instead")
+	fs.Var(upscaleFlag{&config.NoUpscale}, "no-upscale", "Skip the outputs larger than the source instead of upscaling it, or fail with --no-upscale=error")
+	fs.Var(pixelArtFlag{&config.PixelArt}, "pixel-art", "Resize with nearest-neighbor sampling so pixel art keeps crisp edges, or double it with Scale2x first with --pixel-art=scale2x")
+	fs.StringVar(&config.Filter, "filter", "auto", "Resampling filter the artwork is resized with: nearest, bilinear, box, catmullrom, lanczos3, or auto for catmullrom when downscaling and bilinear when upscaling")
+	fs.IntVar(&config.Sharpen, "sharpen", 0, fmt.Sprintf("Unsharp mask strength for downscaled icons up to %dpx, as a percentage at 16px that weakens towards larger sizes (0-200, 0 disables)", maxSharpenSize))
+	fs.BoolVar(&config.LinearLight, "linear-light", true, "Resize in linear light rather than on sRGB values, so high-contrast edges don't get dark halos")
+	fs.BoolVar(&config.Cache, "cache", false, "Keep the prepared source, downscaled to 1024px, in the user cache directory, so later runs on the same source with other presets skip decoding and cropping it")
+	fs.BoolVar(&config.SmartCrop, "smart-crop", false, "Center the crop on the most detailed part of the source instead of its middle, for artwork that isn't centered")
+	fs.BoolVar(&config.TrimAlpha, "trim-alpha", false, "Trim the source to the bounding box of its non-transparent pixels before cropping, so uneven transparent margins don't shift the artwork")
+	fs.StringVar(&config.RemoveBackground, "remove-background", "", fmt.Sprintf("Key out a solid background color around the artwork to transparency before generating, e.g. #FFFFFF or #FFFFFF:15 for a tolerance in percent (default %d)", removeBackgroundTolerance))
+	fs.StringVar(&config.AutoContrast, "auto-contrast", "", "Stretch the source's histogram to the full range before generating, to rescue washed-out exports: luminance, or channels to stretch each color channel on its own")
+	fs.Var(listFlag{&config.MapColor}, "map-color", "Replace a color of the source before generating, e.g. '#112233=>#AA5500'; repeatable")
+	fs.IntVar(&config.MapTolerance, "map-color-tolerance", 10, "How far, as a percentage of the RGB range, a pixel may be from a --map-color color and still be replaced (0-100)")
+	fs.IntVar(&config.HueShift, "hue-shift", 0, "Rotate the hue of the source by this many degrees before generating, e.g. 120 for a per-brand build")
+	fs.StringVar(&config.Tint, "tint", "", "Recolor the source in this #RRGGBB color's hue and saturation, keeping its lightness, before generating")
+	fs.IntVar(&config.RadiusPercent, "radius-percent", 20, "Corner radius as percentage of size for rounded variants")
+	fs.IntVar(&config.RadiusPx, "radius-px", 0, "Corner radius in pixels for every size, instead of --radius-percent")
+	fs.StringVar(&config.Preset, "preset", "macos", "Output sets to generate, comma-separated: macos, web, android, android-notification, watch-complication and macos-template (see icongen rpc presets)")
+	fs.StringVar(&config.RadiusSizes, "radius-sizes", "", "Per-size corner radius overrides in pixels or percent, e.g. 16:3px,32:5px,1024:22%")
+	fs.Float64Var(&config.CornerSmoothing, "corner-smoothing", 0, "Figma-style corner smoothing of rounded variants, from 0 (circular) to 1 (fully smoothed); iOS uses 0.6")
+	fs.StringVar(&config.Mask, "mask", "", "Also generate masked variants (icon_*_circle.png) for each comma-separated shape: circle")
+	fs.StringVar(&config.Effects, "effects", "", "Comma-separated surface effects applied in order on every icon: gloss, inner-shadow")
+	fs.StringVar(&config.Badge, "badge", "", "Label a corner ribbon on every icon with this text, e.g. BETA, DEV or NIGHTLY, to tell build flavors apart")
+	fs.StringVar(&config.BadgeColor, "badge-color", "#FF3B30", "Color of the --badge ribbon (#RRGGBB or #RRGGBBAA)")
+	fs.StringVar(&config.BadgePosition, "badge-position", "top-right", "Corner of the --badge ribbon: top-left, top-right, bottom-left or bottom-right")
+	fs.StringVar(&config.Text, "text", "", "Stamp this text, e.g. a version string, onto every icon large enough to show it legibly")
+	fs.StringVar(&config.TextPosition, "text-position", "bottom", "Where --text goes: top, center or bottom")
+	fs.StringVar(&config.TextColor, "text-color", "#FFFFFF", "Color of the --text (#RRGGBB or #RRGGBBAA)")
+	fs.StringVar(&config.TextFont, "text-font", "", "TrueType font (.ttf) to draw --text in, instead of the built-in pixel font")
+	fs.StringVar(&config.Overlay, "overlay", "", "Composite this image, e.g. a partner logo or environment marker, onto every icon")
+	fs.StringVar(&config.OverlayScale, "overlay-scale", "25%", "Size of the --overlay as a percentage of the icon (1%-100%)")
+	fs.StringVar(&config.OverlayAnchor, "overlay-anchor", "bottom-right", "Where the --overlay goes: top-left, top, top-right, left, center, right, bottom-left, bottom or bottom-right")
+	fs.Float64Var(&config.OverlayOpacity, "overlay-opacity", 1, "Opacity of the --overlay, above 0 and at most 1")
+	fs.StringVar(&config.BorderWidth, "border-width", "", "Stroke the outline of every icon and variant this wide: pixels at every size (1.5px) or a percentage of the size (2%)")
+	fs.StringVar(&config.BorderColor, "border-color", "#000000", "Color of the --border-width stroke (#RRGGBB or #RRGGBBAA)")
+	fs.StringVar(&config.MaskImage, "mask-image", "", "Also generate icon_*_masked.png variants cut to the shape of this image, scaled to every size")
+	fs.StringVar(&config.MaskChannel, "mask-channel", "alpha", "Channel of --mask-image that masks the icon: alpha or luminance")
+	fs.IntVar(&config.PaddingPercent, "padding-percent", 0, "Padding as percentage of image size (0-50)")
+	fs.StringVar(&config.Padding, "padding", "", "Padding per size instead of one --padding-percent, interpolated between the sizes given, e.g. 1024:10%,256:8%,64:5%,16:0%")
+	fs.BoolVar(&config.PaddingIOSMode, "padding-ios-mode", false, "iOS-compliant padding: exclude base icon_1024x1024.png from padding")
+	fs.BoolVar(&config.Recursive, "recursive", false, "Treat input as a directory and generate icons for every source image found in it")
+	fs.StringVar(&config.Flavors, "flavors", "", "Generate a white-label build per flavor, a JSON array of objects with a name, optional input and output, and options overriding the shared ones")
+	fs.IntVar(&config.Jobs, "jobs", 0, "How many outputs to encode and write at once (0 = one per CPU)")
+	fs.IntVar(&config.FlavorJobs, "flavor-jobs", 0, "How many --flavors to generate at once (0 = one per CPU)")
+	fs.StringVar(&config.SourcePattern, "pattern", "", "Filename glob selecting source images in recursive mode (default: any PNG/JPEG/GIF)")
+	fs.BoolVar(&config.LongShadow, "long-shadow", false, fmt.Sprintf("Cast a long shadow, %d%% of the size long unless --long-shadow-length is set", defaultLongShadowLength))
+	fs.IntVar(&config.LongShadowLength, "long-shadow-length", 0, "Long shadow length as percentage of size (0 disables, 0-100)")
+	fs.IntVar(&config.LongShadowAngle, "long-shadow-angle", 45, "Long shadow direction in degrees clockwise from the right (45 = bottom-right)")
+	fs.IntVar(&config.LongShadowOpacity, "long-shadow-opacity", 30, "Long shadow opacity percentage (0-100)")
+	fs.BoolVar(&config.Shadow, "shadow", false, "Drop a blurred shadow behind every icon, shrinking the artwork so it fits")
+	fs.IntVar(&config.ShadowBlur, "shadow-blur", 4, "Drop shadow blur radius as percentage of size (0-25)")
+	fs.IntVar(&config.ShadowOffsetX, "shadow-offset-x", 0, "Drop shadow horizontal offset as percentage of size (-25 to 25)")
+	fs.IntVar(&config.ShadowOffsetY, "shadow-offset-y", 2, "Drop shadow vertical offset as percentage of size (-25 to 25)")
+	fs.IntVar(&config.ShadowOpacity, "shadow-opacity", 40, "Drop shadow opacity percentage (0-100)")
+	fs.StringVar(&config.ShadowColor, "shadow-color", "#000000", "Drop shadow color (#RRGGBB or #RRGGBBAA)")
+
+	fs.StringVar(&config.Fill, "fill", "none", "Fill the bars left by a non-square source: none (transparent) or blur (a blurred, scaled-up copy of the source)")
+	fs.StringVar(&config.Foreground, "foreground", "", "Foreground layer image, instead of the input image; the first positional argument is then the output directory")
+	fs.IntVar(&config.ForegroundScale, "foreground-scale", 100, "Size of the foreground as percentage of the icon (1-200)")
+	fs.StringVar(&config.Background, "background", "", "Background behind the artwork: a solid #RRGGBB[AA] color, e.g. to make the App Store icon opaque, or an image")
+	fs.IntVar(&config.BackgroundScale, "background-scale", 100, "Size of the --background image as percentage of the icon, which it covers at 100 (1-200)")
+	fs.StringVar(&config.BackgroundGradient, "background-gradient", "", "Gradient behind the artwork: #RRGGBB..#RRGGBB[..#RRGGBB][@45deg|@radial]")
+	fs.StringVar(&config.Dither, "dither", "", "Dithering that hides banding when colors are quantized, in --background-gradient and spinner.gif: none, ordered or floyd-steinberg (default ordered for gradients, floyd-steinberg for GIFs)")
+	fs.StringVar(&config.BackgroundPattern, "background-pattern", "", "Tiled background behind the artwork: dots|stripes|checker[:size=N,fg=#RRGGBB,bg=#RRGGBB]")
+	fs.StringVar(&config.Layers, "layers", "", "Ordered layer stack composited bottom to top instead of the foreground and background, as a JSON array; usually set in a --config file")
+	fs.IntVar(&config.SpinnerFrames, "spinner-frames", 0, "Also emit an N-frame rotation series (spinner_NN.png) for loading spinners (0 disables)")
+	fs.IntVar(&config.SpinnerSize, "spinner-size", 64, "Pixel size of the rotation series frames")
+	fs.BoolVar(&config.SpinnerGIF, "spinner-gif", false, "Assemble the rotation series into a looping spinner.gif")
+	fs.StringVar(&config.States, "states", "", "Tinted tray/toolbar variants per named state, e.g. ok:#34c759,warn:#ff9f0a,error:#ff3b30")
+	fs.StringVar(&config.Series, "series", "", "Stamp a number range (1-9) or comma-separated labels (A,B,C) onto copies of every icon")
+	fs.StringVar(&config.SeriesColor, "series-color", "#FFFFFF", "Color of the --series labels")
+	fs.IntVar(&config.BadgeCount, "badge-count", 0, fmt.Sprintf("Also write copies of every icon (icon_*_badgeN.png) with a red notification badge showing this number, 1-%d (0 disables)", badgeCountMax))
+	fs.StringVar(&config.Variant, "variant", "", "Also write recolored copies of every icon (icon_*_grayscale.png) per comma-separated variant: grayscale, invert")
+	fs.StringVar(&config.Monochrome, "monochrome", "", "Also write an adaptive launcher icon with a themed icon layer for Android 13, its silhouette taken from the artwork's alpha or a threshold of its dark pixels (android preset)")
+	fs.IntVar(&config.SafeZone, "safe-zone", defaultSafeZone, "Diameter of the circle the adaptive icon's artwork is fitted into, as a percentage of the 108dp layer (10-100); the default is the 66dp every launcher mask keeps")
+	fs.StringVar(&config.DarkSource, "dark-source", "", "Also write a _dark copy of every icon from this dark-mode artwork, or \"auto\" to invert the source's lightness")
+	fs.StringVar(&config.Precompress, "precompress", "", "Comma-separated precompressed copies of the web preset's site.webmanifest: gz")
+	fs.BoolVar(&config.HashNames, "hash-names", false, "Add a short hash of the source and options to web preset file names for immutable caching")
+	fs.BoolVar(&config.ContentsJSON, "contents-json", false, "Write an Xcode Contents.json so the output directory works as an AppIcon.appiconset")
+	fs.BoolVar(&config.Appearances, "appearances", false, "Also write the iOS 18 dark and tinted variants of the 1024px icon (icon_1024x1024_dark.png, icon_1024x1024_tinted.png)")
+	fs.BoolVar(&config.PreviewHTML, "preview-html", false, "Write an index.html gallery of every generated icon on light, dark and patterned backgrounds")
+	fs.BoolVar(&config.ContactSheet, "contact-sheet", false, "Write a contact_sheet.png montage of every generated icon with labels")
+	fs.BoolVar(&config.DesignSVG, "design-svg", false, "Write a layered icon_grid.svg of the composed icon with mask, padding and safe zone guides for design tools")
+	fs.BoolVar(&config.DebugOverlay, "debug-overlay", false, "Also write a _debug copy of every icon with its platform's keylines and safe zones drawn on top")
+	fs.BoolVar(&config.ReportPDF, "report-pdf", false, "Write a printable contact_sheet.pdf of every generated icon with its settings")
+	fs.StringVar(&config.Watermark, "watermark", "", fmt.Sprintf("Organization identifier to hide, with the generation hash, in outputs of %dpx and up (read it back with icongen watermark)", watermarkMinSize))
+	fs.BoolVar(&config.Provenance, "provenance", false, "Record the tool, source image hash and creation time in every PNG's metadata")
+	fs.IntVar(&config.BitDepth, "bit-depth", 8, "Bits per channel of the icons: 8, or 16 to keep the precision of 16-bit gradient artwork")
+	fs.StringVar(&config.ColorProfile, "color-profile", "srgb", "Color space information to write into every icon: srgb to mark it sRGB, source to copy the ICC profile of a PNG source, or none")
+	fs.StringVar(&config.ColorSpace, "color-space", "srgb", "Color space of the 1024px marketing icon: srgb, or p3 to convert it to Display P3 and tag it with the Display P3 profile; the other icons stay sRGB")
+	fs.StringVar(&config.Flatten, "flatten-marketing", "auto", "Flatten the 1024px marketing icon onto --flatten-color, as App Store Connect rejects icons with alpha: on, off, or auto for on with --padding-ios-mode or --contents-json")
+	fs.StringVar(&config.FlattenColor, "flatten-color", "#FFFFFF", "Opaque color the marketing icon is flattened onto by --flatten-marketing")
+	fs.IntVar(&config.DPI, "dpi", 0, "Pixel density to record in every icon's pHYs chunk, e.g. 72 or 144; 0 keeps the density of a PNG source that has one")
+	fs.Var(listFlag{&config.Meta}, "meta", "Record key=value in every PNG's metadata as a text chunk, e.g. 'Copyright=Example Inc.'; repeatable")
+	fs.BoolVar(&config.Reproducible, "reproducible", false, "Leave the creation time out of the --provenance metadata unless SOURCE_DATE_EPOCH is set, so identical inputs give byte-identical outputs")
+	fs.StringVar(&config.C2PACert, "c2pa-cert", "", "PEM certificate chain, signer first, to sign C2PA content credentials into every PNG with --c2pa-key")
+	fs.StringVar(&config.C2PAKey, "c2pa-key", "", "PEM private key of the --c2pa-cert certificate: ECDSA, Ed25519 or RSA")
+	fs.StringVar(&config.AssetVersion, "asset-version", "", "Version to record in every PNG's metadata and the manifest, e.g. 1.4.0")
+	fs.BoolVar(&config.VersionedDirs, "versioned-dirs", false, "Write into a v<asset-version> directory inside the output directory and point its latest entry there")
+	fs.BoolVar(&config.Check, "check", false, "Don't generate; exit with an error unless the icons are up to date with the source and options")
+	fs.StringVar(&config.Suppress, "suppress", "", "Comma-separated warning codes to silence, each optionally for matching outputs only, e.g. W002,W001:icon_1024*")
+	fs.BoolVar(&config.FailOnSkipped, "fail-on-skipped", false, fmt.Sprintf("Exit with code %d after generating everything else if outputs this build can't produce were skipped", exitSkipped))
+	fs.BoolVar(&config.Stateless, "stateless", false, "Don't read or write the output manifest, for build tools such as Gradle that track outputs themselves")
+	fs.StringVar(&config.MaxMemory, "max-memory", "", "Keep a run under this much memory, e.g. 512MiB, downscaling large PNG sources while decoding them or refusing them")
+	fs.DurationVar(&config.Budget, "budget", 0, "Skip optional effects (--shadow, --effects, --long-shadow, --fill=blur) as needed to finish within this time, e.g. 2s, for preview loops (0 = full quality)")
+	fs.IntVar(&config.MaxPixels, "max-pixels", 0, "Refuse source images with more pixels than this before decoding them (0 = no limit)")
+	fs.StringVar(&config.CPUProfile, "cpuprofile", "", "Write a pprof CPU profile of the run to this file")
+	fs.StringVar(&config.MemProfile, "memprofile", "", "Write a pprof heap profile to this file when the run is done")
+	fs.StringVar(&config.Trace, "trace", "", "Write a runtime execution trace of the run to this file, for go tool trace")
+	fs.BoolVar(&config.Force, "force", false, "Overwrite existing files in the output directory that icongen didn't create")
+	fs.StringVar(&config.ManifestPath, "manifest", "", "Write a JSON manifest of every output (path, dimensions, bytes, SHA-256) to this path")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "List every output that would be written, with its size and settings, without generating anything")
+	fs.BoolVar(&config.Incremental, "incremental", true, "Skip regeneration when source and options are unchanged since the last run")
+	fs.StringVar(&config.First, "first", "", "Comma-separated output sizes in pixels to generate before all others, e.g. 16,32")
+
+	// --no-crop, --no-linear-light and --no-incremental clear the options above
+	fs.Var(negatedBool{&config.CropEnabled}, "no-crop", "Disable center cropping")
+	fs.Var(negatedBool{&config.LinearLight}, "no-linear-light", "Resize on the sRGB-encoded values instead of in linear light")
+	fs.Var(negatedBool{&config.Incremental}, "no-incremental", "Always regenerate every icon, ignoring the manifest")
+}
+
+// negatedBool is a boolean flag that clears the field it points to, for
+// --no-* spellings of options that default to true.
+type negatedBool struct {
+	p *bool
+}
+
+func (b negatedBool) String() string {
+	if b.p == nil {
+		return "false"
+	}
+	return strconv.FormatBool(!*b.p)
+}
+
+func (b negatedBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.p = !v
+	return nil
+}
+
+func (b negatedBool) IsBoolFlag() bool { return true }
+
+// listFlag is a repeatable flag collecting its values, and the
+// comma-separated values of configuration files, into a comma-separated
+// field. Values already present aren't added again, so parsing the command
+// line twice around a configuration file doesn't repeat them.
+type listFlag struct {
+	p *string
+}
+
+func (l listFlag) String() string {
+	if l.p == nil {
+		return ""
+	}
+	return *l.p
+}
+
+func (l listFlag) Set(s string) error {
+	for _, value := range strings.Split(s, ",") {
+		present := false
+		for _, existing := range strings.Split(*l.p, ",") {
+			present = present || existing == value
+		}
+		if present {
+			continue
+		}
+		if *l.p != "" {
+			*l.p += ","
+		}
+		*l.p += value
+	}
+	return nil
 }
 
 func validateConfig(config Config) error {
+	// Each flavor is checked as a configuration of its own, with its input
+	if config.Flavors != "" {
+		return validateFlavors(config)
+	}
+
 	if _, err := os.Stat(config.InputPath); os.IsNotExist(err) {
 		return fmt.Errorf("input image not found: %s", config.InputPath)
 	}
 
+	if config.Recursive && !isDir(config.InputPath) {
+		return fmt.Errorf("recursive mode requires an input directory: %s", config.InputPath)
+	}
+
+	if _, err := filepath.Match(config.SourcePattern, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q: %w", config.SourcePattern, err)
+	}
+
+	if config.MaskImage != "" {
+		if err := validateMaskImage(config.MaskImage, config.MaxPixels); err != nil {
+			return err
+		}
+	}
+
+	if backgroundIsImage(config) {
+		if _, err := os.Stat(config.Background); os.IsNotExist(err) {
+			return fmt.Errorf("background image not found: %s", config.Background)
+		}
+	}
+
+	for _, path := range layerPaths(config) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("layer image not found: %s", path)
+		}
+	}
+
+	if config.Text != "" && config.TextFont != "" {
+		if err := validateTextFont(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Overlay != "" {
+		if _, err := overlayImage(config.Overlay); err != nil {
+			return err
+		}
+	}
+
+	if config.DarkSource != "" && config.DarkSource != darkSourceAuto {
+		if _, err := os.Stat(config.DarkSource); os.IsNotExist(err) {
+			return fmt.Errorf("dark source image not found: %s", config.DarkSource)
+		}
+	}
+
+	if config.C2PACert != "" && config.C2PAKey != "" {
+		if _, err := loadC2PASigner(config); err != nil {
+			return err
+		}
+	}
+
+	if err := checkUpscale(config); err != nil {
+		return err
+	}
+
+	return validateOptions(config)
+}
+
+// validateOptions checks the options that don't depend on the filesystem.
+func validateOptions(config Config) error {
 	if config.TrimPercent < 1 || config.TrimPercent > 100 {
 		return fmt.Errorf("trim percent must be between 1 and 100 (got %d)", config.TrimPercent)
 	}
 
+	if config.SmartCrop && !config.CropEnabled {
+		return fmt.Errorf("--smart-crop chooses where to crop; it can't be combined with --no-crop")
+	}
+	anchor, err := parseCropAnchor(config.CropAnchor)
+	if err != nil {
+		return err
+	}
+	if config.SmartCrop && anchor != overlayAnchors["center"] {
+		return fmt.Errorf("--smart-crop and --crop-anchor both choose where to crop; use one")
+	}
+
+	if err := validateFilter(config); err != nil {
+		return err
+	}
+
+	if config.RemoveBackground != "" {
+		if _, _, err := parseRemoveBackground(config.RemoveBackground); err != nil {
+			return err
+		}
+	}
+
+	if config.AutoContrast != "" {
+		if err := validateAutoContrast(config); err != nil {
+			return err
+		}
+	}
+
+	if err := validateRecolor(config); err != nil {
+		return err
+	}
+
+	if err := validateBitDepth(config.BitDepth); err != nil {
+		return err
+	}
+
+	if err := validateColorProfile(config.ColorProfile); err != nil {
+		return err
+	}
+
+	if err := validateColorSpace(config); err != nil {
+		return err
+	}
+
+	if err := validateFlattenMarketing(config); err != nil {
+		return err
+	}
+
+	if err := validateDither(config.Dither); err != nil {
+		return err
+	}
+
+	if err := validateSharpen(config.Sharpen); err != nil {
+		return err
+	}
+
+	if err := validateSafeZone(config.SafeZone); err != nil {
+		return err
+	}
+
+	if config.DPI < 0 || config.DPI > maxDPI {
+		return fmt.Errorf("dpi must be between 0 and %d (got %d)", maxDPI, config.DPI)
+	}
+
+	if config.Meta != "" {
+		if _, err := parseMeta(config.Meta); err != nil {
+			return err
+		}
+	}
+
 	if config.RadiusPercent < 0 || config.RadiusPercent > 50 {
 		return fmt.Errorf("radius percent must be between 0 and 50 (got %d)", config.RadiusPercent)
 	}
 
+	if config.RadiusPx < 0 {
+		return fmt.Errorf("radius px must not be negative (got %d)", config.RadiusPx)
+	}
+
+	if config.CornerSmoothing < 0 || config.CornerSmoothing > 1 {
+		return fmt.Errorf("corner smoothing must be between 0 and 1 (got %g)", config.CornerSmoothing)
+	}
+
+	if _, err := parsePresets(config.Preset); err != nil {
+		return err
+	}
+
+	if config.ContentsJSON && !hasPreset(config, "macos") {
+		return fmt.Errorf("--contents-json only applies to the macos preset")
+	}
+
+	if config.Appearances && !hasPreset(config, "macos") {
+		return fmt.Errorf("--appearances only applies to the macos preset")
+	}
+
+	if config.Variant != "" {
+		if _, err := parseColorVariants(config.Variant); err != nil {
+			return err
+		}
+	}
+
+	if config.Monochrome != "" {
+		if err := validateMonochrome(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Appearances && config.DarkSource != "" {
+		return fmt.Errorf("--appearances and --dark-source both write icon_1024x1024_dark.png")
+	}
+
+	if config.HashNames && !hasPreset(config, "web") {
+		return fmt.Errorf("--hash-names only applies to the web preset")
+	}
+
+	if config.Precompress != "" {
+		if !hasPreset(config, "web") {
+			return fmt.Errorf("--precompress only applies to the web preset")
+		}
+		if _, err := parsePrecompress(config.Precompress); err != nil {
+			return err
+		}
+	}
+
+	if config.Watermark != "" {
+		if err := validateWatermark(config.Watermark); err != nil {
+			return err
+		}
+	}
+
+	if err := validateC2PA(config); err != nil {
+		return err
+	}
+
+	if config.AssetVersion != "" {
+		if err := validateAssetVersion(config.AssetVersion); err != nil {
+			return err
+		}
+	}
+
+	if config.VersionedDirs && config.AssetVersion == "" {
+		return fmt.Errorf("--versioned-dirs needs an --asset-version")
+	}
+
+	if config.Stateless && config.Check {
+		return fmt.Errorf("--check compares against the output manifest, which --stateless doesn't write")
+	}
+
+	if config.Stateless && config.ManifestPath != "" {
+		return fmt.Errorf("--manifest reads the output manifest, which --stateless doesn't write")
+	}
+
+	if config.First != "" {
+		if _, err := parseFirstSizes(config.First, outputSizes(config)); err != nil {
+			return err
+		}
+	}
+
+	if config.RadiusSizes != "" {
+		if _, err := parseRadiusOverrides(config.RadiusSizes, outputSizes(config)); err != nil {
+			return err
+		}
+	}
+
+	if config.Mask != "" {
+		if _, err := parseMasks(config.Mask); err != nil {
+			return err
+		}
+	}
+
+	if config.MaskImage != "" && config.MaskChannel != "" {
+		if _, ok := maskChannels[config.MaskChannel]; !ok {
+			return fmt.Errorf("unknown mask channel %q (expected alpha or luminance)", config.MaskChannel)
+		}
+	}
+
+	if config.Effects != "" {
+		if _, err := parseEffects(config.Effects); err != nil {
+			return err
+		}
+	}
+
+	if config.Badge != "" {
+		if err := validateBadge(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Text != "" {
+		if err := validateText(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Overlay != "" {
+		if err := validateOverlay(config); err != nil {
+			return err
+		}
+	}
+
+	if config.BorderWidth != "" {
+		if _, err := parseBorderWidth(config.BorderWidth); err != nil {
+			return err
+		}
+		if _, err := parseHexColor(config.BorderColor); err != nil {
+			return fmt.Errorf("invalid border color: %w", err)
+		}
+	}
+
+	if config.Jobs < 0 {
+		return fmt.Errorf("jobs must not be negative (got %d)", config.Jobs)
+	}
+
 	if config.PaddingPercent < 0 || config.PaddingPercent > 50 {
 		return fmt.Errorf("padding percent must be between 0 and 50 (got %d)", config.PaddingPercent)
 	}
 
+	if config.Padding != "" {
+		if config.PaddingPercent > 0 {
+			return fmt.Errorf("--padding and --padding-percent can't be combined")
+		}
+		if _, err := parsePaddingCurve(config.Padding); err != nil {
+			return err
+		}
+	}
+
+	if config.LongShadowLength < 0 || config.LongShadowLength > 100 {
+		return fmt.Errorf("long shadow length must be between 0 and 100 (got %d)", config.LongShadowLength)
+	}
+
+	if config.LongShadowOpacity < 0 || config.LongShadowOpacity > 100 {
+		return fmt.Errorf("long shadow opacity must be between 0 and 100 (got %d)", config.LongShadowOpacity)
+	}
+
+	if config.Shadow {
+		if config.ShadowBlur < 0 || config.ShadowBlur > 25 {
+			return fmt.Errorf("shadow blur must be between 0 and 25 (got %d)", config.ShadowBlur)
+		}
+		if config.ShadowOffsetX < -25 || config.ShadowOffsetX > 25 || config.ShadowOffsetY < -25 || config.ShadowOffsetY > 25 {
+			return fmt.Errorf("shadow offsets must be between -25 and 25 (got %d, %d)", config.ShadowOffsetX, config.ShadowOffsetY)
+		}
+		if config.ShadowOpacity < 0 || config.ShadowOpacity > 100 {
+			return fmt.Errorf("shadow opacity must be between 0 and 100 (got %d)", config.ShadowOpacity)
+		}
+		if _, err := parseHexColor(config.ShadowColor); err != nil {
+			return fmt.Errorf("invalid shadow color: %w", err)
+		}
+	}
+
+	if config.SpinnerFrames < 0 || config.SpinnerFrames > 360 {
+		return fmt.Errorf("spinner frames must be between 0 and 360 (got %d)", config.SpinnerFrames)
+	}
+
+	if config.SpinnerFrames > 0 && (config.SpinnerSize < 1 || config.SpinnerSize > 1024) {
+		return fmt.Errorf("spinner size must be between 1 and 1024 (got %d)", config.SpinnerSize)
+	}
+
+	if config.States != "" {
+		if _, err := parseStates(config.States); err != nil {
+			return err
+		}
+	}
+
+	if config.Series != "" {
+		if _, err := parseSeries(config.Series); err != nil {
+			return err
+		}
+		if _, err := parseHexColor(config.SeriesColor); err != nil {
+			return err
+		}
+	}
+
+	if config.BadgeCount != 0 {
+		if err := validateBadgeCount(config.BadgeCount); err != nil {
+			return err
+		}
+	}
+
+	if config.Suppress != "" {
+		if _, err := parseSuppressions(config.Suppress); err != nil {
+			return err
+		}
+	}
+
+	if config.MaxPixels < 0 {
+		return fmt.Errorf("max pixels must not be negative (got %d)", config.MaxPixels)
+	}
+
+	if config.Budget < 0 {
+		return fmt.Errorf("budget must not be negative (got %s)", config.Budget)
+	}
+
+	if config.MaxMemory != "" {
+		if _, err := parseByteSize(config.MaxMemory); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFill(config.Fill); err != nil {
+		return err
+	}
+	if err := validateFitMode(config); err != nil {
+		return err
+	}
+
+	backgrounds := 0
+	for _, background := range []string{config.Background, config.BackgroundPattern, config.BackgroundGradient} {
+		if background != "" {
+			backgrounds++
+		}
+	}
+	if backgrounds > 1 {
+		return fmt.Errorf("--background, --background-pattern and --background-gradient cannot be combined (set the pattern's bg= instead)")
+	}
+	if backgrounds > 0 && config.Fill == "blur" {
+		return fmt.Errorf("--fill=blur replaces the background, so it cannot be combined with --background, --background-pattern or --background-gradient")
+	}
+
+	if config.Background != "" && !backgroundIsImage(config) {
+		if _, err := parseHexColor(config.Background); err != nil {
+			return err
+		}
+	}
+
+	for name, scale := range map[string]int{"foreground scale": config.ForegroundScale, "background scale": config.BackgroundScale} {
+		if err := validateLayerScale(name, layerScale(scale)); err != nil {
+			return err
+		}
+	}
+
+	if config.BackgroundGradient != "" {
+		if _, err := parseBackgroundGradient(config.BackgroundGradient); err != nil {
+			return err
+		}
+	}
+
+	if config.BackgroundPattern != "" {
+		if _, err := parseBackgroundPattern(config.BackgroundPattern); err != nil {
+			return err
+		}
+	}
+
+	if config.Layers != "" {
+		if _, err := parseLayers(config.Layers); err != nil {
+			return err
+		}
+		if backgrounds > 0 || config.Fill == "blur" || layerScale(config.ForegroundScale) != 100 || longShadowLength(config) > 0 {
+			return fmt.Errorf("--layers replaces the foreground and background, so it cannot be combined with --background, --background-pattern, --background-gradient, --fill=blur, --foreground-scale or --long-shadow")
+		}
+	}
+
 	return nil
 }
 
 func generateIcons(config Config) error {
+	baseDir := config.OutputDir
+	config.OutputDir = assetDir(config)
+
+	if config.Check {
+		return checkIcons(config)
+	}
+
+	// validateConfig can't check the sources of recursive mode one by one
+	if err := checkUpscale(config); err != nil {
+		return err
+	}
+
+	// Drop the optional effects there's no time for before hashing the options
+	if config.Budget > 0 {
+		var err error
+		if config, err = applyBudget(config); err != nil {
+			return err
+		}
+	}
+
 	// Create output directory
 	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	state, err := loadManifestState(config)
+	if err != nil {
+		return fmt.Errorf("failed to check previous outputs: %w", err)
+	}
+	// Don't leave outputs being written behind when the run fails
+	defer state.flush()
+
 	// Clean existing icons if requested
-	if config.Clean {
-		fmt.Printf("Cleaning existing icon_*.png in: %s\n", config.OutputDir)
+	if config.CleanAll {
+		fmt.Fprintf(logOutput(config), "Cleaning existing icon_*.png in: %s\n", config.OutputDir)
 		pattern := filepath.Join(config.OutputDir, "icon_*.png")
 		matches, _ := filepath.Glob(pattern)
 		for _, match := range matches {
 			os.Remove(match)
 		}
+	} else if config.Clean {
+		fmt.Fprintf(logOutput(config), "Cleaning files from the previous run in: %s\n", config.OutputDir)
+		if err := state.clean(); err != nil {
+			return fmt.Errorf("failed to clean previous outputs: %w", err)
+		}
+	}
+
+	// Refuse to clobber files icongen didn't create
+	outputs := expectedOutputs(config)
+	if conflicting := state.conflicts(outputs); len(conflicting) > 0 && !config.Force {
+		return fmt.Errorf("refusing to overwrite existing files not created by icongen (use --force): %s",
+			strings.Join(conflicting, ", "))
 	}
 
-	// Load source image
-	sourceImg, err := loadImage(config.InputPath)
+	// Skip everything if the previous run's outputs are still current
+	if state.allUpToDate(outputs) {
+		fmt.Fprintf(logOutput(config), "Icons in %s are up to date\n", config.OutputDir)
+		return finishRun(config, baseDir)
+	}
+
+	// Load source image, cropped if enabled
+	sourceImg, err := loadSource(config)
 	if err != nil {
-		return fmt.Errorf("failed to load source image: %w", err)
+		return err
 	}
 
-	// Apply cropping if enabled
-	if config.CropEnabled {
-		fmt.Printf("Pre-trimming input to centered %d%% area, then generating PNGs in: %s\n",
+	if config.CropEnabled && config.SmartCrop {
+		fmt.Fprintf(logOutput(config), "Pre-trimming input to the most detailed %d%% area, then generating PNGs in: %s\n",
+			config.TrimPercent, config.OutputDir)
+	} else if config.CropEnabled && config.CropAnchor != "" && config.CropAnchor != "center" {
+		fmt.Fprintf(logOutput(config), "Pre-trimming input to a %d%% area at %s, then generating PNGs in: %s\n",
+			config.TrimPercent, config.CropAnchor, config.OutputDir)
+	} else if config.CropEnabled {
+		fmt.Fprintf(logOutput(config), "Pre-trimming input to centered %d%% area, then generating PNGs in: %s\n",
 			config.TrimPercent, config.OutputDir)
-		sourceImg = cropCenter(sourceImg, config.TrimPercent)
 	} else {
-		fmt.Printf("Cropping disabled; generating PNGs from full image in: %s\n", config.OutputDir)
+		fmt.Fprintf(logOutput(config), "Cropping disabled; generating PNGs from full image in: %s\n", config.OutputDir)
 	}
 
-	// Generate all icon sizes
-	for _, iconSize := range iconSizes {
-		fmt.Printf(" - %s (%dx%d)\n", iconSize.Name, iconSize.Size, iconSize.Size)
+	var pattern backgroundPattern
+	if config.BackgroundPattern != "" {
+		pattern, err = parseBackgroundPattern(config.BackgroundPattern)
+		if err != nil {
+			return err
+		}
+	}
+	backgroundImg, err := loadBackgroundImage(config)
+	if err != nil {
+		return err
+	}
+	layers, err := loadLayers(config)
+	if err != nil {
+		return err
+	}
 
-		// Resize image
-		resized := resizeImage(sourceImg, iconSize.Size)
+	// Mark marketing sizes with --watermark, warning where the tag won't fit
+	hash := generationHash(state)
+	watermark := func(img image.Image, name string, size int) image.Image {
+		marked, ok := watermarkIcon(img, config, size, hash)
+		if !ok {
+			emitWarning(config, warning{Code: "W004", Target: name, Message: "too few opaque pixels to carry the watermark"})
+		}
+		return marked
+	}
 
-		// Apply padding if specified
-		processed := resized
-		shouldApplyPadding := config.PaddingPercent > 0
-		if config.PaddingIOSMode && iconSize.Name == "icon_1024x1024.png" {
-			shouldApplyPadding = false // iOS mode: exclude base 1024x1024 icon only
+	// Generate all icon sizes, the --first ones first, rendering outputs
+	// that come out identical, within a preset or across presets, only once
+	cache := newRenderCache(renderKeys(config))
+	variants := iconVariants(config)
+	first := firstOutputCount(config)
+	standalone := newStandaloneIcons(sourceImg, config)
+	for i, iconSize := range outputSizes(config) {
+		iconSize := iconSize
+		artwork := func() image.Image {
+			return finishIcon(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size), config, iconSize, nil)
 		}
-		if shouldApplyPadding {
-			processed = addPadding(resized, config.PaddingPercent, iconSize.Size)
+		if label, render, ok := standalone.render(iconSize, artwork); ok {
+			if err := saveOutput(config, state, iconSize.Name, label, render); err != nil {
+				return err
+			}
+			continue
 		}
 
-		// Save regular version
-		outputPath := filepath.Join(config.OutputDir, iconSize.Name)
-		if err := saveImage(processed, outputPath); err != nil {
-			return fmt.Errorf("failed to save %s: %w", iconSize.Name, err)
+		// Resize lazily, only once one of this size's outputs turns out stale
+		prepared := func() image.Image {
+			return cache.get(preparedKey(iconSize.Size), func() image.Image {
+				return prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size)
+			})
 		}
 
-		// Generate rounded version
-		if config.RadiusPercent > 0 {
-			roundedName := strings.TrimSuffix(iconSize.Name, ".png") + "_rounded.png"
-			radius := iconSize.Size * config.RadiusPercent / 100
-			fmt.Printf(" - %s (%dx%d, r=%d)\n", roundedName, iconSize.Size, iconSize.Size, radius)
+		// save writes the output of a variant ("" for the regular icon),
+		// finishing it unless an identical output already was
+		save := func(name, variant, label string, finish func() image.Image) error {
+			key := outputKey(config, iconSize, variant)
+			err := saveOutput(config, state, name, label, func() image.Image {
+				return watermark(cache.get(key, finish), name, iconSize.Size)
+			})
+			cache.release(key)
+			cache.release(preparedKey(iconSize.Size))
+			return err
+		}
 
-			rounded := addRoundedCorners(resized, radius)
+		// Save regular version
+		label := fmt.Sprintf("%dx%d", iconSize.Size, iconSize.Size)
+		err := save(iconSize.Name, "", label, func() image.Image {
+			return finishIcon(prepared(), config, iconSize, nil)
+		})
+		if err != nil {
+			return err
+		}
 
-			// Apply padding to rounded version if specified
-			processedRounded := rounded
-			shouldApplyPaddingRounded := config.PaddingPercent > 0
-			if config.PaddingIOSMode && iconSize.Name == "icon_1024x1024.png" {
-				shouldApplyPaddingRounded = false // iOS mode: exclude base 1024x1024 icon only
-			}
-			if shouldApplyPaddingRounded {
-				processedRounded = addPadding(rounded, config.PaddingPercent, iconSize.Size)
+		// Generate rounded and masked versions
+		for _, variant := range variantsFor(variants, iconSize) {
+			variant := variant
+			name := variantIconName(iconSize.Name, variant.Name)
+			label := fmt.Sprintf("%dx%d, %s", iconSize.Size, iconSize.Size, variant.label(iconSize.Size))
+			err := save(name, variant.Name, label, func() image.Image {
+				img := prepared()
+				stop := timeStage("mask")
+				masked := variant.mask(img, iconSize.Size)
+				stop()
+				return finishIcon(masked, config, iconSize, variant.mask)
+			})
+			if err != nil {
+				return err
 			}
+		}
 
-			roundedPath := filepath.Join(config.OutputDir, roundedName)
-			if err := saveImage(processedRounded, roundedPath); err != nil {
-				return fmt.Errorf("failed to save %s: %w", roundedName, err)
-			}
+		if i+1 == first {
+			fmt.Fprintf(logOutput(config), "Sizes %s are ready, generating the rest\n", config.First)
 		}
 	}
 
-	return nil
-}
+	// Generate labelled copies
+	if config.Series != "" {
+		if err := generateSeries(sourceImg, config, pattern, backgroundImg, layers, state); err != nil {
+			return err
+		}
+	}
 
-func loadImage(path string) (image.Image, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	// Generate notification badge copies
+	if config.BadgeCount != 0 {
+		if err := generateBadgeCount(sourceImg, config, pattern, backgroundImg, layers, state); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
-	return img, err
-}
+	// Generate recolored copies
+	if config.Variant != "" {
+		if err := generateColorVariants(sourceImg, config, pattern, backgroundImg, layers, state); err != nil {
+			return err
+		}
+	}
 
-func saveImage(img image.Image, path string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	// Generate dark-mode copies
+	if config.DarkSource != "" {
+		if err := generateDark(sourceImg, config, pattern, backgroundImg, layers, state); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	return png.Encode(file, img)
-}
+	// Generate the Android round launcher icons
+	if hasPreset(config, "android") {
+		if err := generateRoundLaunchers(sourceImg, config, state); err != nil {
+			return err
+		}
+	}
 
-func cropCenter(img image.Image, percent int) image.Image {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+	// Generate the Android adaptive icon with its themed layer
+	if config.Monochrome != "" && hasPreset(config, "android") {
+		if err := generateAdaptive(sourceImg, config, state); err != nil {
+			return err
+		}
+	}
 
-	// Calculate crop dimensions
-	cropWidth := width * percent / 100
-	cropHeight := height * percent / 100
+	// Generate the iOS dark and tinted appearances
+	if config.Appearances {
+		if err := generateAppearances(sourceImg, config, state); err != nil {
+			return err
+		}
+	}
 
-	// Calculate offset to center the crop
-	offsetX := (width - cropWidth) / 2
-	offsetY := (height - cropHeight) / 2
+	// Generate rotation series
+	if config.SpinnerFrames > 0 {
+		if err := generateSpinner(sourceImg, config, state); err != nil {
+			return err
+		}
+	}
 
-	// Create cropped rectangle
-	cropRect := image.Rect(
-		bounds.Min.X+offsetX,
-		bounds.Min.Y+offsetY,
-		bounds.Min.X+offsetX+cropWidth,
-		bounds.Min.Y+offsetY+cropHeight,
-	)
+	// Generate status-state variants
+	if config.States != "" {
+		if err := generateStates(sourceImg, config, state); err != nil {
+			return err
+		}
+	}
 
-	// Create new image with cropped content
-	cropped := image.NewRGBA(image.Rect(0, 0, cropWidth, cropHeight))
-	draw.Draw(cropped, cropped.Bounds(), img, cropRect.Min, draw.Src)
+	// The snippets, sheets and reports below read the outputs written so far
+	if err := state.flush(); err != nil {
+		return err
+	}
 
-	return cropped
-}
+	if config.DebugOverlay {
+		if err := writeDebugOverlays(config, state); err != nil {
+			return err
+		}
+	}
+
+	if config.DesignSVG {
+		if err := writeDesignSVG(sourceImg, config, pattern, backgroundImg, layers, state); err != nil {
+			return err
+		}
+	}
+
+	if config.ContentsJSON {
+		if err := writeXcodeContents(config, state); err != nil {
+			return err
+		}
+	}
 
-func resizeImage(img image.Image, size int) image.Image {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+	if hasPreset(config, complicationPreset) {
+		if err := writeComplicationContents(config, state); err != nil {
+			return err
+		}
+	}
 
-	// Calculate scaling to fit within square while maintaining aspect ratio
-	scale := float64(size) / math.Max(float64(width), float64(height))
-	newWidth := int(float64(width) * scale)
-	newHeight := int(float64(height) * scale)
+	if hasPreset(config, "web") {
+		if err := writeWebSnippets(config, state); err != nil {
+			return err
+		}
+	}
 
-	// Create new image
-	resized := image.NewRGBA(image.Rect(0, 0, size, size))
+	// Write the contact sheets and preview gallery last so they cover every output
+	if config.ContactSheet {
+		if err := writeContactSheet(config, state); err != nil {
+			return err
+		}
+	}
 
-	// Fill with transparent background
-	transparent := &image.Uniform{color.RGBA{0, 0, 0, 0}}
-	draw.Draw(resized, resized.Bounds(), transparent, image.Point{}, draw.Src)
+	if config.ReportPDF {
+		if err := writeReportPDF(config, state); err != nil {
+			return err
+		}
+	}
 
-	// Calculate centering offset
-	offsetX := (size - newWidth) / 2
-	offsetY := (size - newHeight) / 2
+	if config.PreviewHTML {
+		if err := writePreview(config, state); err != nil {
+			return err
+		}
+	}
 
-	// Bilinear interpolation scaling for smoother results
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			// Calculate source coordinates with sub-pixel precision
-			srcXf := float64(x) / scale
-			srcYf := float64(y) / scale
+	// Remember what was generated for the next run
+	if err := state.save(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
 
-			// Get integer and fractional parts
-			srcX := int(srcXf)
-			srcY := int(srcYf)
-			fracX := srcXf - float64(srcX)
-			fracY := srcYf - float64(srcY)
+	return finishRun(config, baseDir)
+}
 
-			// Adjust for bounds offset
-			srcX += bounds.Min.X
-			srcY += bounds.Min.Y
+// finishRun points the latest entry of baseDir at this run's version
+// directory with --versioned-dirs, and writes the --manifest report.
+func finishRun(config Config, baseDir string) error {
+	warnRun(config)
+	if config.VersionedDirs {
+		if err := updateLatest(baseDir, versionDirName(config.AssetVersion)); err != nil {
+			return fmt.Errorf("failed to update %s pointer: %w", latestName, err)
+		}
+	}
+	return writeRunReport(config)
+}
 
-			// Ensure we don't go out of bounds
-			if srcX >= bounds.Max.X-1 {
-				srcX = bounds.Max.X - 2
-				fracX = 1.0
-			}
-			if srcY >= bounds.Max.Y-1 {
-				srcY = bounds.Max.Y - 2
-				fracY = 1.0
-			}
+// checkIcons reports an error unless every output of config is up to date
+// with the previous run's manifest.
+func checkIcons(config Config) error {
+	config.Incremental = true
+	state, err := loadManifestState(config)
+	if err != nil {
+		return fmt.Errorf("failed to check previous outputs: %w", err)
+	}
 
-			if srcX >= bounds.Min.X && srcY >= bounds.Min.Y {
-				// Get the four surrounding pixels
-				c00 := img.At(srcX, srcY)
-				c10 := img.At(srcX+1, srcY)
-				c01 := img.At(srcX, srcY+1)
-				c11 := img.At(srcX+1, srcY+1)
-
-				// Convert to RGBA for interpolation
-				r00, g00, b00, a00 := c00.RGBA()
-				r10, g10, b10, a10 := c10.RGBA()
-				r01, g01, b01, a01 := c01.RGBA()
-				r11, g11, b11, a11 := c11.RGBA()
-
-				// Bilinear interpolation
-				r := bilinearInterpolate(float64(r00), float64(r10), float64(r01), float64(r11), fracX, fracY)
-				g := bilinearInterpolate(float64(g00), float64(g10), float64(g01), float64(g11), fracX, fracY)
-				b := bilinearInterpolate(float64(b00), float64(b10), float64(b01), float64(b11), fracX, fracY)
-				a := bilinearInterpolate(float64(a00), float64(a10), float64(a01), float64(a11), fracX, fracY)
-
-				// Convert back to 8-bit and set pixel
-				interpolated := color.RGBA64{
-					R: uint16(r),
-					G: uint16(g),
-					B: uint16(b),
-					A: uint16(a),
-				}
-				resized.Set(offsetX+x, offsetY+y, interpolated)
-			}
+	var stale []string
+	for _, name := range expectedOutputs(config) {
+		if !state.upToDate(name) {
+			stale = append(stale, name)
 		}
 	}
+	if len(stale) > 0 {
+		return fmt.Errorf("icons in %s are out of date: %s", config.OutputDir, strings.Join(stale, ", "))
+	}
 
-	return resized
+	fmt.Fprintf(logOutput(config), "Icons in %s are up to date\n", config.OutputDir)
+	return nil
 }
 
-func addRoundedCorners(img image.Image, radius int) image.Image {
-	bounds := img.Bounds()
-	size := bounds.Dx() // Assuming square image
+// loadSource loads the source image, prepares it like prepareSource, from
+// the --cache if it holds it, and enforces --non-square=error.
+func loadSource(config Config) (image.Image, error) {
+	reduction, err := checkSourceLimits(config)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create new RGBA image
-	rounded := image.NewRGBA(bounds)
+	load := func() (image.Image, error) {
+		var sourceImg image.Image
+		stop := timeStage("decode")
+		if reduction > 1 {
+			fmt.Fprintf(logOutput(config), "Decoding the source at 1/%d of its size to stay under --max-memory=%s\n", reduction, config.MaxMemory)
+			sourceImg, err = loadReducedPNG(config.InputPath, reduction)
+		} else {
+			sourceImg, err = loadImage(config.InputPath)
+		}
+		stop()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load source image: %w", err)
+		}
+		if config.BitDepth == 16 && !deepImage(sourceImg) {
+			sourceImg = toRGBA64(sourceImg)
+		}
 
-	// Create mask for rounded corners
-	for y := 0; y < size; y++ {
-		for x := 0; x < size; x++ {
-			if shouldKeepPixel(x, y, size, radius) {
-				rounded.Set(x, y, img.At(x, y))
-			} else {
-				// Transparent pixel
-				rounded.Set(x, y, color.RGBA{0, 0, 0, 0})
-			}
+		sourceImg = processSource(sourceImg, config)
+		if err := checkSquare(sourceImg, config); err != nil {
+			return nil, err
 		}
+		return sourceImg, nil
 	}
 
-	return rounded
+	var sourceImg image.Image
+	if config.Cache {
+		sourceImg, err = cachedSource(config, reduction, load)
+	} else {
+		sourceImg, err = load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer timeStage("mipmaps")()
+	return withMipmaps(sourceImg, config), nil
 }
 
-func bilinearInterpolate(c00, c10, c01, c11, fracX, fracY float64) float64 {
-	// Interpolate along X axis
-	top := c00*(1-fracX) + c10*fracX
-	bottom := c01*(1-fracX) + c11*fracX
+// prepareSource processes the decoded source with processSource and builds
+// its mipmaps.
+func prepareSource(sourceImg image.Image, config Config) image.Image {
+	return withMipmaps(processSource(sourceImg, config), config)
+}
 
-	// Interpolate along Y axis
-	return top*(1-fracY) + bottom*fracY
+// processSource keys out the --remove-background of the decoded source,
+// trims its transparent margins, applies the configured crop, squares it for
+// --fit, stretches its contrast and recolors it.
+func processSource(sourceImg image.Image, config Config) image.Image {
+	if config.RemoveBackground != "" {
+		bg, tolerance, _ := parseRemoveBackground(config.RemoveBackground)
+		sourceImg = removeBackground(sourceImg, bg, tolerance)
+	}
+	stop := timeStage("crop")
+	if config.TrimAlpha {
+		sourceImg = trimAlpha(sourceImg)
+	}
+	if config.CropEnabled && config.SmartCrop {
+		sourceImg = cropSalient(sourceImg, config.TrimPercent)
+	} else if config.CropEnabled {
+		anchor, _ := parseCropAnchor(config.CropAnchor)
+		if deepImage(sourceImg) {
+			sourceImg = cropDeep(sourceImg, config.TrimPercent, anchor)
+		} else {
+			sourceImg = cropAnchor(sourceImg, config.TrimPercent, anchor)
+		}
+	}
+	sourceImg = fitSource(sourceImg, config)
+	stop()
+	if config.AutoContrast != "" {
+		sourceImg = autoContrast(sourceImg, config.AutoContrast)
+	}
+	sourceImg = recolorSource(sourceImg, config)
+	if config.PixelArt == "scale2x" {
+		sourceImg = scalePixelArt(sourceImg, config)
+	}
+	return sourceImg
 }
 
-func addPadding(img image.Image, paddingPercent int, targetSize int) image.Image {
-	if paddingPercent <= 0 {
+// prepareIcon resizes the source to size and applies the effects that sit
+// underneath any mask: the long shadow and the background, which is
+// backgroundImg when --background is an image, or the blurred fill. A
+// --layers stack, loaded into layers, replaces all of them. The --overlay,
+// the --text and the --badge ribbon go on top, so masks clip them like the
+// artwork.
+func prepareIcon(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, size int) image.Image {
+	if len(layers) > 0 {
+		return decorateIcon(renderLayers(sourceImg, layers, size), config, size)
+	}
+
+	stop := timeStage(fmt.Sprintf("resize %dpx", size))
+	resized := scaleForeground(sourceImg, size, layerScale(config.ForegroundScale), depthResizer(config))
+	if amount := sharpenAmount(config, sourceImg, size); amount > 0 {
+		resized = unsharpMask(resized, amount)
+	}
+	stop()
+
+	// Cast long shadow behind the artwork
+	if lengthPercent := longShadowLength(config); lengthPercent > 0 {
+		length := size * lengthPercent / 100
+		resized = addLongShadow(resized, float64(config.LongShadowAngle), length, config.LongShadowOpacity)
+	}
+
+	// Fill the transparent area behind the artwork
+	if blurFillActive(config, sourceImg) {
+		resized = addBackground(resized, blurredFill(sourceImg, size))
+	}
+	if backgroundImg != nil {
+		resized = addBackground(resized, scaleBackground(backgroundImg, size, layerScale(config.BackgroundScale)))
+	} else if config.Background != "" && !backgroundIsImage(config) {
+		fill, _ := parseHexColor(config.Background)
+		resized = addBackground(resized, solidBackground(fill, size))
+	}
+	if config.BackgroundGradient != "" {
+		gradient, _ := parseBackgroundGradient(config.BackgroundGradient)
+		gradient.Dither = gradientDither(config)
+		resized = addBackground(resized, renderGradient(gradient, size))
+	}
+	if config.BackgroundPattern != "" {
+		resized = addBackground(resized, renderPattern(pattern, size))
+	}
+
+	return decorateIcon(resized, config, size)
+}
+
+// decorateIcon adds the --overlay, --text and --badge of config to the
+// prepared icon img, in that order.
+func decorateIcon(img image.Image, config Config, size int) image.Image {
+	return addBadge(addText(addOverlay(img, config, size), config, size), config, size)
+}
+
+// finishIcon applies the effects that go on top of the masked icon img of
+// iconSize: the surface effects, the border following shape (nil for the
+// square), the drop shadow and the padding.
+func finishIcon(img image.Image, config Config, iconSize IconSize, shape func(img image.Image, size int) image.Image) image.Image {
+	img = applyEffects(img, config, iconSize.Size)
+	img = addBorder(img, config, iconSize.Size, shape)
+	img = addDropShadow(img, config, iconSize.Size)
+	return padIcon(img, config, iconSize)
+}
+
+// padIcon applies the configured padding to an output of iconSize.
+func padIcon(img image.Image, config Config, iconSize IconSize) image.Image {
+	if !hasPadding(config, iconSize) {
 		return img
 	}
+	return padWith(img, paddingPercent(config, iconSize.Size), iconSize.Size, depthResizer(config))
+}
 
-	bounds := img.Bounds()
-	currentSize := bounds.Dx() // Assuming square image
+// hasPadding reports whether the output of iconSize gets padding.
+func hasPadding(config Config, iconSize IconSize) bool {
+	// iOS mode: exclude base 1024x1024 icon only
+	return paddingPercent(config, iconSize.Size) > 0 && !(config.PaddingIOSMode && iconSize.Name == "icon_1024x1024.png")
+}
+
+// roundedIconName returns the file name of the rounded variant of name.
+func roundedIconName(name string) string {
+	return variantIconName(name, "rounded")
+}
 
-	// Calculate padding size
-	paddingSize := currentSize * paddingPercent / 100
-	paddedSize := currentSize + (paddingSize * 2)
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-	// Create new image with padding
-	padded := image.NewRGBA(image.Rect(0, 0, paddedSize, paddedSize))
+	img, _, err := image.Decode(file)
+	return img, err
+}
 
-	// Fill with transparent background
-	transparent := &image.Uniform{color.RGBA{0, 0, 0, 0}}
-	draw.Draw(padded, padded.Bounds(), transparent, image.Point{}, draw.Src)
+func saveImage(img image.Image, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	// Center the original image in the padded canvas
-	offsetX := paddingSize
-	offsetY := paddingSize
-	dstRect := image.Rect(offsetX, offsetY, offsetX+currentSize, offsetY+currentSize)
-	draw.Draw(padded, dstRect, img, bounds.Min, draw.Src)
+	return encodePNG(file, img)
+}
 
-	// Resize the padded image back to target size
-	resizedPadded := resizeImage(padded, targetSize)
+// logOutput returns the writer the progress messages of config go to.
+func logOutput(config Config) io.Writer {
+	if config.Log != nil {
+		return config.Log
+	}
+	return os.Stdout
+}
 
-	return resizedPadded
+// saveOutput renders and saves the output name unless it is already up to
+// date, and records it in the manifest either way. label describes the output
+// in progress messages. Once the run's job is canceled, it saves what was
+// generated so far for the next run to resume from and returns errCanceled.
+func saveOutput(config Config, state *manifestState, name, label string, render func() image.Image) error {
+	if state.progress.canceled() {
+		if err := state.savePartial(); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		return errCanceled
+	}
+
+	if state.upToDate(name) {
+		fmt.Fprintf(logOutput(config), " - %s (up to date)\n", name)
+		return state.enqueue(name, func() error { return nil })
+	}
+
+	fmt.Fprintf(logOutput(config), " - %s (%s)\n", name, label)
+	path := filepath.Join(config.OutputDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	// Render here, and encode and write with the --jobs in the background
+	img, text := outputImage(render(), config, name), outputText(config, state, name)
+	return state.enqueue(name, func() error {
+		defer timeStage("encode")()
+		var err error
+		if state.credentials != nil {
+			err = saveSignedImage(img, path, outputChunks(config, state, name), text, config, state)
+		} else {
+			err = saveTextImage(img, path, outputChunks(config, state, name), text)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// outputImage applies what happens to the rendered output name as it is
+// saved: --flatten-marketing, --color-space=p3 and --bit-depth, warning
+// where they lose something.
+func outputImage(img image.Image, config Config, name string) image.Image {
+	if flattenOutput(config, name) {
+		flattened, discarded := flattenOpaque(img, flattenColor(config))
+		if discarded {
+			emitWarning(config, warning{Code: "W008", Target: name, Message: fmt.Sprintf("transparency discarded, flattened onto %s for the App Store", flattenColor(config))})
+		}
+		img = flattened
+	}
+	if p3Output(config, name) {
+		img = toDisplayP3(img)
+	}
+	img, widened := outputDepth(img, config)
+	if widened {
+		emitWarning(config, warning{Code: "W007", Target: name, Message: "saved as 16-bit, but went through an option that works in 8 bits"})
+	}
+	return img
+}
+
+func addRoundedCorners(img image.Image, radius int) image.Image {
+	size := img.Bounds().Dx() // Assuming square image
+
+	// Copy the image, then clear the pixels outside the corners in place
+	rounded, scale := maskedCopy(img)
+
+	// Only the corner squares can fall outside
+	for y := 0; y < size; y++ {
+		if y >= radius && y < size-radius {
+			continue
+		}
+		for x := 0; x < size; x++ {
+			if !shouldKeepPixel(x, y, size, radius) {
+				scale(x, y, 0)
+			}
+		}
+	}
+
+	return rounded
 }
 
 func shouldKeepPixel(x, y, size, radius int) bool {
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// manifestName is the file written to the output directory to record what
+// the previous run generated.
+const manifestName = ".icongen-manifest.json"
+
+type manifest struct {
+	SourceHash   string          `json:"source_hash"`
+	OptionsHash  string          `json:"options_hash"`
+	AssetVersion string          `json:"asset_version,omitempty"`
+	Generation   string          `json:"generation_hash,omitempty"`
+	Files        []manifestFile  `json:"files"`
+	Skipped      []skippedOutput `json:"skipped,omitempty"`
+	// Pending lists outputs of an earlier run that a canceled run didn't
+	// get to regenerate; they still belong to icongen
+	Pending []string `json:"pending,omitempty"`
+}
+
+type manifestFile struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// generationReport is the machine-readable summary written by --manifest,
+// covering every output of one or more output directories.
+type generationReport struct {
+	Outputs []reportOutput  `json:"outputs"`
+	Skipped []reportSkipped `json:"skipped,omitempty"`
+}
+
+type reportSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type reportOutput struct {
+	Path    string `json:"path"`
+	Source  string `json:"source_sha256"`
+	Version string `json:"asset_version,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// manifestState tracks which outputs of a run are still up to date with
+// respect to the previous manifest, which existing files icongen wrote itself,
+// and collects the files written this run.
+type manifestState struct {
+	dir          string
+	stateless    bool
+	sourceHash   string
+	optionsHash  string
+	assetVersion string
+	watermarked  bool
+	skipped      []skippedOutput
+	previous     map[string]string
+	owned        map[string]bool
+	files        []manifestFile
+	// color and density are the color space and density chunks of every
+	// icon
+	color    []pngChunk
+	density  []pngChunk
+	progress *runProgress
+	// outputs writes the rendered outputs with --jobs, nil for one job
+	outputs *outputQueue
+	// credentials signs C2PA content credentials into every icon, nil
+	// without --c2pa-cert
+	credentials *c2paSigner
+}
+
+// loadManifestState hashes the source image and options of config and loads
+// the manifest left in the output directory by the previous run. The previous
+// outputs only count as up to date in incremental mode and if both hashes
+// match. In stateless mode there is no previous manifest and none is saved.
+func loadManifestState(config Config) (*manifestState, error) {
+	sourceHash, err := hashFile(config.InputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsHash, err := hashOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceChunk := func(typ string) ([]byte, error) { return readPNGChunk(config.InputPath, typ) }
+	color, err := colorChunks(config, sourceChunk)
+	if err != nil {
+		return nil, err
+	}
+	density, err := densityChunks(config, sourceChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials *c2paSigner
+	if config.C2PACert != "" {
+		if credentials, err = loadC2PASigner(config); err != nil {
+			return nil, err
+		}
+	}
+
+	state := &manifestState{
+		dir:          config.OutputDir,
+		stateless:    config.Stateless,
+		assetVersion: config.AssetVersion,
+		watermarked:  config.Watermark != "",
+		skipped:      skippedOutputs(config),
+		sourceHash:   sourceHash,
+		optionsHash:  optionsHash,
+		color:        color,
+		density:      density,
+		credentials:  credentials,
+		previous:     make(map[string]string),
+		owned:        make(map[string]bool),
+		progress:     config.Progress,
+		outputs:      newOutputQueue(config.Jobs),
+	}
+
+	if config.Stateless {
+		return state, nil
+	}
+
+	prev, err := readManifest(config.OutputDir)
+	if err != nil {
+		// A missing or unreadable manifest just means everything is stale
+		return state, nil
+	}
+	for _, file := range prev.Files {
+		state.owned[file.Name] = true
+	}
+	for _, name := range prev.Pending {
+		state.owned[name] = true
+	}
+	if config.Incremental && prev.SourceHash == sourceHash && prev.OptionsHash == optionsHash {
+		for _, file := range prev.Files {
+			state.previous[file.Name] = file.SHA256
+		}
+	}
+
+	return state, nil
+}
+
+// conflicts returns the names that already exist in the output directory but
+// weren't written by a previous icongen run, so writing them would clobber
+// someone else's files.
+func (s *manifestState) conflicts(names []string) []string {
+	var conflicting []string
+	for _, name := range names {
+		if s.owned[name] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(s.dir, name)); err == nil {
+			conflicting = append(conflicting, name)
+		}
+	}
+	return conflicting
+}
+
+// clean removes the files the previous run recorded in its manifest, leaving
+// everything else in the output directory alone.
+func (s *manifestState) clean() error {
+	for name := range s.owned {
+		// Only ever delete files inside the output directory
+		if !localName(name) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// localName reports whether name is a relative path that stays inside the
+// directory it's relative to.
+func localName(name string) bool {
+	if name == "" || name == "." || filepath.IsAbs(name) || filepath.Clean(name) != name {
+		return false
+	}
+	return name != ".." && !strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
+
+// allUpToDate reports whether every one of names is up to date.
+func (s *manifestState) allUpToDate(names []string) bool {
+	for _, name := range names {
+		if !s.upToDate(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// upToDate reports whether name was generated by a previous run with the same
+// source and options and hasn't been modified or removed since.
+func (s *manifestState) upToDate(name string) bool {
+	want, ok := s.previous[name]
+	if !ok {
+		return false
+	}
+
+	got, err := hashFile(filepath.Join(s.dir, name))
+	return err == nil && got == want
+}
+
+// record adds name to the manifest of this run, hashing its current contents
+// and reading its pixel dimensions. The outputs still queued are recorded
+// first, so the manifest keeps the order the outputs were written in.
+func (s *manifestState) record(name string) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.recordFile(name)
+}
+
+func (s *manifestState) recordFile(name string) error {
+	path := filepath.Join(s.dir, name)
+
+	sum, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// Non-image outputs such as the HTML preview have no dimensions
+	width, height, _ := imageDimensions(path)
+
+	s.files = append(s.files, manifestFile{
+		Name:   name,
+		Width:  width,
+		Height: height,
+		Bytes:  info.Size(),
+		SHA256: sum,
+	})
+	s.progress.step(name)
+	return nil
+}
+
+// save writes the manifest of this run to the output directory.
+func (s *manifestState) save() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if s.stateless {
+		return nil
+	}
+
+	m := manifest{
+		SourceHash:   s.sourceHash,
+		OptionsHash:  s.optionsHash,
+		AssetVersion: s.assetVersion,
+		Files:        s.files,
+		Skipped:      s.skipped,
+	}
+	if s.watermarked {
+		m.Generation = generationHash(s)
+	}
+
+	return writeManifest(s.dir, m)
+}
+
+// savePartial writes the manifest of a run that stops early, so the next run
+// resumes where it left off: the outputs recorded so far count as up to
+// date, and the previous run's outputs not yet regenerated stay icongen's.
+func (s *manifestState) savePartial() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if s.stateless {
+		return nil
+	}
+
+	m := manifest{
+		SourceHash:   s.sourceHash,
+		OptionsHash:  s.optionsHash,
+		AssetVersion: s.assetVersion,
+		Files:        s.files,
+	}
+	recorded := make(map[string]bool)
+	for _, file := range s.files {
+		recorded[file.Name] = true
+	}
+	owned := make([]string, 0, len(s.owned))
+	for name := range s.owned {
+		owned = append(owned, name)
+	}
+	sort.Strings(owned)
+	for _, name := range owned {
+		if recorded[name] {
+			continue
+		}
+		// Outputs still up to date with these hashes keep their entries
+		if sum, ok := s.previous[name]; ok {
+			if info, err := os.Stat(filepath.Join(s.dir, name)); err == nil {
+				m.Files = append(m.Files, manifestFile{Name: name, Bytes: info.Size(), SHA256: sum})
+				continue
+			}
+		}
+		m.Pending = append(m.Pending, name)
+	}
+	sort.Strings(m.Pending)
+
+	return writeManifest(s.dir, m)
+}
+
+// writeManifest writes m as the manifest of dir.
+func writeManifest(dir string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, manifestName), append(data, '\n'), 0644)
+}
+
+// writeRunReport writes the --manifest report of a single-directory run, if
+// one was requested.
+func writeRunReport(config Config) error {
+	if config.ManifestPath == "" {
+		return nil
+	}
+
+	if err := writeReport(config.ManifestPath, []string{config.OutputDir}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", config.ManifestPath, err)
+	}
+	return nil
+}
+
+// writeReport collects the manifests of dirs into a single generation report
+// at path.
+func writeReport(path string, dirs []string) error {
+	report := generationReport{Outputs: []reportOutput{}}
+
+	for _, dir := range dirs {
+		m, err := readManifest(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest in %s: %w", dir, err)
+		}
+		for _, file := range m.Files {
+			report.Outputs = append(report.Outputs, reportOutput{
+				Path:    filepath.ToSlash(filepath.Join(dir, file.Name)),
+				Source:  m.SourceHash,
+				Version: m.AssetVersion,
+				Width:   file.Width,
+				Height:  file.Height,
+				Bytes:   file.Bytes,
+				SHA256:  file.SHA256,
+			})
+		}
+		for _, output := range m.Skipped {
+			report.Skipped = append(report.Skipped, reportSkipped{
+				Path:   filepath.ToSlash(filepath.Join(dir, output.Name)),
+				Reason: output.Reason,
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.SourceHash == "" {
+		return nil, errors.New("manifest has no source hash")
+	}
+
+	return &m, nil
+}
+
+// hashOptions fingerprints every Config field that affects the generated
+// pixels; fields tagged json:"-" are excluded. The mask, background and layer
+// images, the overlay, the text font and the C2PA certificate count by
+// content too.
+func hashOptions(config Config) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	if config.MaskImage != "" {
+		maskHash, err := hashFile(config.MaskImage)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, maskHash...)
+	}
+
+	// Another signer signs the icons again
+	if config.C2PACert != "" {
+		certHash, err := hashFile(config.C2PACert)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, certHash...)
+	}
+
+	if backgroundIsImage(config) {
+		backgroundHash, err := hashFile(config.Background)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, backgroundHash...)
+	}
+
+	for _, path := range layerPaths(config) {
+		layerHash, err := hashFile(path)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, layerHash...)
+	}
+
+	if config.Overlay != "" {
+		overlayHash, err := hashFile(config.Overlay)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, overlayHash...)
+	}
+
+	if config.Text != "" && config.TextFont != "" {
+		fontHash, err := hashFile(config.TextFont)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, fontHash...)
+	}
+
+	if config.DarkSource == darkSourceAuto {
+		data = append(data, darkSourceAuto...)
+	} else if config.DarkSource != "" {
+		darkHash, err := hashFile(config.DarkSource)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, darkHash...)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// imageDimensions reads the pixel dimensions of the image at path without
+// decoding it.
+func imageDimensions(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return cfg.Width, cfg.Height, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
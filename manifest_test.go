@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncrementalGeneration(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{255, 0, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		Incremental:   true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	m, err := readManifest(outputDir)
+	if err != nil {
+		t.Fatalf("Expected manifest after first run: %v", err)
+	}
+	if len(m.Files) != len(expectedOutputs(config)) {
+		t.Errorf("Expected %d files in manifest, got %d", len(expectedOutputs(config)), len(m.Files))
+	}
+
+	// Backdate every output so rewrites are detectable
+	past := time.Now().Add(-time.Hour)
+	for _, name := range expectedOutputs(config) {
+		os.Chtimes(filepath.Join(outputDir, name), past, past)
+	}
+
+	// Tamper with one output and delete another; only those are stale
+	tampered := filepath.Join(outputDir, "icon_32x32.png")
+	if err := saveImage(createTestImage(8, color.RGBA{0, 0, 0, 255}), tampered); err != nil {
+		t.Fatalf("Failed to tamper with output: %v", err)
+	}
+	os.Chtimes(tampered, past, past)
+	deleted := filepath.Join(outputDir, "icon_128x128_rounded.png")
+	os.Remove(deleted)
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to regenerate icons: %v", err)
+	}
+
+	for _, name := range expectedOutputs(config) {
+		info, err := os.Stat(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+			continue
+		}
+
+		rewritten := info.ModTime().After(past.Add(time.Minute))
+		stale := name == "icon_32x32.png" || name == "icon_128x128_rounded.png"
+		if stale && !rewritten {
+			t.Errorf("Expected stale %s to be regenerated", name)
+		}
+		if !stale && rewritten {
+			t.Errorf("Expected up-to-date %s to be skipped", name)
+		}
+	}
+
+	if img, err := loadImage(tampered); err != nil || img.Bounds().Dx() != 32 {
+		t.Errorf("Expected tampered icon to be restored to 32x32")
+	}
+}
+
+func TestIncrementalOptionsChange(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{0, 255, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 0,
+		Incremental:   true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+	first, err := readManifest(outputDir)
+	if err != nil {
+		t.Fatalf("Expected manifest after first run: %v", err)
+	}
+
+	config.TrimPercent = 50
+	state, err := loadManifestState(config)
+	if err != nil {
+		t.Fatalf("Failed to load incremental state: %v", err)
+	}
+	if state.optionsHash == first.OptionsHash {
+		t.Errorf("Expected options hash to change with trim percent")
+	}
+	if state.upToDate("icon_16x16.png") {
+		t.Errorf("Expected outputs to be stale after options change")
+	}
+
+	// Fields that don't affect pixels must not invalidate the manifest
+	config.TrimPercent = 80
+	config.Clean = true
+	state, err = loadManifestState(config)
+	if err != nil {
+		t.Fatalf("Failed to load incremental state: %v", err)
+	}
+	if !state.upToDate("icon_16x16.png") {
+		t.Errorf("Expected outputs to stay up to date when only Clean changes")
+	}
+}
+
+func TestOverwriteProtection(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{0, 0, 255, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	// A user file that happens to share a name with one of our outputs
+	userFile := filepath.Join(outputDir, "icon_16x16.png")
+	if err := os.WriteFile(userFile, []byte("precious"), 0644); err != nil {
+		t.Fatalf("Failed to create user file: %v", err)
+	}
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 0,
+	}
+
+	if err := generateIcons(config); err == nil {
+		t.Fatalf("Expected error when overwriting a file icongen didn't create")
+	}
+	if data, _ := os.ReadFile(userFile); string(data) != "precious" {
+		t.Errorf("Expected user file to be left untouched")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_32x32.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected no outputs to be written when refusing to overwrite")
+	}
+
+	config.Force = true
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Expected --force to overwrite, got: %v", err)
+	}
+
+	// Files from a previous run are ours to overwrite without --force
+	config.Force = false
+	config.TrimPercent = 60
+	if err := generateIcons(config); err != nil {
+		t.Errorf("Expected icongen's own outputs to be overwritten, got: %v", err)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{255, 255, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "icons.json")
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		ManifestPath:  reportPath,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Expected report to be written: %v", err)
+	}
+
+	var report generationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if len(report.Outputs) != len(expectedOutputs(config)) {
+		t.Fatalf("Expected %d outputs in report, got %d", len(expectedOutputs(config)), len(report.Outputs))
+	}
+
+	for _, output := range report.Outputs {
+		info, err := os.Stat(output.Path)
+		if err != nil {
+			t.Errorf("Report lists missing file %s", output.Path)
+			continue
+		}
+		if info.Size() != output.Bytes {
+			t.Errorf("Expected %s to be %d bytes, report says %d", output.Path, info.Size(), output.Bytes)
+		}
+
+		sum, _ := hashFile(output.Path)
+		if sum != output.SHA256 {
+			t.Errorf("Checksum mismatch for %s", output.Path)
+		}
+
+		width, height, _ := imageDimensions(output.Path)
+		if width != output.Width || height != output.Height {
+			t.Errorf("Expected %s to be %dx%d, report says %dx%d", output.Path, width, height, output.Width, output.Height)
+		}
+	}
+}
+
+func TestCleanRemovesOnlyOwnedFiles(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{0, 0, 255, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	// A user file matching the old icon_*.png glob, and a stale output the
+	// next run won't regenerate
+	userFile := filepath.Join(outputDir, "icon_custom.png")
+	if err := saveImage(testImg, userFile); err != nil {
+		t.Fatalf("Failed to create user file: %v", err)
+	}
+
+	config.RadiusPercent = 0
+	config.Clean = true
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons with --clean: %v", err)
+	}
+
+	if _, err := os.Stat(userFile); err != nil {
+		t.Errorf("Expected --clean to keep files icongen didn't create: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_16x16_rounded.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected --clean to remove stale outputs of the previous run")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_16x16.png")); err != nil {
+		t.Errorf("Expected icons to be regenerated after --clean: %v", err)
+	}
+
+	config.Clean = false
+	config.CleanAll = true
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons with --clean-all: %v", err)
+	}
+	if _, err := os.Stat(userFile); !os.IsNotExist(err) {
+		t.Errorf("Expected --clean-all to remove every icon_*.png")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// iconVariant is a masked copy generated next to every regular icon and
+// saved as icon_<size>_<Name>.png.
+type iconVariant struct {
+	Name string
+	// label and describe summarize the variant at a pixel size for progress
+	// output and plans
+	label    func(size int) string
+	describe func(size int) map[string]string
+	mask     func(img image.Image, size int) image.Image
+}
+
+// maskShapes are the shapes --mask accepts, each producing a variant named
+// after the shape.
+var maskShapes = map[string]func(img image.Image) image.Image{
+	"circle": addCircleMask,
+}
+
+// parseMasks parses a comma-separated --mask spec such as "circle".
+func parseMasks(spec string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		if _, ok := maskShapes[name]; !ok {
+			return nil, fmt.Errorf("unknown mask %q (expected circle)", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate mask %q", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// iconVariants lists the masked variants config asks for: rounded corners
+// when a radius is set, then every --mask shape, then the --mask-image.
+func iconVariants(config Config) []iconVariant {
+	var variants []iconVariant
+
+	if hasRoundedVariant(config) {
+		radius := func(size int) int { return cornerRadius(config, size) }
+		variants = append(variants, iconVariant{
+			Name:  "rounded",
+			label: func(size int) string { return fmt.Sprintf("r=%d", radius(size)) },
+			describe: func(size int) map[string]string {
+				settings := map[string]string{"radius": strconv.Itoa(radius(size))}
+				if config.CornerSmoothing > 0 {
+					settings["corner-smoothing"] = strconv.FormatFloat(config.CornerSmoothing, 'f', -1, 64)
+				}
+				return settings
+			},
+			mask: func(img image.Image, size int) image.Image {
+				if config.CornerSmoothing > 0 {
+					return addSmoothCorners(img, radius(size), config.CornerSmoothing)
+				}
+				return addRoundedCorners(img, radius(size))
+			},
+		})
+	}
+
+	if config.Mask != "" {
+		names, err := parseMasks(config.Mask)
+		if err != nil {
+			return variants
+		}
+		for _, name := range names {
+			name, shape := name, maskShapes[name]
+			variants = append(variants, iconVariant{
+				Name:     name,
+				label:    func(size int) string { return name },
+				describe: func(size int) map[string]string { return map[string]string{} },
+				mask:     func(img image.Image, size int) image.Image { return shape(img) },
+			})
+		}
+	}
+
+	if config.MaskImage != "" {
+		variants = append(variants, maskImageVariant(config))
+	}
+
+	return variants
+}
+
+// variantsFor returns the variants generated for iconSize. Android launcher
+// icons get no rounded variant: launchers shape them themselves, and
+// ic_launcher_round.png is the round one they read.
+func variantsFor(variants []iconVariant, iconSize IconSize) []iconVariant {
+	if !isLauncherIcon(iconSize) {
+		return variants
+	}
+	var kept []iconVariant
+	for _, variant := range variants {
+		if variant.Name != "rounded" {
+			kept = append(kept, variant)
+		}
+	}
+	return kept
+}
+
+// variantIconName returns the file name of the variant of name.
+func variantIconName(name, variant string) string {
+	return strings.TrimSuffix(name, ".png") + "_" + variant + ".png"
+}
+
+// addCircleMask returns img cut to the largest centered circle, with an
+// anti-aliased edge.
+func addCircleMask(img image.Image) image.Image {
+	const samples = 4
+
+	bounds := img.Bounds()
+	masked, scale := maskedCopy(img)
+
+	cx := float64(bounds.Dx()) / 2
+	cy := float64(bounds.Dy()) / 2
+	r := math.Min(cx, cy)
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			// Fraction of the pixel's subsamples inside the circle
+			inside := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					dx := float64(x) + (float64(sx)+0.5)/samples - cx
+					dy := float64(y) + (float64(sy)+0.5)/samples - cy
+					if dx*dx+dy*dy <= r*r {
+						inside++
+					}
+				}
+			}
+			if inside == samples*samples {
+				continue
+			}
+
+			scale(x, y, float64(inside)/(samples*samples))
+		}
+	}
+
+	return masked
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddCircleMask(t *testing.T) {
+	img := createTestImage(64, color.RGBA{255, 0, 0, 255})
+	masked := addCircleMask(img)
+
+	tests := []struct {
+		name   string
+		x, y   int
+		opaque bool
+	}{
+		{"center", 32, 32, true},
+		{"edge midpoint", 1, 32, true},
+		{"corner", 2, 2, false},
+		{"far corner", 62, 62, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, a := masked.At(tt.x, tt.y).RGBA()
+			if tt.opaque && a != 0xffff {
+				t.Errorf("Expected opaque pixel at (%d,%d), got alpha %d", tt.x, tt.y, a)
+			}
+			if !tt.opaque && a != 0 {
+				t.Errorf("Expected transparent pixel at (%d,%d), got alpha %d", tt.x, tt.y, a)
+			}
+		})
+	}
+
+	// The edge is anti-aliased rather than cut
+	partial := false
+	for x := 0; x < 64 && !partial; x++ {
+		if _, _, _, a := masked.At(x, 10).RGBA(); a > 0 && a < 0xffff {
+			partial = true
+		}
+	}
+	if !partial {
+		t.Errorf("Expected anti-aliased pixels along the circle's edge")
+	}
+}
+
+func TestParseMasks(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"circle", false},
+		{"square", true},
+		{"circle,circle", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseMasks(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenerateCircleVariants(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 128, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		Mask:          "circle",
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range iconSizes {
+		for _, variant := range []string{"rounded", "circle"} {
+			path := filepath.Join(outputDir, variantIconName(iconSize.Name, variant))
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s variant %s: %v", variant, filepath.Base(path), err)
+			}
+		}
+	}
+}
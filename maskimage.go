@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// maskChannels are the channels of a --mask-image that --mask-channel can
+// select as the mask's coverage.
+var maskChannels = map[string]func(c color.Color) uint32{
+	"alpha": func(c color.Color) uint32 {
+		_, _, _, a := c.RGBA()
+		return a
+	},
+	"luminance": func(c color.Color) uint32 {
+		// Transparent pixels count as black
+		return uint32(color.Gray16Model.Convert(c).(color.Gray16).Y)
+	},
+}
+
+// maskImageVariant returns the variant --mask-image produces, named
+// "masked". The mask image is loaded on first use and scaled to every size.
+func maskImageVariant(config Config) iconVariant {
+	channel := config.MaskChannel
+	if channel == "" {
+		channel = "alpha"
+	}
+	coverage := maskChannels[channel]
+
+	var mask image.Image
+	return iconVariant{
+		Name:  "masked",
+		label: func(size int) string { return "mask " + config.MaskImage },
+		describe: func(size int) map[string]string {
+			return map[string]string{"mask-image": config.MaskImage, "mask-channel": channel}
+		},
+		mask: func(img image.Image, size int) image.Image {
+			if mask == nil {
+				// validateConfig already checked the mask decodes
+				m, err := loadImage(config.MaskImage)
+				if err != nil {
+					return img
+				}
+				mask = m
+			}
+			return applyMaskImage(img, resizeImage(mask, size), coverage)
+		},
+	}
+}
+
+// applyMaskImage scales every pixel of img by the coverage of the pixel at
+// the same position in mask. Pixels outside the mask are cleared.
+func applyMaskImage(img, mask image.Image, coverage func(c color.Color) uint32) image.Image {
+	bounds := img.Bounds()
+	maskBounds := mask.Bounds()
+	masked := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			mp := image.Pt(maskBounds.Min.X+x, maskBounds.Min.Y+y)
+			if !mp.In(maskBounds) {
+				continue
+			}
+			cov := coverage(mask.At(mp.X, mp.Y))
+			if cov == 0 {
+				continue
+			}
+
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			scale := func(v uint32) uint16 { return uint16(v * cov / 0xffff) }
+			masked.SetRGBA64(x, y, color.RGBA64{scale(r), scale(g), scale(b), scale(a)})
+		}
+	}
+
+	return masked
+}
+
+// validateMaskImage checks that the --mask-image exists and decodes, without
+// decoding one larger than maxPixels (0 for no limit).
+func validateMaskImage(path string, maxPixels int) error {
+	if maxPixels > 0 {
+		width, height, err := imageDimensions(path)
+		if err != nil {
+			return fmt.Errorf("failed to load mask image %s: %w", path, err)
+		}
+		if pixels := int64(width) * int64(height); pixels > int64(maxPixels) {
+			return fmt.Errorf("mask image %s is %dx%d (%d pixels), over --max-pixels=%d", path, width, height, pixels, maxPixels)
+		}
+	}
+	if _, err := loadImage(path); err != nil {
+		return fmt.Errorf("failed to load mask image %s: %w", path, err)
+	}
+	return nil
+}
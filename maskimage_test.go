@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyMaskImage(t *testing.T) {
+	img := createTestImage(32, color.RGBA{255, 0, 0, 255})
+
+	tests := []struct {
+		name    string
+		channel string
+		mask    color.RGBA
+		inside  uint32
+	}{
+		// A white square on transparent: alpha and luminance agree
+		{"alpha", "alpha", color.RGBA{255, 255, 255, 255}, 0xffff},
+		{"luminance", "luminance", color.RGBA{255, 255, 255, 255}, 0xffff},
+		// An opaque black square masks everything by luminance, nothing by alpha
+		{"alpha of black", "alpha", color.RGBA{0, 0, 0, 255}, 0xffff},
+		{"luminance of black", "luminance", color.RGBA{0, 0, 0, 255}, 0},
+		// Half-transparent white keeps half the alpha
+		{"partial alpha", "alpha", color.RGBA{128, 128, 128, 128}, 0x8080},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mask := createTestImageWithSquare(32, 16, tt.mask)
+			masked := applyMaskImage(img, mask, maskChannels[tt.channel])
+
+			if _, _, _, a := masked.At(16, 16).RGBA(); a != tt.inside {
+				t.Errorf("Expected alpha %d inside the mask, got %d", tt.inside, a)
+			}
+			if _, _, _, a := masked.At(2, 2).RGBA(); a != 0 {
+				t.Errorf("Expected transparent pixel outside the mask, got alpha %d", a)
+			}
+		})
+	}
+}
+
+func TestMaskImageVariant(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 0, 255, 255}))
+	maskPath := createTempImageFile(t, createTestImageWithSquare(100, 50, color.RGBA{255, 255, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:   inputPath,
+		OutputDir:   outputDir,
+		TrimPercent: 80,
+		MaskImage:   maskPath,
+		MaskChannel: "alpha",
+	}
+	if err := validateConfig(config); err != nil {
+		t.Fatalf("Expected valid config, got: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	img, err := loadImage(filepath.Join(outputDir, "icon_128x128_masked.png"))
+	if err != nil {
+		t.Fatalf("Expected masked variant: %v", err)
+	}
+	if _, _, _, a := img.At(64, 64).RGBA(); a != 0xffff {
+		t.Errorf("Expected opaque center, got alpha %d", a)
+	}
+	if _, _, _, a := img.At(10, 10).RGBA(); a != 0 {
+		t.Errorf("Expected mask to clear the outside, got alpha %d", a)
+	}
+
+	// Changing the mask's pixels invalidates the previous outputs
+	before, _ := hashOptions(config)
+	if err := saveImage(createTestImage(100, color.RGBA{255, 255, 255, 255}), maskPath); err != nil {
+		t.Fatalf("Failed to rewrite mask: %v", err)
+	}
+	if after, _ := hashOptions(config); after == before {
+		t.Errorf("Expected options hash to change with the mask image")
+	}
+}
+
+func TestMaskImageValidation(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 0, 255, 255}))
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"missing mask", Config{InputPath: inputPath, TrimPercent: 80, MaskImage: filepath.Join(t.TempDir(), "none.png")}},
+		{"unknown channel", Config{InputPath: inputPath, TrimPercent: 80, MaskImage: inputPath, MaskChannel: "red"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateConfig(tt.config); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
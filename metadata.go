@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// icongenKeywords are the text keywords icongen writes itself, which --meta
+// can't set.
+var icongenKeywords = []string{"Version", "Software", "Source SHA-256", "Creation Time", settingsKeyword}
+
+// parseMeta parses a comma-separated --meta spec of the form
+// key=value[,key=value...] into text chunks, in order.
+func parseMeta(spec string) ([]pngTextChunk, error) {
+	var chunks []pngTextChunk
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata %q (expected key=value)", entry)
+		}
+		if err := validateKeyword(key); err != nil {
+			return nil, err
+		}
+		for _, reserved := range icongenKeywords {
+			if key == reserved {
+				return nil, fmt.Errorf("metadata key %q is written by icongen itself", key)
+			}
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("metadata key %q is set twice", key)
+		}
+		seen[key] = true
+		chunks = append(chunks, pngTextChunk{key, value})
+	}
+	return chunks, nil
+}
+
+// validateKeyword checks that key can be a PNG text keyword: 1 to 79
+// printable characters, without leading, trailing or consecutive spaces.
+// icongen keeps keywords to ASCII, which every reader handles.
+func validateKeyword(key string) error {
+	if key == "" || len(key) > 79 {
+		return fmt.Errorf("invalid metadata key %q (expected 1 to 79 characters)", key)
+	}
+	for _, r := range key {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("invalid metadata key %q (use printable ASCII characters)", key)
+		}
+	}
+	if strings.TrimSpace(key) != key || strings.Contains(key, "  ") {
+		return fmt.Errorf("invalid metadata key %q (no leading, trailing or double spaces)", key)
+	}
+	return nil
+}
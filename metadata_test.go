@@ -0,0 +1,68 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetaText(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Provenance: true,
+		Meta: "Copyright=Example Inc.,Author=Zoë Müller"}
+	if err := validateOptions(config); err != nil {
+		t.Fatalf("Expected valid --meta: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	for keyword, want := range map[string]string{
+		"Copyright": "Example Inc.",
+		"Author":    "Zoë Müller",
+		"Software":  "icongen",
+	} {
+		if got, ok := pngText(data, keyword); !ok || got != want {
+			t.Errorf("Expected %s %q, got %q", keyword, want, got)
+		}
+	}
+
+	// Non-ASCII text goes in an iTXt chunk, as tEXt is Latin-1
+	if _, ok := pngChunkData(data, "iTXt"); !ok {
+		t.Errorf("Expected an iTXt chunk for the non-ASCII author")
+	}
+	if _, err := loadImage(filepath.Join(outputDir, "icon_32x32.png")); err != nil {
+		t.Errorf("Expected the icon to stay a valid PNG: %v", err)
+	}
+}
+
+func TestParseMetaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"no value", "Copyright"},
+		{"empty key", "=value"},
+		{"long key", string(make([]byte, 80)) + "=value"},
+		{"non-ASCII key", "Urheber©=value"},
+		{"leading space", " Copyright=value"},
+		{"double space", "Copy  right=value"},
+		{"icongen keyword", "Software=other"},
+		{"settings keyword", "icongen settings={}"},
+		{"duplicate", "Copyright=a,Copyright=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMeta(tt.spec); err == nil {
+				t.Errorf("Expected an error for --meta %q", tt.spec)
+			}
+		})
+	}
+}
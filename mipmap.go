@@ -0,0 +1,71 @@
+package main
+
+import "image"
+
+// mipmapImage is a prepared source with a chain of downscaled copies, so the
+// artwork of each icon is resized from the closest copy at least as large
+// instead of from the full resolution source. It reads as the source itself.
+type mipmapImage struct {
+	image.Image
+	// levels are squares, largest first, each half the size of the one
+	// before
+	levels []*image.RGBA
+}
+
+// withMipmaps resizes img once to the largest size its artwork is resized
+// to, or its own size if that is smaller, and halves that with a box filter
+// down to the smallest one. Nearest-neighbor resizing keeps img as it is,
+// since averaging would blur pixel art, and so does a 16-bit img, which
+// every size is resized from directly to keep its precision.
+func withMipmaps(img image.Image, config Config) image.Image {
+	sizes := outputSizes(config)
+	if config.PixelArt != "" || config.Filter == "nearest" || len(sizes) == 0 || deepImage(img) {
+		return img
+	}
+
+	scale := layerScale(config.ForegroundScale)
+	largest, smallest := 0, sizes[0].Size
+	for _, iconSize := range sizes {
+		if iconSize.Size > largest {
+			largest = iconSize.Size
+		}
+		if iconSize.Size < smallest {
+			smallest = iconSize.Size
+		}
+	}
+	largest = largest * scale / 100
+	smallest = smallest * scale / 100
+
+	bounds := img.Bounds()
+	top := bounds.Dx()
+	if bounds.Dy() > top {
+		top = bounds.Dy()
+	}
+	if largest < top {
+		top = largest
+	}
+	if top < 2 || smallest < 1 {
+		return img
+	}
+
+	halve := resizeFiltered
+	if config.LinearLight {
+		halve = resizeLinear
+	}
+	levels := []*image.RGBA{resizer(config)(img, top)}
+	for size := top / 2; size >= smallest; size /= 2 {
+		levels = append(levels, halve(levels[len(levels)-1], size, boxFilter))
+	}
+	return &mipmapImage{Image: img, levels: levels}
+}
+
+// level returns the smallest copy of the source at least size pixels
+// across, or the source itself when size is larger than every copy.
+func (m *mipmapImage) level(size int) image.Image {
+	for i := len(m.levels) - 1; i >= 0; i-- {
+		if m.levels[i].Bounds().Dx() >= size {
+			return m.levels[i]
+		}
+	}
+	return m.Image
+}
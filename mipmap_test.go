@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWithMipmaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		source int
+		config Config
+		expect []int
+	}{
+		{"macos", 1500, Config{Preset: "macos"}, []int{1024, 512, 256, 128, 64, 32, 16}},
+		{"android", 1500, Config{Preset: "android"}, []int{192, 96, 48}},
+		{"small source", 100, Config{Preset: "macos"}, []int{100, 50, 25}},
+		{"foreground scale", 1500, Config{Preset: "android", ForegroundScale: 50}, []int{96, 48, 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := withMipmaps(createTestImage(tt.source, color.RGBA{255, 128, 0, 255}), tt.config)
+			m, ok := img.(*mipmapImage)
+			if !ok {
+				t.Fatalf("Expected mipmaps, got %T", img)
+			}
+			var sizes []int
+			for _, level := range m.levels {
+				sizes = append(sizes, level.Bounds().Dx())
+			}
+			if len(sizes) != len(tt.expect) {
+				t.Fatalf("Expected levels %v, got %v", tt.expect, sizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.expect[i] {
+					t.Errorf("Expected levels %v, got %v", tt.expect, sizes)
+					break
+				}
+			}
+			if m.Bounds() != image.Rect(0, 0, tt.source, tt.source) {
+				t.Errorf("Expected the mipmaps to read as the source, got %v", m.Bounds())
+			}
+		})
+	}
+
+	for _, config := range []Config{{PixelArt: "nearest"}, {Filter: "nearest"}} {
+		if _, ok := withMipmaps(createTestImage(64, color.RGBA{255, 0, 0, 255}), config).(*mipmapImage); ok {
+			t.Errorf("Expected no mipmaps for %+v", config)
+		}
+	}
+}
+
+func TestMipmapLevel(t *testing.T) {
+	source := createTestImage(1500, color.RGBA{255, 128, 0, 255})
+	m := withMipmaps(source, Config{Preset: "macos"}).(*mipmapImage)
+
+	tests := []struct {
+		size   int
+		expect int
+	}{
+		{16, 16},
+		{20, 32},
+		{180, 256},
+		{1024, 1024},
+		{1200, 1500},
+	}
+
+	for _, tt := range tests {
+		if got := m.level(tt.size).Bounds().Dx(); got != tt.expect {
+			t.Errorf("level(%d): expected %dpx, got %dpx", tt.size, tt.expect, got)
+		}
+	}
+}
+
+func TestMipmapResize(t *testing.T) {
+	// A one-pixel checkerboard averages to purple at every size
+	config := Config{Preset: "macos", LinearLight: true}
+	m := withMipmaps(checkerboard(1024), config)
+
+	for _, size := range []int{16, 24, 32} {
+		resized := resizer(config)(m, size)
+		if resized.Bounds() != image.Rect(0, 0, size, size) {
+			t.Fatalf("Expected a %dpx square, got %v", size, resized.Bounds())
+		}
+		if c := resized.RGBAAt(size/2, size/2); c.R < 170 || c.B < 170 || c.R > 195 || c.B > 195 {
+			t.Errorf("Expected an even linear-light purple at %dpx, got %v", size, c)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+)
+
+// Android notification icons are 24dp white silhouettes on transparency;
+// the system tints them and ignores their colors. The artwork fills the
+// 22dp live area inside a 1dp margin.
+const (
+	notificationPreset  = "android-notification"
+	notificationIcon    = "ic_stat_notification.png"
+	notificationArtwork = 22 * 100 / 24
+)
+
+// notificationSizes are the outputs of the android-notification preset.
+var notificationSizes = []IconSize{
+	{"drawable-mdpi/" + notificationIcon, 24},
+	{"drawable-hdpi/" + notificationIcon, 36},
+	{"drawable-xhdpi/" + notificationIcon, 48},
+	{"drawable-xxhdpi/" + notificationIcon, 72},
+	{"drawable-xxxhdpi/" + notificationIcon, 96},
+}
+
+// isNotificationIcon reports whether iconSize is one of the
+// android-notification preset's silhouettes, which are generated on their
+// own rather than as regular icons with variants.
+func isNotificationIcon(iconSize IconSize) bool {
+	dir, name := filepath.Split(iconSize.Name)
+	return strings.HasPrefix(dir, "drawable-") && name == notificationIcon
+}
+
+// notificationMode returns the --monochrome mode the notification
+// silhouette is extracted from img with: the one --monochrome sets, else
+// alpha if img has any transparency to take the shape from, and threshold,
+// its dark pixels, if it is opaque.
+func notificationMode(config Config, img image.Image) string {
+	if config.Monochrome != "" {
+		return config.Monochrome
+	}
+	bounds := img.Bounds()
+	at := pixelReader(img)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := at(x, y); a != 0xffff {
+				return "alpha"
+			}
+		}
+	}
+	return "threshold"
+}
+
+// notificationTarget describes the output of the notification icon
+// iconSize.
+func notificationTarget(iconSize IconSize) plannedOutput {
+	return plannedOutput{
+		Name:    iconSize.Name,
+		Width:   iconSize.Size,
+		Height:  iconSize.Size,
+		Format:  "png",
+		Variant: "notification",
+	}
+}
+
+// notificationSilhouette returns a function extracting the white silhouette
+// of sourceImg the notification icons, complication templates and macOS
+// template images are made from, once, on first use.
+func notificationSilhouette(sourceImg image.Image, config Config) func() image.Image {
+	var silhouette image.Image
+	return func() image.Image {
+		if silhouette == nil {
+			silhouette = monochromeModes[notificationMode(config, sourceImg)](sourceImg)
+		}
+		return silhouette
+	}
+}
+
+// renderNotificationIcon returns the label and renderer of the notification
+// icon iconSize: the silhouette, scaled into the live area. Backgrounds,
+// effects, overlays and masks don't apply, as only the shape shows.
+func renderNotificationIcon(silhouette func() image.Image, config Config, iconSize IconSize) (string, func() image.Image) {
+	label := fmt.Sprintf("%dx%d, notification", iconSize.Size, iconSize.Size)
+	return label, func() image.Image {
+		return scaleForeground(silhouette(), iconSize.Size, notificationArtwork, depthResizer(config))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotificationIcons(t *testing.T) {
+	// A red square on transparency
+	inputPath := createTempImageFile(t, createTestImageWithSquare(200, 120, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "android," + notificationPreset, TrimPercent: 80, RadiusPercent: 20, Background: "#3366CC"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range notificationSizes {
+		img, err := loadImage(filepath.Join(outputDir, iconSize.Name))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", iconSize.Name, err)
+		}
+		if img.Bounds().Dx() != iconSize.Size {
+			t.Errorf("%s: expected %dpx, got %dpx", iconSize.Name, iconSize.Size, img.Bounds().Dx())
+		}
+		center := color.NRGBAModel.Convert(img.At(iconSize.Size/2, iconSize.Size/2)).(color.NRGBA)
+		if center != (color.NRGBA{255, 255, 255, 255}) {
+			t.Errorf("%s: expected a white silhouette, got %v", iconSize.Name, center)
+		}
+		if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("%s: expected transparency around the silhouette, without the background", iconSize.Name)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, roundedIconName(iconSize.Name))); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no rounded variant, got %v", iconSize.Name, err)
+		}
+	}
+
+	// The launcher icons come with their round twins
+	if _, err := os.Stat(filepath.Join(outputDir, "mipmap-mdpi/ic_launcher_round.png")); err != nil {
+		t.Errorf("Expected the round launcher icons: %v", err)
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	notifications := 0
+	for _, target := range targets {
+		if target.Variant == "notification" {
+			notifications++
+		}
+	}
+	if notifications != len(notificationSizes) {
+		t.Errorf("Expected %d notification targets, got %d", len(notificationSizes), notifications)
+	}
+}
+
+func TestNotificationMode(t *testing.T) {
+	transparent := createTestImageWithSquare(32, 16, color.RGBA{255, 0, 0, 255})
+	opaque := createTestImageWithBorder(32, color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, 8)
+
+	tests := []struct {
+		name   string
+		config Config
+		img    image.Image
+		want   string
+	}{
+		{"transparent source", Config{}, transparent, "alpha"},
+		{"opaque source", Config{}, opaque, "threshold"},
+		{"--monochrome", Config{Monochrome: "threshold"}, transparent, "threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notificationMode(tt.config, tt.img); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// outputQueue writes rendered outputs in the background, up to --jobs at a
+// time, so encoding the large PNGs overlaps with rendering the next outputs
+// and with each other. Rendering stays on the calling goroutine, and the
+// outputs are recorded in the manifest in the order they were queued, so
+// the manifest doesn't depend on which encoder finishes first.
+type outputQueue struct {
+	slots   chan struct{}
+	pending []queuedOutput
+}
+
+type queuedOutput struct {
+	name string
+	done chan error
+}
+
+// newOutputQueue returns a queue running jobs writers, one per CPU for 0,
+// or nil to write every output right away for a single job.
+func newOutputQueue(jobs int) *outputQueue {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs == 1 {
+		return nil
+	}
+	return &outputQueue{slots: make(chan struct{}, jobs)}
+}
+
+// enqueue writes the output name with write and records it, in the
+// background when outputs are written concurrently. It waits for a free
+// writer first, so at most --jobs rendered outputs are held in memory.
+func (s *manifestState) enqueue(name string, write func() error) error {
+	if s.outputs == nil {
+		if err := write(); err != nil {
+			return err
+		}
+		if err := s.recordFile(name); err != nil {
+			return fmt.Errorf("failed to record %s: %w", name, err)
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	s.outputs.slots <- struct{}{}
+	go func() {
+		done <- write()
+		<-s.outputs.slots
+	}()
+	s.outputs.pending = append(s.outputs.pending, queuedOutput{name, done})
+	return s.recordWritten(false)
+}
+
+// flush waits for every queued output and records them, returning the first
+// error any of them ran into.
+func (s *manifestState) flush() error {
+	return s.recordWritten(true)
+}
+
+// recordWritten records the queued outputs that are written, in the order
+// they were queued. Without wait it stops at the first one still being
+// written or failing; with wait it waits for all of them.
+func (s *manifestState) recordWritten(wait bool) error {
+	if s.outputs == nil {
+		return nil
+	}
+
+	var first error
+	for len(s.outputs.pending) > 0 {
+		next := s.outputs.pending[0]
+		var err error
+		if wait {
+			err = <-next.done
+		} else {
+			select {
+			case err = <-next.done:
+			default:
+				return nil
+			}
+		}
+		s.outputs.pending = s.outputs.pending[1:]
+
+		if err == nil {
+			if err = s.recordFile(next.name); err != nil {
+				err = fmt.Errorf("failed to record %s: %w", next.name, err)
+			}
+		}
+		if err != nil && !wait {
+			return err
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
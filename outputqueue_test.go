@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConcurrentOutputs(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(300, color.RGBA{255, 128, 0, 255}))
+
+	generate := func(jobs int) (string, *manifest) {
+		outputDir := t.TempDir()
+		config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, CropEnabled: true, Mask: "circle", Jobs: jobs}
+		if err := generateIcons(config); err != nil {
+			t.Fatalf("Failed to generate icons with %d jobs: %v", jobs, err)
+		}
+		m, err := readManifest(outputDir)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		return outputDir, m
+	}
+
+	serialDir, serial := generate(1)
+	concurrentDir, concurrent := generate(4)
+
+	if len(serial.Files) != len(concurrent.Files) {
+		t.Fatalf("Expected %d files, got %d", len(serial.Files), len(concurrent.Files))
+	}
+	for i, file := range serial.Files {
+		if concurrent.Files[i].Name != file.Name || concurrent.Files[i].SHA256 != file.SHA256 {
+			t.Errorf("Expected %s at %d in the manifest, got %s", file.Name, i, concurrent.Files[i].Name)
+		}
+		a, _ := os.ReadFile(filepath.Join(serialDir, file.Name))
+		b, _ := os.ReadFile(filepath.Join(concurrentDir, file.Name))
+		if !bytes.Equal(a, b) {
+			t.Errorf("Expected %s to come out the same with --jobs", file.Name)
+		}
+	}
+}
+
+func TestOutputQueueOrder(t *testing.T) {
+	dir := t.TempDir()
+	state := &manifestState{dir: dir, outputs: newOutputQueue(3)}
+
+	// Later outputs finish first, but are recorded in the order queued
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		name, delay := name, time.Duration(3-i)*10*time.Millisecond
+		err := state.enqueue(name, func() error {
+			time.Sleep(delay)
+			return os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+		})
+		if err != nil {
+			t.Fatalf("Failed to queue %s: %v", name, err)
+		}
+	}
+	if err := state.flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	var names []string
+	for _, file := range state.files {
+		names = append(names, file.Name)
+	}
+	if strings.Join(names, ",") != "a.txt,b.txt,c.txt" {
+		t.Errorf("Expected the outputs recorded in order, got %v", names)
+	}
+}
+
+func TestOutputQueueError(t *testing.T) {
+	dir := t.TempDir()
+	state := &manifestState{dir: dir, outputs: newOutputQueue(2)}
+
+	failure := errors.New("disk full")
+	state.enqueue("bad.txt", func() error { return failure })
+	state.enqueue("good.txt", func() error {
+		return os.WriteFile(filepath.Join(dir, "good.txt"), nil, 0644)
+	})
+
+	if err := state.flush(); !errors.Is(err, failure) {
+		t.Errorf("Expected the write error from flush, got %v", err)
+	}
+	if len(state.files) != 1 || state.files[0].Name != "good.txt" {
+		t.Errorf("Expected only good.txt to be recorded, got %v", state.files)
+	}
+	if err := validateOptions(Config{TrimPercent: 80, Jobs: -1}); err == nil {
+		t.Errorf("Expected negative jobs to fail")
+	}
+}
+
+func TestNewOutputQueue(t *testing.T) {
+	if newOutputQueue(1) != nil {
+		t.Errorf("Expected a single job to write outputs right away")
+	}
+	if q := newOutputQueue(4); q == nil || cap(q.slots) != 4 {
+		t.Errorf("Expected four writers, got %v", q)
+	}
+}
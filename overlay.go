@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// overlayAnchors maps every --overlay-anchor to where the overlay sits
+// between the margins, as fractions of the space left across and down.
+var overlayAnchors = map[string][2]float64{
+	"top-left":     {0, 0},
+	"top":          {0.5, 0},
+	"top-right":    {1, 0},
+	"left":         {0, 0.5},
+	"center":       {0.5, 0.5},
+	"right":        {1, 0.5},
+	"bottom-left":  {0, 1},
+	"bottom":       {0.5, 1},
+	"bottom-right": {1, 1},
+}
+
+// overlayMargin keeps the --overlay this far from the edges, as a fraction
+// of the size.
+const overlayMargin = 0.05
+
+var (
+	overlayImagesMu sync.Mutex
+	// overlayImages holds the --overlay images loaded so far, by path
+	overlayImages = map[string]image.Image{}
+)
+
+// parseOverlayScale parses an --overlay-scale percentage such as 25% or 25.
+func parseOverlayScale(spec string) (int, error) {
+	scale, err := strconv.Atoi(strings.TrimSuffix(spec, "%"))
+	if err != nil || scale < 1 || scale > 100 {
+		return 0, fmt.Errorf("overlay scale must be a percentage between 1%% and 100%% (got %q)", spec)
+	}
+	return scale, nil
+}
+
+// validateOverlay checks the --overlay options of config that don't depend
+// on the filesystem.
+func validateOverlay(config Config) error {
+	if _, err := parseOverlayScale(config.OverlayScale); err != nil {
+		return err
+	}
+	if _, ok := overlayAnchors[config.OverlayAnchor]; !ok {
+		return fmt.Errorf("unknown overlay anchor %q (expected top-left, top, top-right, left, center, right, bottom-left, bottom or bottom-right)", config.OverlayAnchor)
+	}
+	if config.OverlayOpacity <= 0 || config.OverlayOpacity > 1 {
+		return fmt.Errorf("overlay opacity must be above 0 and at most 1 (got %g)", config.OverlayOpacity)
+	}
+	return nil
+}
+
+// overlayImage returns the image at path, loading it on first use.
+func overlayImage(path string) (image.Image, error) {
+	overlayImagesMu.Lock()
+	defer overlayImagesMu.Unlock()
+
+	if img, ok := overlayImages[path]; ok {
+		return img, nil
+	}
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load overlay image %s: %w", path, err)
+	}
+	overlayImages[path] = img
+	return img, nil
+}
+
+// addOverlay composites the --overlay image onto img at the --overlay-anchor,
+// fitted into a square of --overlay-scale percent of size and faded to the
+// --overlay-opacity.
+func addOverlay(img image.Image, config Config, size int) image.Image {
+	if config.Overlay == "" {
+		return img
+	}
+	scale, err := parseOverlayScale(config.OverlayScale)
+	if err != nil {
+		return img
+	}
+	anchor, ok := overlayAnchors[config.OverlayAnchor]
+	if !ok {
+		return img
+	}
+	// validateConfig already checked the overlay decodes
+	overlay, err := overlayImage(config.Overlay)
+	if err != nil {
+		return img
+	}
+
+	box := size * scale / 100
+	if box < 1 {
+		return img
+	}
+	// resizeImage centers the fitted overlay in the box; place just the
+	// overlay itself, so it sits flush against the margins
+	bounds := overlay.Bounds()
+	fit := float64(box) / math.Max(float64(bounds.Dx()), float64(bounds.Dy()))
+	width, height := int(float64(bounds.Dx())*fit), int(float64(bounds.Dy())*fit)
+	fitted := resizeImage(overlay, box)
+	from := image.Pt((box-width)/2, (box-height)/2)
+
+	canvas := img.Bounds()
+	margin := float64(size) * overlayMargin
+	x := int(math.Round(margin + (float64(canvas.Dx())-2*margin-float64(width))*anchor[0]))
+	y := int(math.Round(margin + (float64(canvas.Dy())-2*margin-float64(height))*anchor[1]))
+
+	overlaid := toRGBA(img)
+	opacity := image.NewUniform(color.Alpha{uint8(math.Round(config.OverlayOpacity * 255))})
+	draw.DrawMask(overlaid, image.Rect(x, y, x+width, y+height), fitted, from, opacity, image.Point{}, draw.Over)
+	return overlaid
+}
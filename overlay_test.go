@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestValidateOverlay(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"defaults", Config{OverlayScale: "25%", OverlayAnchor: "bottom-right", OverlayOpacity: 1}, false},
+		{"bare percentage", Config{OverlayScale: "40", OverlayAnchor: "center", OverlayOpacity: 0.8}, false},
+		{"scale too large", Config{OverlayScale: "150%", OverlayAnchor: "top", OverlayOpacity: 1}, true},
+		{"scale not a number", Config{OverlayScale: "big", OverlayAnchor: "top", OverlayOpacity: 1}, true},
+		{"unknown anchor", Config{OverlayScale: "25%", OverlayAnchor: "middle", OverlayOpacity: 1}, true},
+		{"opacity zero", Config{OverlayScale: "25%", OverlayAnchor: "top", OverlayOpacity: 0}, true},
+		{"opacity percent", Config{OverlayScale: "25%", OverlayAnchor: "top", OverlayOpacity: 80}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOverlay(tt.config)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddOverlay(t *testing.T) {
+	// A red mark twice as wide as it is tall
+	mark := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			mark.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	config := Config{
+		Overlay:        createTempImageFile(t, mark),
+		OverlayScale:   "50%",
+		OverlayAnchor:  "bottom-right",
+		OverlayOpacity: 1,
+	}
+	icon := createTestImage(200, color.RGBA{0, 0, 255, 255})
+
+	// The mark is fitted to 100x50 and sits flush against the 10 pixel margins
+	overlaid := addOverlay(icon, config, 200)
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{91, 141, color.RGBA{255, 0, 0, 255}},
+		{188, 188, color.RGBA{255, 0, 0, 255}},
+		{89, 170, color.RGBA{0, 0, 255, 255}},
+		{150, 139, color.RGBA{0, 0, 255, 255}},
+		{195, 195, color.RGBA{0, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		if c := color.RGBAModel.Convert(overlaid.At(tt.x, tt.y)).(color.RGBA); c != tt.want {
+			t.Errorf("Expected %v at (%d, %d), got %v", tt.want, tt.x, tt.y, c)
+		}
+	}
+
+	// Opacity blends the mark with the artwork underneath
+	config.OverlayAnchor = "center"
+	config.OverlayOpacity = 0.5
+	overlaid = addOverlay(icon, config, 200)
+	if c := color.RGBAModel.Convert(overlaid.At(100, 100)).(color.RGBA); c.R < 120 || c.R > 135 || c.B < 120 || c.B > 135 {
+		t.Errorf("Expected a half-transparent mark in the center, got %v", c)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// paddingPoint is the padding percentage a --padding curve gives one size.
+type paddingPoint struct {
+	Size    int
+	Percent float64
+}
+
+// paddingCurve is a --padding spec: padding percentages at some sizes, in
+// increasing order of size, which the sizes in between interpolate.
+type paddingCurve []paddingPoint
+
+// parsePaddingCurve parses a comma-separated --padding spec of SIZE:PERCENT
+// entries, e.g. "1024:10%,256:8%,64:5%,16:0%".
+func parsePaddingCurve(spec string) (paddingCurve, error) {
+	var curve paddingCurve
+	seen := make(map[int]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		sizeText, percentText, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		size, err := strconv.Atoi(sizeText)
+		if !ok || err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid padding %q (expected SIZE:PERCENT, e.g. 64:5%%)", entry)
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(percentText, "%"), 64)
+		if err != nil || percent < 0 || percent > 50 {
+			return nil, fmt.Errorf("invalid padding %q (expected a percentage between 0 and 50)", entry)
+		}
+		if seen[size] {
+			return nil, fmt.Errorf("duplicate padding size %d", size)
+		}
+		seen[size] = true
+		curve = append(curve, paddingPoint{size, percent})
+	}
+	sort.Slice(curve, func(i, j int) bool { return curve[i].Size < curve[j].Size })
+	return curve, nil
+}
+
+// resolve returns the padding percentage at size. Sizes between two points
+// interpolate on a logarithmic scale, so each doubling of the size moves
+// the same distance along the curve; sizes beyond the ends take the nearest
+// end's percentage.
+func (c paddingCurve) resolve(size int) float64 {
+	if size <= c[0].Size {
+		return c[0].Percent
+	}
+	for i := 1; i < len(c); i++ {
+		if size <= c[i].Size {
+			lo, hi := c[i-1], c[i]
+			t := math.Log2(float64(size)/float64(lo.Size)) / math.Log2(float64(hi.Size)/float64(lo.Size))
+			return lo.Percent + (hi.Percent-lo.Percent)*t
+		}
+	}
+	return c[len(c)-1].Percent
+}
+
+// paddingPercent returns the padding percentage of an icon of size: from
+// the --padding curve when there is one, or the --padding-percent.
+func paddingPercent(config Config, size int) float64 {
+	if config.Padding == "" {
+		return float64(config.PaddingPercent)
+	}
+	curve, err := parsePaddingCurve(config.Padding)
+	if err != nil {
+		return 0
+	}
+	return curve.resolve(size)
+}
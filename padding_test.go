@@ -0,0 +1,89 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestParsePaddingCurve(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    paddingCurve
+		wantErr bool
+	}{
+		{"1024:10%,256:8%,64:5%,16:0%", paddingCurve{{16, 0}, {64, 5}, {256, 8}, {1024, 10}}, false},
+		{"32:2.5", paddingCurve{{32, 2.5}}, false},
+		{"64:5%, 16:0%", paddingCurve{{16, 0}, {64, 5}}, false},
+		{"64", nil, true},
+		{"0:5%", nil, true},
+		{"64:60%", nil, true},
+		{"64:five", nil, true},
+		{"64:5%,64:6%", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			curve, err := parsePaddingCurve(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(curve) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, curve)
+			}
+			for i := range curve {
+				if curve[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, curve)
+				}
+			}
+		})
+	}
+}
+
+func TestPaddingCurveResolve(t *testing.T) {
+	curve, _ := parsePaddingCurve("1024:10%,256:8%,64:5%,16:0%")
+	tests := []struct {
+		size int
+		want float64
+	}{
+		{8, 0},
+		{16, 0},
+		{32, 2.5},
+		{64, 5},
+		{128, 6.5},
+		{512, 9},
+		{1024, 10},
+		{2048, 10},
+	}
+	for _, tt := range tests {
+		if got := curve.resolve(tt.size); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%dpx: expected %v%%, got %v%%", tt.size, tt.want, got)
+		}
+	}
+}
+
+func TestPaddingPerSize(t *testing.T) {
+	config := Config{Padding: "1024:10%,64:5%,16:0%"}
+	if hasPadding(config, IconSize{"icon_16x16.png", 16}) {
+		t.Errorf("Expected no padding at 16px")
+	}
+	if !hasPadding(config, IconSize{"icon_64x64.png", 64}) {
+		t.Errorf("Expected padding at 64px")
+	}
+
+	// An opaque 64px icon padded by 5% keeps a 3px transparent margin
+	icon := createTestImage(64, color.RGBA{255, 0, 0, 255})
+	padded := toRGBA(padIcon(icon, config, IconSize{"icon_64x64.png", 64}))
+	if a := padded.RGBAAt(1, 32).A; a != 0 {
+		t.Errorf("Expected a transparent margin, got alpha %d", a)
+	}
+	if a := padded.RGBAAt(32, 32).A; a != 255 {
+		t.Errorf("Expected the artwork in the middle, got alpha %d", a)
+	}
+
+	if err := validateOptions(Config{Padding: "64:5%", PaddingPercent: 10}); err == nil {
+		t.Errorf("Expected --padding and --padding-percent to conflict")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const reportPDFName = "contact_sheet.pdf"
+
+func init() {
+	registerFormat(formatBackend{Name: "pdf", Extensions: []string{".pdf"}, Encode: true})
+}
+
+// pdfDocument assembles a minimal PDF file. Objects are numbered in the order
+// they are added, starting at 1.
+type pdfDocument struct {
+	objects [][]byte
+}
+
+// reserve allocates an object number to be filled in later with set.
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(n int, obj string) {
+	d.objects[n-1] = []byte(obj)
+}
+
+func (d *pdfDocument) add(obj string) int {
+	n := d.reserve()
+	d.set(n, obj)
+	return n
+}
+
+// addStream adds a stream object with the given dictionary entries.
+func (d *pdfDocument) addStream(dict string, data []byte) int {
+	n := d.reserve()
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	obj.Write(data)
+	obj.WriteString("\nendstream")
+	d.objects[n-1] = obj.Bytes()
+	return n
+}
+
+// addImage adds img as an RGB image XObject with its alpha channel as a soft
+// mask, both Flate-compressed.
+func (d *pdfDocument) addImage(img image.Image) (int, error) {
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a > 0 {
+				// PDF images are not premultiplied
+				r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+			}
+			rgb = append(rgb, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			alpha = append(alpha, uint8(a>>8))
+		}
+	}
+
+	compressedAlpha, err := deflate(alpha)
+	if err != nil {
+		return 0, err
+	}
+	compressedRGB, err := deflate(rgb)
+	if err != nil {
+		return 0, err
+	}
+
+	mask := d.addStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode",
+		bounds.Dx(), bounds.Dy()), compressedAlpha)
+
+	return d.addStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /SMask %d 0 R /Filter /FlateDecode",
+		bounds.Dx(), bounds.Dy(), mask), compressedRGB), nil
+}
+
+// bytes serializes the document with root as the catalog object.
+func (d *pdfDocument) bytes(root int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, obj := range d.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, root, xref)
+
+	return buf.Bytes()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfString escapes s as a PDF literal string. Only printable ASCII is passed
+// through; other characters are replaced.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// reportPDFTargets lists the outputs --report-pdf adds to a run.
+func reportPDFTargets(config Config) []plannedOutput {
+	if !config.ReportPDF {
+		return nil
+	}
+	return []plannedOutput{{Name: reportPDFName, Format: "pdf", Variant: "report"}}
+}
+
+// settingsSummary lists the options that affect the generated pixels as
+// "Name: value" lines, skipping those left at their zero value.
+func settingsSummary(config Config) []string {
+	data, _ := json.Marshal(config)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+
+	var lines []string
+	for name, value := range fields {
+		switch v := value.(type) {
+		case bool:
+			if !v {
+				continue
+			}
+		case float64:
+			if v == 0 {
+				continue
+			}
+		case string:
+			if v == "" {
+				continue
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %v", name, value))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// writeReportPDF writes a printable A4 contact sheet of every image recorded
+// so far in state, with file names, dimensions and the generation settings.
+func writeReportPDF(config Config, state *manifestState) error {
+	const (
+		pageWidth  = 595.0
+		pageHeight = 842.0
+		margin     = 40.0
+		columns    = 4
+		cellWidth  = (pageWidth - 2*margin) / columns
+		cellHeight = 130.0
+		maxIcon    = 96.0
+	)
+
+	doc := &pdfDocument{}
+	catalog := doc.reserve()
+	pages := doc.reserve()
+	font := doc.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	var pageRefs []string
+	var content bytes.Buffer
+	var xobjects []string
+
+	text := func(x, y, size float64, s string) {
+		fmt.Fprintf(&content, "BT /F1 %.1f Tf %.2f %.2f Td %s Tj ET\n", size, x, y, pdfString(s))
+	}
+
+	flushPage := func() {
+		stream := doc.addStream("", content.Bytes())
+		page := doc.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 %d 0 R >> /XObject << %s >> >> /Contents %d 0 R >>",
+			pages, pageWidth, pageHeight, font, strings.Join(xobjects, " "), stream))
+		pageRefs = append(pageRefs, fmt.Sprintf("%d 0 R", page))
+		content.Reset()
+		xobjects = nil
+	}
+
+	// Title and settings on the first page
+	y := pageHeight - margin - 18
+	text(margin, y, 18, "Icons generated from "+filepath.Base(config.InputPath))
+	y -= 24
+	for _, line := range settingsSummary(config) {
+		text(margin, y, 9, line)
+		y -= 12
+	}
+	y -= 12
+
+	column := 0
+	for _, file := range iconFiles(state) {
+		if column == 0 && y-cellHeight < margin {
+			flushPage()
+			y = pageHeight - margin
+		}
+
+		img, err := loadImage(filepath.Join(config.OutputDir, file.Name))
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", file.Name, err)
+		}
+		ref, err := doc.addImage(img)
+		if err != nil {
+			return err
+		}
+		imageName := fmt.Sprintf("Im%d", ref)
+		xobjects = append(xobjects, fmt.Sprintf("/%s %d 0 R", imageName, ref))
+
+		// Icons are shown at one point per pixel, capped to fit the cell
+		w := float64(file.Width)
+		h := float64(file.Height)
+		if scale := maxIcon / math.Max(w, h); scale < 1 {
+			w, h = w*scale, h*scale
+		}
+		x := margin + float64(column)*cellWidth
+		fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n",
+			w, h, x+(cellWidth-w)/2, y-maxIcon+(maxIcon-h)/2, imageName)
+		text(x+4, y-maxIcon-14, 7, file.Name)
+		text(x+4, y-maxIcon-23, 7, fmt.Sprintf("%dx%d, %d bytes", file.Width, file.Height, file.Bytes))
+
+		column++
+		if column == columns {
+			column = 0
+			y -= cellHeight
+		}
+	}
+	flushPage()
+
+	doc.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(pageRefs)))
+	doc.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+
+	fmt.Fprintf(logOutput(config), " - %s (%d pages)\n", reportPDFName, len(pageRefs))
+	if err := os.WriteFile(filepath.Join(config.OutputDir, reportPDFName), doc.bytes(catalog), 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", reportPDFName, err)
+	}
+
+	if err := state.record(reportPDFName); err != nil {
+		return fmt.Errorf("failed to record %s: %w", reportPDFName, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"icon_16x16.png", "(icon_16x16.png)"},
+		{"a (b) c\\d", `(a \(b\) c\\d)`},
+		{"16×16", "(16?16)"},
+	}
+
+	for _, tt := range tests {
+		if got := pdfString(tt.input); got != tt.expected {
+			t.Errorf("pdfString(%q) = %s, expected %s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteReportPDF(t *testing.T) {
+	testImg := createTestImageWithSquare(64, 40, color.RGBA{255, 128, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		ReportPDF:     true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, reportPDFName))
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", reportPDFName, err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("Expected a complete PDF file")
+	}
+
+	// Every xref entry must point at the start of its object
+	m := regexp.MustCompile(`xref\n0 (\d+)\n`).FindSubmatchIndex(data)
+	if m == nil {
+		t.Fatalf("Expected an xref table")
+	}
+	count, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+	entries := data[m[1]:]
+	for i := 1; i < count; i++ {
+		entry := entries[i*20 : i*20+10]
+		offset, err := strconv.Atoi(string(entry))
+		if err != nil {
+			t.Fatalf("Invalid xref entry %d: %q", i, entry)
+		}
+		if !bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i))) {
+			t.Errorf("xref entry %d doesn't point at object %d", i, i)
+		}
+	}
+
+	// One image XObject (plus its soft mask) per icon
+	images := bytes.Count(data, []byte("/ColorSpace /DeviceRGB"))
+	if images != len(expectedOutputs(config))-1 {
+		t.Errorf("Expected %d images in the PDF, got %d", len(expectedOutputs(config))-1, images)
+	}
+	if !bytes.Contains(data, []byte("(TrimPercent: 80)")) {
+		t.Errorf("Expected settings to be listed in the PDF")
+	}
+}
@@ -0,0 +1,705 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The stages below are the building blocks every icon is made of. Each is a
+// pure function: it returns a new image starting at the origin and leaves
+// its input unchanged, so the rest of icongen and its tests can use each on
+// its own. They are unexported, as package main can't be imported; other
+// programs drive icongen through the command line or icongen rpc.
+
+// cropCenter returns the centered percent of img, 1-100, in each dimension,
+// the area --trim-percent keeps. The result starts at the origin; img is
+// left unchanged.
+func cropCenter(img image.Image, percent int) *image.RGBA {
+	return cropAnchor(img, percent, [2]float64{0.5, 0.5})
+}
+
+// cropAnchor returns percent of img, 1-100, in each dimension like
+// cropCenter, placed by anchor instead: the fractions, 0-1, of the space
+// left across and down that go before the crop, so {0, 0} keeps the top-left
+// corner and {0.5, 0.5} the center, as --crop-anchor sets. The result starts
+// at the origin; img is left unchanged.
+func cropAnchor(img image.Image, percent int, anchor [2]float64) *image.RGBA {
+	cropRect := anchoredCrop(img.Bounds(), percent, anchor)
+
+	// Create new image with cropped content
+	cropped := image.NewRGBA(image.Rect(0, 0, cropRect.Dx(), cropRect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, cropRect.Min, draw.Src)
+
+	return cropped
+}
+
+// anchoredCrop returns the area of bounds cropAnchor keeps.
+func anchoredCrop(bounds image.Rectangle, percent int, anchor [2]float64) image.Rectangle {
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Calculate crop dimensions
+	cropWidth := width * percent / 100
+	cropHeight := height * percent / 100
+
+	// Calculate offset to place the crop at the anchor
+	offsetX := int(float64(width-cropWidth) * anchor[0])
+	offsetY := int(float64(height-cropHeight) * anchor[1])
+
+	// Create cropped rectangle
+	return image.Rect(
+		bounds.Min.X+offsetX,
+		bounds.Min.Y+offsetY,
+		bounds.Min.X+offsetX+cropWidth,
+		bounds.Min.Y+offsetY+cropHeight,
+	)
+}
+
+// cropSalient returns percent of img, 1-100, in each dimension like
+// cropCenter, but centered on the visually dense part of img rather than its
+// middle, the area --smart-crop keeps, so artwork that isn't centered in the
+// source still ends up centered in the icon. The density is the strength of
+// the edges of each pixel's lightness and transparency; the crop is centered
+// on their centroid as far as the image allows. The result starts at the
+// origin; img is left unchanged.
+func cropSalient(img image.Image, percent int) *image.RGBA {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Lightness over black, so transparent areas count as dark, and alpha
+	values := make([][2]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			values[y*width+x] = [2]float64{0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b), float64(a)}
+		}
+	}
+	// Edges are repeated outwards
+	at := func(x, y, channel int) float64 {
+		if x < 0 {
+			x = 0
+		} else if x >= width {
+			x = width - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= height {
+			y = height - 1
+		}
+		return values[y*width+x][channel]
+	}
+
+	// Centroid of the edge strength
+	var total, sumX, sumY float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			energy := 0.0
+			for channel := 0; channel < 2; channel++ {
+				energy += math.Abs(at(x+1, y, channel)-at(x-1, y, channel)) + math.Abs(at(x, y+1, channel)-at(x, y-1, channel))
+			}
+			total += energy
+			sumX += energy * (float64(x) + 0.5)
+			sumY += energy * (float64(y) + 0.5)
+		}
+	}
+	if total == 0 {
+		return cropCenter(img, percent)
+	}
+
+	cropWidth := width * percent / 100
+	cropHeight := height * percent / 100
+	clamp := func(v float64, hi int) int {
+		return int(math.Max(0, math.Min(float64(hi), math.Round(v))))
+	}
+	offsetX := clamp(sumX/total-float64(cropWidth)/2, width-cropWidth)
+	offsetY := clamp(sumY/total-float64(cropHeight)/2, height-cropHeight)
+
+	cropped := image.NewRGBA(image.Rect(0, 0, cropWidth, cropHeight))
+	draw.Draw(cropped, cropped.Bounds(), img, image.Pt(bounds.Min.X+offsetX, bounds.Min.Y+offsetY), draw.Src)
+
+	return cropped
+}
+
+// trimAlpha returns the tight bounding box of the pixels of img that aren't
+// fully transparent, the area --trim-alpha keeps, so uneven transparent
+// margins don't shift or shrink the artwork. A fully transparent img is
+// returned whole. The result starts at the origin; img is left unchanged.
+func trimAlpha(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	content := image.Rectangle{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+				content = content.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if content.Empty() {
+		content = bounds
+	}
+
+	trimmed := image.NewRGBA(image.Rect(0, 0, content.Dx(), content.Dy()))
+	draw.Draw(trimmed, trimmed.Bounds(), img, content.Min, draw.Src)
+
+	return trimmed
+}
+
+// resizeImage scales img to fit a size x size square with bilinear
+// interpolation, keeping its aspect ratio and centering it on transparency,
+// as every icon is resized. The result starts at the origin; img is left
+// unchanged.
+func resizeImage(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Calculate scaling to fit within square while maintaining aspect ratio
+	scale := float64(size) / math.Max(float64(width), float64(height))
+	newWidth := int(float64(width) * scale)
+	newHeight := int(float64(height) * scale)
+
+	// Create new image
+	resized := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	// Fill with transparent background
+	transparent := &image.Uniform{color.RGBA{0, 0, 0, 0}}
+	draw.Draw(resized, resized.Bounds(), transparent, image.Point{}, draw.Src)
+
+	// Calculate centering offset
+	offsetX := (size - newWidth) / 2
+	offsetY := (size - newHeight) / 2
+
+	// Bilinear interpolation scaling for smoother results
+	at := pixelReader(img)
+	for y := 0; y < newHeight; y++ {
+		for x := 0; x < newWidth; x++ {
+			// Calculate source coordinates with sub-pixel precision
+			srcXf := float64(x) / scale
+			srcYf := float64(y) / scale
+
+			// Get integer and fractional parts
+			srcX := int(srcXf)
+			srcY := int(srcYf)
+			fracX := srcXf - float64(srcX)
+			fracY := srcYf - float64(srcY)
+
+			// Adjust for bounds offset
+			srcX += bounds.Min.X
+			srcY += bounds.Min.Y
+
+			// Ensure we don't go out of bounds
+			if srcX >= bounds.Max.X-1 {
+				srcX = bounds.Max.X - 2
+				fracX = 1.0
+			}
+			if srcY >= bounds.Max.Y-1 {
+				srcY = bounds.Max.Y - 2
+				fracY = 1.0
+			}
+
+			if srcX >= bounds.Min.X && srcY >= bounds.Min.Y {
+				// Get the four surrounding pixels
+				r00, g00, b00, a00 := at(srcX, srcY)
+				r10, g10, b10, a10 := at(srcX+1, srcY)
+				r01, g01, b01, a01 := at(srcX, srcY+1)
+				r11, g11, b11, a11 := at(srcX+1, srcY+1)
+
+				// Bilinear interpolation
+				r := bilinearInterpolate(float64(r00), float64(r10), float64(r01), float64(r11), fracX, fracY)
+				g := bilinearInterpolate(float64(g00), float64(g10), float64(g01), float64(g11), fracX, fracY)
+				b := bilinearInterpolate(float64(b00), float64(b10), float64(b01), float64(b11), fracX, fracY)
+				a := bilinearInterpolate(float64(a00), float64(a10), float64(a01), float64(a11), fracX, fracY)
+
+				// Convert back to 8-bit and set pixel
+				setPixel(resized, offsetX+x, offsetY+y, uint32(r+0.5), uint32(g+0.5), uint32(b+0.5), uint32(a+0.5))
+			}
+		}
+	}
+
+	return resized
+}
+
+// A resampleFilter is a resampling kernel resizeFiltered resizes with. Kernel
+// weighs a source pixel at distance x, in pixels, from the point being
+// sampled and is zero beyond Support. Downscaling widens it to cover every
+// source pixel.
+type resampleFilter struct {
+	Support float64
+	Kernel  func(x float64) float64
+}
+
+var (
+	// bilinearFilter blends neighboring pixels linearly, the softest one
+	bilinearFilter = resampleFilter{1, func(x float64) float64 {
+		return math.Max(0, 1-math.Abs(x))
+	}}
+	// boxFilter averages the source pixels under each icon pixel
+	boxFilter = resampleFilter{0.5, func(x float64) float64 {
+		if x >= -0.5 && x < 0.5 {
+			return 1
+		}
+		return 0
+	}}
+	// catmullRomFilter is a sharp cubic with little ringing
+	catmullRomFilter = resampleFilter{2, func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1 {
+			return (3*x*x*x - 5*x*x + 2) / 2
+		}
+		if x < 2 {
+			return (-x*x*x + 5*x*x - 8*x + 4) / 2
+		}
+		return 0
+	}}
+	// lanczos3Filter is a windowed sinc, the sharpest of them
+	lanczos3Filter = resampleFilter{3, func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		if math.Abs(x) >= 3 {
+			return 0
+		}
+		return 3 * math.Sin(math.Pi*x) * math.Sin(math.Pi*x/3) / (math.Pi * math.Pi * x * x)
+	}}
+)
+
+// resizeFiltered scales img like resizeImage, but by convolving it with
+// filter, which keeps downscales sharp and free of aliasing, as --filter
+// resizes. The result starts at the origin; img is left unchanged.
+func resizeFiltered(img image.Image, size int, filter resampleFilter) *image.RGBA {
+	return fitFilter(img, size, filter, false)
+}
+
+// resizeLinear scales img like resizeFiltered, but blends in linear light:
+// the sRGB colors are decoded before filtering and encoded again after, so
+// high-contrast edges don't get dark halos or shift in brightness, as
+// --linear-light resizes. The result starts at the origin; img is left
+// unchanged.
+func resizeLinear(img image.Image, size int, filter resampleFilter) *image.RGBA {
+	return fitFilter(img, size, filter, true)
+}
+
+func fitFilter(img image.Image, size int, filter resampleFilter, linear bool) *image.RGBA {
+	resized := image.NewRGBA(image.Rect(0, 0, size, size))
+	resample(img, size, filter, linear, func(x, y int, r, g, b, a uint32) { setPixel(resized, x, y, r, g, b, a) })
+	return resized
+}
+
+// fitFilterDeep resizes img like resizeFiltered, or resizeLinear if linear,
+// into 16 bits per channel, for 16-bit sources.
+func fitFilterDeep(img image.Image, size int, filter resampleFilter, linear bool) *image.RGBA64 {
+	resized := image.NewRGBA64(image.Rect(0, 0, size, size))
+	resample(img, size, filter, linear, func(x, y int, r, g, b, a uint32) { setPixel64(resized, x, y, r, g, b, a) })
+	return resized
+}
+
+// resample resizes img to fit a size x size square with filter, centered,
+// and stores every pixel of the result with set, as premultiplied 16-bit
+// channels.
+func resample(img image.Image, size int, filter resampleFilter, linear bool, set func(x, y int, r, g, b, a uint32)) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	scale := float64(size) / math.Max(float64(width), float64(height))
+	newWidth := int(float64(width) * scale)
+	newHeight := int(float64(height) * scale)
+	// A source cropped down to nothing leaves the icon transparent
+	if width == 0 || height == 0 || newWidth == 0 || newHeight == 0 {
+		return
+	}
+
+	// Premultiplied channels, so transparent pixels don't bleed their color
+	var decode []float64
+	if linear {
+		decode = srgbDecodeTable()
+	}
+	at := pixelReader(img)
+	pixelsBuf := getChannels(width * height)
+	defer putChannels(pixelsBuf)
+	pixels := *pixelsBuf
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := at(bounds.Min.X+x, bounds.Min.Y+y)
+			pixel := [4]float64{float64(r), float64(g), float64(b), float64(a)}
+			if linear && a > 0 {
+				for c := 0; c < 3; c++ {
+					pixel[c] = decode[int(pixel[c]*0xffff/pixel[3]+0.5)] * pixel[3]
+				}
+			}
+			pixels[y*width+x] = pixel
+		}
+	}
+
+	// Resize the rows, then the columns of the result
+	columns := filterWeights(width, newWidth, scale, filter)
+	wideBuf := getChannels(newWidth * height)
+	defer putChannels(wideBuf)
+	wide := *wideBuf
+	for y := 0; y < height; y++ {
+		for x, taps := range columns {
+			for _, tap := range taps {
+				for c := 0; c < 4; c++ {
+					wide[y*newWidth+x][c] += pixels[y*width+tap.index][c] * tap.weight
+				}
+			}
+		}
+	}
+	rows := filterWeights(height, newHeight, scale, filter)
+
+	offsetX := (size - newWidth) / 2
+	offsetY := (size - newHeight) / 2
+	for y, taps := range rows {
+		for x := 0; x < newWidth; x++ {
+			var sum [4]float64
+			for _, tap := range taps {
+				for c := 0; c < 4; c++ {
+					sum[c] += wide[tap.index*newWidth+x][c] * tap.weight
+				}
+			}
+			// Sharp kernels overshoot; keep the color within its alpha
+			a := math.Max(0, math.Min(0xffff, sum[3]))
+			clamp := func(v float64) uint32 {
+				v = math.Max(0, math.Min(a, v))
+				if linear && a > 0 {
+					v = linearToSRGB(v/a) * a
+				}
+				return uint32(v + 0.5)
+			}
+			set(offsetX+x, offsetY+y, clamp(sum[0]), clamp(sum[1]), clamp(sum[2]), uint32(a+0.5))
+		}
+	}
+}
+
+// pixelReader returns a function reading the premultiplied 16-bit channels
+// of img at a point inside its bounds, as img.At(x, y).RGBA() does. Decoded
+// PNGs and the results of every stage are *image.RGBA or *image.NRGBA, or
+// *image.RGBA64 for 16-bit sources, which are read straight from their Pix
+// slices, without an interface call and an allocation per pixel.
+func pixelReader(img image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return func(x, y int) (r, g, b, a uint32) {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4 : i+4]
+			return uint32(p[0]) * 0x101, uint32(p[1]) * 0x101, uint32(p[2]) * 0x101, uint32(p[3]) * 0x101
+		}
+	case *image.NRGBA:
+		return func(x, y int) (r, g, b, a uint32) {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4 : i+4]
+			// Premultiply as color.NRGBA does
+			a = uint32(p[3])
+			r = uint32(p[0]) * 0x101 * a / 0xff
+			g = uint32(p[1]) * 0x101 * a / 0xff
+			b = uint32(p[2]) * 0x101 * a / 0xff
+			return r, g, b, a * 0x101
+		}
+	case *image.RGBA64:
+		return func(x, y int) (r, g, b, a uint32) {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+8 : i+8]
+			return uint32(p[0])<<8 | uint32(p[1]), uint32(p[2])<<8 | uint32(p[3]), uint32(p[4])<<8 | uint32(p[5]), uint32(p[6])<<8 | uint32(p[7])
+		}
+	case *mipmapImage:
+		return pixelReader(img.Image)
+	}
+	return func(x, y int) (r, g, b, a uint32) {
+		return img.At(x, y).RGBA()
+	}
+}
+
+// setPixel stores premultiplied 16-bit channels at (x, y) of img, rounded
+// to the nearest 8-bit value. img.Set with a color.RGBA64 truncates
+// instead, which darkens the colors of translucent pixels once they're
+// un-premultiplied for the PNG, the fainter the more: a dark fringe around
+// the edges of the artwork.
+func setPixel(img *image.RGBA, x, y int, r, g, b, a uint32) {
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	p[0], p[1], p[2], p[3] = to8(r), to8(g), to8(b), to8(a)
+}
+
+// setPixel64 stores premultiplied 16-bit channels at (x, y) of img.
+func setPixel64(img *image.RGBA64, x, y int, r, g, b, a uint32) {
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+8 : i+8]
+	p[0], p[1], p[2], p[3] = uint8(r>>8), uint8(r), uint8(g>>8), uint8(g)
+	p[4], p[5], p[6], p[7] = uint8(b>>8), uint8(b), uint8(a>>8), uint8(a)
+}
+
+// to8 rounds a 16-bit channel to 8 bits. A color channel never rounds past
+// the alpha it's premultiplied by.
+func to8(v uint32) uint8 {
+	return uint8((v*0xff + 0x7fff) / 0xffff)
+}
+
+// srgbToLinear decodes an sRGB channel value, 0-1, to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+var (
+	srgbDecodeOnce sync.Once
+	srgbDecode     []float64
+)
+
+// srgbDecodeTable returns srgbToLinear for every 16-bit channel value, which
+// spares resizeLinear a power per source pixel.
+func srgbDecodeTable() []float64 {
+	srgbDecodeOnce.Do(func() {
+		srgbDecode = make([]float64, 0x10000)
+		for i := range srgbDecode {
+			srgbDecode[i] = srgbToLinear(float64(i) / 0xffff)
+		}
+	})
+	return srgbDecode
+}
+
+// linearToSRGB encodes a linear light value, 0-1, as an sRGB channel value.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+type filterTap struct {
+	index  int
+	weight float64
+}
+
+// filterWeights returns, for each of the n pixels a line of length pixels
+// is resized to by scale, the source pixels filter samples and their
+// normalized weights. Samples past the ends repeat the edge pixels.
+func filterWeights(length, n int, scale float64, filter resampleFilter) [][]filterTap {
+	stretch := math.Max(1, 1/scale)
+	support := filter.Support * stretch
+
+	weights := make([][]filterTap, n)
+	for i := range weights {
+		center := (float64(i)+0.5)/scale - 0.5
+		var taps []filterTap
+		total := 0.0
+		for j := int(math.Ceil(center - support)); j <= int(math.Floor(center+support)); j++ {
+			weight := filter.Kernel((float64(j) - center) / stretch)
+			if weight == 0 {
+				continue
+			}
+			index := j
+			if index < 0 {
+				index = 0
+			} else if index >= length {
+				index = length - 1
+			}
+			taps = append(taps, filterTap{index, weight})
+			total += weight
+		}
+		for k := range taps {
+			taps[k].weight /= total
+		}
+		weights[i] = taps
+	}
+	return weights
+}
+
+// resizeNearest scales img like resizeImage, but with nearest-neighbor
+// sampling, so every icon pixel takes the color of one source pixel and pixel
+// art keeps its hard edges, as --pixel-art resizes. The result starts at the
+// origin; img is left unchanged.
+func resizeNearest(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	scale := float64(size) / math.Max(float64(width), float64(height))
+	newWidth := int(float64(width) * scale)
+	newHeight := int(float64(height) * scale)
+
+	resized := image.NewRGBA(image.Rect(0, 0, size, size))
+	offsetX := (size - newWidth) / 2
+	offsetY := (size - newHeight) / 2
+
+	at := pixelReader(img)
+	for y := 0; y < newHeight; y++ {
+		// Sample the source pixel under the center of the icon pixel
+		srcY := int((float64(y) + 0.5) / scale)
+		if srcY >= height {
+			srcY = height - 1
+		}
+		for x := 0; x < newWidth; x++ {
+			srcX := int((float64(x) + 0.5) / scale)
+			if srcX >= width {
+				srcX = width - 1
+			}
+			r, g, b, a := at(bounds.Min.X+srcX, bounds.Min.Y+srcY)
+			setPixel(resized, offsetX+x, offsetY+y, r, g, b, a)
+		}
+	}
+
+	return resized
+}
+
+// scale2x doubles img with the Scale2x (EPX) algorithm, which splits every
+// pixel into four and rounds the corners where its neighbors form a
+// diagonal, so upscaled pixel art gets smooth diagonals without blurring,
+// as --pixel-art=scale2x does. The result starts at the origin; img is left
+// unchanged.
+func scale2x(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Edges are repeated outwards
+	at := func(x, y int) color.RGBA {
+		if x < 0 {
+			x = 0
+		} else if x >= width {
+			x = width - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= height {
+			y = height - 1
+		}
+		return color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width*2, height*2))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := at(x, y)
+			up, right, left, down := at(x, y-1), at(x+1, y), at(x-1, y), at(x, y+1)
+
+			e0, e1, e2, e3 := p, p, p, p
+			if left == up && left != down && up != right {
+				e0 = up
+			}
+			if up == right && up != left && right != down {
+				e1 = right
+			}
+			if down == left && down != right && left != up {
+				e2 = left
+			}
+			if right == down && right != up && down != left {
+				e3 = down
+			}
+			scaled.SetRGBA(2*x, 2*y, e0)
+			scaled.SetRGBA(2*x+1, 2*y, e1)
+			scaled.SetRGBA(2*x, 2*y+1, e2)
+			scaled.SetRGBA(2*x+1, 2*y+1, e3)
+		}
+	}
+
+	return scaled
+}
+
+func bilinearInterpolate(c00, c10, c01, c11, fracX, fracY float64) float64 {
+	// Interpolate along X axis
+	top := c00*(1-fracX) + c10*fracX
+	bottom := c01*(1-fracX) + c11*fracX
+
+	// Interpolate along Y axis
+	return top*(1-fracY) + bottom*fracY
+}
+
+// addPadding surrounds the square img with paddingPercent of its width of
+// transparency on every side and fits the result into a targetSize square,
+// as --padding-percent does. With no padding it returns a copy of img.
+func addPadding(img image.Image, paddingPercent int, targetSize int) *image.RGBA {
+	if paddingPercent <= 0 {
+		return toRGBA(img)
+	}
+	return padWith(img, float64(paddingPercent), targetSize, func(img image.Image, size int) image.Image { return resizeImage(img, size) }).(*image.RGBA)
+}
+
+// padWith pads img like addPadding, by a paddingPercent that may be
+// fractional, resizing the result with fit. A 16-bit img is padded on a
+// 16-bit canvas.
+func padWith(img image.Image, paddingPercent float64, targetSize int, fit func(image.Image, int) image.Image) image.Image {
+
+	bounds := img.Bounds()
+	currentSize := bounds.Dx() // Assuming square image
+
+	// Calculate padding size
+	paddingSize := int(float64(currentSize) * paddingPercent / 100)
+	paddedSize := currentSize + (paddingSize * 2)
+
+	// Create new image with padding, on a recycled transparent canvas
+	var padded draw.Image
+	if deepImage(img) {
+		padded = image.NewRGBA64(image.Rect(0, 0, paddedSize, paddedSize))
+	} else {
+		canvas := getCanvas(image.Rect(0, 0, paddedSize, paddedSize))
+		defer putCanvas(canvas)
+		padded = canvas
+	}
+
+	// Center the original image in the padded canvas
+	offsetX := paddingSize
+	offsetY := paddingSize
+	dstRect := image.Rect(offsetX, offsetY, offsetX+currentSize, offsetY+currentSize)
+	draw.Draw(padded, dstRect, img, bounds.Min, draw.Src)
+
+	// Resize the padded image back to target size
+	resizedPadded := fit(padded, targetSize)
+
+	return resizedPadded
+}
+
+// maskShape selects the shape applyMask cuts an icon to.
+type maskShape struct {
+	// Name is "rounded", or a --mask shape such as "circle"
+	Name string
+	// Radius in pixels and Smoothing, from 0 for circular arcs to 1 for
+	// fully continuous curvature, shape the corners of "rounded"
+	Radius    int
+	Smoothing float64
+}
+
+// applyMask returns the square img cut to shape, with everything outside it
+// transparent.
+func applyMask(img image.Image, shape maskShape) (*image.RGBA, error) {
+	if shape.Name == "rounded" {
+		if shape.Radius < 0 || shape.Smoothing < 0 || shape.Smoothing > 1 {
+			return nil, fmt.Errorf("invalid rounded mask (radius %d, smoothing %g)", shape.Radius, shape.Smoothing)
+		}
+		if shape.Smoothing > 0 {
+			return toRGBA(addSmoothCorners(img, shape.Radius, shape.Smoothing)), nil
+		}
+		return toRGBA(addRoundedCorners(img, shape.Radius)), nil
+	}
+
+	mask, ok := maskShapes[shape.Name]
+	if !ok {
+		names := []string{"rounded"}
+		for name := range maskShapes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown mask shape %q (expected %s)", shape.Name, strings.Join(names, ", "))
+	}
+	return toRGBA(mask(img)), nil
+}
+
+// pngEncoder is the encoder of every PNG icongen writes. Its settings are
+// fixed, rather than left to the encoder's defaults, so the same pixels
+// always encode to the same bytes.
+var pngEncoder = png.Encoder{CompressionLevel: png.DefaultCompression}
+
+// encodePNG writes img to w as a PNG, the format of every icon.
+func encodePNG(w io.Writer, img image.Image) error {
+	return pngEncoder.Encode(w, img)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestPipelineStagesLeaveInputUnchanged(t *testing.T) {
+	source := createTestImageWithSquare(64, 32, color.RGBA{255, 0, 0, 255})
+	original := toRGBA(source)
+
+	stages := []struct {
+		name string
+		run  func(img image.Image) image.Image
+	}{
+		{"cropCenter", func(img image.Image) image.Image { return cropCenter(img, 50) }},
+		{"cropSalient", func(img image.Image) image.Image { return cropSalient(img, 50) }},
+		{"trimAlpha", func(img image.Image) image.Image { return trimAlpha(img) }},
+		{"resizeImage", func(img image.Image) image.Image { return resizeImage(img, 32) }},
+		{"addPadding", func(img image.Image) image.Image { return addPadding(img, 10, 64) }},
+		{"applyMask", func(img image.Image) image.Image {
+			masked, _ := applyMask(img, maskShape{Name: "circle"})
+			return masked
+		}},
+	}
+
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			result := stage.run(source)
+			if result.Bounds().Min != (image.Point{}) {
+				t.Errorf("Expected the result to start at the origin, got %v", result.Bounds())
+			}
+			if !bytes.Equal(toRGBA(source).Pix, original.Pix) {
+				t.Errorf("Expected the input to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestCropCenterOffsetBounds(t *testing.T) {
+	// A sub-image doesn't start at the origin
+	full := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	full.Set(50, 50, color.RGBA{0, 255, 0, 255})
+	sub := full.SubImage(image.Rect(20, 20, 80, 80))
+
+	cropped := cropCenter(sub, 50)
+	if cropped.Bounds() != image.Rect(0, 0, 30, 30) {
+		t.Fatalf("Expected 30x30 at the origin, got %v", cropped.Bounds())
+	}
+	// (50, 50) is 15 pixels into the crop, which starts at (35, 35)
+	if got := cropped.RGBAAt(15, 15); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("Expected the marked pixel at (15, 15), got %v", got)
+	}
+}
+
+func TestCropAnchor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(99, 99, color.RGBA{0, 0, 255, 255})
+
+	tests := []struct {
+		name   string
+		anchor [2]float64
+		at     image.Point
+		expect color.RGBA
+	}{
+		{"top-left keeps the corner", [2]float64{0, 0}, image.Pt(0, 0), color.RGBA{255, 0, 0, 255}},
+		{"bottom-right keeps the corner", [2]float64{1, 1}, image.Pt(49, 49), color.RGBA{0, 0, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cropped := cropAnchor(img, 50, tt.anchor)
+			if cropped.Bounds() != image.Rect(0, 0, 50, 50) {
+				t.Fatalf("Expected 50x50 at the origin, got %v", cropped.Bounds())
+			}
+			if got := cropped.RGBAAt(tt.at.X, tt.at.Y); got != tt.expect {
+				t.Errorf("Expected %v at %v, got %v", tt.expect, tt.at, got)
+			}
+		})
+	}
+}
+
+func TestCropSalient(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	tests := []struct {
+		name   string
+		square image.Rectangle
+		expect image.Point // where the square's top-left corner ends up
+	}{
+		{"off-center logo", image.Rect(60, 60, 80, 80), image.Pt(15, 15)},
+		{"clamped to the edge", image.Rect(80, 10, 100, 30), image.Pt(30, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+			draw.Draw(img, tt.square, &image.Uniform{red}, image.Point{}, draw.Src)
+
+			cropped := cropSalient(img, 50)
+			if cropped.Bounds() != image.Rect(0, 0, 50, 50) {
+				t.Fatalf("Expected 50x50 at the origin, got %v", cropped.Bounds())
+			}
+			if got := cropped.RGBAAt(tt.expect.X, tt.expect.Y); got != red {
+				t.Errorf("Expected the square's corner at %v, got %v", tt.expect, got)
+			}
+			if got := cropped.RGBAAt(tt.expect.X-1, tt.expect.Y-1); got == red {
+				t.Errorf("Expected nothing of the square before %v", tt.expect)
+			}
+		})
+	}
+
+	// Without any detail the crop stays centered
+	plain := createTestImage(100, color.RGBA{0, 128, 255, 255})
+	if !bytes.Equal(cropSalient(plain, 50).Pix, cropCenter(plain, 50).Pix) {
+		t.Errorf("Expected a featureless image to be cropped in the center")
+	}
+}
+
+func TestTrimAlpha(t *testing.T) {
+	tests := []struct {
+		name   string
+		marks  []image.Point
+		bounds image.Rectangle
+		expect image.Rectangle
+	}{
+		{"uneven margins", []image.Point{{10, 5}, {29, 44}}, image.Rect(0, 0, 50, 50), image.Rect(0, 0, 20, 40)},
+		{"offset bounds", []image.Point{{25, 25}}, image.Rect(20, 20, 40, 40), image.Rect(0, 0, 1, 1)},
+		{"fully transparent", nil, image.Rect(0, 0, 8, 6), image.Rect(0, 0, 8, 6)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(tt.bounds)
+			for _, p := range tt.marks {
+				img.Set(p.X, p.Y, color.RGBA{0, 0, 255, 255})
+			}
+
+			trimmed := trimAlpha(img)
+			if trimmed.Bounds() != tt.expect {
+				t.Fatalf("Expected %v, got %v", tt.expect, trimmed.Bounds())
+			}
+			if len(tt.marks) > 0 && trimmed.RGBAAt(0, 0) != (color.RGBA{0, 0, 255, 255}) {
+				t.Errorf("Expected the top-left mark at the origin, got %v", trimmed.RGBAAt(0, 0))
+			}
+		})
+	}
+}
+
+func TestResizeImageKeepsAspectRatio(t *testing.T) {
+	wide := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			wide.Set(x, y, color.RGBA{0, 0, 255, 255})
+		}
+	}
+
+	fitted := resizeImage(wide, 40)
+	tests := []struct {
+		y      int
+		opaque bool
+	}{
+		{5, false},
+		{20, true},
+		{34, false},
+	}
+	for _, tt := range tests {
+		if opaque := fitted.RGBAAt(20, tt.y).A == 255; opaque != tt.opaque {
+			t.Errorf("Expected opaque=%v at y=%d, got %v", tt.opaque, tt.y, opaque)
+		}
+	}
+}
+
+// opaqueImage hides the concrete type of an image, so stages read it
+// through At.
+type opaqueImage struct {
+	image.Image
+}
+
+func TestResizeFastPathMatchesAt(t *testing.T) {
+	// Semi-transparent noise, offset from the origin
+	bounds := image.Rect(3, 5, 83, 65)
+	rgba := image.NewRGBA(bounds)
+	nrgba := image.NewNRGBA(bounds)
+	seed := uint32(1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			seed = seed*1664525 + 1013904223
+			c := color.NRGBA{uint8(seed >> 24), uint8(seed >> 16), uint8(seed >> 8), uint8(seed)}
+			nrgba.SetNRGBA(x, y, c)
+			rgba.Set(x, y, c)
+		}
+	}
+
+	stages := map[string]func(image.Image, int) *image.RGBA{
+		"resizeImage":   resizeImage,
+		"resizeNearest": resizeNearest,
+		"resizeFiltered": func(img image.Image, size int) *image.RGBA {
+			return resizeFiltered(img, size, catmullRomFilter)
+		},
+		"resizeLinear": func(img image.Image, size int) *image.RGBA {
+			return resizeLinear(img, size, lanczos3Filter)
+		},
+	}
+
+	for name, stage := range stages {
+		for _, img := range []image.Image{rgba, nrgba} {
+			for _, size := range []int{16, 37, 120} {
+				fast := stage(img, size)
+				slow := stage(opaqueImage{img}, size)
+				if !bytes.Equal(fast.Pix, slow.Pix) {
+					t.Errorf("%s of %T at %dpx: expected the fast path to match At", name, img, size)
+				}
+			}
+		}
+	}
+}
+
+func TestApplyMask(t *testing.T) {
+	source := createTestImage(64, color.RGBA{255, 0, 0, 255})
+
+	tests := []struct {
+		name          string
+		shape         maskShape
+		expectErr     bool
+		cornerOpaque  bool
+		expectedWidth int
+	}{
+		{"square corners", maskShape{Name: "rounded"}, false, true, 64},
+		{"rounded", maskShape{Name: "rounded", Radius: 16}, false, false, 64},
+		{"smooth", maskShape{Name: "rounded", Radius: 16, Smoothing: 0.6}, false, false, 64},
+		{"circle", maskShape{Name: "circle"}, false, false, 64},
+		{"unknown", maskShape{Name: "star"}, true, false, 0},
+		{"negative radius", maskShape{Name: "rounded", Radius: -1}, true, false, 0},
+		{"smoothing too large", maskShape{Name: "rounded", Radius: 8, Smoothing: 2}, true, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, err := applyMask(source, tt.shape)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if masked.Bounds().Dx() != tt.expectedWidth {
+				t.Errorf("Expected width %d, got %d", tt.expectedWidth, masked.Bounds().Dx())
+			}
+			if opaque := masked.RGBAAt(0, 0).A == 255; opaque != tt.cornerOpaque {
+				t.Errorf("Expected corner opaque=%v, got %v", tt.cornerOpaque, opaque)
+			}
+			if masked.RGBAAt(32, 32).A != 255 {
+				t.Errorf("Expected an opaque center")
+			}
+		})
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	source := createTestImageWithSquare(16, 8, color.RGBA{0, 128, 255, 255})
+
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, source); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Expected a PNG: %v", err)
+	}
+	if !bytes.Equal(toRGBA(decoded).Pix, toRGBA(source).Pix) {
+		t.Errorf("Expected the decoded image to match the source")
+	}
+}
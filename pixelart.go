@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// pixelArtFlag is --pixel-art: on its own it resizes with nearest-neighbor
+// sampling, and --pixel-art=scale2x smooths diagonals with Scale2x first.
+type pixelArtFlag struct {
+	p *string
+}
+
+func (f pixelArtFlag) String() string {
+	if f.p == nil {
+		return ""
+	}
+	return *f.p
+}
+
+func (f pixelArtFlag) Set(s string) error {
+	switch s {
+	case "nearest", "scale2x":
+		*f.p = s
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("expected nearest or scale2x")
+	}
+	*f.p = ""
+	if v {
+		*f.p = "nearest"
+	}
+	return nil
+}
+
+func (f pixelArtFlag) IsBoolFlag() bool { return true }
+
+// scalePixelArt doubles img with Scale2x until it is at least as large as
+// the largest output, for --pixel-art=scale2x. Larger sources are returned
+// unchanged.
+func scalePixelArt(img image.Image, config Config) image.Image {
+	largest := 0
+	for _, iconSize := range presetSizes(config) {
+		if iconSize.Size > largest {
+			largest = iconSize.Size
+		}
+	}
+
+	for {
+		bounds := img.Bounds()
+		if bounds.Dx() >= largest || bounds.Dy() >= largest || bounds.Empty() {
+			return img
+		}
+		img = scale2x(img)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+// checkerboard returns a size x size image of alternating red and blue pixels.
+func checkerboard(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if (x+y)%2 == 1 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestPixelArtFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		expect  string
+		wantErr bool
+	}{
+		{nil, "", false},
+		{[]string{"--pixel-art"}, "nearest", false},
+		{[]string{"--pixel-art=nearest"}, "nearest", false},
+		{[]string{"--pixel-art=scale2x"}, "scale2x", false},
+		{[]string{"--pixel-art=false"}, "", false},
+		{[]string{"--pixel-art=hq4x"}, "", true},
+	}
+
+	for _, tt := range tests {
+		var config Config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		defineFlags(fs, &config)
+
+		err := fs.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: expected error %v, got %v", tt.args, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && config.PixelArt != tt.expect {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.expect, config.PixelArt)
+		}
+	}
+}
+
+func TestResizeNearest(t *testing.T) {
+	source := checkerboard(4)
+
+	for _, size := range []int{16, 10, 3} {
+		resized := resizeNearest(source, size)
+		if resized.Bounds() != image.Rect(0, 0, size, size) {
+			t.Fatalf("Expected a %dpx square, got %v", size, resized.Bounds())
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				c := resized.RGBAAt(x, y)
+				if c != (color.RGBA{255, 0, 0, 255}) && c != (color.RGBA{0, 0, 255, 255}) {
+					t.Fatalf("Expected only source colors at %dpx, got %v at (%d, %d)", size, c, x, y)
+				}
+			}
+		}
+	}
+
+	// Integer scales turn every source pixel into a block
+	resized := resizeNearest(source, 16)
+	if resized.RGBAAt(3, 3) != (color.RGBA{255, 0, 0, 255}) || resized.RGBAAt(4, 3) != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected 4px blocks, got %v and %v", resized.RGBAAt(3, 3), resized.RGBAAt(4, 3))
+	}
+
+	// A wide source is centered on transparency like resizeImage
+	wide := resizeNearest(image.NewRGBA(image.Rect(0, 0, 4, 2)), 8)
+	if wide.Bounds().Dx() != 8 || wide.RGBAAt(0, 0).A != 0 {
+		t.Errorf("Expected a transparent 8px square, got %v", wide.Bounds())
+	}
+}
+
+func TestScale2x(t *testing.T) {
+	// A diagonal of red on white
+	white := color.RGBA{255, 255, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	source := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			source.SetRGBA(x, y, white)
+		}
+	}
+	source.SetRGBA(0, 1, red)
+	source.SetRGBA(1, 0, red)
+
+	scaled := scale2x(source)
+	if scaled.Bounds() != image.Rect(0, 0, 6, 6) {
+		t.Fatalf("Expected a 6x6 image, got %v", scaled.Bounds())
+	}
+
+	tests := []struct {
+		x, y   int
+		expect color.RGBA
+	}{
+		// The white corner between the two red pixels is rounded off
+		{2, 2, red},
+		{3, 3, white},
+		{3, 2, white},
+		{2, 3, white},
+		// Pixels without a diagonal are doubled as they are
+		{2, 0, red},
+		{3, 1, red},
+		{5, 5, white},
+	}
+
+	for _, tt := range tests {
+		if got := scaled.RGBAAt(tt.x, tt.y); got != tt.expect {
+			t.Errorf("At (%d, %d): expected %v, got %v", tt.x, tt.y, tt.expect, got)
+		}
+	}
+
+	// A checkerboard has no diagonals to smooth
+	if scaled := scale2x(checkerboard(4)); scaled.RGBAAt(2, 2) != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected a checkerboard to be doubled as it is, got %v", scaled.RGBAAt(2, 2))
+	}
+}
+
+func TestScalePixelArt(t *testing.T) {
+	config := Config{Preset: "android", PixelArt: "scale2x"}
+
+	scaled := scalePixelArt(checkerboard(16), config)
+	if scaled.Bounds().Dx() != 256 {
+		t.Errorf("Expected a 16px source to be doubled to 256px for the 192px android icon, got %v", scaled.Bounds())
+	}
+
+	large := checkerboard(200)
+	if scaled := scalePixelArt(large, config); scaled != image.Image(large) {
+		t.Errorf("Expected a source larger than every output to be left alone")
+	}
+}
+
+func TestPixelArtIcon(t *testing.T) {
+	source := checkerboard(4)
+
+	icon := toRGBA(prepareIcon(source, Config{PixelArt: "nearest"}, backgroundPattern{}, nil, nil, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if c := icon.RGBAAt(x, y); c.G != 0 || (c.R != 0) == (c.B != 0) {
+				t.Fatalf("Expected only source colors with --pixel-art, got %v at (%d, %d)", c, x, y)
+			}
+		}
+	}
+
+	padded := toRGBA(padIcon(icon, Config{PixelArt: "nearest", PaddingPercent: 10}, IconSize{"icon_32x32.png", 32}))
+	if c := padded.RGBAAt(16, 16); c.A != 255 || (c.R != 0) == (c.B != 0) {
+		t.Errorf("Expected padding to keep source colors with --pixel-art, got %v", c)
+	}
+
+	smooth := toRGBA(prepareIcon(source, Config{}, backgroundPattern{}, nil, nil, 32))
+	if c := smooth.RGBAAt(12, 12); c.R == 0 || c.B == 0 {
+		t.Errorf("Expected the default resize to blend the checkerboard, got %v", c)
+	}
+}
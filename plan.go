@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// plannedOutput is one output of a run, fully resolved from the
+// configuration before anything is generated.
+type plannedOutput struct {
+	Path     string            `json:"path"`
+	Name     string            `json:"name"`
+	Width    int               `json:"width,omitempty"`
+	Height   int               `json:"height,omitempty"`
+	Format   string            `json:"format"`
+	Variant  string            `json:"variant"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// planOutputs returns every output a run with config would write, in
+// generation order, without generating or writing anything. The result
+// depends only on config and, in recursive mode, on the source images found
+// in the input directory, so --dry-run, cleanup and the rpc plan method all
+// see the same list.
+func planOutputs(config Config) ([]plannedOutput, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	configs := []Config{config}
+	if config.Recursive {
+		var err error
+		if configs, err = sourceConfigs(config); err != nil {
+			return nil, err
+		}
+	} else if config.Flavors != "" {
+		var err error
+		if configs, err = flavorConfigs(config); err != nil {
+			return nil, err
+		}
+	}
+
+	var targets []plannedOutput
+	for _, c := range configs {
+		for _, target := range planTargets(c) {
+			target.Path = filepath.Join(assetDir(c), target.Name)
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// planTargets lists the outputs generateIcons writes for a single source.
+// Path is left for planOutputs to fill in.
+func planTargets(config Config) []plannedOutput {
+	var targets []plannedOutput
+	for _, iconSize := range outputSizes(config) {
+		if isNotificationIcon(iconSize) {
+			targets = append(targets, notificationTarget(iconSize))
+			continue
+		}
+		if isComplicationImage(iconSize) {
+			targets = append(targets, complicationTarget(iconSize))
+			continue
+		}
+		if isTemplateImage(iconSize) {
+			targets = append(targets, templateTarget(iconSize))
+			continue
+		}
+		targets = append(targets, iconTarget(config, iconSize, iconSize.Name, "regular"))
+		for _, variant := range variantsFor(iconVariants(config), iconSize) {
+			target := iconTarget(config, iconSize, variantIconName(iconSize.Name, variant.Name), variant.Name)
+			for key, value := range variant.describe(iconSize.Size) {
+				target.Settings[key] = value
+			}
+			targets = append(targets, target)
+		}
+	}
+	targets = append(targets, seriesTargets(config)...)
+	targets = append(targets, badgeCountTargets(config)...)
+	targets = append(targets, colorVariantTargets(config)...)
+	targets = append(targets, darkTargets(config)...)
+	targets = append(targets, roundTargets(config)...)
+	targets = append(targets, adaptiveTargets(config)...)
+	targets = append(targets, appearanceTargets(config)...)
+	targets = append(targets, spinnerTargets(config)...)
+	targets = append(targets, stateTargets(config)...)
+	targets = append(targets, debugTargets(config)...)
+	targets = append(targets, designSVGTargets(config)...)
+	targets = append(targets, xcodeContentsTargets(config)...)
+	targets = append(targets, complicationContentsTargets(config)...)
+	targets = append(targets, webTargets(config)...)
+	targets = append(targets, contactSheetTargets(config)...)
+	targets = append(targets, reportPDFTargets(config)...)
+	return append(targets, previewTargets(config)...)
+}
+
+// iconTarget describes the output name of iconSize, recording the border and
+// padding it gets.
+func iconTarget(config Config, iconSize IconSize, name, variant string) plannedOutput {
+	target := plannedOutput{
+		Name:     name,
+		Width:    iconSize.Size,
+		Height:   iconSize.Size,
+		Format:   "png",
+		Variant:  variant,
+		Settings: map[string]string{},
+	}
+	if config.Watermark != "" && iconSize.Size >= watermarkMinSize {
+		target.Settings["watermark"] = config.Watermark
+	}
+	if config.Layers != "" {
+		target.Settings["layers"] = layerSummary(config)
+	}
+	if config.Effects != "" {
+		target.Settings["effects"] = config.Effects
+	}
+	if config.Badge != "" {
+		target.Settings["badge"] = fmt.Sprintf("%s,color=%s,position=%s", config.Badge, config.BadgeColor, config.BadgePosition)
+	}
+	if config.Overlay != "" {
+		target.Settings["overlay"] = fmt.Sprintf("%s,scale=%s,anchor=%s,opacity=%g", config.Overlay, config.OverlayScale, config.OverlayAnchor, config.OverlayOpacity)
+	}
+	if text := textSettings(config, iconSize.Size); text != "" {
+		target.Settings["text"] = text
+	}
+	if config.Shadow {
+		target.Settings["shadow"] = fmt.Sprintf("blur=%d%%,x=%d%%,y=%d%%,opacity=%d%%,color=%s",
+			config.ShadowBlur, config.ShadowOffsetX, config.ShadowOffsetY, config.ShadowOpacity, config.ShadowColor)
+	}
+	if config.BorderWidth != "" {
+		if w, err := parseBorderWidth(config.BorderWidth); err == nil && w.resolve(iconSize.Size) > 0 {
+			target.Settings["border-width"] = strconv.FormatFloat(w.resolve(iconSize.Size), 'f', -1, 64)
+			target.Settings["border-color"] = config.BorderColor
+		}
+	}
+	if hasPadding(config, iconSize) {
+		target.Settings["padding-percent"] = strconv.FormatFloat(paddingPercent(config, iconSize.Size), 'f', -1, 64)
+	}
+	return target
+}
+
+// printPlan prints the outputs a run with config would write, one per line.
+func printPlan(config Config) error {
+	targets, err := planOutputs(config)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		size := "-"
+		if target.Width > 0 {
+			size = fmt.Sprintf("%dx%d", target.Width, target.Height)
+		}
+
+		keys := make([]string, 0, len(target.Settings))
+		for key := range target.Settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		settings := make([]string, len(keys))
+		for i, key := range keys {
+			settings[i] = key + "=" + target.Settings[key]
+		}
+
+		fmt.Fprintf(logOutput(config), "%s\t%s\t%s\t%s\t%s\n", target.Path, size, target.Format, target.Variant, strings.Join(settings, ","))
+	}
+	fmt.Fprintf(logOutput(config), "%d outputs (dry run, nothing written)\n", len(targets))
+	warnRun(config)
+	return nil
+}
+
+// expectedOutputs lists the file names a run with config generates.
+func expectedOutputs(config Config) []string {
+	return targetNames(planTargets(config))
+}
+
+func targetNames(targets []plannedOutput) []string {
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.Name
+	}
+	return names
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:      inputPath,
+		OutputDir:      outputDir,
+		TrimPercent:    80,
+		RadiusPercent:  20,
+		PaddingPercent: 10,
+		PaddingIOSMode: true,
+		SpinnerFrames:  4,
+		SpinnerSize:    32,
+		States:         "ok:#34c759",
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("planOutputs failed: %v", err)
+	}
+
+	expected := 2*len(iconSizes) + 4 + len(trayIconSizes)
+	if len(targets) != expected {
+		t.Fatalf("Expected %d targets, got %d", expected, len(targets))
+	}
+
+	byName := make(map[string]plannedOutput)
+	for _, target := range targets {
+		if target.Path != filepath.Join(outputDir, target.Name) {
+			t.Errorf("Expected %s to be planned in the output directory, got %s", target.Name, target.Path)
+		}
+		byName[target.Name] = target
+	}
+
+	tests := []struct {
+		name     string
+		width    int
+		variant  string
+		settings map[string]string
+	}{
+		{"icon_16x16.png", 16, "regular", map[string]string{"padding-percent": "10"}},
+		{"icon_1024x1024.png", 1024, "regular", map[string]string{}},
+		{"icon_128x128_rounded.png", 128, "rounded", map[string]string{"padding-percent": "10", "radius": "25"}},
+		{"spinner_01.png", 32, "spinner", map[string]string{"angle": "90"}},
+		{"icon_22x22_ok.png", 22, "state", map[string]string{"state": "ok", "color": "#34C759"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("Expected %s in the plan", tt.name)
+			}
+			if target.Width != tt.width || target.Height != tt.width {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.width, tt.width, target.Width, target.Height)
+			}
+			if target.Variant != tt.variant {
+				t.Errorf("Expected variant %q, got %q", tt.variant, target.Variant)
+			}
+			if len(target.Settings) != len(tt.settings) {
+				t.Errorf("Expected settings %v, got %v", tt.settings, target.Settings)
+			}
+			for key, value := range tt.settings {
+				if target.Settings[key] != value {
+					t.Errorf("Expected setting %s=%s, got %v", key, value, target.Settings)
+				}
+			}
+		})
+	}
+
+	// Planning must not write anything
+	entries, _ := os.ReadDir(outputDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected planOutputs to leave the output directory empty, found %d entries", len(entries))
+	}
+}
+
+func TestPlanRecursive(t *testing.T) {
+	root := createSourceTree(t, []string{"a/logo.png", "b/badge.png"})
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:   root,
+		OutputDir:   outputDir,
+		Recursive:   true,
+		TrimPercent: 80,
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("planOutputs failed: %v", err)
+	}
+
+	if len(targets) != 2*len(iconSizes) {
+		t.Fatalf("Expected %d targets, got %d", 2*len(iconSizes), len(targets))
+	}
+	if expected := filepath.Join(outputDir, "a", "logo", "icon_16x16.png"); targets[0].Path != expected {
+		t.Errorf("Expected first target %s, got %s", expected, targets[0].Path)
+	}
+}
+
+func TestPlanInvalidConfig(t *testing.T) {
+	config := Config{InputPath: "nonexistent.png", TrimPercent: 80}
+	if _, err := planOutputs(config); err == nil {
+		t.Errorf("Expected error for invalid config")
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"os"
+)
+
+// The decoder below reads a PNG a row at a time, so a source too large to
+// decode whole within --max-memory can still be downscaled as it is read.
+// image/png only decodes whole images.
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngHeader is the IHDR chunk of a PNG, with its palette and transparency.
+type pngHeader struct {
+	width, height int
+	depth         int
+	colorType     int
+	interlaced    bool
+	palette       [][4]uint32 // premultiplied 16-bit RGBA of each index
+	transparent   []uint16    // the gray or RGB samples tRNS makes transparent
+}
+
+// channels returns how many samples a pixel of colorType has.
+func (h pngHeader) channels() int {
+	switch h.colorType {
+	case 2:
+		return 3
+	case 4:
+		return 2
+	case 6:
+		return 4
+	}
+	return 1
+}
+
+// pngChunks reads the chunks of a PNG from r, after its signature.
+type pngChunks struct {
+	r *bufio.Reader
+}
+
+// maxChunkLength is the largest chunk length the PNG specification allows.
+const maxChunkLength = 0x7fffffff
+
+// next reads the length and type of the next chunk.
+func (c pngChunks) next() (uint32, string, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, "", err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > maxChunkLength {
+		return 0, "", fmt.Errorf("chunk length %d out of range", length)
+	}
+	return length, string(header[4:]), nil
+}
+
+// data reads the length bytes of a chunk of typ and checks its CRC. The
+// buffer grows as the data arrives, so a length past the end of the input
+// fails without allocating it.
+func (c pngChunks) data(typ string, length uint32) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(c.r, int64(length)+4))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(length)+4 {
+		return nil, fmt.Errorf("invalid PNG: %s chunk truncated", typ)
+	}
+	sum := crc32.Update(crc32.ChecksumIEEE([]byte(typ)), crc32.IEEETable, data[:length])
+	if binary.BigEndian.Uint32(data[length:]) != sum {
+		return nil, fmt.Errorf("invalid PNG: %s checksum mismatch", typ)
+	}
+	return data[:length], nil
+}
+
+// readPNGHeader reads a PNG up to its first IDAT chunk, returning its header
+// and the length of that chunk.
+func readPNGHeader(c pngChunks) (pngHeader, uint32, error) {
+	var signature [8]byte
+	if _, err := io.ReadFull(c.r, signature[:]); err != nil || string(signature[:]) != pngSignature {
+		return pngHeader{}, 0, fmt.Errorf("not a PNG file")
+	}
+
+	var h pngHeader
+	var rawPalette, trns []byte
+	for {
+		length, typ, err := c.next()
+		if err != nil {
+			return h, 0, fmt.Errorf("invalid PNG: %w", err)
+		}
+		if typ == "IDAT" {
+			if h.width == 0 {
+				return h, 0, fmt.Errorf("invalid PNG: missing IHDR")
+			}
+			h.palette, h.transparent = pngPalette(h, rawPalette, trns)
+			return h, length, nil
+		}
+		if typ != "IHDR" && typ != "PLTE" && typ != "tRNS" {
+			if _, err := c.r.Discard(int(length) + 4); err != nil {
+				return h, 0, fmt.Errorf("invalid PNG: %w", err)
+			}
+			continue
+		}
+
+		data, err := c.data(typ, length)
+		if err != nil {
+			return h, 0, err
+		}
+		switch typ {
+		case "IHDR":
+			if len(data) != 13 {
+				return h, 0, fmt.Errorf("invalid PNG: bad IHDR length")
+			}
+			h.width = int(binary.BigEndian.Uint32(data[0:4]))
+			h.height = int(binary.BigEndian.Uint32(data[4:8]))
+			h.depth = int(data[8])
+			h.colorType = int(data[9])
+			h.interlaced = data[12] != 0
+			if err := checkPNGFormat(h); err != nil {
+				return h, 0, err
+			}
+		case "PLTE":
+			rawPalette = data
+		case "tRNS":
+			trns = data
+		}
+	}
+}
+
+// checkPNGFormat reports whether h is a valid PNG format.
+func checkPNGFormat(h pngHeader) error {
+	valid := map[int][]int{0: {1, 2, 4, 8, 16}, 2: {8, 16}, 3: {1, 2, 4, 8}, 4: {8, 16}, 6: {8, 16}}
+	for _, depth := range valid[h.colorType] {
+		if depth == h.depth {
+			if h.width <= 0 || h.height <= 0 {
+				return fmt.Errorf("invalid PNG: %dx%d", h.width, h.height)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid PNG: color type %d at bit depth %d", h.colorType, h.depth)
+}
+
+// pngPalette returns the premultiplied colors of a palette PNG, or the
+// transparent samples of tRNS for gray and RGB ones. Indexes past the palette
+// read as opaque black, as image/png decodes them.
+func pngPalette(h pngHeader, rawPalette, trns []byte) ([][4]uint32, []uint16) {
+	if h.colorType != 3 {
+		var transparent []uint16
+		for i := 0; i+1 < len(trns); i += 2 {
+			transparent = append(transparent, binary.BigEndian.Uint16(trns[i:]))
+		}
+		return nil, transparent
+	}
+
+	palette := make([][4]uint32, 256)
+	for i := range palette {
+		palette[i] = [4]uint32{0, 0, 0, 0xffff}
+	}
+	for i := 0; i*3+2 < len(rawPalette); i++ {
+		a := uint32(0xff)
+		if i < len(trns) {
+			a = uint32(trns[i])
+		}
+		for c := 0; c < 3; c++ {
+			palette[i][c] = uint32(rawPalette[i*3+c]) * 0x101 * a / 0xff
+		}
+		palette[i][3] = a * 0x101
+	}
+	return palette, nil
+}
+
+// idatReader reads the image data spread over consecutive IDAT chunks.
+type idatReader struct {
+	chunks    pngChunks
+	remaining uint32
+	crc       uint32
+}
+
+func (d *idatReader) Read(p []byte) (int, error) {
+	for d.remaining == 0 {
+		var sum [4]byte
+		if _, err := io.ReadFull(d.chunks.r, sum[:]); err != nil {
+			return 0, err
+		}
+		if binary.BigEndian.Uint32(sum[:]) != d.crc {
+			return 0, fmt.Errorf("invalid PNG: IDAT checksum mismatch")
+		}
+		length, typ, err := d.chunks.next()
+		if err != nil {
+			return 0, err
+		}
+		if typ != "IDAT" {
+			return 0, io.EOF
+		}
+		d.remaining = length
+		d.crc = crc32.ChecksumIEEE([]byte("IDAT"))
+	}
+
+	if uint32(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.chunks.r.Read(p)
+	d.remaining -= uint32(n)
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, p[:n])
+	return n, err
+}
+
+// unfilterPNGRow reverses the filter of row, whose first byte names it,
+// against the unfiltered previous row prev, for bpp bytes per pixel.
+func unfilterPNGRow(row, prev []byte, bpp int) error {
+	filter, cur := row[0], row[1:]
+	switch filter {
+	case 0:
+	case 1:
+		for i := bpp; i < len(cur); i++ {
+			cur[i] += cur[i-bpp]
+		}
+	case 2:
+		for i := range cur {
+			cur[i] += prev[i]
+		}
+	case 3:
+		for i := range cur {
+			left := 0
+			if i >= bpp {
+				left = int(cur[i-bpp])
+			}
+			cur[i] += byte((left + int(prev[i])) / 2)
+		}
+	case 4:
+		for i := range cur {
+			var a, c int
+			if i >= bpp {
+				a, c = int(cur[i-bpp]), int(prev[i-bpp])
+			}
+			b := int(prev[i])
+			pa, pb, pc := abs(b-c), abs(a-c), abs(a+b-2*c)
+			switch {
+			case pa <= pb && pa <= pc:
+				cur[i] += byte(a)
+			case pb <= pc:
+				cur[i] += byte(b)
+			default:
+				cur[i] += byte(c)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid PNG: unknown filter type %d", filter)
+	}
+	return nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// pngPixel returns the premultiplied 16-bit RGBA of pixel x of the
+// unfiltered row, as image/png's decoded image would.
+func pngPixel(h pngHeader, row []byte, x int) [4]uint32 {
+	sample := func(i int) uint32 {
+		switch h.depth {
+		case 16:
+			return uint32(binary.BigEndian.Uint16(row[i*2:]))
+		case 8:
+			return uint32(row[i])
+		}
+		bit := i * h.depth
+		return uint32(row[bit/8]>>(8-h.depth-bit%8)) & (1<<h.depth - 1)
+	}
+	// Scale a sample to 16 bits
+	full := func(v uint32) uint32 {
+		return v * 0xffff / (1<<h.depth - 1)
+	}
+	transparent := func(samples ...uint32) bool {
+		if len(h.transparent) < len(samples) {
+			return false
+		}
+		for i, v := range samples {
+			if uint32(h.transparent[i]) != v {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch h.colorType {
+	case 0:
+		v := sample(x)
+		if transparent(v) {
+			return [4]uint32{}
+		}
+		return [4]uint32{full(v), full(v), full(v), 0xffff}
+	case 2:
+		r, g, b := sample(x*3), sample(x*3+1), sample(x*3+2)
+		if transparent(r, g, b) {
+			return [4]uint32{}
+		}
+		return [4]uint32{full(r), full(g), full(b), 0xffff}
+	case 3:
+		return h.palette[sample(x)]
+	case 4:
+		v, a := full(sample(x*2)), full(sample(x*2+1))
+		return [4]uint32{v * a / 0xffff, v * a / 0xffff, v * a / 0xffff, a}
+	}
+	a := full(sample(x*4 + 3))
+	return [4]uint32{full(sample(x*4)) * a / 0xffff, full(sample(x*4+1)) * a / 0xffff, full(sample(x*4+2)) * a / 0xffff, a}
+}
+
+// decodePNGReduced decodes a non-interlaced PNG from r a row at a time,
+// averaging every factor x factor block of pixels into one, so only a couple
+// of rows of the full-resolution image are held at once. Blocks at the right
+// and bottom edges average the pixels they have. Like image/png, it keeps
+// 16-bit PNGs at 16 bits, as an *image.RGBA64, and returns an *image.RGBA
+// for the others.
+func decodePNGReduced(r io.Reader, factor int) (image.Image, error) {
+	chunks := pngChunks{bufio.NewReader(r)}
+	h, length, err := readPNGHeader(chunks)
+	if err != nil {
+		return nil, err
+	}
+	if h.interlaced {
+		return nil, fmt.Errorf("interlaced PNGs can't be decoded a row at a time")
+	}
+
+	zr, err := zlib.NewReader(&idatReader{chunks: chunks, remaining: length, crc: crc32.ChecksumIEEE([]byte("IDAT"))})
+	if err != nil {
+		return nil, fmt.Errorf("invalid PNG: %w", err)
+	}
+	defer zr.Close()
+
+	rowBytes := (h.width*h.channels()*h.depth + 7) / 8
+	bpp := (h.channels()*h.depth + 7) / 8
+	row := make([]byte, 1+rowBytes)
+	prev := make([]byte, rowBytes)
+
+	width := (h.width + factor - 1) / factor
+	height := (h.height + factor - 1) / factor
+	var reduced image.Image
+	var set func(x, y int, r, g, b, a uint32)
+	if h.depth == 16 {
+		deep := image.NewRGBA64(image.Rect(0, 0, width, height))
+		reduced, set = deep, func(x, y int, r, g, b, a uint32) { setPixel64(deep, x, y, r, g, b, a) }
+	} else {
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		reduced, set = rgba, func(x, y int, r, g, b, a uint32) { setPixel(rgba, x, y, r, g, b, a) }
+	}
+	sums := make([][4]uint64, width)
+
+	for y := 0; y < h.height; y++ {
+		if _, err := io.ReadFull(zr, row); err != nil {
+			return nil, fmt.Errorf("invalid PNG: row %d: %w", y, err)
+		}
+		if err := unfilterPNGRow(row, prev, bpp); err != nil {
+			return nil, err
+		}
+		for x := 0; x < h.width; x++ {
+			pixel := pngPixel(h, row[1:], x)
+			for c := 0; c < 4; c++ {
+				sums[x/factor][c] += uint64(pixel[c])
+			}
+		}
+		copy(prev, row[1:])
+
+		// Write a row of blocks once it is complete
+		if y%factor != factor-1 && y != h.height-1 {
+			continue
+		}
+		rows := y%factor + 1
+		for bx := range sums {
+			count := uint64(rows * (minInt(h.width, (bx+1)*factor) - bx*factor))
+			set(bx, y/factor, uint32(sums[bx][0]/count), uint32(sums[bx][1]/count), uint32(sums[bx][2]/count), uint32(sums[bx][3]/count))
+			sums[bx] = [4]uint64{}
+		}
+	}
+
+	// Read to the end of the data so its checksums are verified
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return nil, fmt.Errorf("invalid PNG: %w", err)
+	}
+	return reduced, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// pngStreamable reports whether the file at path is a PNG decodePNGReduced
+// can read, that is one that isn't interlaced.
+func pngStreamable(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	h, _, err := readPNGHeader(pngChunks{bufio.NewReader(file)})
+	return err == nil && !h.interlaced
+}
+
+// loadReducedPNG decodes the PNG at path at 1/factor of its size with
+// decodePNGReduced.
+func loadReducedPNG(path string, factor int) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodePNGReduced(file, factor)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noisyImage returns a width x height image of model with every pixel
+// different, so every PNG filter type gets used.
+func noisyImage(width, height int, model color.Model) image.Image {
+	img := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			seed = seed*1664525 + 1013904223
+			img.Set(x, y, model.Convert(color.NRGBA64{uint16(seed), uint16(seed >> 8), uint16(x * 300), uint16(seed >> 16)}))
+		}
+	}
+	return img
+}
+
+// palettedImage returns an image using colors entries of a palette, with
+// transparent ones if alpha is set.
+func palettedImage(width, height, colors int, alpha bool) image.Image {
+	palette := make(color.Palette, colors)
+	for i := range palette {
+		a := uint8(255)
+		if alpha {
+			a = uint8(i * 255 / colors)
+		}
+		palette[i] = color.NRGBA{uint8(i * 37), uint8(i * 91), uint8(255 - i), a}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	for i := range img.Pix {
+		img.Pix[i] = uint8((i*7 + i/width) % colors)
+	}
+	return img
+}
+
+// boxReduce averages every factor x factor block of img like
+// decodePNGReduced, into an *image.RGBA64 for a 16-bit img.
+func boxReduce(img image.Image, factor int) image.Image {
+	b := img.Bounds()
+	rect := image.Rect(0, 0, (b.Dx()+factor-1)/factor, (b.Dy()+factor-1)/factor)
+	var reduced image.Image = image.NewRGBA(rect)
+	if deepImage(img) {
+		reduced = image.NewRGBA64(rect)
+	}
+	for by := 0; by < rect.Dy(); by++ {
+		for bx := 0; bx < rect.Dx(); bx++ {
+			var sum [4]uint64
+			var count uint64
+			for y := by * factor; y < (by+1)*factor && y < b.Dy(); y++ {
+				for x := bx * factor; x < (bx+1)*factor && x < b.Dx(); x++ {
+					r, g, bl, a := img.At(x, y).RGBA()
+					sum[0], sum[1], sum[2], sum[3] = sum[0]+uint64(r), sum[1]+uint64(g), sum[2]+uint64(bl), sum[3]+uint64(a)
+					count++
+				}
+			}
+			r, g, bl, a := uint32(sum[0]/count), uint32(sum[1]/count), uint32(sum[2]/count), uint32(sum[3]/count)
+			if deep, ok := reduced.(*image.RGBA64); ok {
+				setPixel64(deep, bx, by, r, g, bl, a)
+			} else {
+				setPixel(reduced.(*image.RGBA), bx, by, r, g, bl, a)
+			}
+		}
+	}
+	return reduced
+}
+
+// pixBytes returns the pixel data of an *image.RGBA or *image.RGBA64.
+func pixBytes(img image.Image) []byte {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return img.Pix
+	case *image.RGBA64:
+		return img.Pix
+	}
+	return nil
+}
+
+func TestDecodePNGReduced(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"gray", noisyImage(67, 45, color.GrayModel)},
+		{"gray16", noisyImage(67, 45, color.Gray16Model)},
+		{"rgb", noisyImage(67, 45, color.RGBAModel)},
+		{"nrgba", noisyImage(300, 200, color.NRGBAModel)},
+		{"nrgba64", noisyImage(67, 45, color.NRGBA64Model)},
+		{"palette 1-bit", palettedImage(67, 45, 2, false)},
+		{"palette 2-bit", palettedImage(67, 45, 4, false)},
+		{"palette 4-bit", palettedImage(67, 45, 16, true)},
+		{"palette 8-bit", palettedImage(67, 45, 200, true)},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, tt.img); err != nil {
+			t.Fatalf("Failed to encode %s: %v", tt.name, err)
+		}
+		decoded, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", tt.name, err)
+		}
+
+		for _, factor := range []int{1, 2, 3, 8} {
+			t.Run(fmt.Sprintf("%s/%d", tt.name, factor), func(t *testing.T) {
+				got, err := decodePNGReduced(bytes.NewReader(buf.Bytes()), factor)
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				want := boxReduce(decoded, factor)
+				if got.Bounds() != want.Bounds() {
+					t.Fatalf("Expected %v, got %v", want.Bounds(), got.Bounds())
+				}
+				if deepImage(got) != deepImage(decoded) {
+					t.Fatalf("Expected a 16-bit result for a 16-bit PNG only, got %T for %T", got, decoded)
+				}
+				if !bytes.Equal(pixBytes(got), pixBytes(want)) {
+					t.Errorf("Expected the pixels of image/png averaged over %dx%d blocks", factor, factor)
+				}
+			})
+		}
+	}
+}
+
+// interlace marks the PNG data as interlaced, without re-encoding its pixels.
+func interlace(data []byte) []byte {
+	data = append([]byte(nil), data...)
+	ihdr := data[8+8 : 8+8+13]
+	ihdr[12] = 1
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+func TestDecodePNGReducedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, noisyImage(20, 20, color.NRGBAModel))
+	data := buf.Bytes()
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	// IHDR claiming 0xffffffff bytes, which would wrap to a 3-byte buffer
+	oversized := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(oversized[8:12], 0xffffffff)
+	// IHDR claiming more bytes than the file holds
+	overlong := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(overlong[8:12], 0x7fffffff)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a PNG", []byte("GIF89a")},
+		{"interlaced", interlace(data)},
+		{"corrupt data", corrupt},
+		{"truncated", data[:len(data)/2]},
+		{"oversized chunk", oversized},
+		{"chunk past the end", overlong},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodePNGReduced(bytes.NewReader(tt.data), 2); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestReducedSource(t *testing.T) {
+	config := Config{Preset: "android", TrimPercent: 80}
+	need := estimateMemory(config, 1200, 1200)
+	config.MaxMemory = fmt.Sprint(need - 1)
+
+	dir := t.TempDir()
+	source := noisyImage(1200, 1200, color.NRGBAModel)
+
+	pngPath := filepath.Join(dir, "source.png")
+	if err := saveImage(source, pngPath); err != nil {
+		t.Fatalf("Failed to save source: %v", err)
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, source)
+	interlacedPath := filepath.Join(dir, "interlaced.png")
+	os.WriteFile(interlacedPath, interlace(buf.Bytes()), 0644)
+	jpegPath := filepath.Join(dir, "source.jpg")
+	file, _ := os.Create(jpegPath)
+	jpeg.Encode(file, source, nil)
+	file.Close()
+
+	config.InputPath = pngPath
+	if factor, err := checkSourceLimits(config); err != nil || factor != 2 {
+		t.Errorf("Expected the PNG decoded at half size, got %d, %v", factor, err)
+	}
+	img, err := loadSource(config)
+	if err != nil {
+		t.Fatalf("Failed to load the reduced source: %v", err)
+	}
+	if img.Bounds().Dx() != 600 {
+		t.Errorf("Expected a 600px source, got %v", img.Bounds())
+	}
+
+	for _, path := range []string{interlacedPath, jpegPath} {
+		config.InputPath = path
+		if _, err := checkSourceLimits(config); err == nil || !strings.Contains(err.Error(), "non-interlaced PNG") {
+			t.Errorf("%s: expected an error about PNG sources, got %v", filepath.Base(path), err)
+		}
+	}
+
+	// Shrinking below the largest icon isn't an option
+	config.InputPath = pngPath
+	config.MaxMemory = "1MiB"
+	if _, err := checkSourceLimits(config); err == nil || strings.Contains(err.Error(), "non-interlaced PNG") {
+		t.Errorf("Expected the plain --max-memory error, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+	"sync"
+)
+
+// The buffers a stage builds and drops again, such as the working channels of
+// resizeFiltered and the padded canvas of addPadding, are recycled through
+// these pools instead of being allocated for every icon. Images a stage
+// returns are never pooled: the caller owns them.
+var (
+	channelPool sync.Pool // *[][4]float64
+	canvasPool  sync.Pool // *image.RGBA
+)
+
+// getChannels returns n zeroed pixels of float channels, recycled from
+// channelPool if possible. Hand them back with putChannels.
+func getChannels(n int) *[][4]float64 {
+	if p, ok := channelPool.Get().(*[][4]float64); ok && cap(*p) >= n {
+		*p = (*p)[:n]
+		for i := range *p {
+			(*p)[i] = [4]float64{}
+		}
+		return p
+	}
+	p := make([][4]float64, n)
+	return &p
+}
+
+func putChannels(p *[][4]float64) {
+	channelPool.Put(p)
+}
+
+// getCanvas returns a transparent image of r, recycled from canvasPool if
+// possible. Hand it back with putCanvas once nothing refers to it.
+func getCanvas(r image.Rectangle) *image.RGBA {
+	n := 4 * r.Dx() * r.Dy()
+	if img, ok := canvasPool.Get().(*image.RGBA); ok && cap(img.Pix) >= n {
+		img.Pix = img.Pix[:n]
+		for i := range img.Pix {
+			img.Pix[i] = 0
+		}
+		img.Stride = 4 * r.Dx()
+		img.Rect = r
+		return img
+	}
+	return image.NewRGBA(r)
+}
+
+func putCanvas(img *image.RGBA) {
+	canvasPool.Put(img)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGetCanvasIsTransparent(t *testing.T) {
+	used := getCanvas(image.Rect(0, 0, 8, 8))
+	for i := range used.Pix {
+		used.Pix[i] = 0xff
+	}
+	putCanvas(used)
+
+	// Whether recycled or new, a canvas comes back cleared at its size
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 4, 6), image.Rect(0, 0, 16, 16)} {
+		canvas := getCanvas(r)
+		if canvas.Bounds() != r || canvas.Stride != 4*r.Dx() || len(canvas.Pix) != 4*r.Dx()*r.Dy() {
+			t.Errorf("Expected a canvas of %v, got %v with stride %d", r, canvas.Bounds(), canvas.Stride)
+		}
+		for i, v := range canvas.Pix {
+			if v != 0 {
+				t.Fatalf("Expected a transparent canvas of %v, got %#x at %d", r, v, i)
+			}
+		}
+		putCanvas(canvas)
+	}
+}
+
+func TestGetChannelsIsZeroed(t *testing.T) {
+	used := getChannels(10)
+	for i := range *used {
+		(*used)[i] = [4]float64{1, 2, 3, 4}
+	}
+	putChannels(used)
+
+	channels := getChannels(6)
+	if len(*channels) != 6 {
+		t.Fatalf("Expected 6 pixels, got %d", len(*channels))
+	}
+	for i, c := range *channels {
+		if c != ([4]float64{}) {
+			t.Fatalf("Expected zeroed channels, got %v at %d", c, i)
+		}
+	}
+	putChannels(channels)
+}
+
+func TestPooledStagesRepeat(t *testing.T) {
+	// Recycled buffers don't carry over into the next result
+	red := createTestImageWithSquare(64, 40, color.RGBA{255, 0, 0, 255})
+	blue := createTestImageWithSquare(64, 20, color.RGBA{0, 0, 255, 255})
+
+	first := addPadding(red, 10, 64)
+	addPadding(blue, 10, 64)
+	if again := addPadding(red, 10, 64); !bytes.Equal(first.Pix, again.Pix) {
+		t.Errorf("Expected addPadding to repeat its result")
+	}
+
+	filtered := resizeFiltered(red, 16, lanczos3Filter)
+	resizeFiltered(blue, 16, lanczos3Filter)
+	if again := resizeFiltered(red, 16, lanczos3Filter); !bytes.Equal(filtered.Pix, again.Pix) {
+		t.Errorf("Expected resizeFiltered to repeat its result")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// preset is a named set of outputs for a target platform.
+type preset struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Sizes       []IconSize `json:"sizes"`
+}
+
+// presets lists the output sets icongen knows, in the order they're listed
+// to users.
+var presets = []preset{
+	{
+		Name:        "macos",
+		Description: "macOS .iconset PNGs from 16x16 to 512x512@2x, plus a 1024x1024 base",
+		Sizes:       iconSizes,
+	},
+	{
+		Name:        "web",
+		Description: "Favicons, the Apple touch icon and Android Chrome icons, with a site.webmanifest and a head.html snippet",
+		Sizes:       webIconSizes,
+	},
+	{
+		Name:        "android",
+		Description: "Android launcher icons, mipmap-mdpi to mipmap-xxxhdpi/ic_launcher.png, ready to use as a res directory",
+		Sizes: []IconSize{
+			{"mipmap-mdpi/ic_launcher.png", 48},
+			{"mipmap-hdpi/ic_launcher.png", 72},
+			{"mipmap-xhdpi/ic_launcher.png", 96},
+			{"mipmap-xxhdpi/ic_launcher.png", 144},
+			{"mipmap-xxxhdpi/ic_launcher.png", 192},
+		},
+	},
+	{
+		Name:        notificationPreset,
+		Description: "Android notification icons, drawable-mdpi to drawable-xxxhdpi/ic_stat_notification.png, as white silhouettes of the artwork",
+		Sizes:       notificationSizes,
+	},
+	{
+		Name:        complicationPreset,
+		Description: "Apple Watch complication images for every watch case, as a Complication.complicationset with its Contents.json files",
+		Sizes:       complicationSizes(),
+	},
+	{
+		Name:        templatePreset,
+		Description: "macOS menu bar and toolbar template images, icon_16x16Template.png to icon_22x22Template@2x.png, as black silhouettes of the artwork",
+		Sizes:       templateSizes,
+	},
+}
+
+// findPreset returns the preset called name.
+func findPreset(name string) (preset, error) {
+	var names []string
+	for _, p := range presets {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return preset{}, fmt.Errorf("unknown preset %q (use one of %s)", name, strings.Join(names, ", "))
+}
+
+// parsePresets parses a comma-separated --preset spec such as "macos,web".
+// An empty spec means macOS.
+func parsePresets(spec string) ([]preset, error) {
+	if spec == "" {
+		spec = "macos"
+	}
+
+	var selected []preset
+	seen := make(map[string]bool)
+	names := make(map[string]string)
+	for _, name := range strings.Split(spec, ",") {
+		p, err := findPreset(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate preset %q", name)
+		}
+		seen[name] = true
+
+		for _, iconSize := range p.Sizes {
+			if other, ok := names[iconSize.Name]; ok {
+				return nil, fmt.Errorf("presets %s and %s both write %s", other, name, iconSize.Name)
+			}
+			names[iconSize.Name] = name
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// hasPreset reports whether config generates the preset called name.
+func hasPreset(config Config, name string) bool {
+	selected, err := parsePresets(config.Preset)
+	if err != nil {
+		return false
+	}
+	for _, p := range selected {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// outputSizes returns the icon sizes of the configured presets in generation
+// order, with hashed web preset names under --hash-names. The sizes listed
+// in --first come first. --no-upscale leaves out those larger than the source.
+func outputSizes(config Config) []IconSize {
+	sizes := presetSizes(config)
+	if config.NoUpscale == "skip" {
+		sizes = withoutUpscaled(config, sizes)
+	}
+	return sizes
+}
+
+// presetSizes returns the icon sizes of the configured presets as
+// outputSizes does, whether the source fills them or not.
+func presetSizes(config Config) []IconSize {
+	selected, err := parsePresets(config.Preset)
+	if err != nil {
+		return iconSizes
+	}
+
+	var sizes []IconSize
+	for _, p := range selected {
+		if config.HashNames && p.Name == "web" {
+			sizes = append(sizes, hashedIconSizes(config, p.Sizes)...)
+			continue
+		}
+		sizes = append(sizes, p.Sizes...)
+	}
+	if config.First != "" {
+		if first, err := parseFirstSizes(config.First, sizes); err == nil {
+			sizes = prioritizeSizes(sizes, first)
+		}
+	}
+	return sizes
+}
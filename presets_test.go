@@ -0,0 +1,133 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindPreset(t *testing.T) {
+	for _, name := range []string{"macos", "android"} {
+		if _, err := findPreset(name); err != nil {
+			t.Errorf("Expected preset %s: %v", name, err)
+		}
+	}
+	if _, err := findPreset("windows"); err == nil {
+		t.Errorf("Expected error for unknown preset")
+	}
+}
+
+func TestParsePresets(t *testing.T) {
+	tests := []struct {
+		spec      string
+		sizes     int
+		expectErr bool
+	}{
+		{"", len(iconSizes), false},
+		{"macos", len(iconSizes), false},
+		{"macos,web", len(iconSizes) + len(webIconSizes), false},
+		{"web,android,macos", len(iconSizes) + len(webIconSizes) + 5, false},
+		{"macos,macos", 0, true},
+		{"macos,windows", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parsePresets(tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if sizes := outputSizes(Config{Preset: tt.spec}); len(sizes) != tt.sizes {
+				t.Errorf("Expected %d sizes, got %d", tt.sizes, len(sizes))
+			}
+		})
+	}
+}
+
+func TestMultiplePresets(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 200, 80, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "macos,web", TrimPercent: 80, HashNames: true}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, name := range []string{"icon_1024x1024.png", webManifestName, webHeadName} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	// Only the web preset's names are hashed
+	if matches, _ := filepath.Glob(filepath.Join(outputDir, "favicon-32x32.*.png")); len(matches) != 1 {
+		t.Errorf("Expected a hashed favicon-32x32, got %v", matches)
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, Preset: "web,android", ContentsJSON: true}); err == nil {
+		t.Errorf("Expected --contents-json without the macos preset to fail")
+	}
+}
+
+func TestAndroidPresetStateless(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 200, 80, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:   inputPath,
+		OutputDir:   outputDir,
+		Preset:      "android",
+		TrimPercent: 80,
+		Stateless:   true,
+		Force:       true,
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, name := range []string{"mipmap-mdpi/ic_launcher.png", "mipmap-xxxhdpi/ic_launcher.png"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	if width, _, _ := imageDimensions(filepath.Join(outputDir, "mipmap-xxxhdpi", "ic_launcher.png")); width != 192 {
+		t.Errorf("Expected xxxhdpi launcher icon to be 192 pixels, got %d", width)
+	}
+
+	// Gradle snapshots the whole output directory, so nothing else belongs in it
+	if _, err := os.Stat(filepath.Join(outputDir, manifestName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest in stateless mode")
+	}
+
+	// Without a manifest every run regenerates, overwriting thanks to --force
+	if err := generateIcons(config); err != nil {
+		t.Errorf("Expected stateless rerun to succeed, got: %v", err)
+	}
+}
+
+func TestLocalName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"icon_16x16.png", true},
+		{"mipmap-mdpi/ic_launcher.png", true},
+		{"../icon.png", false},
+		{"/etc/passwd", false},
+		{"a/../../b", false},
+		{".", false},
+		{"..", false},
+	}
+
+	for _, tt := range tests {
+		if got := localName(tt.name); got != tt.want {
+			t.Errorf("Expected localName(%q) to be %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+const previewName = "index.html"
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { font: 13px -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 24px; color: #222; }
+  table { border-collapse: collapse; }
+  th, td { padding: 12px 16px; text-align: center; vertical-align: middle; }
+  th { font-weight: 600; }
+  td.name { text-align: left; font-family: ui-monospace, Menlo, monospace; }
+  td.light { background: #ffffff; }
+  td.dark { background: #1c1c1e; }
+  td.pattern { background: repeating-conic-gradient(#d0d0d0 0 25%, #ffffff 0 50%) 0 0 / 16px 16px; }
+  img { display: block; margin: 0 auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>File</th><th>Size</th><th>Light</th><th>Dark</th><th>Pattern</th></tr>
+{{- range .Icons}}
+<tr>
+  <td class="name">{{.Name}}</td>
+  <td>{{.Width}}×{{.Height}}</td>
+  <td class="light"><img src="{{.Name}}" width="{{.Width}}" height="{{.Height}}" alt=""></td>
+  <td class="dark"><img src="{{.Name}}" width="{{.Width}}" height="{{.Height}}" alt=""></td>
+  <td class="pattern"><img src="{{.Name}}" width="{{.Width}}" height="{{.Height}}" alt=""></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// previewTargets lists the outputs --preview-html adds to a run.
+func previewTargets(config Config) []plannedOutput {
+	if !config.PreviewHTML {
+		return nil
+	}
+	return []plannedOutput{{Name: previewName, Format: "html", Variant: "preview"}}
+}
+
+// writePreview writes an HTML gallery of every image recorded so far in state
+// to the output directory, showing each at actual size on light, dark and
+// patterned backgrounds.
+func writePreview(config Config, state *manifestState) error {
+	icons := iconFiles(state)
+
+	var buf bytes.Buffer
+	err := previewTemplate.Execute(&buf, struct {
+		Title string
+		Icons []manifestFile
+	}{
+		Title: "Icons generated from " + filepath.Base(config.InputPath),
+		Icons: icons,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(logOutput(config), " - %s (%d icons)\n", previewName, len(icons))
+	if err := os.WriteFile(filepath.Join(config.OutputDir, previewName), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", previewName, err)
+	}
+
+	if err := state.record(previewName); err != nil {
+		return fmt.Errorf("failed to record %s: %w", previewName, err)
+	}
+	return nil
+}
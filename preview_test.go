@@ -0,0 +1,47 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePreview(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{255, 0, 128, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		PreviewHTML:   true,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, previewName))
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", previewName, err)
+	}
+	html := string(data)
+
+	for _, name := range expectedOutputs(config) {
+		if name == previewName {
+			continue
+		}
+		if !strings.Contains(html, `src="`+name+`"`) {
+			t.Errorf("Expected preview to show %s", name)
+		}
+	}
+
+	if !strings.Contains(html, `width="1024" height="1024"`) {
+		t.Errorf("Expected icons to be shown at actual size")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseFirstSizes parses a --first spec, a comma-separated list of output
+// sizes in pixels such as "16,32", in the order they should be generated.
+func parseFirstSizes(spec string, sizes []IconSize) ([]int, error) {
+	known := make(map[int]bool)
+	for _, iconSize := range sizes {
+		known[iconSize.Size] = true
+	}
+
+	var first []int
+	seen := make(map[int]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || !known[size] {
+			return nil, fmt.Errorf("invalid --first size %q (use one of %s)", entry, knownSizes(sizes))
+		}
+		if seen[size] {
+			return nil, fmt.Errorf("duplicate --first size %d", size)
+		}
+		seen[size] = true
+		first = append(first, size)
+	}
+	return first, nil
+}
+
+// prioritizeSizes moves the sizes listed in first to the front of sizes, in
+// the order of first. The rest, and outputs of the same size, keep their
+// order.
+func prioritizeSizes(sizes []IconSize, first []int) []IconSize {
+	rank := make(map[int]int)
+	for i, size := range first {
+		rank[size] = i
+	}
+	priority := func(iconSize IconSize) int {
+		if r, ok := rank[iconSize.Size]; ok {
+			return r
+		}
+		return len(first)
+	}
+
+	prioritized := append([]IconSize(nil), sizes...)
+	sort.SliceStable(prioritized, func(i, j int) bool {
+		return priority(prioritized[i]) < priority(prioritized[j])
+	})
+	return prioritized
+}
+
+// firstOutputCount returns how many of the output sizes of config --first
+// moves to the front.
+func firstOutputCount(config Config) int {
+	if config.First == "" {
+		return 0
+	}
+	first, err := parseFirstSizes(config.First, outputSizes(config))
+	if err != nil {
+		return 0
+	}
+	listed := make(map[int]bool)
+	for _, size := range first {
+		listed[size] = true
+	}
+
+	count := 0
+	for _, iconSize := range outputSizes(config) {
+		if listed[iconSize.Size] {
+			count++
+		}
+	}
+	return count
+}
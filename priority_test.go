@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestParseFirstSizes(t *testing.T) {
+	tests := []struct {
+		spec      string
+		expectErr bool
+	}{
+		{"16,32", false},
+		{"1024", false},
+		{"32, 16", false},
+		{"17", true},
+		{"16,16", true},
+		{"16px", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseFirstSizes(tt.spec, iconSizes)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFirstSizesOrder(t *testing.T) {
+	config := Config{First: "32,16"}
+	sizes := outputSizes(config)
+	if len(sizes) != len(iconSizes) {
+		t.Fatalf("Expected %d sizes, got %d", len(iconSizes), len(sizes))
+	}
+
+	// The 32 pixel outputs come first in their usual order, then 16
+	want := []string{"icon_16x16@2x.png", "icon_32x32.png", "icon_16x16.png", "icon_32x32@2x.png"}
+	for i, name := range want {
+		if sizes[i].Name != name {
+			t.Errorf("Expected output %d to be %s, got %s", i, name, sizes[i].Name)
+		}
+	}
+	if count := firstOutputCount(config); count != 3 {
+		t.Errorf("Expected 3 outputs moved to the front, got %d", count)
+	}
+
+	// The embedded settings still go in the last of the largest icons
+	if carrier := settingsCarrier(Config{First: "1024"}); carrier != "icon_1024x1024.png" {
+		t.Errorf("Expected the settings carrier to stay icon_1024x1024.png, got %s", carrier)
+	}
+	if count := firstOutputCount(Config{}); count != 0 {
+		t.Errorf("Expected no outputs moved without --first, got %d", count)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts the CPU profile and execution trace that
+// --cpuprofile and --trace ask for, and returns a function that stops them
+// and writes the --memprofile heap profile, to call once the run is done.
+// Read the results with go tool pprof and go tool trace.
+func startProfiling(config Config) (func() error, error) {
+	var stops []func() error
+	stop := func() error {
+		var first error
+		for i := len(stops) - 1; i >= 0; i-- {
+			if err := stops[i](); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	if config.CPUProfile != "" {
+		file, err := os.Create(config.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		})
+	}
+
+	if config.Trace != "" {
+		file, err := os.Create(config.Trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return file.Close()
+		})
+	}
+
+	if config.MemProfile != "" {
+		stops = append(stops, func() error {
+			file, err := os.Create(config.MemProfile)
+			if err != nil {
+				return fmt.Errorf("failed to create memory profile: %w", err)
+			}
+			defer file.Close()
+
+			// Collect first so the profile shows what the run left live
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(file); err != nil {
+				return fmt.Errorf("failed to write memory profile: %w", err)
+			}
+			return nil
+		})
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiling(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		InputPath:   createTempImageFile(t, createTestImage(300, color.RGBA{255, 128, 0, 255})),
+		OutputDir:   filepath.Join(dir, "icons"),
+		TrimPercent: 80,
+		CropEnabled: true,
+		CPUProfile:  filepath.Join(dir, "cpu.pprof"),
+		MemProfile:  filepath.Join(dir, "mem.pprof"),
+		Trace:       filepath.Join(dir, "run.trace"),
+	}
+
+	stop, err := startProfiling(config)
+	if err != nil {
+		t.Fatalf("Failed to start profiling: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Failed to stop profiling: %v", err)
+	}
+
+	// pprof profiles are gzipped protobufs
+	for _, path := range []string{config.CPUProfile, config.MemProfile} {
+		data, err := os.ReadFile(path)
+		if err != nil || !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+			t.Errorf("Expected a pprof profile in %s, got %d bytes, %v", filepath.Base(path), len(data), err)
+		}
+	}
+	if data, err := os.ReadFile(config.Trace); err != nil || !bytes.HasPrefix(data, []byte("go 1.")) {
+		t.Errorf("Expected an execution trace, got %d bytes, %v", len(data), err)
+	}
+
+	if _, ok := generationSettings(config)["cpuprofile"]; ok {
+		t.Errorf("Expected the profiling flags left out of the generation settings")
+	}
+}
+
+func TestProfilingErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing", "out.pprof")
+
+	// A trace that can't be created stops the CPU profile started before it
+	config := Config{CPUProfile: filepath.Join(dir, "cpu.pprof"), Trace: missing}
+	if _, err := startProfiling(config); err == nil {
+		t.Fatalf("Expected an error for a trace in a missing directory")
+	}
+	stop, err := startProfiling(Config{CPUProfile: filepath.Join(dir, "again.pprof")})
+	if err != nil {
+		t.Fatalf("Expected the CPU profile to be stopped after the error, got %v", err)
+	}
+	stop()
+
+	stop, err = startProfiling(Config{MemProfile: missing})
+	if err != nil {
+		t.Fatalf("Expected the memory profile to be written at the end, got %v", err)
+	}
+	if err := stop(); err == nil {
+		t.Errorf("Expected an error writing the memory profile")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// pngTextChunk is a keyword and text written into a saved PNG as tEXt.
+type pngTextChunk struct {
+	Keyword string
+	Text    string
+}
+
+// creationTimeFormat is the RFC 1123 date format the PNG specification
+// recommends for the Creation Time keyword.
+const creationTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// outputText lists the text chunks saveOutput writes into the PNG called
+// name: the --asset-version, with --provenance the tool, the source image's
+// hash and the creation time, if there's one to record, the --meta entries,
+// and in the settings carrier the generation settings.
+func outputText(config Config, state *manifestState, name string) []pngTextChunk {
+	var chunks []pngTextChunk
+	if config.AssetVersion != "" {
+		chunks = append(chunks, pngTextChunk{"Version", config.AssetVersion})
+	}
+	if config.Provenance {
+		chunks = append(chunks,
+			pngTextChunk{"Software", software()},
+			pngTextChunk{"Source SHA-256", state.sourceHash},
+		)
+		if created, ok := creationTime(config.Reproducible); ok {
+			chunks = append(chunks, pngTextChunk{"Creation Time", created.Format(creationTimeFormat)})
+		}
+	}
+	if config.Meta != "" {
+		meta, _ := parseMeta(config.Meta)
+		chunks = append(chunks, meta...)
+	}
+	if name == settingsCarrier(config) {
+		chunks = append(chunks, pngTextChunk{settingsKeyword, settingsText(config)})
+	}
+	return chunks
+}
+
+// software returns the Software text --provenance records: icongen, with
+// its version when it was installed from a tagged release.
+func software() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return "icongen " + info.Main.Version
+	}
+	return "icongen"
+}
+
+// creationTime returns the time to record as the creation time: the
+// SOURCE_DATE_EPOCH reproducible builds set, or else now. With
+// --reproducible there's no time to record unless SOURCE_DATE_EPOCH is set.
+func creationTime(reproducible bool) (time.Time, bool) {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC(), true
+	}
+	if reproducible {
+		return time.Time{}, false
+	}
+	return time.Now().UTC(), true
+}
+
+// saveTextImage saves img as a PNG at path with chunks, then the text
+// chunks inserted after the IHDR chunk, in order.
+func saveTextImage(img image.Image, path string, chunks []pngChunk, text []pngTextChunk) error {
+	if len(chunks) == 0 && len(text) == 0 {
+		return saveImage(img, path)
+	}
+
+	data, err := encodeTextImage(img, chunks, text)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// encodeTextImage encodes img as a PNG with chunks, then the text chunks,
+// inserted after the IHDR chunk, in order.
+func encodeTextImage(img image.Image, chunks []pngChunk, text []pngTextChunk) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	for i := len(text) - 1; i >= 0; i-- {
+		var err error
+		if data, err = addPNGText(data, text[i].Keyword, text[i].Text); err != nil {
+			return nil, err
+		}
+	}
+	for i := len(chunks) - 1; i >= 0; i-- {
+		var err error
+		if data, err = addPNGChunk(data, chunks[i].Type, chunks[i].Data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
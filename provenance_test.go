@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvenanceText(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Provenance: true, AssetVersion: "2.0"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	sourceHash, _ := hashFile(inputPath)
+
+	for keyword, want := range map[string]string{
+		"Version":        "2.0",
+		"Software":       "icongen",
+		"Source SHA-256": sourceHash,
+		"Creation Time":  "Tue, 14 Nov 2023 22:13:20 GMT",
+	} {
+		if got, ok := pngText(data, keyword); !ok || got != want {
+			t.Errorf("Expected %s %q, got %q", keyword, want, got)
+		}
+	}
+}
+
+func TestNoProvenanceByDefault(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	if err := generateIcons(Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80}); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	if _, ok := pngText(data, "Software"); ok {
+		t.Errorf("Expected no provenance text without --provenance")
+	}
+}
+
+func TestReproducibleOutputs(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	inputPath := createTempImageFile(t, createTestImage(128, color.RGBA{0, 128, 255, 255}))
+	config := Config{InputPath: inputPath, TrimPercent: 80, RadiusPercent: 20, Preset: "macos,web",
+		Provenance: true, Reproducible: true, Jobs: 4}
+
+	var runs []string
+	for i := 0; i < 2; i++ {
+		runConfig := config
+		runConfig.OutputDir = t.TempDir()
+		if err := generateIcons(runConfig); err != nil {
+			t.Fatalf("Failed to generate icons: %v", err)
+		}
+		runs = append(runs, runConfig.OutputDir)
+	}
+
+	entries, err := os.ReadDir(runs[0])
+	if err != nil {
+		t.Fatalf("Failed to list outputs: %v", err)
+	}
+	for _, entry := range entries {
+		first, err1 := os.ReadFile(filepath.Join(runs[0], entry.Name()))
+		second, err2 := os.ReadFile(filepath.Join(runs[1], entry.Name()))
+		if err1 != nil || err2 != nil {
+			t.Errorf("Failed to read %s: %v, %v", entry.Name(), err1, err2)
+			continue
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("Expected %s byte-identical across runs", entry.Name())
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(runs[0], "icon_32x32.png"))
+	if err != nil {
+		t.Fatalf("Failed to read icon: %v", err)
+	}
+	if _, ok := pngText(data, "Creation Time"); ok {
+		t.Errorf("Expected no creation time with --reproducible and no SOURCE_DATE_EPOCH")
+	}
+	if _, ok := pngText(data, "Source SHA-256"); !ok {
+		t.Errorf("Expected the rest of the provenance with --reproducible")
+	}
+}
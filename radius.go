@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// radiusSpec is a corner radius given either in pixels or as a percentage of
+// the icon size.
+type radiusSpec struct {
+	Value   int
+	Percent bool
+}
+
+// resolve returns the radius in pixels for an icon of size pixels, capped at
+// half the size.
+func (r radiusSpec) resolve(size int) int {
+	radius := r.Value
+	if r.Percent {
+		radius = size * r.Value / 100
+	}
+	if radius > size/2 {
+		radius = size / 2
+	}
+	return radius
+}
+
+// parseRadiusOverrides parses a --radius-sizes spec of the form
+// SIZE:RADIUS[,SIZE:RADIUS...], where SIZE is an output size in pixels and
+// RADIUS is either "Npx" or "N%", e.g. "16:3px,32:5px,1024:22%".
+func parseRadiusOverrides(spec string, sizes []IconSize) (map[int]radiusSpec, error) {
+	known := make(map[int]bool)
+	for _, iconSize := range sizes {
+		known[iconSize.Size] = true
+	}
+
+	overrides := make(map[int]radiusSpec)
+	for _, entry := range strings.Split(spec, ",") {
+		sizeText, radiusText, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid radius override %q (expected SIZE:Npx or SIZE:N%%)", entry)
+		}
+
+		size, err := strconv.Atoi(sizeText)
+		if err != nil || !known[size] {
+			return nil, fmt.Errorf("invalid radius override %q (%s isn't an output size; use one of %s)", entry, sizeText, knownSizes(sizes))
+		}
+		if _, dup := overrides[size]; dup {
+			return nil, fmt.Errorf("duplicate radius override for size %d", size)
+		}
+
+		var r radiusSpec
+		switch {
+		case strings.HasSuffix(radiusText, "px"):
+			r.Value, err = strconv.Atoi(strings.TrimSuffix(radiusText, "px"))
+		case strings.HasSuffix(radiusText, "%"):
+			r.Value, err = strconv.Atoi(strings.TrimSuffix(radiusText, "%"))
+			r.Percent = true
+		default:
+			err = fmt.Errorf("missing unit")
+		}
+		if err != nil || r.Value < 0 || (r.Percent && r.Value > 50) {
+			return nil, fmt.Errorf("invalid radius override %q (expected SIZE:Npx or SIZE:N%% with N%% between 0 and 50)", entry)
+		}
+
+		overrides[size] = r
+	}
+	return overrides, nil
+}
+
+// knownSizes lists the distinct pixel sizes of iconSizes.
+func knownSizes(iconSizes []IconSize) string {
+	seen := make(map[int]bool)
+	var sizes []int
+	for _, iconSize := range iconSizes {
+		if !seen[iconSize.Size] {
+			seen[iconSize.Size] = true
+			sizes = append(sizes, iconSize.Size)
+		}
+	}
+	sort.Ints(sizes)
+
+	parts := make([]string, len(sizes))
+	for i, size := range sizes {
+		parts[i] = strconv.Itoa(size)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasRoundedVariant reports whether config asks for rounded variants.
+func hasRoundedVariant(config Config) bool {
+	return config.RadiusPercent > 0 || config.RadiusPx > 0 || config.RadiusSizes != ""
+}
+
+// cornerRadius returns the corner radius in pixels of the rounded variant at
+// size: a --radius-sizes override for that size, else --radius-px, else
+// --radius-percent.
+func cornerRadius(config Config, size int) int {
+	if config.RadiusSizes != "" {
+		overrides, err := parseRadiusOverrides(config.RadiusSizes, outputSizes(config))
+		if err == nil {
+			if r, ok := overrides[size]; ok {
+				return r.resolve(size)
+			}
+		}
+	}
+	if config.RadiusPx > 0 {
+		return radiusSpec{Value: config.RadiusPx}.resolve(size)
+	}
+	return radiusSpec{Value: config.RadiusPercent, Percent: true}.resolve(size)
+}
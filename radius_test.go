@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCornerRadius(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		size     int
+		expected int
+	}{
+		{"percent", Config{RadiusPercent: 20}, 128, 25},
+		{"pixels override percent", Config{RadiusPercent: 20, RadiusPx: 6}, 128, 6},
+		{"pixels capped at half the size", Config{RadiusPx: 40}, 64, 32},
+		{"size override in pixels", Config{RadiusPercent: 20, RadiusSizes: "16:5px"}, 16, 5},
+		{"size override in percent", Config{RadiusPx: 6, RadiusSizes: "1024:22%"}, 1024, 225},
+		{"other sizes fall back", Config{RadiusPx: 6, RadiusSizes: "1024:22%"}, 512, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cornerRadius(tt.config, tt.size); got != tt.expected {
+				t.Errorf("Expected radius %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRadiusOverrides(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"16:3px,32:5px,1024:22%", false},
+		{"16:0px", false},
+		{"17:3px", true},
+		{"16:3", true},
+		{"16:60%", true},
+		{"16:-1px", true},
+		{"16:3px,16:4px", true},
+		{"16", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseRadiusOverrides(tt.spec, iconSizes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRoundedVariantWithPixelRadiusOnly(t *testing.T) {
+	config := Config{RadiusPercent: 0, RadiusPx: 4}
+	variants := iconVariants(config)
+	if len(variants) != 1 || variants[0].Name != "rounded" {
+		t.Fatalf("Expected a rounded variant from --radius-px alone, got %d variants", len(variants))
+	}
+}
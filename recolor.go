@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// colorMapping is one --map-color replacement.
+type colorMapping struct {
+	From color.NRGBA
+	To   color.NRGBA
+}
+
+// parseColorMappings parses a comma-separated --map-color spec of the form
+// #RRGGBB=>#RRGGBB[,#RRGGBB=>#RRGGBB...].
+func parseColorMappings(spec string) ([]colorMapping, error) {
+	var mappings []colorMapping
+	seen := make(map[color.NRGBA]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(entry, "=>")
+		if !ok {
+			return nil, fmt.Errorf("invalid color mapping %q (expected #RRGGBB=>#RRGGBB)", entry)
+		}
+		fromColor, err := parseHexColor(strings.TrimSpace(from))
+		if err != nil {
+			return nil, err
+		}
+		toColor, err := parseHexColor(strings.TrimSpace(to))
+		if err != nil {
+			return nil, err
+		}
+
+		mapping := colorMapping{
+			From: color.NRGBAModel.Convert(fromColor).(color.NRGBA),
+			To:   color.NRGBAModel.Convert(toColor).(color.NRGBA),
+		}
+		if seen[mapping.From] {
+			return nil, fmt.Errorf("color %s is mapped twice", strings.TrimSpace(from))
+		}
+		seen[mapping.From] = true
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// validateRecolor checks the --map-color, --hue-shift and --tint of config.
+func validateRecolor(config Config) error {
+	if config.MapColor != "" {
+		if _, err := parseColorMappings(config.MapColor); err != nil {
+			return err
+		}
+	}
+	if config.MapTolerance < 0 || config.MapTolerance > 100 {
+		return fmt.Errorf("map color tolerance must be between 0 and 100 (got %d)", config.MapTolerance)
+	}
+
+	if config.HueShift < -360 || config.HueShift > 360 {
+		return fmt.Errorf("hue shift must be between -360 and 360 degrees (got %d)", config.HueShift)
+	}
+	if config.Tint != "" {
+		if _, err := parseHexColor(config.Tint); err != nil {
+			return err
+		}
+		if config.HueShift != 0 {
+			return fmt.Errorf("--hue-shift and --tint both set the hue; use one")
+		}
+	}
+	return nil
+}
+
+// recolorSource applies the --map-color replacements of config to the
+// source, then its --hue-shift or --tint, before anything else, so every
+// output of a white-label build shows the brand's colors.
+func recolorSource(img image.Image, config Config) image.Image {
+	if config.MapColor != "" {
+		mappings, _ := parseColorMappings(config.MapColor)
+		img = mapColors(img, mappings, config.MapTolerance)
+	}
+
+	switch {
+	case config.Tint != "":
+		tint, _ := parseHexColor(config.Tint)
+		h, s, _ := rgbToHSL(color.NRGBAModel.Convert(tint).(color.NRGBA))
+		return mapHSL(img, func(_, _, l float64) (float64, float64, float64) { return h, s, l })
+	case config.HueShift%360 != 0:
+		shift := float64(config.HueShift)
+		return mapHSL(img, func(h, s, l float64) (float64, float64, float64) {
+			return math.Mod(h+shift+360, 360), s, l
+		})
+	}
+	return img
+}
+
+// mapHSL returns a copy of img with every pixel's hue (0-360), saturation
+// and lightness (0-1) passed through f, keeping its alpha.
+func mapHSL(img image.Image, f func(h, s, l float64) (float64, float64, float64)) image.Image {
+	bounds := img.Bounds()
+	mapped := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			px := hslToRGB(f(rgbToHSL(c)))
+			px.A = c.A
+			mapped.SetNRGBA(x, y, px)
+		}
+	}
+
+	return mapped
+}
+
+// rgbToHSL converts c to hue in degrees, saturation and lightness.
+func rgbToHSL(c color.NRGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	l = (hi + lo) / 2
+
+	chroma := hi - lo
+	if chroma == 0 {
+		return 0, 0, l
+	}
+	s = chroma / (1 - math.Abs(2*l-1))
+
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/chroma+6, 6)
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+	return h * 60, s, l
+}
+
+// hslToRGB converts hue in degrees, saturation and lightness to an opaque
+// color.
+func hslToRGB(h, s, l float64) color.NRGBA {
+	chroma := (1 - math.Abs(2*l-1)) * s
+	sector := h / 60
+	x := chroma * (1 - math.Abs(math.Mod(sector, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case sector < 1:
+		r, g = chroma, x
+	case sector < 2:
+		r, g = x, chroma
+	case sector < 3:
+		g, b = chroma, x
+	case sector < 4:
+		g, b = x, chroma
+	case sector < 5:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+
+	m := l - chroma/2
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v+m)) * 255))
+	}
+	return color.NRGBA{channel(r), channel(g), channel(b), 255}
+}
+
+// mapColors returns a copy of img with every pixel within tolerance percent
+// of the RGB range of a mapping's From color replaced by its To color. The
+// pixel's offset from From carries over, so shading within the tolerance
+// survives, and its alpha is kept, so anti-aliased edges stay smooth. A
+// pixel close to several colors takes the mapping of the closest.
+func mapColors(img image.Image, mappings []colorMapping, tolerance int) image.Image {
+	bounds := img.Bounds()
+	mapped := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+
+			best, bestDistance := -1, float64(tolerance)
+			for i, m := range mappings {
+				if distance := colorDistance(c, m.From); distance <= bestDistance {
+					best, bestDistance = i, distance
+				}
+			}
+
+			if best >= 0 {
+				m := mappings[best]
+				shift := func(v, from, to uint8) uint8 {
+					return uint8(math.Max(0, math.Min(255, float64(v)-float64(from)+float64(to))))
+				}
+				c = color.NRGBA{shift(c.R, m.From.R, m.To.R), shift(c.G, m.From.G, m.To.G), shift(c.B, m.From.B, m.To.B), c.A}
+			}
+			mapped.SetNRGBA(x, y, c)
+		}
+	}
+
+	return mapped
+}
+
+// colorDistance returns the Euclidean distance of a and b in RGB as a
+// percentage of the distance from black to white, ignoring alpha.
+func colorDistance(a, b color.NRGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt(dr*dr+dg*dg+db*db) / math.Sqrt(3*255*255) * 100
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHSLRoundTrip(t *testing.T) {
+	colors := []color.NRGBA{
+		{0, 0, 0, 255},
+		{255, 255, 255, 255},
+		{128, 128, 128, 255},
+		{255, 0, 0, 255},
+		{255, 200, 0, 255},
+		{0, 128, 255, 255},
+		{52, 199, 89, 255},
+		{175, 82, 222, 255},
+	}
+
+	for _, c := range colors {
+		if got := hslToRGB(rgbToHSL(c)); got != c {
+			t.Errorf("Expected %v to round-trip, got %v", c, got)
+		}
+	}
+}
+
+func TestRecolorSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		input  color.NRGBA
+		expect color.NRGBA
+	}{
+		{"no recolor", Config{}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{255, 0, 0, 255}},
+		{"red to green", Config{HueShift: 120}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 255, 0, 255}},
+		{"red to blue backwards", Config{HueShift: -120}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}},
+		{"full turn", Config{HueShift: 360}, color.NRGBA{255, 200, 0, 255}, color.NRGBA{255, 200, 0, 255}},
+		{"gray has no hue", Config{HueShift: 90}, color.NRGBA{128, 128, 128, 255}, color.NRGBA{128, 128, 128, 255}},
+		{"alpha kept", Config{HueShift: 120}, color.NRGBA{255, 0, 0, 100}, color.NRGBA{0, 255, 0, 100}},
+		{"tint keeps lightness", Config{Tint: "#0000FF"}, color.NRGBA{255, 128, 128, 255}, color.NRGBA{128, 128, 255, 255}},
+		{"tint turns gray blue", Config{Tint: "#0000FF"}, color.NRGBA{128, 128, 128, 255}, color.NRGBA{1, 1, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.SetNRGBA(0, 0, tt.input)
+			got := color.NRGBAModel.Convert(recolorSource(img, tt.config).At(0, 0)).(color.NRGBA)
+			if got != tt.expect {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestValidateRecolor(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"hue shift", Config{HueShift: -90}, false},
+		{"tint", Config{Tint: "#FF2D55"}, false},
+		{"hue shift too large", Config{HueShift: 400}, true},
+		{"invalid tint", Config{Tint: "pink"}, true},
+		{"both", Config{HueShift: 30, Tint: "#FF2D55"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRecolor(tt.config)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseColorMappings(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expected  int
+		expectErr bool
+	}{
+		{"single", "#112233=>#AA5500", 1, false},
+		{"several", "#112233=>#AA5500,#FFFFFF=>#000000", 2, false},
+		{"short hex", "#123=>#ABC", 1, false},
+		{"missing arrow", "#112233:#AA5500", 0, true},
+		{"invalid color", "#112233=>orange", 0, true},
+		{"mapped twice", "#112233=>#AA5500,#112233=>#000000", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, err := parseColorMappings(tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(mappings) != tt.expected {
+				t.Errorf("Expected %d mappings, got %d", tt.expected, len(mappings))
+			}
+		})
+	}
+}
+
+func TestMapColors(t *testing.T) {
+	mappings := []colorMapping{
+		{From: color.NRGBA{0x11, 0x22, 0x33, 255}, To: color.NRGBA{0xAA, 0x55, 0x00, 255}},
+		{From: color.NRGBA{0x20, 0x22, 0x33, 255}, To: color.NRGBA{0x00, 0x00, 0xFF, 255}},
+	}
+
+	tests := []struct {
+		name      string
+		tolerance int
+		input     color.NRGBA
+		expect    color.NRGBA
+	}{
+		{"exact match", 0, color.NRGBA{0x11, 0x22, 0x33, 255}, color.NRGBA{0xAA, 0x55, 0x00, 255}},
+		{"shading carries over", 10, color.NRGBA{0x0F, 0x24, 0x33, 255}, color.NRGBA{0xA8, 0x57, 0x00, 255}},
+		{"alpha kept", 10, color.NRGBA{0x11, 0x22, 0x33, 90}, color.NRGBA{0xAA, 0x55, 0x00, 90}},
+		{"closest wins", 10, color.NRGBA{0x1F, 0x22, 0x33, 255}, color.NRGBA{0x00, 0x00, 0xFF, 255}},
+		{"outside tolerance", 10, color.NRGBA{0xFF, 0xFF, 0xFF, 255}, color.NRGBA{0xFF, 0xFF, 0xFF, 255}},
+		{"close but no tolerance", 0, color.NRGBA{0x12, 0x22, 0x33, 255}, color.NRGBA{0x12, 0x22, 0x33, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.SetNRGBA(0, 0, tt.input)
+			got := color.NRGBAModel.Convert(mapColors(img, mappings, tt.tolerance).At(0, 0)).(color.NRGBA)
+			if got != tt.expect {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestMapColorFlag(t *testing.T) {
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, &config)
+
+	args := []string{"--map-color=#112233=>#AA5500", "--map-color", "#FFFFFF=>#000000"}
+	// The command line is parsed again after a configuration file
+	for i := 0; i < 2; i++ {
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+	}
+
+	if expected := "#112233=>#AA5500,#FFFFFF=>#000000"; config.MapColor != expected {
+		t.Errorf("Expected %q, got %q", expected, config.MapColor)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// findSourceImages walks root and returns the candidate source images in
+// lexical order. A file is a candidate if its base name matches pattern, or,
+// when pattern is empty, if it has a known image extension. Previously
+// generated icon_*.png files, the outputs an earlier run recorded in its
+// .icongen-manifest.json, the output directories of earlier runs and
+// hidden directories are never candidates.
+func findSourceImages(root, pattern string) ([]string, error) {
+	var sources []string
+	generated := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			m, err := readManifest(path)
+			if err != nil {
+				return nil
+			}
+			if path != root && !holdsSource(path, m) {
+				return filepath.SkipDir
+			}
+			for _, file := range m.Files {
+				generated[filepath.Join(path, file.Name)] = true
+			}
+			for _, pending := range m.Pending {
+				generated[filepath.Join(path, pending)] = true
+			}
+			return nil
+		}
+
+		if generated[path] || strings.HasPrefix(name, "icon_") && strings.HasSuffix(name, ".png") {
+			return nil
+		}
+
+		if pattern != "" {
+			matched, err := filepath.Match(pattern, name)
+			if err != nil {
+				return err
+			}
+			if matched {
+				sources = append(sources, path)
+			}
+			return nil
+		}
+
+		if decodableExtension(filepath.Ext(name)) {
+			sources = append(sources, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// holdsSource reports whether dir, which holds the manifest m of an earlier
+// run, also holds that run's source image, as it does when the icons were
+// written next to their source. Otherwise dir is an output directory of its
+// own.
+func holdsSource(dir string, m *manifest) bool {
+	recorded := make(map[string]bool)
+	for _, file := range m.Files {
+		recorded[file.Name] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || recorded[entry.Name()] || !decodableExtension(filepath.Ext(entry.Name())) {
+			continue
+		}
+		if sum, err := hashFile(filepath.Join(dir, entry.Name())); err == nil && sum == m.SourceHash {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceConfigs expands a recursive config into one config per source image
+// found under its input directory.
+func sourceConfigs(config Config) ([]Config, error) {
+	sources, err := findSourceImages(config.InputPath, config.SourcePattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan input directory: %w", err)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no source images found in %s", config.InputPath)
+	}
+
+	var configs []Config
+	for _, source := range sources {
+		rel, err := filepath.Rel(config.InputPath, source)
+		if err != nil {
+			return nil, err
+		}
+		stem := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+
+		sourceConfig := config
+		sourceConfig.Recursive = false
+		sourceConfig.ManifestPath = ""
+		sourceConfig.InputPath = source
+		sourceConfig.OutputDir = filepath.Join(config.OutputDir, filepath.Dir(rel), stem)
+		configs = append(configs, sourceConfig)
+	}
+	return configs, nil
+}
+
+// generateConfig generates the icons of config, for every source image found
+// under the input directory in recursive mode or for every flavor.
+func generateConfig(config Config) error {
+	if config.Recursive {
+		return generateRecursive(config)
+	}
+	if config.Flavors != "" {
+		return generateFlavors(config)
+	}
+	return generateIcons(config)
+}
+
+// generateRecursive generates an icon set for every source image found under
+// config.InputPath, mirroring the directory structure under config.OutputDir.
+// Each source gets its own directory named after the file, so several sources
+// in the same directory don't overwrite each other.
+func generateRecursive(config Config) error {
+	configs, err := sourceConfigs(config)
+	if err != nil {
+		return err
+	}
+
+	var outputDirs []string
+	for _, sourceConfig := range configs {
+		fmt.Fprintf(logOutput(config), "Processing %s\n", sourceConfig.InputPath)
+		if err := generateIcons(sourceConfig); err != nil {
+			return fmt.Errorf("%s: %w", sourceConfig.InputPath, err)
+		}
+		outputDirs = append(outputDirs, assetDir(sourceConfig))
+	}
+
+	if config.ManifestPath != "" {
+		if err := writeReport(config.ManifestPath, outputDirs); err != nil {
+			return fmt.Errorf("failed to write %s: %w", config.ManifestPath, err)
+		}
+	}
+
+	return nil
+}
+
+// isDir reports whether path exists and is a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
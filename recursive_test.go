@@ -0,0 +1,160 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Helper function to create a source tree for recursive mode tests
+func createSourceTree(t *testing.T, files []string) string {
+	root := t.TempDir()
+	testImg := createTestImage(64, color.RGBA{0, 128, 255, 255})
+
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", file, err)
+		}
+		if filepath.Ext(file) == ".png" {
+			if err := saveImage(testImg, path); err != nil {
+				t.Fatalf("Failed to save %s: %v", file, err)
+			}
+		} else if err := os.WriteFile(path, []byte("not an image"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	return root
+}
+
+func TestFindSourceImages(t *testing.T) {
+	root := createSourceTree(t, []string{
+		"apps/alpha/AppIcon.png",
+		"apps/alpha/icon_16x16.png",
+		"apps/beta/logo.png",
+		"apps/beta/README.md",
+		".git/objects/blob.png",
+		"top.png",
+	})
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:    "by extension",
+			pattern: "",
+			expected: []string{
+				"apps/alpha/AppIcon.png",
+				"apps/beta/logo.png",
+				"top.png",
+			},
+		},
+		{
+			name:     "by pattern",
+			pattern:  "AppIcon*.png",
+			expected: []string{"apps/alpha/AppIcon.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := findSourceImages(root, tt.pattern)
+			if err != nil {
+				t.Fatalf("Failed to find sources: %v", err)
+			}
+
+			var rel []string
+			for _, source := range sources {
+				r, _ := filepath.Rel(root, source)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+
+			if !reflect.DeepEqual(rel, tt.expected) {
+				t.Errorf("Expected sources %v, got %v", tt.expected, rel)
+			}
+		})
+	}
+}
+
+func TestGenerateRecursive(t *testing.T) {
+	root := createSourceTree(t, []string{
+		"apps/alpha/AppIcon.png",
+		"apps/beta/AppIcon.png",
+		"apps/beta/notes.txt",
+	})
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     root,
+		OutputDir:     outputDir,
+		Recursive:     true,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 0,
+	}
+
+	if err := validateConfig(config); err != nil {
+		t.Fatalf("Expected valid config but got error: %v", err)
+	}
+	if err := generateRecursive(config); err != nil {
+		t.Fatalf("Failed to generate icons recursively: %v", err)
+	}
+
+	for _, dir := range []string{"apps/alpha/AppIcon", "apps/beta/AppIcon"} {
+		for _, iconSize := range iconSizes {
+			path := filepath.Join(outputDir, dir, iconSize.Name)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Errorf("Expected %s to be generated", filepath.Join(dir, iconSize.Name))
+			}
+		}
+	}
+}
+
+func TestFindSourceImagesSkipsEarlierOutputs(t *testing.T) {
+	root := createSourceTree(t, []string{
+		"apps/alpha/AppIcon.png",
+		"apps/beta/logo.png",
+	})
+
+	// A recursive run into the input tree, and a run writing next to its source
+	config := Config{InputPath: root, OutputDir: root, Recursive: true, Preset: "web,android", TrimPercent: 80, RadiusPercent: 20}
+	if err := generateRecursive(config); err != nil {
+		t.Fatalf("Failed to generate icons recursively: %v", err)
+	}
+	single := Config{InputPath: filepath.Join(root, "apps/beta/logo.png"), OutputDir: filepath.Join(root, "apps/beta"), Preset: "web", TrimPercent: 80, RadiusPercent: 20}
+	if err := generateIcons(single); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	sources, err := findSourceImages(root, "")
+	if err != nil {
+		t.Fatalf("Failed to find sources: %v", err)
+	}
+	var rel []string
+	for _, source := range sources {
+		r, _ := filepath.Rel(root, source)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	expected := []string{"apps/alpha/AppIcon.png", "apps/beta/logo.png"}
+	if !reflect.DeepEqual(rel, expected) {
+		t.Errorf("Expected sources %v, got %v", expected, rel)
+	}
+}
+
+func TestGenerateRecursiveRequiresDirectory(t *testing.T) {
+	config := Config{
+		InputPath:     createTempImageFile(t, createTestImage(32, color.RGBA{255, 0, 0, 255})),
+		Recursive:     true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+	}
+
+	if err := validateConfig(config); err == nil {
+		t.Errorf("Expected error for recursive mode on a single file")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+)
+
+// renderedFile is an output rendered in memory rather than written to disk.
+type renderedFile struct {
+	Name string
+	Data []byte
+}
+
+// renderIconSet renders the icons of config from the encoded source image,
+// as PNG files in memory, for callers without a filesystem such as the
+// WebAssembly build: the regular, rounded and masked icons, the standalone
+// icons and the android round launchers, as generateIcons saves them, with
+// the marketing icon flattened or converted to Display P3, the --bit-depth,
+// the --watermark and the color, density and text chunks. The source is
+// prepared as by loadSource; the input and output paths of config are
+// ignored. With --dark-source=auto the _dark copies of the regular icons
+// and their variants follow.
+func renderIconSet(source []byte, config Config) ([]renderedFile, error) {
+	if err := validateOptions(config); err != nil {
+		return nil, err
+	}
+
+	sourceImg, _, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode source image: %w", err)
+	}
+	if config.BitDepth == 16 && !deepImage(sourceImg) {
+		sourceImg = toRGBA64(sourceImg)
+	}
+	sourceImg = prepareSource(sourceImg, config)
+	if err := checkSquare(sourceImg, config); err != nil {
+		return nil, err
+	}
+
+	state, err := renderState(source, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var pattern backgroundPattern
+	if config.BackgroundPattern != "" {
+		if pattern, err = parseBackgroundPattern(config.BackgroundPattern); err != nil {
+			return nil, err
+		}
+	}
+
+	// Layers without a path read no files
+	layers, err := loadLayers(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []renderedFile
+	save := func(name string, img image.Image) error {
+		data, err := encodeTextImage(outputImage(img, config, name), outputChunks(config, state, name), outputText(config, state, name))
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		files = append(files, renderedFile{Name: name, Data: data})
+		return nil
+	}
+
+	// Mark marketing sizes with --watermark, as generateIcons does
+	hash := generationHash(state)
+	watermark := func(img image.Image, name string, size int) image.Image {
+		marked, ok := watermarkIcon(img, config, size, hash)
+		if !ok {
+			emitWarning(config, warning{Code: "W004", Target: name, Message: "too few opaque pixels to carry the watermark"})
+		}
+		return marked
+	}
+
+	standalone := newStandaloneIcons(sourceImg, config)
+	for _, iconSize := range outputSizes(config) {
+		for _, icon := range renderIcons(sourceImg, config, pattern, nil, layers, standalone, iconSize) {
+			img := icon.Image
+			if !isStandaloneIcon(iconSize) {
+				img = watermark(img, icon.Name, iconSize.Size)
+			}
+			if err := save(icon.Name, img); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if hasPreset(config, "android") {
+		render := roundLauncher(sourceImg, config)
+		for _, iconSize := range roundSizes(config) {
+			if err := save(iconSize.Name, render(iconSize)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if config.DarkSource == darkSourceAuto {
+		darkImg := invertLightness(sourceImg)
+		for _, iconSize := range outputSizes(config) {
+			if isStandaloneIcon(iconSize) {
+				continue
+			}
+			for _, icon := range renderIcons(darkImg, config, pattern, nil, layers, standalone, iconSize) {
+				if err := save(darkIconName(icon.Name), icon.Image); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// renderState stands in for the manifest state of a run for renderIconSet:
+// it holds the hashes of the encoded source and the options, and the color
+// and density chunks taken from the source, that outputs are saved with.
+func renderState(source []byte, config Config) (*manifestState, error) {
+	sum := sha256.Sum256(source)
+	optionsHash, err := hashOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceChunk := func(typ string) ([]byte, error) { return findPNGChunk(bytes.NewReader(source), typ) }
+	color, err := colorChunks(config, sourceChunk)
+	if err != nil {
+		return nil, err
+	}
+	density, err := densityChunks(config, sourceChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifestState{
+		stateless:    true,
+		sourceHash:   hex.EncodeToString(sum[:]),
+		optionsHash:  optionsHash,
+		assetVersion: config.AssetVersion,
+		watermarked:  config.Watermark != "",
+		color:        color,
+		density:      density,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngData encodes img as a PNG source for renderIconSet.
+func pngData(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode the source: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRenderIconSet(t *testing.T) {
+	source := pngData(t, createTestImage(128, color.RGBA{0, 128, 255, 255}))
+
+	config := Config{
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+	}
+
+	files, err := renderIconSet(source, config)
+	if err != nil {
+		t.Fatalf("Failed to render icon set: %v", err)
+	}
+
+	if len(files) != 2*len(iconSizes) {
+		t.Fatalf("Expected %d files, got %d", 2*len(iconSizes), len(files))
+	}
+
+	for i, file := range files {
+		iconSize := iconSizes[i/2]
+		expectedName := iconSize.Name
+		if i%2 == 1 {
+			expectedName = roundedIconName(iconSize.Name)
+		}
+		if file.Name != expectedName {
+			t.Errorf("Expected file %d to be %s, got %s", i, expectedName, file.Name)
+		}
+
+		img, err := png.Decode(bytes.NewReader(file.Data))
+		if err != nil {
+			t.Errorf("Failed to decode %s: %v", file.Name, err)
+			continue
+		}
+		if img.Bounds().Dx() != iconSize.Size {
+			t.Errorf("Expected %s to be %dpx, got %dpx", file.Name, iconSize.Size, img.Bounds().Dx())
+		}
+	}
+
+	config.RadiusPercent = 0
+	files, err = renderIconSet(source, config)
+	if err != nil {
+		t.Fatalf("Failed to render icon set: %v", err)
+	}
+	if len(files) != len(iconSizes) {
+		t.Errorf("Expected %d files without rounded variants, got %d", len(iconSizes), len(files))
+	}
+
+	config.TrimPercent = 0
+	if _, err := renderIconSet(source, config); err == nil {
+		t.Errorf("Expected error for invalid options")
+	}
+}
+
+func TestRenderIconSetMatchesCLI(t *testing.T) {
+	// A red square on transparency
+	inputPath := createTempImageFile(t, createTestImageWithSquare(200, 120, color.RGBA{255, 0, 0, 255}))
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		Preset:        "macos,android," + notificationPreset + "," + templatePreset + "," + complicationPreset,
+		TrimPercent:   80,
+		RadiusPercent: 20,
+		Background:    "#3366CC",
+		Flatten:       "on",
+		FlattenColor:  "#FFFFFF",
+		ColorSpace:    "p3",
+		BitDepth:      16,
+		DPI:           144,
+		Watermark:     "acme",
+		Provenance:    true,
+		Reproducible:  true,
+		Stateless:     true,
+		DarkSource:    darkSourceAuto,
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+	files, err := renderIconSet(source, config)
+	if err != nil {
+		t.Fatalf("Failed to render icon set: %v", err)
+	}
+
+	rendered := make(map[string]bool)
+	for _, file := range files {
+		rendered[file.Name] = true
+		saved, err := os.ReadFile(filepath.Join(outputDir, file.Name))
+		if err != nil {
+			t.Errorf("Rendered %s, which the CLI didn't save: %v", file.Name, err)
+			continue
+		}
+		if !bytes.Equal(file.Data, saved) {
+			t.Errorf("Expected %s to match the CLI's", file.Name)
+		}
+	}
+	filepath.WalkDir(outputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".png") {
+			return err
+		}
+		name, _ := filepath.Rel(outputDir, path)
+		if !rendered[filepath.ToSlash(name)] {
+			t.Errorf("The CLI saved %s, which wasn't rendered", name)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// renderCache shares rendered images between the outputs of a run that come
+// out identical, such as icon_32x32.png and icon_16x16@2x.png, or the same
+// size in several presets. Every key is rendered at most once and dropped
+// after its last use, so only images still needed are kept.
+type renderCache struct {
+	images map[string]image.Image
+	uses   map[string]int
+}
+
+// newRenderCache returns a cache for a run that will use each of keys once
+// per occurrence.
+func newRenderCache(keys []string) *renderCache {
+	c := &renderCache{images: make(map[string]image.Image), uses: make(map[string]int)}
+	for _, key := range keys {
+		c.uses[key]++
+	}
+	return c
+}
+
+// get returns the image of key, rendering it on first use.
+func (c *renderCache) get(key string, render func() image.Image) image.Image {
+	if img, ok := c.images[key]; ok {
+		return img
+	}
+	img := render()
+	if c.uses[key] > 1 {
+		c.images[key] = img
+	}
+	return img
+}
+
+// release marks one use of key as done, whether or not it needed the
+// image, and drops the image after the last one.
+func (c *renderCache) release(key string) {
+	c.uses[key]--
+	if c.uses[key] <= 0 {
+		delete(c.images, key)
+	}
+}
+
+// preparedKey identifies the prepared artwork at a pixel size, which every
+// output of that size starts from.
+func preparedKey(size int) string {
+	return fmt.Sprintf("prepared %d", size)
+}
+
+// outputKey identifies the finished image of a variant ("" for the regular
+// icon) at iconSize. Outputs with the same key are identical.
+func outputKey(config Config, iconSize IconSize, variant string) string {
+	return fmt.Sprintf("output %d %s %t", iconSize.Size, variant, hasPadding(config, iconSize))
+}
+
+// renderKeys lists the cache keys of every regular and variant output of
+// config, with one entry per use. Notification icons, complication images
+// and template images don't use the cache.
+func renderKeys(config Config) []string {
+	var keys []string
+	for _, iconSize := range outputSizes(config) {
+		if isStandaloneIcon(iconSize) {
+			continue
+		}
+		for _, variant := range append([]string{""}, variantNames(config, iconSize)...) {
+			keys = append(keys, preparedKey(iconSize.Size), outputKey(config, iconSize, variant))
+		}
+	}
+	return keys
+}
+
+// variantNames lists the names of the variants of config for iconSize.
+func variantNames(config Config, iconSize IconSize) []string {
+	var names []string
+	for _, variant := range variantsFor(iconVariants(config), iconSize) {
+		names = append(names, variant.Name)
+	}
+	return names
+}
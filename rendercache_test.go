@@ -0,0 +1,57 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	cache := newRenderCache([]string{"a", "b", "a"})
+	rendered := 0
+	render := func() image.Image {
+		rendered++
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+
+	first := cache.get("a", render)
+	cache.release("a")
+	cache.get("b", render)
+	cache.release("b")
+	if second := cache.get("a", render); second != first {
+		t.Errorf("Expected the second use of a key to share the first render")
+	}
+	cache.release("a")
+
+	if rendered != 2 {
+		t.Errorf("Expected 2 renders, got %d", rendered)
+	}
+	if len(cache.images) != 0 {
+		t.Errorf("Expected every image dropped after its last use, %d left", len(cache.images))
+	}
+}
+
+func TestRenderKeysShareAcrossPresets(t *testing.T) {
+	distinct := func(keys []string) int {
+		seen := make(map[string]bool)
+		for _, key := range keys {
+			seen[key] = true
+		}
+		return len(seen)
+	}
+
+	// macOS repeats 32, 256, 512 and 1024; web adds 16, 32 and 512 again
+	macos := renderKeys(Config{Preset: "macos"})
+	both := renderKeys(Config{Preset: "macos,web"})
+	if got, want := distinct(macos), 2*7; got != want {
+		t.Errorf("Expected %d distinct macOS renders, got %d", want, got)
+	}
+	if got, want := distinct(both), 2*9; got != want {
+		t.Errorf("Expected %d distinct renders for macos and web, got %d", want, got)
+	}
+
+	// Padding that skips the base icon keeps it apart from icon_512x512@2x.png
+	padded := renderKeys(Config{Preset: "macos", PaddingPercent: 10, PaddingIOSMode: true})
+	if got, want := distinct(padded), 2*7+1; got != want {
+		t.Errorf("Expected %d distinct padded renders, got %d", want, got)
+	}
+}
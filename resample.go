@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// resampleFilters are the --filter kernels, by name. "nearest" resizes with
+// resizeNearest instead, and "auto" picks one by direction in resizer.
+var resampleFilters = map[string]resampleFilter{
+	"bilinear":   bilinearFilter,
+	"box":        boxFilter,
+	"catmullrom": catmullRomFilter,
+	"lanczos3":   lanczos3Filter,
+}
+
+// validateFilter checks --filter, which --pixel-art leaves no choice for.
+func validateFilter(config Config) error {
+	if config.Filter == "" || config.Filter == "auto" {
+		return nil
+	}
+	if _, ok := resampleFilters[config.Filter]; !ok && config.Filter != "nearest" {
+		return fmt.Errorf("unknown filter %q (expected nearest, bilinear, box, catmullrom, lanczos3 or auto)", config.Filter)
+	}
+	if config.PixelArt != "" && config.Filter != "nearest" {
+		return fmt.Errorf("--pixel-art resizes with nearest-neighbor sampling; it can't be combined with --filter=%s", config.Filter)
+	}
+	return nil
+}
+
+// resizer returns the stage the artwork is resized with: resizeNearest for
+// --pixel-art, the --filter otherwise, in linear light with --linear-light.
+// The default, auto, downscales with Catmull-Rom, which keeps small icons
+// sharp, and upscales bilinearly. A source with mipmaps is resized from its
+// closest level.
+func resizer(config Config) func(image.Image, int) *image.RGBA {
+	if config.PixelArt != "" || config.Filter == "nearest" {
+		return resizeNearest
+	}
+	return func(img image.Image, size int) *image.RGBA {
+		if m, ok := img.(*mipmapImage); ok {
+			img = m.level(size)
+			if bounds := img.Bounds(); bounds.Dx() == size && bounds.Dy() == size {
+				return toRGBA(img)
+			}
+		}
+		name := filterName(config, img, size)
+		if config.LinearLight {
+			return resizeLinear(img, size, resampleFilters[name])
+		}
+		if name == "bilinear" {
+			return resizeImage(img, size)
+		}
+		return resizeFiltered(img, size, resampleFilters[name])
+	}
+}
+
+// filterName returns the --filter img is resized to size with, resolving
+// auto.
+func filterName(config Config, img image.Image, size int) string {
+	name := config.Filter
+	if _, ok := resampleFilters[name]; !ok {
+		bounds := img.Bounds()
+		name = "bilinear"
+		if bounds.Dx() > size || bounds.Dy() > size {
+			name = "catmullrom"
+		}
+	}
+	return name
+}
+
+// depthResizer returns the resizer of config, which resizes 16-bit images
+// to 16-bit results with the same filter. Nearest-neighbor sampling stays
+// 8-bit.
+func depthResizer(config Config) func(image.Image, int) image.Image {
+	resize := resizer(config)
+	nearest := config.PixelArt != "" || config.Filter == "nearest"
+	return func(img image.Image, size int) image.Image {
+		if !deepImage(img) || nearest {
+			return resize(img, size)
+		}
+		return fitFilterDeep(img, size, resampleFilters[filterName(config, img, size)], config.LinearLight)
+	}
+}
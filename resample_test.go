@@ -0,0 +1,253 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestValidateFilter(t *testing.T) {
+	tests := []struct {
+		config  Config
+		wantErr bool
+	}{
+		{Config{}, false},
+		{Config{Filter: "auto"}, false},
+		{Config{Filter: "lanczos3"}, false},
+		{Config{Filter: "box"}, false},
+		{Config{Filter: "bicubic"}, true},
+		{Config{Filter: "nearest", PixelArt: "nearest"}, false},
+		{Config{Filter: "lanczos3", PixelArt: "scale2x"}, true},
+	}
+
+	for _, tt := range tests {
+		config := tt.config
+		config.TrimPercent = 80
+		if err := validateOptions(config); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: expected error %v, got %v", tt.config, tt.wantErr, err)
+		}
+	}
+}
+
+func TestResizeFilteredUniform(t *testing.T) {
+	orange := color.RGBA{255, 128, 0, 255}
+	source := createTestImage(60, orange)
+
+	for name, filter := range map[string]resampleFilter{"box": boxFilter, "catmullrom": catmullRomFilter, "lanczos3": lanczos3Filter} {
+		for _, size := range []int{16, 60, 100} {
+			resized := resizeFiltered(source, size, filter)
+			if resized.Bounds() != image.Rect(0, 0, size, size) {
+				t.Fatalf("%s: expected a %dpx square, got %v", name, size, resized.Bounds())
+			}
+			for _, p := range []image.Point{{0, 0}, {size / 2, size / 2}, {size - 1, size - 1}} {
+				if got := resized.RGBAAt(p.X, p.Y); got != orange {
+					t.Errorf("%s at %dpx: expected %v at %v, got %v", name, size, orange, p, got)
+				}
+			}
+		}
+	}
+}
+
+func TestResizeFilteredEmpty(t *testing.T) {
+	// A 1x1 source cropped by --trim-percent leaves nothing to resize
+	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	resized := resizeFiltered(empty, 16, lanczos3Filter)
+	if resized.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Fatalf("Expected a 16px square, got %v", resized.Bounds())
+	}
+	if _, _, _, a := resized.At(8, 8).RGBA(); a != 0 {
+		t.Errorf("Expected a transparent icon, got alpha %d", a)
+	}
+
+	source := createTempImageFile(t, createTestImage(1, color.RGBA{255, 0, 0, 255}))
+	config := Config{InputPath: source, OutputDir: t.TempDir(), TrimPercent: 80, Filter: "lanczos3"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons from a 1x1 source: %v", err)
+	}
+}
+
+func TestResizeFilteredAntialiases(t *testing.T) {
+	// A one-pixel checkerboard averages to purple; point sampling aliases it
+	source := checkerboard(64)
+
+	boxed := resizeFiltered(source, 16, boxFilter)
+	if c := boxed.RGBAAt(8, 8); c.R < 120 || c.R > 135 || c.B < 120 || c.B > 135 {
+		t.Errorf("Expected the box filter to average to purple, got %v", c)
+	}
+	lanczos := resizeFiltered(source, 16, lanczos3Filter)
+	if c := lanczos.RGBAAt(8, 8); c.R < 100 || c.B < 100 {
+		t.Errorf("Expected Lanczos to average to purple, got %v", c)
+	}
+}
+
+func TestResizeFilteredTransparency(t *testing.T) {
+	// Transparent pixels don't darken the edge of the artwork
+	source := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(source, image.Rect(0, 0, 20, 40), &image.Uniform{color.RGBA{255, 255, 255, 255}}, image.Point{}, draw.Src)
+
+	resized := resizeFiltered(source, 10, catmullRomFilter)
+	edge := resized.RGBAAt(5, 5)
+	if edge.A == 0 || edge.A == 255 {
+		t.Fatalf("Expected a partly transparent edge, got %v", edge)
+	}
+	if edge.R != edge.A || edge.G != edge.A || edge.B != edge.A {
+		t.Errorf("Expected a premultiplied white edge, got %v", edge)
+	}
+	if c := resized.RGBAAt(9, 5); c.A != 0 {
+		t.Errorf("Expected overshoot to be clamped to transparency, got %v", c)
+	}
+}
+
+func TestResizeEdgeColors(t *testing.T) {
+	// A logo color fading out to transparency keeps its color at every
+	// alpha once un-premultiplied, rather than darkening towards the edge
+	want := color.NRGBA{100, 150, 200, 0}
+	source := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			c := want
+			c.A = uint8(255 - 2*x)
+			source.SetNRGBA(x, y, c)
+		}
+	}
+
+	stages := map[string]func(image.Image, int) *image.RGBA{
+		"resizeImage": resizeImage,
+		"resizeFiltered": func(img image.Image, size int) *image.RGBA {
+			return resizeFiltered(img, size, catmullRomFilter)
+		},
+		"resizeLinear": func(img image.Image, size int) *image.RGBA {
+			return resizeLinear(img, size, boxFilter)
+		},
+		"addPadding": func(img image.Image, size int) *image.RGBA {
+			return addPadding(resizeFiltered(img, 2*size, boxFilter), 10, size)
+		},
+	}
+	for name, stage := range stages {
+		for _, size := range []int{16, 48, 200} {
+			resized := stage(source, size)
+			for i := 0; i < len(resized.Pix); i += 4 {
+				a := int(resized.Pix[i+3])
+				if a < 8 {
+					continue
+				}
+				// Rounding the color and alpha to 8 bits each moves the
+				// un-premultiplied color by up to half of 255/a
+				tolerance := 255/a + 1
+				got := color.NRGBAModel.Convert(color.RGBA{resized.Pix[i], resized.Pix[i+1], resized.Pix[i+2], resized.Pix[i+3]}).(color.NRGBA)
+				for c, v := range []uint8{got.R, got.G, got.B} {
+					if expect := []uint8{want.R, want.G, want.B}[c]; abs(int(v)-int(expect)) > tolerance {
+						t.Errorf("%s at %dpx: expected %v at alpha %d, got %v", name, size, want, a, got)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestResizer(t *testing.T) {
+	source := checkerboard(64)
+
+	// auto downscales with Catmull-Rom and upscales bilinearly
+	if got, expect := resizer(Config{})(source, 16), resizeFiltered(source, 16, catmullRomFilter); got.RGBAAt(8, 8) != expect.RGBAAt(8, 8) {
+		t.Errorf("Expected auto to downscale with Catmull-Rom, got %v", got.RGBAAt(8, 8))
+	}
+	small := checkerboard(8)
+	if got, expect := resizer(Config{Filter: "auto"})(small, 32), resizeImage(small, 32); got.RGBAAt(10, 10) != expect.RGBAAt(10, 10) {
+		t.Errorf("Expected auto to upscale bilinearly, got %v", got.RGBAAt(10, 10))
+	}
+
+	if got, expect := resizer(Config{Filter: "bilinear"})(source, 16), resizeImage(source, 16); got.RGBAAt(8, 8) != expect.RGBAAt(8, 8) {
+		t.Errorf("Expected --filter=bilinear to resize like resizeImage, got %v", got.RGBAAt(8, 8))
+	}
+	if got := resizer(Config{Filter: "lanczos3", PixelArt: "nearest"})(source, 16).RGBAAt(8, 8); got.G != 0 || (got.R != 0) == (got.B != 0) {
+		t.Errorf("Expected --pixel-art to take precedence, got %v", got)
+	}
+}
+
+func TestResizeLinear(t *testing.T) {
+	// A one-pixel black and white checkerboard is half as bright in linear
+	// light, which sRGB encodes far above the midpoint
+	source := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(255 * ((x + y) % 2))
+			source.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	if c := resizeLinear(source, 16, boxFilter).RGBAAt(8, 8); c.R < 185 || c.R > 190 {
+		t.Errorf("Expected a linear-light average of about 188, got %v", c)
+	}
+	if c := resizeFiltered(source, 16, boxFilter).RGBAAt(8, 8); c.R < 126 || c.R > 129 {
+		t.Errorf("Expected an sRGB average of about 128, got %v", c)
+	}
+
+	// Flat colors and edges against transparency keep their color
+	orange := color.RGBA{255, 128, 0, 255}
+	if c := resizeLinear(createTestImage(60, orange), 16, lanczos3Filter).RGBAAt(8, 8); c != orange {
+		t.Errorf("Expected %v to survive the round trip, got %v", orange, c)
+	}
+	half := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(half, image.Rect(0, 0, 20, 40), &image.Uniform{orange}, image.Point{}, draw.Src)
+	edge := color.NRGBAModel.Convert(resizeLinear(half, 10, catmullRomFilter).At(5, 5)).(color.NRGBA)
+	if edge.A == 0 || edge.A == 255 || edge.R < 250 || edge.G < 120 || edge.G > 136 {
+		t.Errorf("Expected a partly transparent orange edge, got %v", edge)
+	}
+}
+
+func TestResizeLinearEmpty(t *testing.T) {
+	// Linear light is the default, so an empty crop must not panic with the
+	// default flags either
+	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	resized := resizeLinear(empty, 16, lanczos3Filter)
+	if resized.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Errorf("Expected a 16x16 icon, got %v", resized.Bounds())
+	}
+	if c := resized.RGBAAt(8, 8); c.A != 0 {
+		t.Errorf("Expected a transparent icon, got %v", c)
+	}
+
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, &config)
+	source := createTempImageFile(t, createTestImage(1, color.RGBA{255, 0, 0, 255}))
+	if err := fs.Parse([]string{"--input", source, "--output", t.TempDir(), "--trim-percent", "80"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons from a degenerate source: %v", err)
+	}
+}
+
+func TestLinearLightFlag(t *testing.T) {
+	tests := []struct {
+		args   []string
+		expect bool
+	}{
+		{nil, true},
+		{[]string{"--no-linear-light"}, false},
+		{[]string{"--linear-light=false"}, false},
+	}
+
+	for _, tt := range tests {
+		var config Config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		defineFlags(fs, &config)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if config.LinearLight != tt.expect {
+			t.Errorf("%v: expected %v, got %v", tt.args, tt.expect, config.LinearLight)
+		}
+	}
+
+	source := checkerboard(64)
+	got := resizer(Config{LinearLight: true})(source, 16).RGBAAt(8, 8)
+	if expect := resizeLinear(source, 16, catmullRomFilter).RGBAAt(8, 8); got != expect {
+		t.Errorf("Expected --linear-light to resize in linear light, got %v", got)
+	}
+}
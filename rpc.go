@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// MarshalJSON leaves the result out of error responses, as JSON-RPC 2.0
+// requires, and sends it as null when a method has none.
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	type response rpcResponse
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *rpcError       `json:"error"`
+		}{r.JSONRPC, r.ID, r.Error})
+	}
+	return json.Marshal(response(r))
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcMethods maps JSON-RPC method names to handlers. "presets" takes no
+// params and "progress" and "cancel" take a job id, {"job": "1"}; the others
+// take the generation options as params: an object keyed by flag name, e.g.
+// {"input": "logo.png", "trim-percent": 75}. The progress messages of the
+// runs they start go to log.
+var rpcMethods = map[string]func(params json.RawMessage, log io.Writer) (interface{}, error){
+	"presets":  rpcPresets,
+	"validate": rpcValidate,
+	"plan":     rpcPlan,
+	"generate": rpcGenerate,
+	"submit":   rpcSubmit,
+	"progress": rpcProgress,
+	"cancel":   rpcCancel,
+}
+
+// runRPC implements "icongen rpc": it serves JSON-RPC 2.0 over stdin and
+// stdout, one message per line, until stdin is closed.
+func runRPC(args []string) error {
+	flags := flag.NewFlagSet("rpc", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s rpc\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Serve JSON-RPC 2.0 over stdin/stdout. Methods: presets, validate, plan, generate, submit, progress, cancel.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Let submitted jobs finish once the client hangs up
+	defer rpcJobs.wait()
+	// Progress output goes to stderr so stdout carries only responses
+	return serveRPC(os.Stdin, os.Stdout, os.Stderr)
+}
+
+// serveRPC answers the requests read from r on w, writing the progress
+// messages of the runs they start to log. Notifications, requests without
+// an id, are handled but not answered.
+func serveRPC(r io.Reader, w, log io.Writer) error {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			// The stream can't be resynchronized after malformed JSON
+			encoder.Encode(rpcResponse{
+				JSONRPC: "2.0",
+				ID:      json.RawMessage("null"),
+				Error:   &rpcError{Code: rpcParseError, Message: err.Error()},
+			})
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+
+		response := handleRPC(raw, log)
+		if response == nil {
+			continue
+		}
+		if err := encoder.Encode(response); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+}
+
+// handleRPC handles one request, returning nil for notifications.
+func handleRPC(raw json.RawMessage, log io.Writer) *rpcResponse {
+	var request rpcRequest
+	if err := json.Unmarshal(raw, &request); err != nil || request.JSONRPC != "2.0" || request.Method == "" {
+		return &rpcResponse{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("null"),
+			Error:   &rpcError{Code: rpcInvalidRequest, Message: "invalid JSON-RPC 2.0 request"},
+		}
+	}
+
+	response := &rpcResponse{JSONRPC: "2.0", ID: request.ID}
+
+	method, ok := rpcMethods[request.Method]
+	if !ok {
+		response.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+	} else if result, err := method(request.Params, log); err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		response.Error = rpcErr
+	} else {
+		response.Result = result
+	}
+
+	if len(request.ID) == 0 {
+		return nil
+	}
+	return response
+}
+
+// rpcConfig builds a Config from the defaults overridden by the options in
+// params, logging to log.
+func rpcConfig(params json.RawMessage, log io.Writer) (Config, error) {
+	raw := map[string]interface{}{}
+	if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &raw); err != nil {
+			return Config{}, &rpcError{Code: rpcInvalidParams, Message: "params must be an object of options keyed by flag name"}
+		}
+	}
+
+	values, err := optionValues(raw)
+	if err != nil {
+		return Config{}, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	var config Config
+	fs := flag.NewFlagSet("rpc", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &config)
+	if err := applyOptions(fs, values); err != nil {
+		return Config{}, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	setDefaultOutputDir(&config)
+	config.Log = log
+	return config, nil
+}
+
+func rpcPresets(params json.RawMessage, log io.Writer) (interface{}, error) {
+	return presets, nil
+}
+
+func rpcValidate(params json.RawMessage, log io.Writer) (interface{}, error) {
+	config, err := rpcConfig(params, log)
+	if err == nil {
+		err = validateConfig(config)
+	}
+
+	result := struct {
+		Valid bool   `json:"valid"`
+		Error string `json:"error,omitempty"`
+	}{Valid: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+func rpcPlan(params json.RawMessage, log io.Writer) (interface{}, error) {
+	config, err := rpcConfig(params, log)
+	if err != nil {
+		return nil, err
+	}
+	return planOutputs(config)
+}
+
+func rpcGenerate(params json.RawMessage, log io.Writer) (interface{}, error) {
+	config, err := rpcConfig(params, log)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := generateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return struct {
+		Outputs []plannedOutput `json:"outputs"`
+	}{targets}, nil
+}
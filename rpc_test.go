@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeRPC(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 128, 255, 255}))
+	outputDir := t.TempDir()
+	options := fmt.Sprintf(`{"input": %q, "output": %q, "radius-percent": 0}`, inputPath, outputDir)
+
+	requests := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "presets"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "validate", "params": {"input": "missing.png"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "plan", "params": ` + options + `}`,
+		`{"jsonrpc": "2.0", "method": "presets"}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "generate", "params": ` + options + `}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "plan", "params": {"trim-percentage": 75}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "explode"}`,
+	}, "\n")
+
+	var out, log bytes.Buffer
+	if err := serveRPC(strings.NewReader(requests), &out, &log); err != nil {
+		t.Fatalf("serveRPC failed: %v", err)
+	}
+	if strings.Contains(out.String(), "icon_16x16.png (") || !strings.Contains(log.String(), "icon_16x16.png (") {
+		t.Errorf("Expected progress messages in the log only, got %q", log.String())
+	}
+
+	type response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	var responses []response
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var response response
+		if err := decoder.Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		responses = append(responses, response)
+	}
+
+	// The notification gets no response
+	if len(responses) != 6 {
+		t.Fatalf("Expected 6 responses, got %d", len(responses))
+	}
+
+	var presetList []preset
+	json.Unmarshal(responses[0].Result, &presetList)
+	if len(presetList) == 0 || presetList[0].Name != "macos" {
+		t.Errorf("Expected the macos preset, got %s", responses[0].Result)
+	}
+
+	if !strings.Contains(string(responses[1].Result), `"valid":false`) {
+		t.Errorf("Expected validation to fail for a missing input, got %s", responses[1].Result)
+	}
+
+	var targets []plannedOutput
+	json.Unmarshal(responses[2].Result, &targets)
+	if len(targets) != len(iconSizes) {
+		t.Errorf("Expected %d planned targets, got %d", len(iconSizes), len(targets))
+	}
+
+	if responses[3].Error != nil {
+		t.Fatalf("Expected generate to succeed, got %v", responses[3].Error)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_16x16.png")); err != nil {
+		t.Errorf("Expected generate to write icons: %v", err)
+	}
+
+	if responses[4].Error == nil || responses[4].Error.Code != rpcInvalidParams {
+		t.Errorf("Expected invalid params error for an unknown option, got %+v", responses[4].Error)
+	}
+	if responses[5].Error == nil || responses[5].Error.Code != rpcMethodNotFound {
+		t.Errorf("Expected method not found error, got %+v", responses[5].Error)
+	}
+}
+
+func TestServeRPCParseError(t *testing.T) {
+	var out bytes.Buffer
+	if err := serveRPC(strings.NewReader(`{"jsonrpc": `), &out, io.Discard); err == nil {
+		t.Errorf("Expected error for malformed JSON")
+	}
+	if !strings.Contains(out.String(), fmt.Sprint(rpcParseError)) {
+		t.Errorf("Expected parse error response, got %s", out.String())
+	}
+}
+
+func TestRPCResponseResult(t *testing.T) {
+	rpcMethods["nothing"] = func(params json.RawMessage, log io.Writer) (interface{}, error) {
+		return nil, nil
+	}
+	defer delete(rpcMethods, "nothing")
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"nothing", `{"jsonrpc":"2.0","id":1,"result":null}`},
+		{"explode", `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"unknown method \"explode\""}}`},
+	}
+	for _, tt := range tests {
+		response := handleRPC(json.RawMessage(`{"jsonrpc": "2.0", "id": 1, "method": "`+tt.method+`"}`), io.Discard)
+		data, err := json.Marshal(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.method, tt.want, data)
+		}
+	}
+}
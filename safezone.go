@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// defaultSafeZone is the diameter of the adaptive icon safe zone as a
+// percentage of the layer: the 66dp circle of the 108dp layer that every
+// launcher mask keeps.
+const defaultSafeZone = 66 * 100 / 108
+
+// safeZoneAlpha is the opacity from which a pixel counts as part of the
+// artwork when fitting it into the safe zone, so faint anti-aliasing and
+// shadows don't shrink it.
+const safeZoneAlpha = 0xffff / 16
+
+// validateSafeZone checks the --safe-zone percentage.
+func validateSafeZone(percent int) error {
+	if percent != 0 && (percent < 10 || percent > 100) {
+		return fmt.Errorf("safe zone must be between 10 and 100 (got %d)", percent)
+	}
+	return nil
+}
+
+// safeZone returns the --safe-zone percentage, defaultSafeZone unless set.
+func safeZone(config Config) int {
+	if config.SafeZone == 0 {
+		return defaultSafeZone
+	}
+	return config.SafeZone
+}
+
+// contentRadius returns how far the visible pixels of the square img reach
+// from its center, as a fraction of half its width: 1 for artwork touching
+// the middle of an edge, up to √2 for artwork filling a corner, and 0 for
+// an empty img.
+func contentRadius(img image.Image) float64 {
+	bounds := img.Bounds()
+	half := float64(bounds.Dx()) / 2
+	at := pixelReader(img)
+	radius := 0.0
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if _, _, _, a := at(bounds.Min.X+x, bounds.Min.Y+y); a < safeZoneAlpha {
+				continue
+			}
+			// The pixel's far corner from the center
+			dx := math.Abs(float64(x)+0.5-half) + 0.5
+			dy := math.Abs(float64(y)+0.5-half) + 0.5
+			radius = math.Max(radius, math.Hypot(dx, dy))
+		}
+	}
+	return radius / half
+}
+
+// safeZoneScale returns the size of the artwork img in an adaptive icon
+// layer, as a percentage of the layer, that fits its visible pixels into
+// the --safe-zone circle: smaller for artwork reaching into its corners,
+// larger for artwork with transparent margins, up to the full layer.
+func safeZoneScale(config Config, img image.Image) int {
+	zone := float64(safeZone(config))
+	radius := contentRadius(img)
+	if radius == 0 {
+		return int(zone)
+	}
+	return int(math.Min(100, zone/radius))
+}
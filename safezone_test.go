@@ -0,0 +1,92 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestContentRadius(t *testing.T) {
+	circle := addCircleMask(createTestImage(100, color.RGBA{255, 0, 0, 255}))
+	tests := []struct {
+		name string
+		img  image.Image
+		want float64
+	}{
+		{"full square", createTestImage(100, color.RGBA{255, 0, 0, 255}), math.Sqrt2},
+		{"circle", circle, 1},
+		{"centered half square", createTestImageWithSquare(100, 50, color.RGBA{255, 0, 0, 255}), math.Sqrt2 / 2},
+		{"empty", createTestImage(100, color.RGBA{}), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contentRadius(tt.img); math.Abs(got-tt.want) > 0.03 {
+				t.Errorf("Expected %.3f, got %.3f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSafeZoneScale(t *testing.T) {
+	square := createTestImage(100, color.RGBA{255, 0, 0, 255})
+	circle := addCircleMask(square)
+	tests := []struct {
+		name   string
+		config Config
+		img    image.Image
+		want   int
+	}{
+		{"square", Config{}, square, 43},
+		{"circle", Config{}, circle, 60},
+		{"custom zone", Config{SafeZone: 80}, circle, 79},
+		{"small artwork", Config{}, createTestImageWithSquare(100, 20, color.RGBA{255, 0, 0, 255}), 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := safeZoneScale(tt.config, tt.img); got < tt.want-1 || got > tt.want+1 {
+				t.Errorf("Expected about %d%%, got %d%%", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSafeZone(t *testing.T) {
+	// Opaque artwork reaching into its corners
+	inputPath := createTempImageFile(t, createTestImage(256, color.RGBA{255, 200, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "android", Monochrome: "alpha"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	img, err := loadImage(filepath.Join(outputDir, "mipmap-xxxhdpi", adaptiveForeground))
+	if err != nil {
+		t.Fatalf("Failed to load the foreground: %v", err)
+	}
+	size := float64(img.Bounds().Dx())
+	zone := size * float64(defaultSafeZone) / 100 / 2
+	bounds := img.Bounds()
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a >= safeZoneAlpha && math.Hypot(float64(x)+0.5-size/2, float64(y)+0.5-size/2) > zone+1 {
+				t.Fatalf("Expected the artwork inside the %.0fpx safe zone, found it at (%d, %d)", zone, x, y)
+			}
+		}
+	}
+}
+
+func TestValidateSafeZone(t *testing.T) {
+	for _, percent := range []int{0, 10, 61, 100} {
+		if err := validateSafeZone(percent); err != nil {
+			t.Errorf("%d: unexpected error: %v", percent, err)
+		}
+	}
+	for _, percent := range []int{5, 101} {
+		if err := validateSafeZone(percent); err == nil {
+			t.Errorf("%d: expected an error", percent)
+		}
+	}
+}
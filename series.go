@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var seriesRangePattern = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// parseSeries parses a --series spec into the labels to stamp: either an
+// inclusive number range such as "1-9", or a comma-separated list of labels
+// such as "A,B,C".
+func parseSeries(spec string) ([]string, error) {
+	var labels []string
+
+	if m := seriesRangePattern.FindStringSubmatch(spec); m != nil {
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		if from > to {
+			return nil, fmt.Errorf("invalid series range %q (start is after end)", spec)
+		}
+		if to-from >= 100 {
+			return nil, fmt.Errorf("invalid series range %q (at most 100 labels)", spec)
+		}
+		for n := from; n <= to; n++ {
+			labels = append(labels, strconv.Itoa(n))
+		}
+	} else {
+		labels = strings.Split(spec, ",")
+	}
+
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		if label == "" {
+			return nil, fmt.Errorf("invalid series %q (empty label)", spec)
+		}
+		if !supportedText(label) {
+			return nil, fmt.Errorf("series label %q has characters the built-in font can't draw", label)
+		}
+		name := seriesFileLabel(label)
+		if seen[name] {
+			return nil, fmt.Errorf("series labels must be unique (got %q twice)", name)
+		}
+		seen[name] = true
+	}
+
+	return labels, nil
+}
+
+// seriesFileLabel turns label into the suffix used in output file names.
+func seriesFileLabel(label string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// seriesIconName returns the file name of the copy of name stamped with label.
+func seriesIconName(name, label string) string {
+	return strings.TrimSuffix(name, ".png") + "_" + seriesFileLabel(label) + ".png"
+}
+
+// seriesTargets lists the outputs the --series copies of config produce.
+func seriesTargets(config Config) []plannedOutput {
+	if config.Series == "" {
+		return nil
+	}
+
+	labels, err := parseSeries(config.Series)
+	if err != nil {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, label := range labels {
+		for _, iconSize := range outputSizes(config) {
+			target := iconTarget(config, iconSize, seriesIconName(iconSize.Name, label), "series")
+			target.Settings["label"] = label
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// generateSeries writes one copy of every regular icon per series label, with
+// the label stamped in the middle.
+func generateSeries(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, state *manifestState) error {
+	labels, err := parseSeries(config.Series)
+	if err != nil {
+		return err
+	}
+
+	textColor, err := parseHexColor(config.SeriesColor)
+	if err != nil {
+		return err
+	}
+
+	for _, iconSize := range outputSizes(config) {
+		iconSize := iconSize
+
+		var base image.Image
+		for _, label := range labels {
+			label := label
+			name := seriesIconName(iconSize.Name, label)
+			desc := fmt.Sprintf("%dx%d, %q", iconSize.Size, iconSize.Size, label)
+
+			err := saveOutput(config, state, name, desc, func() image.Image {
+				if base == nil {
+					base = finishIcon(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size), config, iconSize, nil)
+				}
+				return stampLabel(base, label, textColor)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// stampLabel returns a copy of img with label centered on it, drawn over a
+// soft drop shadow so it stays legible on any artwork.
+func stampLabel(img image.Image, label string, c color.RGBA) image.Image {
+	bounds := img.Bounds()
+	size := float64(bounds.Dx())
+
+	stamped := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(stamped, stamped.Bounds(), img, bounds.Min, draw.Src)
+
+	// Cap height is 45% of the icon unless the label would get too wide
+	height := size * 0.45
+	if width := measureText(label, height); width > size*0.8 {
+		height *= size * 0.8 / width
+	}
+	width := measureText(label, height)
+
+	x := (size - width) / 2
+	y := (float64(bounds.Dy()) - height) / 2
+	offset := height / glyphHeight / 2
+
+	drawText(stamped, label, x+offset, y+offset, height, color.RGBA{0, 0, 0, 96})
+	drawText(stamped, label, x, y, height, c)
+
+	return stamped
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSeries(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expected  []string
+		expectErr bool
+	}{
+		{"number range", "1-4", []string{"1", "2", "3", "4"}, false},
+		{"labels", "A,B,C", []string{"A", "B", "C"}, false},
+		{"shortcut labels", "F1,F2", []string{"F1", "F2"}, false},
+		{"reversed range", "5-1", nil, true},
+		{"empty label", "A,,B", nil, true},
+		{"unsupported character", "A,日", nil, true},
+		{"duplicate file label", "a,A", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := parseSeries(tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(labels, tt.expected) {
+				t.Errorf("Expected labels %v, got %v", tt.expected, labels)
+			}
+		})
+	}
+}
+
+func TestStampLabel(t *testing.T) {
+	base := createTestImage(100, color.RGBA{0, 0, 255, 255})
+	white := color.RGBA{255, 255, 255, 255}
+
+	stamped := stampLabel(base, "1", white)
+
+	// The label must leave the corners alone and put white in the middle
+	if c := color.RGBAModel.Convert(stamped.At(2, 2)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected corner untouched, got %v", c)
+	}
+
+	var sawWhite bool
+	for y := 30; y < 70; y++ {
+		for x := 30; x < 70; x++ {
+			if color.RGBAModel.Convert(stamped.At(x, y)).(color.RGBA) == white {
+				sawWhite = true
+			}
+		}
+	}
+	if !sawWhite {
+		t.Errorf("Expected label pixels in the middle of the icon")
+	}
+}
+
+func TestGenerateSeries(t *testing.T) {
+	testImg := createTestImage(64, color.RGBA{60, 60, 60, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   true,
+		TrimPercent:   80,
+		RadiusPercent: 0,
+		Series:        "1-2",
+		SeriesColor:   "#FFFFFF",
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, label := range []string{"1", "2"} {
+		for _, iconSize := range iconSizes {
+			path := filepath.Join(outputDir, seriesIconName(iconSize.Name, label))
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to be generated", filepath.Base(path))
+			}
+		}
+	}
+}
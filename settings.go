@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// settingsKeyword is the tEXt keyword the generation settings are embedded
+// under.
+const settingsKeyword = "icongen settings"
+
+// settingsCarrier returns the output the generation settings are embedded
+// in: the last of the largest regular icons, icon_1024x1024.png for macOS.
+func settingsCarrier(config Config) string {
+	var carrier IconSize
+	for _, iconSize := range outputSizes(config) {
+		if iconSize.Size >= carrier.Size {
+			carrier = iconSize
+		}
+	}
+	return carrier.Name
+}
+
+// generationSettings returns the options of config that differ from their
+// defaults, keyed by flag name as in a configuration file. The input and
+// output are left out, so the settings apply to any source, and so are the
+// flags that only steer a run, such as --jobs, --force, --dry-run, the
+// resource limits and the profiling flags.
+func generationSettings(config Config) map[string]string {
+	var bound Config
+	fs := flag.NewFlagSet("settings", flag.ContinueOnError)
+	defineFlags(fs, &bound)
+	bound = config
+
+	skipped := map[string]bool{"input": true, "output": true, "foreground": true, "recursive": true, "pattern": true, "jobs": true,
+		"flavor-jobs": true, "force": true, "clean": true, "clean-all": true, "incremental": true, "manifest": true, "stateless": true,
+		"check": true, "dry-run": true, "fail-on-skipped": true, "budget": true, "suppress": true, "max-memory": true, "max-pixels": true,
+		"c2pa-cert": true, "c2pa-key": true,
+		"cpuprofile": true, "memprofile": true, "trace": true}
+	settings := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		// --no-* flags mirror options that are recorded already
+		if _, negated := f.Value.(negatedBool); negated || skipped[f.Name] {
+			return
+		}
+		if value := f.Value.String(); value != f.DefValue {
+			settings[f.Name] = value
+		}
+	})
+	return settings
+}
+
+// settingsText returns the generation settings of config as the JSON
+// embedded in the settings carrier.
+func settingsText(config Config) string {
+	data, _ := json.Marshal(generationSettings(config))
+	return string(data)
+}
+
+// runSettingsFrom implements "icongen settings-from": it reads the settings
+// embedded in an icon and prints them as a JSON configuration file, or
+// regenerates the icons from a source image with them.
+func runSettingsFrom(args []string) error {
+	fs := flag.NewFlagSet("settings-from", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s settings-from icon_1024x1024.png [input-image [output-dir]]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Print the generation settings embedded in an icon as a JSON configuration file for --config.\n")
+		fmt.Fprintf(fs.Output(), "Given an input image, generate icons from it with those settings instead.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 3 {
+		fs.Usage()
+		return fmt.Errorf("settings-from requires an icon generated by icongen")
+	}
+
+	settings, err := readSettings(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 1 {
+		return printSettings(os.Stdout, settings)
+	}
+
+	var config Config
+	flags := flag.NewFlagSet("settings", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	defineFlags(flags, &config)
+	if err := applyOptions(flags, settings); err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	config.InputPath = fs.Arg(1)
+	config.OutputDir = fs.Arg(2)
+	setDefaultOutputDir(&config)
+
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	applyMemoryLimit(config)
+	if err := generateIcons(config); err != nil {
+		return fmt.Errorf("failed to generate icons: %w", err)
+	}
+	fmt.Printf("✅ Done. Regenerated icons with the settings of %s.\n", fs.Arg(0))
+	return nil
+}
+
+// readSettings returns the generation settings embedded in the PNG at path.
+func readSettings(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	text, ok := pngText(data, settingsKeyword)
+	if !ok {
+		return nil, fmt.Errorf("%s has no embedded settings (icongen embeds them in the largest regular icon only)", path)
+	}
+
+	var settings map[string]string
+	if err := json.Unmarshal([]byte(text), &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse the settings in %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// printSettings writes settings to w as an indented JSON configuration
+// file, in key order.
+func printSettings(w io.Writer, settings map[string]string) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
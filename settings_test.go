@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"image/color"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// defaultConfig returns the Config the command line starts from.
+func defaultConfig() Config {
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &config)
+	return config
+}
+
+func TestSettingsCarrier(t *testing.T) {
+	tests := []struct {
+		preset string
+		want   string
+	}{
+		{"", "icon_1024x1024.png"},
+		{"macos", "icon_1024x1024.png"},
+		{"android", "mipmap-xxxhdpi/ic_launcher.png"},
+	}
+
+	for _, tt := range tests {
+		if got := settingsCarrier(Config{Preset: tt.preset}); got != tt.want {
+			t.Errorf("Preset %q: expected %s, got %s", tt.preset, tt.want, got)
+		}
+	}
+}
+
+func TestGenerationSettings(t *testing.T) {
+	config := defaultConfig()
+	if settings := generationSettings(config); len(settings) != 0 {
+		t.Errorf("Expected no settings for the defaults, got %v", settings)
+	}
+
+	config.InputPath = "art/logo.png"
+	config.OutputDir = "build"
+	config.TrimPercent = 90
+	config.CropEnabled = false
+	config.Badge = "BETA"
+	// Run controls aren't generation settings
+	config.Force = true
+	config.DryRun = true
+	config.MaxMemory = "512MiB"
+	config.Suppress = "W001"
+
+	settings := generationSettings(config)
+	want := map[string]string{"trim-percent": "90", "crop": "false", "badge": "BETA"}
+	if len(settings) != len(want) {
+		t.Errorf("Expected settings %v, got %v", want, settings)
+	}
+	for key, value := range want {
+		if settings[key] != value {
+			t.Errorf("Expected %s=%s, got %q", key, value, settings[key])
+		}
+	}
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := defaultConfig()
+	config.InputPath = inputPath
+	config.OutputDir = outputDir
+	config.RadiusPercent = 10
+	config.Badge = "DEV"
+	config.Layers = `[{"type":"fill","color":"#FFFFFF"},{"type":"source","scale":80}]`
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	settings, err := readSettings(filepath.Join(outputDir, "icon_1024x1024.png"))
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if _, err := readSettings(filepath.Join(outputDir, "icon_16x16.png")); err == nil {
+		t.Errorf("Expected only the largest icon to carry the settings")
+	}
+
+	var recovered Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(fs, &recovered)
+	if err := applyOptions(fs, settings); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	recovered.InputPath = inputPath
+	recovered.OutputDir = outputDir
+
+	want, _ := hashOptions(config)
+	if got, _ := hashOptions(recovered); got != want {
+		t.Errorf("Expected the recovered settings to reproduce the options, got %+v", recovered)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestGaussianBlur(t *testing.T) {
+	const n = 41
+	values := make([]float64, n*n)
+	values[20*n+20] = 1
+
+	blurred := gaussianBlur(values, n, n, 3)
+
+	sum := 0.0
+	for _, v := range blurred {
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("Expected blur to preserve the total, got %g", sum)
+	}
+	if blurred[20*n+17] != blurred[20*n+23] || blurred[17*n+20] != blurred[23*n+20] {
+		t.Errorf("Expected a symmetric blur")
+	}
+	if blurred[20*n+20] <= blurred[20*n+23] {
+		t.Errorf("Expected the blur to peak at the centre")
+	}
+
+	if got := gaussianBlur(values, n, n, 0); &got[0] != &values[0] {
+		t.Errorf("Expected no blur for a zero sigma")
+	}
+}
+
+func TestAddDropShadow(t *testing.T) {
+	img := createTestImage(100, color.RGBA{0, 0, 255, 255})
+	config := Config{
+		Shadow:        true,
+		ShadowBlur:    4,
+		ShadowOffsetY: 4,
+		ShadowOpacity: 50,
+		ShadowColor:   "#000000",
+	}
+
+	shadowed := addDropShadow(img, config, 100)
+
+	// The artwork shrinks by the blur and offset, so the edge is now shadow
+	if r, g, b, a := shadowed.At(50, 50).RGBA(); b != 0xffff || a != 0xffff || r != 0 || g != 0 {
+		t.Errorf("Expected the artwork to stay in the centre, got %d,%d,%d,%d", r, g, b, a)
+	}
+	_, _, top, topAlpha := shadowed.At(50, 1).RGBA()
+	_, _, bottom, bottomAlpha := shadowed.At(50, 98).RGBA()
+	if top != 0 || bottom != 0 {
+		t.Errorf("Expected the artwork to be shrunk away from the edges")
+	}
+	if bottomAlpha <= topAlpha {
+		t.Errorf("Expected the shadow to fall below the artwork (top alpha %d, bottom alpha %d)", topAlpha, bottomAlpha)
+	}
+	if bottomAlpha > 0xffff/2+1 {
+		t.Errorf("Expected shadow alpha to stay within the opacity, got %d", bottomAlpha)
+	}
+
+	config.Shadow = false
+	if got := addDropShadow(img, config, 100); got != img {
+		t.Errorf("Expected no shadow without --shadow")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// maxSharpenSize is the largest output --sharpen applies to. Larger icons
+// keep enough detail after downscaling.
+const maxSharpenSize = 48
+
+// validateSharpen checks the --sharpen percentage.
+func validateSharpen(percent int) error {
+	if percent < 0 || percent > 200 {
+		return fmt.Errorf("sharpen must be between 0 and 200 (got %d)", percent)
+	}
+	return nil
+}
+
+// sharpenAmount returns how strongly an output of size resized from img is
+// sharpened, as a fraction of the difference to its blur: the --sharpen
+// percentage at 16px, and less in proportion to the size above that, down
+// to a third at 48px. Outputs larger than maxSharpenSize or not downscaled,
+// and pixel art, aren't sharpened.
+func sharpenAmount(config Config, img image.Image, size int) float64 {
+	nearest := config.PixelArt != "" || config.Filter == "nearest"
+	if config.Sharpen == 0 || nearest || size > maxSharpenSize || size >= img.Bounds().Dx() {
+		return 0
+	}
+	return float64(config.Sharpen) / 100 * 16 / math.Max(16, float64(size))
+}
+
+// unsharpMask sharpens img by amount with an unsharp mask: every pixel
+// moves away from a 3x3 Gaussian blur of its neighborhood, which brings
+// back the edge contrast downscaling smooths away. It works on the
+// premultiplied channels, alpha included, so the silhouette sharpens with
+// the colors, and returns an image as deep as img.
+func unsharpMask(img image.Image, amount float64) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	at := pixelReader(img)
+	pixels := make([][4]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, a := at(bounds.Min.X+x, bounds.Min.Y+y)
+			pixels[y*w+x] = [4]float64{float64(r), float64(g), float64(b), float64(a)}
+		}
+	}
+
+	kernel := [3]float64{0.25, 0.5, 0.25}
+	canvas := newCanvasLike(img, image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var blur [4]float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					// Edge pixels repeat outwards
+					sx, sy := x+dx, y+dy
+					if sx < 0 || sx >= w {
+						sx = x
+					}
+					if sy < 0 || sy >= h {
+						sy = y
+					}
+					weight := kernel[dx+1] * kernel[dy+1]
+					for c, v := range pixels[sy*w+sx] {
+						blur[c] += v * weight
+					}
+				}
+			}
+
+			var out [4]uint32
+			p := pixels[y*w+x]
+			for c := 3; c >= 0; c-- {
+				limit := float64(0xffff)
+				if c < 3 {
+					limit = float64(out[3])
+				}
+				out[c] = uint32(math.Max(0, math.Min(limit, math.Round(p[c]+amount*(p[c]-blur[c])))))
+			}
+			if deep, ok := canvas.(*image.RGBA64); ok {
+				deep.SetRGBA64(x, y, color.RGBA64{uint16(out[0]), uint16(out[1]), uint16(out[2]), uint16(out[3])})
+			} else {
+				setPixel(canvas.(*image.RGBA), x, y, out[0], out[1], out[2], out[3])
+			}
+		}
+	}
+	return canvas
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSharpenAmount(t *testing.T) {
+	source := createTestImage(512, color.RGBA{255, 0, 0, 255})
+	tests := []struct {
+		name   string
+		config Config
+		size   int
+		want   float64
+	}{
+		{"off", Config{}, 16, 0},
+		{"16px", Config{Sharpen: 60}, 16, 0.6},
+		{"32px", Config{Sharpen: 60}, 32, 0.3},
+		{"48px", Config{Sharpen: 60}, 48, 0.2},
+		{"64px", Config{Sharpen: 60}, 64, 0},
+		{"pixel art", Config{Sharpen: 60, PixelArt: "nearest"}, 16, 0},
+		{"nearest filter", Config{Sharpen: 60, Filter: "nearest"}, 16, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharpenAmount(tt.config, source, tt.size); got < tt.want-1e-9 || got > tt.want+1e-9 {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := sharpenAmount(Config{Sharpen: 60}, createTestImage(16, color.RGBA{}), 32); got != 0 {
+		t.Errorf("Expected upscaled outputs not to be sharpened, got %v", got)
+	}
+}
+
+func TestUnsharpMask(t *testing.T) {
+	// A soft vertical edge from dark gray to light gray
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	levels := []uint8{60, 60, 60, 100, 160, 200, 200, 200}
+	for y := 0; y < 8; y++ {
+		for x, v := range levels {
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	sharpened := unsharpMask(img, 1).(*image.RGBA)
+	if before, after := img.RGBAAt(3, 4).R, sharpened.RGBAAt(3, 4).R; after >= before {
+		t.Errorf("Expected the dark side of the edge to get darker, got %d from %d", after, before)
+	}
+	if before, after := img.RGBAAt(4, 4).R, sharpened.RGBAAt(4, 4).R; after <= before {
+		t.Errorf("Expected the light side of the edge to get lighter, got %d from %d", after, before)
+	}
+	if c := sharpened.RGBAAt(0, 0); c != img.RGBAAt(0, 0) {
+		t.Errorf("Expected flat areas to stay as they are, got %v", c)
+	}
+
+	// A translucent square on transparency stays premultiplied
+	square := createTestImageWithSquare(8, 4, color.RGBA{200, 100, 0, 200})
+	sharpened = unsharpMask(square, 2).(*image.RGBA)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if c := sharpened.RGBAAt(x, y); c.R > c.A || c.G > c.A {
+				t.Fatalf("Expected premultiplied colors, got %v at (%d, %d)", c, x, y)
+			}
+		}
+	}
+
+	if !deepImage(unsharpMask(toRGBA64(img), 1)) {
+		t.Errorf("Expected a 16-bit image to stay 16-bit")
+	}
+}
+
+func TestValidateSharpen(t *testing.T) {
+	for _, percent := range []int{0, 50, 200} {
+		if err := validateSharpen(percent); err != nil {
+			t.Errorf("%d: unexpected error: %v", percent, err)
+		}
+	}
+	for _, percent := range []int{-1, 201} {
+		if err := validateSharpen(percent); err == nil {
+			t.Errorf("%d: expected an error", percent)
+		}
+	}
+}
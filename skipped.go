@@ -0,0 +1,23 @@
+package main
+
+// exitSkipped is the exit code of a run that generated everything it could
+// but skipped outputs with --fail-on-skipped, so CI can tell it apart from a
+// failed run (exit code 1).
+const exitSkipped = 3
+
+// skippedOutput is an output a run asked for that this build can't produce.
+type skippedOutput struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// skippedOutputs lists the requested outputs of config that need a backend
+// this build leaves out. Rather than failing the whole run, they're left out
+// of the plan, reported as W003 warnings and recorded in the manifest.
+func skippedOutputs(config Config) []skippedOutput {
+	var skipped []skippedOutput
+	if config.SpinnerFrames > 0 && config.SpinnerGIF && !hasEncoder("gif") {
+		skipped = append(skipped, skippedOutput{spinnerGIFName, "GIF encoding is not available in this build"})
+	}
+	return skipped
+}
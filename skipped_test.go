@@ -0,0 +1,74 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withoutFormat removes the named backend for the duration of the test, as if
+// this build left it out.
+func withoutFormat(t *testing.T, name string) {
+	backend, ok := formatBackends[name]
+	delete(formatBackends, name)
+	t.Cleanup(func() {
+		if ok {
+			formatBackends[name] = backend
+		}
+	})
+}
+
+func TestSkippedOutputs(t *testing.T) {
+	withoutFormat(t, "gif")
+
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		TrimPercent:   80,
+		SpinnerFrames: 4,
+		SpinnerSize:   32,
+		SpinnerGIF:    true,
+	}
+
+	if err := validateConfig(config); err != nil {
+		t.Fatalf("Expected --spinner-gif without GIF support to validate, got %v", err)
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Expected the rest of the run to succeed, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, spinnerGIFName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no %s, got %v", spinnerGIFName, err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, spinnerFrameName(3))); err != nil {
+		t.Errorf("Expected the spinner frames to be generated: %v", err)
+	}
+
+	m, err := readManifest(outputDir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(m.Skipped) != 1 || m.Skipped[0].Name != spinnerGIFName {
+		t.Errorf("Expected %s to be recorded as skipped, got %+v", spinnerGIFName, m.Skipped)
+	}
+	for _, name := range expectedOutputs(config) {
+		if name == spinnerGIFName {
+			t.Errorf("Expected %s to be left out of the plan", spinnerGIFName)
+		}
+	}
+}
+
+func TestNoSkippedOutputs(t *testing.T) {
+	config := Config{SpinnerFrames: 4, SpinnerGIF: true}
+	if hasEncoder("gif") {
+		if skipped := skippedOutputs(config); len(skipped) != 0 {
+			t.Errorf("Expected nothing skipped with GIF support, got %+v", skipped)
+		}
+	}
+	if skipped := skippedOutputs(Config{SpinnerGIF: true}); len(skipped) != 0 {
+		t.Errorf("Expected nothing skipped without spinner frames, got %+v", skipped)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// With --cache, the prepared source is kept across runs in the user cache
+// directory, downscaled to an intermediate that covers every preset, so
+// generating the macos, android and web sets one after the other decodes,
+// crops and resizes a large source only once.
+
+// intermediateSize is the side of the cached intermediate, the largest icon
+// any preset generates, so runs with different presets share it.
+const intermediateSize = 1024
+
+// sourceCacheEntries is how many intermediates the cache keeps; older ones
+// are removed as new ones are added.
+const sourceCacheEntries = 16
+
+const sourceCacheMagic = "icongen-source 2\n"
+
+// sourceFlags are the flags the prepared source depends on. Only they key
+// the cache, so runs that differ in presets or effects share an entry.
+var sourceFlags = []string{
+	"remove-background", "trim-alpha", "crop", "trim-percent", "smart-crop", "crop-anchor",
+	"fit", "non-square", "auto-contrast", "map-color", "map-color-tolerance", "hue-shift",
+	"tint", "pixel-art", "filter", "linear-light", "bit-depth",
+}
+
+// sourceCacheDir returns where cached intermediates are kept.
+func sourceCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "icongen", "sources"), nil
+}
+
+// intermediateSide returns the side the prepared source is cached at: at
+// least intermediateSize, more if the largest artwork of the run is larger.
+func intermediateSide(config Config) int {
+	side := largestOutputSize(config) * layerScale(config.ForegroundScale) / 100
+	if side < intermediateSize {
+		side = intermediateSize
+	}
+	return side
+}
+
+// sourceCacheKey identifies the prepared source of config, decoded at
+// 1/reduction of its size: the hash of the source file, the source flags
+// and the intermediate side.
+func sourceCacheKey(config Config, reduction int) (string, error) {
+	sourceHash, err := hashFile(config.InputPath)
+	if err != nil {
+		return "", err
+	}
+
+	settings := generationSettings(config)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%d\n%d\n", sourceHash, reduction, intermediateSide(config))
+	for _, name := range sourceFlags {
+		fmt.Fprintf(&b, "%s=%s\n", name, settings[name])
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// toIntermediate downscales a prepared source to the intermediate side. A
+// smaller source, and pixel art, which is never averaged, are kept at their
+// own size. A 16-bit source stays at 16 bits, as an *image.RGBA64; others
+// become an *image.RGBA.
+func toIntermediate(img image.Image, config Config) image.Image {
+	side := intermediateSide(config)
+	bounds := img.Bounds()
+	if config.PixelArt != "" || config.Filter == "nearest" || (bounds.Dx() <= side && bounds.Dy() <= side) {
+		if deepImage(img) {
+			return toRGBA64(img)
+		}
+		return toRGBA(img)
+	}
+	return depthResizer(config)(img, side)
+}
+
+// cachedSource returns the prepared source of config from the cache, or
+// prepares it with load, downscales it to the intermediate and caches that.
+// The cache is best effort: if it can't be read or written, the source is
+// prepared as usual.
+func cachedSource(config Config, reduction int, load func() (image.Image, error)) (image.Image, error) {
+	dir, err := sourceCacheDir()
+	key := ""
+	if err == nil {
+		key, err = sourceCacheKey(config, reduction)
+	}
+	if err != nil {
+		img, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return toIntermediate(img, config), nil
+	}
+
+	path := filepath.Join(dir, key+".gz")
+	if img, err := readIntermediate(path); err == nil {
+		fmt.Fprintf(logOutput(config), "Reusing the prepared source from %s\n", dir)
+		return img, nil
+	}
+
+	img, err := load()
+	if err != nil {
+		return nil, err
+	}
+	intermediate := toIntermediate(img, config)
+	if err := writeIntermediate(path, intermediate); err == nil {
+		pruneSourceCache(dir)
+	}
+	return intermediate, nil
+}
+
+// readIntermediate reads a cached intermediate and marks it as recently
+// used.
+func readIntermediate(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(zr)
+	magic := make([]byte, len(sourceCacheMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != sourceCacheMagic {
+		return nil, fmt.Errorf("not a cached source: %s", path)
+	}
+	// The width, height and bits per channel
+	var header [3]uint32
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	width, height := header[0], header[1]
+	if width == 0 || height == 0 || width > 1<<16 || height > 1<<16 {
+		return nil, fmt.Errorf("invalid cached source size %dx%d", width, height)
+	}
+	rect := image.Rect(0, 0, int(width), int(height))
+	var img image.Image
+	var pix []byte
+	switch header[2] {
+	case 8:
+		rgba := image.NewRGBA(rect)
+		img, pix = rgba, rgba.Pix
+	case 16:
+		deep := image.NewRGBA64(rect)
+		img, pix = deep, deep.Pix
+	default:
+		return nil, fmt.Errorf("invalid cached source depth %d", header[2])
+	}
+	if _, err := io.ReadFull(r, pix); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return img, nil
+}
+
+// writeIntermediate caches img, an *image.RGBA or *image.RGBA64, at path,
+// through a temporary file so a concurrent run never reads half of it.
+func writeIntermediate(path string, img image.Image) error {
+	var rect image.Rectangle
+	var pix []byte
+	var stride, depth int
+	switch img := img.(type) {
+	case *image.RGBA:
+		rect, pix, stride, depth = img.Rect, img.Pix, img.Stride, 8
+	case *image.RGBA64:
+		rect, pix, stride, depth = img.Rect, img.Pix, img.Stride, 16
+	default:
+		return fmt.Errorf("can't cache a %T", img)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".source-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw, _ := gzip.NewWriterLevel(tmp, gzip.BestSpeed)
+	zw.Write([]byte(sourceCacheMagic))
+	binary.Write(zw, binary.BigEndian, [3]uint32{uint32(rect.Dx()), uint32(rect.Dy()), uint32(depth)})
+	rowBytes := rect.Dx() * depth / 2
+	for y := 0; y < rect.Dy(); y++ {
+		zw.Write(pix[y*stride : y*stride+rowBytes])
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// pruneSourceCache removes all but the sourceCacheEntries most recently
+// used intermediates in dir.
+func pruneSourceCache(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type cached struct {
+		path string
+		used int64
+	}
+	var files []cached
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !strings.HasSuffix(entry.Name(), ".gz") {
+			continue
+		}
+		files = append(files, cached{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used > files[j].used })
+	for i := sourceCacheEntries; i < len(files); i++ {
+		os.Remove(files[i].path)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useCacheDir points the user cache directory at a temporary one.
+func useCacheDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+	cacheDir, err := sourceCacheDir()
+	if err != nil {
+		t.Fatalf("Failed to find the cache directory: %v", err)
+	}
+	return cacheDir
+}
+
+func TestCachedSource(t *testing.T) {
+	useCacheDir(t)
+	inputPath := createTempImageFile(t, createTestImage(1500, color.RGBA{255, 128, 0, 255}))
+
+	loads := 0
+	load := func() (image.Image, error) {
+		loads++
+		return createTestImage(1500, color.RGBA{255, 128, 0, 255}), nil
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		loads  int
+		size   int
+	}{
+		{"first run", Config{Preset: "macos"}, 1, 1024},
+		{"other preset", Config{Preset: "android"}, 1, 1024},
+		{"other effects", Config{Preset: "web", Mask: "circle"}, 1, 1024},
+		{"other hue", Config{Preset: "macos", HueShift: 90}, 2, 1024},
+		{"larger artwork", Config{Preset: "macos", ForegroundScale: 120}, 3, 1228},
+	}
+
+	for _, tt := range tests {
+		tt.config.InputPath = inputPath
+		tt.config.TrimPercent = 80
+		img, err := cachedSource(tt.config, 1, load)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if loads != tt.loads {
+			t.Errorf("%s: expected %d loads, got %d", tt.name, tt.loads, loads)
+		}
+		if img.Bounds().Dx() != tt.size {
+			t.Errorf("%s: expected a %dpx intermediate, got %v", tt.name, tt.size, img.Bounds())
+		}
+	}
+
+	// --bit-depth=16 keys its own entry, which keeps the 16-bit source
+	deepLoad := func() (image.Image, error) {
+		loads++
+		return toRGBA64(createTestImage(1500, color.RGBA{255, 128, 0, 255})), nil
+	}
+	config := Config{InputPath: inputPath, TrimPercent: 80, Preset: "macos", BitDepth: 16}
+	for run := 0; run < 2; run++ {
+		img, err := cachedSource(config, 1, deepLoad)
+		if err != nil {
+			t.Fatalf("16-bit: expected no error, got %v", err)
+		}
+		if !deepImage(img) {
+			t.Errorf("16-bit: expected a 16-bit intermediate on run %d, got %T", run+1, img)
+		}
+	}
+	if loads != 4 {
+		t.Errorf("16-bit: expected a single load of its own, got %d loads", loads-3)
+	}
+}
+
+func TestCachedRunsMatch(t *testing.T) {
+	useCacheDir(t)
+	inputPath := createTempImageFile(t, createTestImageWithBorder(1200, color.RGBA{255, 128, 0, 255}, color.RGBA{0, 64, 255, 128}, 100))
+
+	generate := func() string {
+		outputDir := t.TempDir()
+		config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, CropEnabled: true, Cache: true, LinearLight: true}
+		if err := generateIcons(config); err != nil {
+			t.Fatalf("Failed to generate icons: %v", err)
+		}
+		return outputDir
+	}
+
+	missDir, hitDir := generate(), generate()
+	for _, name := range []string{"icon_1024x1024.png", "icon_32x32.png", "icon_16x16.png"} {
+		a, _ := os.ReadFile(filepath.Join(missDir, name))
+		b, _ := os.ReadFile(filepath.Join(hitDir, name))
+		if len(a) == 0 || !bytes.Equal(a, b) {
+			t.Errorf("Expected %s to come out the same from the cache", name)
+		}
+	}
+}
+
+func TestPruneSourceCache(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	old := time.Now().Add(-time.Hour)
+	for i := 0; i < sourceCacheEntries+3; i++ {
+		path := filepath.Join(dir, string(rune('a'+i))+".gz")
+		if err := writeIntermediate(path, img); err != nil {
+			t.Fatalf("Failed to write an intermediate: %v", err)
+		}
+		// The first three are the least recently used
+		if i < 3 {
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	pruneSourceCache(dir)
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != sourceCacheEntries {
+		t.Errorf("Expected %d entries, got %d", sourceCacheEntries, len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest entry to be removed")
+	}
+
+	if got, err := readIntermediate(filepath.Join(dir, "d.gz")); err != nil || got.Bounds() != img.Rect {
+		t.Errorf("Expected the intermediate read back, got %v, %v", got, err)
+	}
+	os.WriteFile(filepath.Join(dir, "bad.gz"), []byte("nope"), 0644)
+	if _, err := readIntermediate(filepath.Join(dir, "bad.gz")); err == nil {
+		t.Errorf("Expected an error reading a corrupt entry")
+	}
+}
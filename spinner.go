@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"strconv"
+)
+
+// spinnerFrameName returns the file name of frame i of the rotation series.
+func spinnerFrameName(i int) string {
+	return fmt.Sprintf("spinner_%02d.png", i)
+}
+
+const spinnerGIFName = "spinner.gif"
+
+// spinnerTargets lists the outputs the rotation series of config produces.
+func spinnerTargets(config Config) []plannedOutput {
+	var targets []plannedOutput
+	for i := 0; i < config.SpinnerFrames; i++ {
+		targets = append(targets, plannedOutput{
+			Name:    spinnerFrameName(i),
+			Width:   config.SpinnerSize,
+			Height:  config.SpinnerSize,
+			Format:  "png",
+			Variant: "spinner",
+			Settings: map[string]string{
+				"angle": strconv.FormatFloat(360*float64(i)/float64(config.SpinnerFrames), 'f', -1, 64),
+			},
+		})
+	}
+	if config.SpinnerFrames > 0 && config.SpinnerGIF && hasEncoder("gif") {
+		targets = append(targets, plannedOutput{
+			Name:     spinnerGIFName,
+			Width:    config.SpinnerSize,
+			Height:   config.SpinnerSize,
+			Format:   "gif",
+			Variant:  "spinner",
+			Settings: map[string]string{"frames": strconv.Itoa(config.SpinnerFrames)},
+		})
+	}
+	return targets
+}
+
+// generateSpinner writes an N-frame clockwise rotation series of sourceImg at
+// config.SpinnerSize, and optionally assembles the frames into a looping GIF
+// that completes one revolution per second.
+func generateSpinner(sourceImg image.Image, config Config, state *manifestState) error {
+	names := targetNames(spinnerTargets(config))
+	if state.allUpToDate(names) {
+		for _, name := range names {
+			fmt.Fprintf(logOutput(config), " - %s (up to date)\n", name)
+			if err := state.record(name); err != nil {
+				return fmt.Errorf("failed to record %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	base := resizeImage(sourceImg, config.SpinnerSize)
+
+	var frames []*image.RGBA
+	for i := 0; i < config.SpinnerFrames; i++ {
+		name := spinnerFrameName(i)
+		degrees := 360 * float64(i) / float64(config.SpinnerFrames)
+		fmt.Fprintf(logOutput(config), " - %s (%dx%d, %.1f°)\n", name, config.SpinnerSize, config.SpinnerSize, degrees)
+
+		frame := rotateImage(base, degrees)
+		frames = append(frames, frame)
+
+		if err := saveImage(frame, filepath.Join(config.OutputDir, name)); err != nil {
+			return fmt.Errorf("failed to save %s: %w", name, err)
+		}
+		if err := state.record(name); err != nil {
+			return fmt.Errorf("failed to record %s: %w", name, err)
+		}
+	}
+
+	if config.SpinnerGIF && hasEncoder("gif") {
+		fmt.Fprintf(logOutput(config), " - %s (%d frames)\n", spinnerGIFName, len(frames))
+		if err := saveGIF(frames, filepath.Join(config.OutputDir, spinnerGIFName), config.Dither); err != nil {
+			return fmt.Errorf("failed to save %s: %w", spinnerGIFName, err)
+		}
+		if err := state.record(spinnerGIFName); err != nil {
+			return fmt.Errorf("failed to record %s: %w", spinnerGIFName, err)
+		}
+	}
+
+	return nil
+}
+
+// rotateImage rotates img clockwise by degrees about its center, keeping the
+// original canvas size. Pixels rotated in from outside the source are
+// transparent.
+func rotateImage(img image.Image, degrees float64) *image.RGBA {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	rotated := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	rad := degrees * math.Pi / 180
+	sin, cos := math.Sincos(rad)
+	centerX := float64(width) / 2
+	centerY := float64(height) / 2
+
+	at := func(x, y int) (float64, float64, float64, float64) {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return 0, 0, 0, 0
+		}
+		r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return float64(r), float64(g), float64(b), float64(a)
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Map the destination pixel center back into the source
+			dx := float64(x) + 0.5 - centerX
+			dy := float64(y) + 0.5 - centerY
+			srcXf := dx*cos + dy*sin + centerX - 0.5
+			srcYf := -dx*sin + dy*cos + centerY - 0.5
+
+			srcX := int(math.Floor(srcXf))
+			srcY := int(math.Floor(srcYf))
+			fracX := srcXf - float64(srcX)
+			fracY := srcYf - float64(srcY)
+
+			r00, g00, b00, a00 := at(srcX, srcY)
+			r10, g10, b10, a10 := at(srcX+1, srcY)
+			r01, g01, b01, a01 := at(srcX, srcY+1)
+			r11, g11, b11, a11 := at(srcX+1, srcY+1)
+
+			a := bilinearInterpolate(a00, a10, a01, a11, fracX, fracY)
+			if a == 0 {
+				continue
+			}
+
+			rotated.Set(x, y, color.RGBA64{
+				R: uint16(bilinearInterpolate(r00, r10, r01, r11, fracX, fracY)),
+				G: uint16(bilinearInterpolate(g00, g10, g01, g11, fracX, fracY)),
+				B: uint16(bilinearInterpolate(b00, b10, b01, b11, fracX, fracY)),
+				A: uint16(a),
+			})
+		}
+	}
+
+	return rotated
+}
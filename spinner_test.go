@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateImage(t *testing.T) {
+	// A red bar along the top edge of a transparent canvas
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		degrees float64
+		opaque  image.Point
+		clear   image.Point
+	}{
+		{"no rotation", 0, image.Point{20, 2}, image.Point{37, 20}},
+		{"quarter turn", 90, image.Point{37, 20}, image.Point{20, 2}},
+		{"half turn", 180, image.Point{20, 37}, image.Point{20, 2}},
+		{"three quarter turn", 270, image.Point{2, 20}, image.Point{37, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotated := rotateImage(img, tt.degrees)
+
+			if rotated.Bounds() != img.Bounds() {
+				t.Fatalf("Expected bounds %v, got %v", img.Bounds(), rotated.Bounds())
+			}
+			if _, _, _, a := rotated.At(tt.opaque.X, tt.opaque.Y).RGBA(); a < 0xf000 {
+				t.Errorf("Expected opaque pixel at %v, got alpha %#x", tt.opaque, a)
+			}
+			if _, _, _, a := rotated.At(tt.clear.X, tt.clear.Y).RGBA(); a != 0 {
+				t.Errorf("Expected transparent pixel at %v, got alpha %#x", tt.clear, a)
+			}
+		})
+	}
+}
+
+func TestGenerateSpinner(t *testing.T) {
+	testImg := createTestImageWithSquare(100, 60, color.RGBA{0, 200, 100, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   false,
+		TrimPercent:   100,
+		RadiusPercent: 0,
+		SpinnerFrames: 8,
+		SpinnerSize:   32,
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for i := 0; i < config.SpinnerFrames; i++ {
+		img, err := loadImage(filepath.Join(outputDir, spinnerFrameName(i)))
+		if err != nil {
+			t.Errorf("Failed to load frame %d: %v", i, err)
+			continue
+		}
+		if img.Bounds().Dx() != 32 || img.Bounds().Dy() != 32 {
+			t.Errorf("Expected 32x32 frame, got %v", img.Bounds())
+		}
+	}
+}
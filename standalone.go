@@ -0,0 +1,46 @@
+package main
+
+import "image"
+
+// Notification icons, complication images and template images stand apart
+// from the regular icons: they are drawn from the artwork's silhouette, or
+// are the finished artwork as is, and have no variants. generateIcons,
+// compare and renderIconSet render them alike through standaloneIcons.
+
+// isStandaloneIcon reports whether iconSize is a notification icon, a
+// complication image or a template image.
+func isStandaloneIcon(iconSize IconSize) bool {
+	return isNotificationIcon(iconSize) || isComplicationImage(iconSize) || isTemplateImage(iconSize)
+}
+
+// standaloneIcons renders the standalone icons of a source, extracting its
+// white and black silhouettes once, on first use.
+type standaloneIcons struct {
+	config     Config
+	silhouette func() image.Image
+	template   func() image.Image
+}
+
+func newStandaloneIcons(sourceImg image.Image, config Config) standaloneIcons {
+	silhouette := notificationSilhouette(sourceImg, config)
+	return standaloneIcons{config: config, silhouette: silhouette, template: templateSilhouette(silhouette)}
+}
+
+// render returns the progress label and the renderer of iconSize, or false
+// if it is a regular icon. artwork renders the finished icon, which graphic
+// complications show.
+func (s standaloneIcons) render(iconSize IconSize, artwork func() image.Image) (string, func() image.Image, bool) {
+	var label string
+	var render func() image.Image
+	switch {
+	case isNotificationIcon(iconSize):
+		label, render = renderNotificationIcon(s.silhouette, s.config, iconSize)
+	case isTemplateImage(iconSize):
+		label, render = renderTemplateImage(s.template, s.config, iconSize)
+	case isComplicationImage(iconSize):
+		label, render = renderComplicationImage(artwork, s.silhouette, s.config, iconSize)
+	default:
+		return "", nil, false
+	}
+	return label, render, true
+}
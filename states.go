@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strings"
+)
+
+// trayIconSizes are the menu bar, tray and toolbar sizes status-state variants
+// are generated at.
+var trayIconSizes = []IconSize{
+	{"icon_16x16.png", 16},
+	{"icon_16x16@2x.png", 32},
+	{"icon_22x22.png", 22},
+	{"icon_22x22@2x.png", 44},
+}
+
+// iconState is a named status color such as ok:#34c759.
+type iconState struct {
+	Name  string
+	Color color.RGBA
+}
+
+var stateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// parseStates parses a --states spec of the form NAME:#RRGGBB[,NAME:#RRGGBB...].
+func parseStates(spec string) ([]iconState, error) {
+	var states []iconState
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		name, hex, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid state %q (expected name:#RRGGBB)", entry)
+		}
+		if !stateNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid state name %q (use lowercase letters, digits and dashes)", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate state %q", name)
+		}
+		seen[name] = true
+
+		c, err := parseHexColor(hex)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, iconState{Name: name, Color: c})
+	}
+
+	return states, nil
+}
+
+// stateIconName returns the file name of the state variant of name.
+func stateIconName(name, state string) string {
+	return strings.TrimSuffix(name, ".png") + "_" + state + ".png"
+}
+
+// stateTargets lists the outputs the --states variants of config produce.
+func stateTargets(config Config) []plannedOutput {
+	if config.States == "" {
+		return nil
+	}
+
+	states, err := parseStates(config.States)
+	if err != nil {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, state := range states {
+		for _, iconSize := range trayIconSizes {
+			targets = append(targets, plannedOutput{
+				Name:     stateIconName(iconSize.Name, state.Name),
+				Width:    iconSize.Size,
+				Height:   iconSize.Size,
+				Format:   "png",
+				Variant:  "state",
+				Settings: map[string]string{"state": state.Name, "color": formatHexColor(state.Color)},
+			})
+		}
+	}
+	return targets
+}
+
+// generateStates writes one tinted copy of the artwork per state at every
+// tray icon size.
+func generateStates(sourceImg image.Image, config Config, state *manifestState) error {
+	states, err := parseStates(config.States)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		for _, iconSize := range trayIconSizes {
+			iconSize := iconSize
+			c := s.Color
+			name := stateIconName(iconSize.Name, s.Name)
+			label := fmt.Sprintf("%dx%d, %s", iconSize.Size, iconSize.Size, s.Name)
+
+			err := saveOutput(config, state, name, label, func() image.Image {
+				return tintImage(resizeImage(sourceImg, iconSize.Size), c)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// tintImage fills the artwork's silhouette with a solid color, keeping only
+// the alpha channel of img.
+func tintImage(img image.Image, c color.RGBA) image.Image {
+	bounds := img.Bounds()
+	tinted := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	// Un-premultiply the tint color once
+	fill := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			_, _, _, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			px := fill
+			px.A = uint8((a >> 8) * uint32(fill.A) / 255)
+			tinted.SetNRGBA(x, y, px)
+		}
+	}
+
+	return tinted
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStates(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expected  int
+		expectErr bool
+	}{
+		{"three states", "ok:#34c759,warn:#ff9f0a,error:#ff3b30", 3, false},
+		{"single state", "busy:#007aff", 1, false},
+		{"missing color", "ok", 0, true},
+		{"bad color", "ok:green", 0, true},
+		{"bad name", "Ok State:#34c759", 0, true},
+		{"duplicate", "ok:#34c759,ok:#000000", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states, err := parseStates(tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(states) != tt.expected {
+				t.Errorf("Expected %d states, got %d", tt.expected, len(states))
+			}
+		})
+	}
+}
+
+func TestTintImage(t *testing.T) {
+	testImg := createTestImageWithSquare(20, 10, color.RGBA{10, 20, 30, 255})
+	tint := color.RGBA{52, 199, 89, 255}
+
+	tinted := tintImage(testImg, tint)
+
+	if c := color.RGBAModel.Convert(tinted.At(10, 10)).(color.RGBA); c != tint {
+		t.Errorf("Expected silhouette filled with %v, got %v", tint, c)
+	}
+	if _, _, _, a := tinted.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("Expected transparent area to stay transparent, got alpha %#x", a)
+	}
+}
+
+func TestGenerateStates(t *testing.T) {
+	testImg := createTestImageWithSquare(64, 40, color.RGBA{0, 0, 0, 255})
+	inputPath := createTempImageFile(t, testImg)
+	outputDir := t.TempDir()
+
+	config := Config{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		CropEnabled:   false,
+		TrimPercent:   100,
+		RadiusPercent: 0,
+		States:        "ok:#34c759,error:#ff3b30",
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, state := range []string{"ok", "error"} {
+		for _, iconSize := range trayIconSizes {
+			path := filepath.Join(outputDir, stateIconName(iconSize.Name, state))
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to be generated", filepath.Base(path))
+				continue
+			}
+			img, err := loadImage(path)
+			if err != nil {
+				t.Errorf("Failed to load %s: %v", filepath.Base(path), err)
+				continue
+			}
+			if img.Bounds().Dx() != iconSize.Size {
+				t.Errorf("Expected %s to be %dpx, got %dpx", filepath.Base(path), iconSize.Size, img.Bounds().Dx())
+			}
+		}
+	}
+}
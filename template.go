@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// macOS template images are black shapes on transparency that AppKit
+// recolors to match the menu bar or toolbar they sit in; it recognizes them
+// by the Template suffix of their name.
+const templatePreset = "macos-template"
+
+// templateSizes are the outputs of the macos-template preset: the 16, 18
+// and 22pt sizes of toolbar and menu bar items, at 1x and 2x.
+var templateSizes = []IconSize{
+	{"icon_16x16Template.png", 16},
+	{"icon_16x16Template@2x.png", 32},
+	{"icon_18x18Template.png", 18},
+	{"icon_18x18Template@2x.png", 36},
+	{"icon_22x22Template.png", 22},
+	{"icon_22x22Template@2x.png", 44},
+}
+
+// isTemplateImage reports whether iconSize is one of the macos-template
+// preset's images, which are generated on their own rather than as regular
+// icons with variants.
+func isTemplateImage(iconSize IconSize) bool {
+	stem := strings.TrimSuffix(strings.TrimSuffix(iconSize.Name, ".png"), "@2x")
+	return strings.HasSuffix(stem, "Template")
+}
+
+// templateTarget describes the output of the template image iconSize.
+func templateTarget(iconSize IconSize) plannedOutput {
+	return plannedOutput{
+		Name:    iconSize.Name,
+		Width:   iconSize.Size,
+		Height:  iconSize.Size,
+		Format:  "png",
+		Variant: "template",
+	}
+}
+
+// templateSilhouette returns a function turning the white silhouette that
+// silhouette extracts into the black one template images are resized from,
+// once, on first use.
+func templateSilhouette(silhouette func() image.Image) func() image.Image {
+	var black image.Image
+	return func() image.Image {
+		if black == nil {
+			black = tintImage(silhouette(), color.RGBA{0, 0, 0, 255})
+		}
+		return black
+	}
+}
+
+// renderTemplateImage returns the label and renderer of the template image
+// iconSize: the black silhouette, scaled to fill it. Colors, backgrounds,
+// effects, overlays and masks don't apply, as AppKit only keeps the shape.
+func renderTemplateImage(silhouette func() image.Image, config Config, iconSize IconSize) (string, func() image.Image) {
+	label := fmt.Sprintf("%dx%d, template", iconSize.Size, iconSize.Size)
+	return label, func() image.Image {
+		return scaleForeground(silhouette(), iconSize.Size, 100, depthResizer(config))
+	}
+}
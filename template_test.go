@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateImages(t *testing.T) {
+	// A red square on transparency
+	inputPath := createTempImageFile(t, createTestImageWithSquare(200, 120, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "macos," + templatePreset, TrimPercent: 80, RadiusPercent: 20, Background: "#3366CC"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range templateSizes {
+		img, err := loadImage(filepath.Join(outputDir, iconSize.Name))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", iconSize.Name, err)
+		}
+		if img.Bounds().Dx() != iconSize.Size {
+			t.Errorf("%s: expected %dpx, got %dpx", iconSize.Name, iconSize.Size, img.Bounds().Dx())
+		}
+		center := color.NRGBAModel.Convert(img.At(iconSize.Size/2, iconSize.Size/2)).(color.NRGBA)
+		if center != (color.NRGBA{0, 0, 0, 255}) {
+			t.Errorf("%s: expected a black silhouette, got %v", iconSize.Name, center)
+		}
+		if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("%s: expected transparency around the silhouette, without the background", iconSize.Name)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, roundedIconName(iconSize.Name))); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no rounded variant, got %v", iconSize.Name, err)
+		}
+	}
+
+	// The app icons keep their background and rounded variants
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_16x16_rounded.png")); err != nil {
+		t.Errorf("Expected the app icons' rounded variants: %v", err)
+	}
+
+	targets, err := planOutputs(config)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	templates := 0
+	for _, target := range targets {
+		if target.Variant == "template" {
+			templates++
+		}
+	}
+	if templates != len(templateSizes) {
+		t.Errorf("Expected %d template targets, got %d", len(templateSizes), templates)
+	}
+}
+
+func TestIsTemplateImage(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"icon_16x16Template.png", true},
+		{"icon_22x22Template@2x.png", true},
+		{"icon_16x16.png", false},
+		{"icon_16x16@2x.png", false},
+	}
+	for _, tt := range tests {
+		if got := isTemplateImage(IconSize{tt.name, 16}); got != tt.want {
+			t.Errorf("isTemplateImage(%s) = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// The built-in font is a 5x7 pixel font with one column of spacing between
+// glyphs. Glyphs are scaled to any height with exact area coverage, so text
+// stays anti-aliased at every icon size without depending on a font file.
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'0':  {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1':  {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2':  {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3':  {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4':  {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5':  {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6':  {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7':  {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8':  {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9':  {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'A':  {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B':  {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C':  {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D':  {"###..", "#..#.", "#...#", "#...#", "#...#", "#..#.", "###.."},
+	'E':  {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F':  {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G':  {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H':  {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I':  {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J':  {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K':  {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L':  {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N':  {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O':  {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P':  {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q':  {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R':  {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S':  {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V':  {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X':  {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y':  {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z':  {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	' ':  {".....", ".....", ".....", ".....", ".....", ".....", "....."},
+	'.':  {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	',':  {".....", ".....", ".....", ".....", ".##..", "..#..", ".#..."},
+	':':  {".....", ".##..", ".##..", ".....", ".##..", ".##..", "....."},
+	'-':  {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'+':  {".....", "..#..", "..#..", "#####", "..#..", "..#..", "....."},
+	'=':  {".....", ".....", "#####", ".....", "#####", ".....", "....."},
+	'_':  {".....", ".....", ".....", ".....", ".....", ".....", "#####"},
+	'/':  {".....", "....#", "...#.", "..#..", ".#...", "#....", "....."},
+	'!':  {"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#.."},
+	'?':  {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+	'#':  {".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#."},
+	'%':  {"##...", "##..#", "...#.", "..#..", ".#...", "#..##", "...##"},
+	'&':  {".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#"},
+	'@':  {".###.", "#...#", "#.###", "#.#.#", "#.###", "#....", ".###."},
+	'(':  {"...#.", "..#..", ".#...", ".#...", ".#...", "..#..", "...#."},
+	')':  {".#...", "..#..", "...#.", "...#.", "...#.", "..#..", ".#..."},
+	'\'': {"..#..", "..#..", ".#...", ".....", ".....", ".....", "....."},
+}
+
+// supportedText reports whether every character of text has a glyph in the
+// built-in font. Lowercase letters are drawn as uppercase.
+func supportedText(text string) bool {
+	for _, r := range strings.ToUpper(text) {
+		if _, ok := glyphs[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// measureText returns the width in pixels of text drawn at height pixels.
+func measureText(text string, height float64) float64 {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	cell := height / glyphHeight
+	return float64(n*glyphAdvance-1) * cell
+}
+
+// drawText composites text onto dst with its top-left corner at (x, y) and
+// cap height of height pixels. Characters without a glyph are drawn as spaces.
+func drawText(dst *image.RGBA, text string, x, y, height float64, c color.RGBA) {
+	cell := height / glyphHeight
+	if cell <= 0 {
+		return
+	}
+
+	runes := []rune(strings.ToUpper(text))
+	width := measureText(text, height)
+
+	// on reports whether the font cell (col, row) of the whole string is set
+	on := func(col, row int) bool {
+		if row < 0 || row >= glyphHeight || col < 0 {
+			return false
+		}
+		i := col / glyphAdvance
+		gx := col % glyphAdvance
+		if i >= len(runes) || gx >= glyphWidth {
+			return false
+		}
+		glyph, ok := glyphs[runes[i]]
+		return ok && glyph[row][gx] == '#'
+	}
+
+	bounds := dst.Bounds().Intersect(image.Rect(
+		int(math.Floor(x)), int(math.Floor(y)),
+		int(math.Ceil(x+width)), int(math.Ceil(y+height)),
+	))
+
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			// Sum the areas of the font cells overlapping this pixel
+			x0 := (float64(px) - x) / cell
+			y0 := (float64(py) - y) / cell
+			x1 := x0 + 1/cell
+			y1 := y0 + 1/cell
+
+			var coverage float64
+			for row := int(math.Floor(y0)); float64(row) < y1; row++ {
+				overlapY := math.Min(y1, float64(row+1)) - math.Max(y0, float64(row))
+				for col := int(math.Floor(x0)); float64(col) < x1; col++ {
+					if on(col, row) {
+						overlapX := math.Min(x1, float64(col+1)) - math.Max(x0, float64(col))
+						coverage += overlapX * overlapY
+					}
+				}
+			}
+			coverage *= cell * cell
+			if coverage <= 0 {
+				continue
+			}
+
+			blendPixel(dst, px, py, c, math.Min(coverage, 1))
+		}
+	}
+}
+
+// blendPixel composites the premultiplied color c, scaled by coverage, over
+// the pixel at (x, y).
+func blendPixel(dst *image.RGBA, x, y int, c color.RGBA, coverage float64) {
+	under := dst.RGBAAt(x, y)
+	a := float64(c.A) * coverage / 255
+
+	over := func(src, dst uint8) uint8 {
+		return uint8(math.Round(float64(src)*coverage + float64(dst)*(1-a)))
+	}
+	dst.SetRGBA(x, y, color.RGBA{over(c.R, under.R), over(c.G, under.G), over(c.B, under.B), over(c.A, under.A)})
+}
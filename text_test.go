@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGlyphsAreWellFormed(t *testing.T) {
+	for r, glyph := range glyphs {
+		for row, line := range glyph {
+			if len(line) != glyphWidth {
+				t.Errorf("Glyph %q row %d has width %d, expected %d", r, row, len(line), glyphWidth)
+			}
+		}
+	}
+}
+
+func TestSupportedText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"BETA", true},
+		{"v2.3.1", true},
+		{"Hello, World!", true},
+		{"日本", false},
+		{"a~b", false},
+	}
+
+	for _, tt := range tests {
+		if got := supportedText(tt.text); got != tt.expected {
+			t.Errorf("supportedText(%q) = %v, expected %v", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestMeasureText(t *testing.T) {
+	// One glyph is 5 cells wide; each additional glyph adds 6 cells
+	if got := measureText("A", 70); got != 50 {
+		t.Errorf("Expected single glyph width 50, got %v", got)
+	}
+	if got := measureText("AB", 70); got != 110 {
+		t.Errorf("Expected two glyph width 110, got %v", got)
+	}
+	if got := measureText("", 70); got != 0 {
+		t.Errorf("Expected empty width 0, got %v", got)
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	white := color.RGBA{255, 255, 255, 255}
+
+	// "I" at 10px per cell: the stem covers cells x=2, rows 1-5
+	drawText(dst, "I", 5, 0, 70, white)
+
+	if c := dst.RGBAAt(5+25, 35); c != white {
+		t.Errorf("Expected solid stem pixel, got %v", c)
+	}
+	if c := dst.RGBAAt(5+5, 35); c.A != 0 {
+		t.Errorf("Expected empty cell to stay transparent, got %v", c)
+	}
+
+	// Fractional scales produce partial coverage at glyph edges
+	dst = image.NewRGBA(image.Rect(0, 0, 10, 10))
+	drawText(dst, "I", 0.5, 0, 7, white)
+	if c := dst.RGBAAt(2, 3); c.A == 0 || c.A == 255 {
+		t.Errorf("Expected anti-aliased edge pixel, got alpha %d", c.A)
+	}
+}
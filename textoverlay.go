@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// textPositions maps every --text-position to where the text sits between
+// the top and bottom margins, as a fraction of the space left.
+var textPositions = map[string]float64{
+	"top":    0,
+	"center": 0.5,
+	"bottom": 1,
+}
+
+const (
+	// textMargin keeps --text this far from the edges, as a fraction of the
+	// size, clear of rounded corners
+	textMargin = 0.08
+	// textMinHeight is the smallest cap height in pixels --text is drawn at;
+	// smaller icons are left without it rather than smudged
+	textMinHeight = 6
+)
+
+var (
+	textFontsMu sync.Mutex
+	// textFonts holds the --text-font files loaded so far, by path
+	textFonts = map[string]*ttFont{}
+)
+
+// validateText checks the --text options of config that don't depend on the
+// filesystem.
+func validateText(config Config) error {
+	if _, ok := textPositions[config.TextPosition]; !ok {
+		return fmt.Errorf("unknown text position %q (expected top, center or bottom)", config.TextPosition)
+	}
+	if _, err := parseHexColor(config.TextColor); err != nil {
+		return fmt.Errorf("invalid text color: %w", err)
+	}
+	if config.TextFont == "" && !supportedText(config.Text) {
+		return fmt.Errorf("text %q has characters the built-in font can't draw; pass a --text-font", config.Text)
+	}
+	return nil
+}
+
+// validateTextFont checks that the --text-font of config loads and has a
+// glyph for every character of the --text.
+func validateTextFont(config Config) error {
+	font, err := textFont(config.TextFont)
+	if err != nil {
+		return err
+	}
+	if !font.hasGlyphs(config.Text) {
+		return fmt.Errorf("font %s has no glyphs for some characters of %q", config.TextFont, config.Text)
+	}
+	return nil
+}
+
+// textFont returns the font at path, loading it on first use.
+func textFont(path string) (*ttFont, error) {
+	textFontsMu.Lock()
+	defer textFontsMu.Unlock()
+
+	if font, ok := textFonts[path]; ok {
+		return font, nil
+	}
+	font, err := loadFont(path)
+	if err != nil {
+		return nil, err
+	}
+	textFonts[path] = font
+	return font, nil
+}
+
+// textFace returns how to measure and draw the --text of config: in the
+// --text-font, or else the built-in font.
+func textFace(config Config) (func(text string, height float64) float64, func(dst *image.RGBA, text string, x, y, height float64, c color.RGBA), error) {
+	if config.TextFont == "" {
+		return measureText, drawText, nil
+	}
+	font, err := textFont(config.TextFont)
+	if err != nil {
+		return nil, nil, err
+	}
+	return font.measure, font.draw, nil
+}
+
+// textHeight returns the cap height of the --text on an icon of size: 16%
+// of the size unless the text would get wider than the margins allow. It is
+// 0 for icons too small to show the text legibly, which go without it.
+func textHeight(config Config, size int, measure func(text string, height float64) float64) float64 {
+	s := float64(size)
+	height := s * 0.16
+	if width := measure(config.Text, height); width > s*(1-2*textMargin) {
+		height *= s * (1 - 2*textMargin) / width
+	}
+	if height < textMinHeight {
+		return 0
+	}
+	return height
+}
+
+// textSettings describes the --text an icon of size gets, or returns "" if
+// it gets none.
+func textSettings(config Config, size int) string {
+	measure, _, err := textFace(config)
+	if err != nil || config.Text == "" || textHeight(config, size, measure) == 0 {
+		return ""
+	}
+	font := "built-in"
+	if config.TextFont != "" {
+		font = config.TextFont
+	}
+	return fmt.Sprintf("%s,position=%s,color=%s,font=%s", config.Text, config.TextPosition, config.TextColor, font)
+}
+
+// addText stamps the --text of config onto img, centered horizontally at the
+// --text-position, over a soft drop shadow so it stays legible on any
+// artwork.
+func addText(img image.Image, config Config, size int) image.Image {
+	if config.Text == "" {
+		return img
+	}
+	textColor, err := parseHexColor(config.TextColor)
+	if err != nil {
+		return img
+	}
+	position, ok := textPositions[config.TextPosition]
+	if !ok {
+		return img
+	}
+	// validateConfig already checked the font loads
+	measure, draw, err := textFace(config)
+	if err != nil {
+		return img
+	}
+	height := textHeight(config, size, measure)
+	if height == 0 {
+		return img
+	}
+	width := measure(config.Text, height)
+
+	bounds := img.Bounds()
+	margin := float64(size) * textMargin
+	x := (float64(bounds.Dx()) - width) / 2
+	y := margin + (float64(bounds.Dy())-2*margin-height)*position
+	offset := height / 14
+
+	stamped := toRGBA(img)
+	draw(stamped, config.Text, x+offset, y+offset, height, color.RGBA{0, 0, 0, 96})
+	draw(stamped, config.Text, x, y, height, textColor)
+	return stamped
+}
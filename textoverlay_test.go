@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateText(t *testing.T) {
+	fontPath := createTestFontFile(t)
+
+	tests := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"version", Config{Text: "v2.3.1", TextPosition: "bottom", TextColor: "#FFFFFF"}, false},
+		{"unknown position", Config{Text: "v2", TextPosition: "left", TextColor: "#FFFFFF"}, true},
+		{"bad color", Config{Text: "v2", TextPosition: "top", TextColor: "white"}, true},
+		{"unsupported character", Config{Text: "β", TextPosition: "top", TextColor: "#FFFFFF"}, true},
+		{"font", Config{Text: "HI", TextPosition: "center", TextColor: "#FFFFFF", TextFont: fontPath}, false},
+		{"missing glyph", Config{Text: "HIZ", TextPosition: "center", TextColor: "#FFFFFF", TextFont: fontPath}, true},
+		{"missing font", Config{Text: "HI", TextPosition: "center", TextColor: "#FFFFFF", TextFont: filepath.Join(t.TempDir(), "none.ttf")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateText(tt.config)
+			if err == nil && tt.config.TextFont != "" {
+				err = validateTextFont(tt.config)
+			}
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddText(t *testing.T) {
+	icon := createTestImage(200, color.RGBA{0, 0, 255, 255})
+	config := Config{Text: "HI", TextPosition: "bottom", TextColor: "#FF0000", TextFont: createTestFontFile(t)}
+
+	// The cap height is 32 pixels, ending at the bottom margin of 16
+	stamped := addText(icon, config, 200)
+	if c := color.RGBAModel.Convert(stamped.At(90, 170)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the text near the bottom, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(stamped.At(90, 30)).(color.RGBA); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("Expected the top untouched, got %v", c)
+	}
+
+	config.TextPosition = "top"
+	stamped = addText(icon, config, 200)
+	if c := color.RGBAModel.Convert(stamped.At(90, 30)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected the text near the top, got %v", c)
+	}
+
+	// Icons too small for legible text go without it
+	small := createTestImage(32, color.RGBA{0, 0, 255, 255})
+	if stamped := addText(small, config, 32); stamped != small {
+		t.Errorf("Expected no text on a 32 pixel icon")
+	}
+	if textSettings(config, 32) != "" || textSettings(config, 200) == "" {
+		t.Errorf("Expected the plan to record the text only where it is drawn")
+	}
+
+	// Long text shrinks to fit between the margins
+	config = Config{Text: "1.0.0-RC1", TextPosition: "center", TextColor: "#FFFFFF"}
+	if height := textHeight(config, 200, measureText); measureText(config.Text, height) > 200*(1-2*textMargin)+0.001 {
+		t.Errorf("Expected the text to fit between the margins")
+	}
+}
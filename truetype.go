@@ -0,0 +1,594 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"sort"
+)
+
+// ttFont is a TrueType font read with just enough of the format to draw
+// text: the character map, the horizontal metrics and the quadratic glyph
+// outlines. OpenType fonts with CFF outlines aren't supported.
+type ttFont struct {
+	unitsPerEm float64
+	// capHeight is the height of capital letters in font units
+	capHeight  float64
+	numGlyphs  int
+	longLoca   bool
+	numMetrics int
+	tables     map[string][]byte
+	cmap       func(r rune) int
+}
+
+// ttPoint is a point of a glyph outline in font units, y up.
+type ttPoint struct {
+	x, y    float64
+	onCurve bool
+}
+
+var errBadFont = errors.New("malformed TrueType font")
+
+// loadFont reads the TrueType font at path.
+func loadFont(path string) (*ttFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font %s: %w", path, err)
+	}
+	font, err := parseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font %s: %w", path, err)
+	}
+	return font, nil
+}
+
+// parseFont reads a TrueType font from data.
+func parseFont(data []byte) (*ttFont, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000, 0x74727565: // 1.0 and "true"
+	case 0x4f54544f: // "OTTO"
+		return nil, errors.New("OpenType fonts with CFF outlines aren't supported, use a TrueType font")
+	default:
+		return nil, errBadFont
+	}
+
+	f := &ttFont{tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errBadFont
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, errBadFont
+		}
+		f.tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "loca", "glyf"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("font has no %s table", tag)
+		}
+	}
+
+	head, hhea, maxp := f.tables["head"], f.tables["hhea"], f.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errBadFont
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) != 0
+	f.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	f.numMetrics = int(binary.BigEndian.Uint16(hhea[34:]))
+	if f.numMetrics == 0 || len(f.tables["hmtx"]) < 4*f.numMetrics {
+		return nil, errBadFont
+	}
+
+	cmap, err := parseCmap(f.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.cmap = cmap
+
+	// The cap height is the top of the H, or else 70% of the em
+	f.capHeight = 0.7 * f.unitsPerEm
+	if g := f.cmap('H'); g != 0 {
+		if glyph := f.glyphData(g); len(glyph) >= 10 {
+			if top := float64(int16(binary.BigEndian.Uint16(glyph[8:]))); top > 0 {
+				f.capHeight = top
+			}
+		}
+	}
+	return f, nil
+}
+
+// parseCmap returns the Unicode character map of a cmap table, from its
+// format 12 (full Unicode) or format 4 (BMP) subtable.
+func parseCmap(cmap []byte) (func(r rune) int, error) {
+	if len(cmap) < 4 {
+		return nil, errBadFont
+	}
+	var format4, format12 []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			return nil, errBadFont
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) || !(platform == 0 || platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	if format12 != nil && len(format12) >= 16 {
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if 16+12*groups > len(format12) {
+			return nil, errBadFont
+		}
+		return func(r rune) int {
+			for i := 0; i < groups; i++ {
+				group := format12[16+12*i:]
+				start, end := rune(binary.BigEndian.Uint32(group)), rune(binary.BigEndian.Uint32(group[4:]))
+				if r >= start && r <= end {
+					return int(binary.BigEndian.Uint32(group[8:])) + int(r-start)
+				}
+			}
+			return 0
+		}, nil
+	}
+
+	if format4 != nil && len(format4) >= 14 {
+		segments := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		if 16+8*segments > len(format4) {
+			return nil, errBadFont
+		}
+		ends := format4[14:]
+		starts := format4[16+2*segments:]
+		deltas := format4[16+4*segments:]
+		rangeOffsets := format4[16+6*segments:]
+		return func(r rune) int {
+			if r > 0xFFFF {
+				return 0
+			}
+			c := uint16(r)
+			for i := 0; i < segments; i++ {
+				if c > binary.BigEndian.Uint16(ends[2*i:]) {
+					continue
+				}
+				start := binary.BigEndian.Uint16(starts[2*i:])
+				if c < start {
+					return 0
+				}
+				delta := binary.BigEndian.Uint16(deltas[2*i:])
+				rangeOffset := int(binary.BigEndian.Uint16(rangeOffsets[2*i:]))
+				if rangeOffset == 0 {
+					return int(c + delta)
+				}
+				// The offset is relative to the idRangeOffset entry itself
+				at := 2*i + rangeOffset + 2*int(c-start)
+				if at+2 > len(rangeOffsets) {
+					return 0
+				}
+				if g := binary.BigEndian.Uint16(rangeOffsets[at:]); g != 0 {
+					return int(g + delta)
+				}
+				return 0
+			}
+			return 0
+		}, nil
+	}
+
+	return nil, errors.New("font has no Unicode character map")
+}
+
+// hasGlyphs reports whether the font has a glyph for every character of
+// text other than spaces.
+func (f *ttFont) hasGlyphs(text string) bool {
+	for _, r := range text {
+		if r != ' ' && f.cmap(r) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// advance returns the advance width of glyph g in font units.
+func (f *ttFont) advance(g int) float64 {
+	if g >= f.numMetrics {
+		g = f.numMetrics - 1
+	}
+	return float64(binary.BigEndian.Uint16(f.tables["hmtx"][4*g:]))
+}
+
+// glyphData returns the glyf entry of glyph g, empty for blank glyphs.
+func (f *ttFont) glyphData(g int) []byte {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	if g < 0 || g >= f.numGlyphs {
+		return nil
+	}
+	var start, end int
+	if f.longLoca {
+		if 4*g+8 > len(loca) {
+			return nil
+		}
+		start, end = int(binary.BigEndian.Uint32(loca[4*g:])), int(binary.BigEndian.Uint32(loca[4*g+4:]))
+	} else {
+		if 2*g+4 > len(loca) {
+			return nil
+		}
+		start, end = 2*int(binary.BigEndian.Uint16(loca[2*g:])), 2*int(binary.BigEndian.Uint16(loca[2*g+2:]))
+	}
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// contours returns the outline of glyph g as closed contours, following
+// composite glyphs up to a few levels deep.
+func (f *ttFont) contours(g, depth int) [][]ttPoint {
+	data := f.glyphData(g)
+	if len(data) < 10 || depth > 8 {
+		return nil
+	}
+	numContours := int(int16(binary.BigEndian.Uint16(data)))
+	if numContours >= 0 {
+		return simpleContours(data, numContours)
+	}
+
+	// A composite glyph places other glyphs with an affine transform
+	const (
+		argsAreWords  = 0x0001
+		argsAreXY     = 0x0002
+		haveScale     = 0x0008
+		moreComponent = 0x0020
+		haveXYScale   = 0x0040
+		haveTwoByTwo  = 0x0080
+	)
+	var result [][]ttPoint
+	p := 10
+	for {
+		if p+4 > len(data) {
+			return result
+		}
+		flags := binary.BigEndian.Uint16(data[p:])
+		component := int(binary.BigEndian.Uint16(data[p+2:]))
+		p += 4
+
+		var dx, dy float64
+		if flags&argsAreWords != 0 {
+			if p+4 > len(data) {
+				return result
+			}
+			dx, dy = float64(int16(binary.BigEndian.Uint16(data[p:]))), float64(int16(binary.BigEndian.Uint16(data[p+2:])))
+			p += 4
+		} else {
+			if p+2 > len(data) {
+				return result
+			}
+			dx, dy = float64(int8(data[p])), float64(int8(data[p+1]))
+			p += 2
+		}
+		if flags&argsAreXY == 0 {
+			// Point matching offsets are rare; place the component as is
+			dx, dy = 0, 0
+		}
+
+		f2dot14 := func(at int) float64 {
+			return float64(int16(binary.BigEndian.Uint16(data[at:]))) / 16384
+		}
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		switch {
+		case flags&haveScale != 0 && p+2 <= len(data):
+			a = f2dot14(p)
+			d = a
+			p += 2
+		case flags&haveXYScale != 0 && p+4 <= len(data):
+			a, d = f2dot14(p), f2dot14(p+2)
+			p += 4
+		case flags&haveTwoByTwo != 0 && p+8 <= len(data):
+			a, b, c, d = f2dot14(p), f2dot14(p+2), f2dot14(p+4), f2dot14(p+6)
+			p += 8
+		}
+
+		for _, contour := range f.contours(component, depth+1) {
+			placed := make([]ttPoint, len(contour))
+			for i, pt := range contour {
+				placed[i] = ttPoint{a*pt.x + c*pt.y + dx, b*pt.x + d*pt.y + dy, pt.onCurve}
+			}
+			result = append(result, placed)
+		}
+		if flags&moreComponent == 0 {
+			return result
+		}
+	}
+}
+
+// simpleContours decodes the points of a simple glyph.
+func simpleContours(data []byte, numContours int) [][]ttPoint {
+	p := 10
+	if p+2*numContours+2 > len(data) {
+		return nil
+	}
+	ends := make([]int, numContours)
+	for i := range ends {
+		ends[i] = int(binary.BigEndian.Uint16(data[p+2*i:]))
+	}
+	p += 2 * numContours
+	if numContours == 0 {
+		return nil
+	}
+	numPoints := ends[numContours-1] + 1
+	p += 2 + int(binary.BigEndian.Uint16(data[p:])) // skip the instructions
+
+	const (
+		onCurve  = 0x01
+		xShort   = 0x02
+		yShort   = 0x04
+		repeat   = 0x08
+		xSameOrP = 0x10
+		ySameOrP = 0x20
+	)
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if p >= len(data) {
+			return nil
+		}
+		flag := data[p]
+		p++
+		flags = append(flags, flag)
+		if flag&repeat != 0 {
+			if p >= len(data) {
+				return nil
+			}
+			for n := int(data[p]); n > 0 && len(flags) < numPoints; n-- {
+				flags = append(flags, flag)
+			}
+			p++
+		}
+	}
+
+	coordinates := func(short, sameOrPositive byte) []float64 {
+		values := make([]float64, numPoints)
+		v := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if p >= len(data) {
+					return nil
+				}
+				if flag&sameOrPositive != 0 {
+					v += int(data[p])
+				} else {
+					v -= int(data[p])
+				}
+				p++
+			case flag&sameOrPositive == 0:
+				if p+2 > len(data) {
+					return nil
+				}
+				v += int(int16(binary.BigEndian.Uint16(data[p:])))
+				p += 2
+			}
+			values[i] = float64(v)
+		}
+		return values
+	}
+	xs := coordinates(xShort, xSameOrP)
+	ys := coordinates(yShort, ySameOrP)
+	if xs == nil || ys == nil {
+		return nil
+	}
+
+	contours := make([][]ttPoint, 0, numContours)
+	start := 0
+	for _, end := range ends {
+		if end < start || end >= numPoints {
+			return nil
+		}
+		contour := make([]ttPoint, 0, end-start+1)
+		for i := start; i <= end; i++ {
+			contour = append(contour, ttPoint{xs[i], ys[i], flags[i]&onCurve != 0})
+		}
+		contours = append(contours, contour)
+		start = end + 1
+	}
+	return contours
+}
+
+// measure returns the width in pixels of text drawn with a cap height of
+// height pixels.
+func (f *ttFont) measure(text string, height float64) float64 {
+	scale := height / f.capHeight
+	var width float64
+	for _, r := range text {
+		width += f.advance(f.cmap(r)) * scale
+	}
+	return width
+}
+
+// draw composites text onto dst with its top-left corner, at the top of the
+// capitals, at (x, y) and a cap height of height pixels. Descenders reach
+// below y+height.
+func (f *ttFont) draw(dst *image.RGBA, text string, x, y, height float64, c color.RGBA) {
+	scale := height / f.capHeight
+	baseline := y + height
+
+	var edges [][4]float64
+	pen := x
+	for _, r := range text {
+		g := f.cmap(r)
+		for _, contour := range f.contours(g, 0) {
+			points := flattenContour(contour)
+			for i := range points {
+				a, b := points[i], points[(i+1)%len(points)]
+				edges = append(edges, [4]float64{
+					pen + a[0]*scale, baseline - a[1]*scale,
+					pen + b[0]*scale, baseline - b[1]*scale,
+				})
+			}
+		}
+		pen += f.advance(g) * scale
+	}
+	fillEdges(dst, edges, c)
+}
+
+// flattenContour turns a contour of on- and off-curve points into a polygon,
+// approximating each quadratic curve with line segments.
+func flattenContour(contour []ttPoint) [][2]float64 {
+	n := len(contour)
+	if n == 0 {
+		return nil
+	}
+
+	// Start on an on-curve point, inventing the midpoint of two off-curve
+	// points if there is none
+	first := -1
+	for i, pt := range contour {
+		if pt.onCurve {
+			first = i
+			break
+		}
+	}
+	var start ttPoint
+	var rest []ttPoint
+	if first < 0 {
+		a, b := contour[0], contour[1%n]
+		start = ttPoint{(a.x + b.x) / 2, (a.y + b.y) / 2, true}
+		rest = append(append(rest, contour[1:]...), contour[0])
+	} else {
+		start = contour[first]
+		rest = append(append(rest, contour[first+1:]...), contour[:first]...)
+	}
+
+	points := [][2]float64{{start.x, start.y}}
+	current := start
+	var control *ttPoint
+	for _, pt := range rest {
+		if pt.onCurve {
+			if control != nil {
+				points = appendQuad(points, current, *control, pt)
+				control = nil
+			} else {
+				points = append(points, [2]float64{pt.x, pt.y})
+			}
+			current = pt
+			continue
+		}
+		if control != nil {
+			mid := ttPoint{(control.x + pt.x) / 2, (control.y + pt.y) / 2, true}
+			points = appendQuad(points, current, *control, mid)
+			current = mid
+		}
+		pt := pt
+		control = &pt
+	}
+	if control != nil {
+		points = appendQuad(points, current, *control, start)
+	}
+	return points
+}
+
+// appendQuad appends the quadratic curve from a through control b to c,
+// excluding a, as line segments.
+func appendQuad(points [][2]float64, a, b, c ttPoint) [][2]float64 {
+	const steps = 8
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / steps
+		u := 1 - t
+		points = append(points, [2]float64{
+			u*u*a.x + 2*u*t*b.x + t*t*c.x,
+			u*u*a.y + 2*u*t*b.y + t*t*c.y,
+		})
+	}
+	return points
+}
+
+// fillEdges fills the polygon made of edges (x0, y0, x1, y1) in pixel
+// coordinates with c, using the nonzero winding rule. Coverage is sampled on
+// 16 scanlines per pixel row and exactly along each scanline.
+func fillEdges(dst *image.RGBA, edges [][4]float64, c color.RGBA) {
+	if len(edges) == 0 {
+		return
+	}
+	const subsamples = 16
+
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, e := range edges {
+		minY = math.Min(minY, math.Min(e[1], e[3]))
+		maxY = math.Max(maxY, math.Max(e[1], e[3]))
+	}
+	bounds := dst.Bounds()
+	top := int(math.Max(math.Floor(minY), float64(bounds.Min.Y)))
+	bottom := int(math.Min(math.Ceil(maxY), float64(bounds.Max.Y)))
+
+	type crossing struct {
+		x       float64
+		winding int
+	}
+	coverage := make([]float64, bounds.Dx())
+	for py := top; py < bottom; py++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+		for s := 0; s < subsamples; s++ {
+			sy := float64(py) + (float64(s)+0.5)/subsamples
+
+			var crossings []crossing
+			for _, e := range edges {
+				x0, y0, x1, y1 := e[0], e[1], e[2], e[3]
+				winding := 1
+				if y0 > y1 {
+					x0, y0, x1, y1 = x1, y1, x0, y0
+					winding = -1
+				}
+				if sy < y0 || sy >= y1 {
+					continue
+				}
+				crossings = append(crossings, crossing{x0 + (sy-y0)*(x1-x0)/(y1-y0), winding})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i, cr := range crossings {
+				winding += cr.winding
+				if winding == 0 || i+1 == len(crossings) {
+					continue
+				}
+				// Inside between this crossing and the next
+				x0 := math.Max(cr.x, float64(bounds.Min.X))
+				x1 := math.Min(crossings[i+1].x, float64(bounds.Max.X))
+				for x0 < x1 {
+					px := math.Floor(x0)
+					end := math.Min(x1, px+1)
+					coverage[int(px)-bounds.Min.X] += (end - x0) / subsamples
+					x0 = end
+				}
+			}
+		}
+		for i, v := range coverage {
+			if v > 0 {
+				blendPixel(dst, bounds.Min.X+i, py, c, math.Min(v, 1))
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTestFont returns a TrueType font with a single glyph, a square of
+// 500x700 units on a 1000 unit em with an advance of 600, mapped to 'H' and
+// 'I'. 'I' is a composite of the square shifted right by 100 units.
+func buildTestFont() []byte {
+	be := binary.BigEndian
+	u16 := func(b *bytes.Buffer, v ...int) {
+		for _, x := range v {
+			binary.Write(b, be, uint16(x))
+		}
+	}
+
+	var square bytes.Buffer
+	u16(&square, 1, 0, 0, 500, 700) // one contour and its bounds
+	u16(&square, 3, 0)              // last point, no instructions
+	square.Write([]byte{0x01, 0x01, 0x01, 0x01})
+	u16(&square, 0, 500, 0, -500) // x deltas
+	u16(&square, 0, 0, 700, 0)    // y deltas
+	var composite bytes.Buffer
+	u16(&composite, 0xFFFF, 100, 0, 600, 700)
+	u16(&composite, 0x0003, 1, 100, 0) // word args as x/y offsets, glyph 1
+
+	var glyf, loca bytes.Buffer
+	u16(&loca, 0, 0)
+	glyf.Write(square.Bytes())
+	u16(&loca, glyf.Len()/2)
+	glyf.Write(composite.Bytes())
+	u16(&loca, glyf.Len()/2)
+
+	head := make([]byte, 54)
+	be.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	be.PutUint16(hhea[34:], 3)
+	maxp := make([]byte, 6)
+	be.PutUint16(maxp[4:], 3)
+	var hmtx bytes.Buffer
+	u16(&hmtx, 0, 0, 600, 0, 600, 0)
+
+	var cmap bytes.Buffer
+	u16(&cmap, 0, 1, 3, 1, 0, 12) // one Windows Unicode subtable at 12
+	// Format 4 with segments 'H'-'I' and the final 0xFFFF
+	u16(&cmap, 4, 32, 0, 4, 4, 1, 0)
+	u16(&cmap, 'I', 0xFFFF, 0, 'H', 0xFFFF)
+	u16(&cmap, 1-'H', 1, 0, 0)
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{
+		{"cmap", cmap.Bytes()}, {"glyf", glyf.Bytes()}, {"head", head}, {"hhea", hhea},
+		{"hmtx", hmtx.Bytes()}, {"loca", loca.Bytes()}, {"maxp", maxp},
+	}
+	var font bytes.Buffer
+	binary.Write(&font, be, uint32(0x00010000))
+	u16(&font, len(tables), 0, 0, 0)
+	offset := 12 + 16*len(tables)
+	for _, t := range tables {
+		font.WriteString(t.tag)
+		binary.Write(&font, be, uint32(0))
+		binary.Write(&font, be, uint32(offset))
+		binary.Write(&font, be, uint32(len(t.data)))
+		offset += len(t.data)
+	}
+	for _, t := range tables {
+		font.Write(t.data)
+	}
+	return font.Bytes()
+}
+
+func createTestFontFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.ttf")
+	if err := os.WriteFile(path, buildTestFont(), 0644); err != nil {
+		t.Fatalf("Failed to write test font: %v", err)
+	}
+	return path
+}
+
+func TestParseFont(t *testing.T) {
+	font, err := parseFont(buildTestFont())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if g := font.cmap('H'); g != 1 {
+		t.Errorf("Expected 'H' to map to glyph 1, got %d", g)
+	}
+	if g := font.cmap('Z'); g != 0 {
+		t.Errorf("Expected 'Z' to be missing, got glyph %d", g)
+	}
+	if font.capHeight != 700 {
+		t.Errorf("Expected a cap height of 700 from the H, got %v", font.capHeight)
+	}
+	if !font.hasGlyphs("HI H") || font.hasGlyphs("HZ") {
+		t.Errorf("Expected hasGlyphs to check every character but spaces")
+	}
+	if width := font.measure("HI", 70); width != 120 {
+		t.Errorf("Expected two advances of 60 pixels, got %v", width)
+	}
+
+	for _, data := range [][]byte{nil, []byte("OTTO\x00\x00\x00\x00\x00\x00\x00\x00"), buildTestFont()[:40]} {
+		if _, err := parseFont(data); err == nil {
+			t.Errorf("Expected error for %d bytes of broken font", len(data))
+		}
+	}
+}
+
+func TestFontDraw(t *testing.T) {
+	font, err := parseFont(buildTestFont())
+	if err != nil {
+		t.Fatalf("Failed to parse font: %v", err)
+	}
+
+	// At a cap height of 70 pixels the square is 50x70, the composite copy
+	// 10 pixels further into its advance
+	dst := image.NewRGBA(image.Rect(0, 0, 140, 100))
+	font.draw(dst, "HI", 10, 10, 70, color.RGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{35, 45, 255}, // inside the H
+		{65, 45, 0},   // between the glyphs
+		{95, 45, 255}, // inside the I
+		{135, 45, 0},  // past the I
+		{35, 5, 0},    // above the capitals
+		{35, 79, 255}, // just above the baseline
+		{35, 80, 0},   // below the baseline
+	}
+	for _, tt := range tests {
+		if a := dst.RGBAAt(tt.x, tt.y).A; a != tt.alpha {
+			t.Errorf("Expected alpha %d at (%d, %d), got %d", tt.alpha, tt.x, tt.y, a)
+		}
+	}
+
+	// Edges on half pixels are half covered
+	dst = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	font.draw(dst, "H", 0.5, 0, 7, color.RGBA{255, 255, 255, 255})
+	if a := dst.RGBAAt(0, 3).A; a < 120 || a > 135 {
+		t.Errorf("Expected a half covered edge pixel, got alpha %d", a)
+	}
+}
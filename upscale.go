@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// upscaleFlag is --no-upscale: on its own it skips the outputs larger than
+// the source, and --no-upscale=error fails the run instead.
+type upscaleFlag struct {
+	p *string
+}
+
+func (u upscaleFlag) String() string {
+	if u.p == nil {
+		return ""
+	}
+	return *u.p
+}
+
+func (u upscaleFlag) Set(s string) error {
+	switch s {
+	case "skip", "error":
+		*u.p = s
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("expected skip or error")
+	}
+	*u.p = ""
+	if v {
+		*u.p = "skip"
+	}
+	return nil
+}
+
+func (u upscaleFlag) IsBoolFlag() bool { return true }
+
+// sourceDimensions returns the size of the source image from its header, as
+// cropped by --trim-percent.
+func sourceDimensions(config Config) (int, int, error) {
+	width, height, err := imageDimensions(config.InputPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if config.CropEnabled {
+		width = width * config.TrimPercent / 100
+		height = height * config.TrimPercent / 100
+	}
+	return width, height, nil
+}
+
+// sourceResolution returns the largest icon size a width x height source
+// fills without upscaling: its longer side, or its shorter one with
+// --fit=cover, which only keeps the square of it.
+func sourceResolution(config Config, width, height int) int {
+	longest := width
+	if height > longest {
+		longest = height
+	}
+	if fitMode(config) == "cover" {
+		return width + height - longest
+	}
+	return longest
+}
+
+// withoutUpscaled leaves the sizes larger than the source out of sizes for
+// --no-upscale. Without a readable source every size is kept.
+func withoutUpscaled(config Config, sizes []IconSize) []IconSize {
+	width, height, err := sourceDimensions(config)
+	if err != nil {
+		return sizes
+	}
+	resolution := sourceResolution(config, width, height)
+
+	var kept []IconSize
+	for _, iconSize := range sizes {
+		if iconSize.Size <= resolution {
+			kept = append(kept, iconSize)
+		}
+	}
+	return kept
+}
+
+// checkUpscale returns an error for --no-upscale=error when an output is
+// larger than the source, naming the largest one.
+func checkUpscale(config Config) error {
+	if config.NoUpscale != "error" {
+		return nil
+	}
+	width, height, err := sourceDimensions(config)
+	if err != nil {
+		return nil
+	}
+
+	resolution := sourceResolution(config, width, height)
+	var largest IconSize
+	for _, iconSize := range presetSizes(config) {
+		if iconSize.Size > resolution && iconSize.Size > largest.Size {
+			largest = iconSize
+		}
+	}
+	if largest.Size == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s needs a %dpx source, but the source is %dx%d after cropping (--no-upscale=error); use a larger source or --no-upscale to skip the sizes it can't fill",
+		largest.Name, largest.Size, width, height)
+}
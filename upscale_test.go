@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoUpscaleFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		expect  string
+		wantErr bool
+	}{
+		{nil, "", false},
+		{[]string{"--no-upscale"}, "skip", false},
+		{[]string{"--no-upscale=skip"}, "skip", false},
+		{[]string{"--no-upscale=error"}, "error", false},
+		{[]string{"--no-upscale=false"}, "", false},
+		{[]string{"--no-upscale=blur"}, "", true},
+	}
+
+	for _, tt := range tests {
+		var config Config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		defineFlags(fs, &config)
+
+		err := fs.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: expected error %v, got %v", tt.args, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && config.NoUpscale != tt.expect {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.expect, config.NoUpscale)
+		}
+	}
+}
+
+func TestNoUpscaleSkip(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(300, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "macos", TrimPercent: 80, CropEnabled: true, NoUpscale: "skip"}
+	for _, iconSize := range outputSizes(config) {
+		if iconSize.Size > 240 {
+			t.Errorf("Expected no size above the 240px cropped source, got %s", iconSize.Name)
+		}
+	}
+
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_128x128.png")); err != nil {
+		t.Errorf("Expected icon_128x128.png: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "icon_1024x1024.png")); !os.IsNotExist(err) {
+		t.Errorf("Expected icon_1024x1024.png to be skipped, got %v", err)
+	}
+
+	codes := map[string]int{}
+	for _, w := range runWarnings(config) {
+		codes[w.Code]++
+	}
+	if codes["W006"] != 6 || codes["W001"] != 0 {
+		t.Errorf("Expected six W006 and no W001 warnings, got %v", codes)
+	}
+}
+
+func TestNoUpscaleError(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(300, color.RGBA{255, 128, 0, 255}))
+
+	config := Config{InputPath: inputPath, Preset: "macos", TrimPercent: 80, CropEnabled: true, NoUpscale: "error"}
+	err := validateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "1024px") || !strings.Contains(err.Error(), "240x240") {
+		t.Errorf("Expected the largest size and the source size in the error, got %v", err)
+	}
+
+	config.Preset = "android"
+	if err := validateConfig(config); err != nil {
+		t.Errorf("Expected a 240px source to fill the android preset, got %v", err)
+	}
+
+	// --fit=cover only keeps the square of the shorter side
+	wide := image.NewRGBA(image.Rect(0, 0, 300, 150))
+	config = Config{InputPath: createTempImageFile(t, wide), Preset: "android", TrimPercent: 80, NoUpscale: "error"}
+	if err := checkUpscale(config); err != nil {
+		t.Errorf("Expected a 300x150 source to fill the android preset, got %v", err)
+	}
+	config.FitMode = "cover"
+	if err := checkUpscale(config); err == nil {
+		t.Errorf("Expected a 150px square to be too small for the android preset with --fit=cover")
+	}
+}
+
+func TestNoUpscaleSnippets(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(200, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+
+	// The 160px cropped source covers the favicons only
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "web", TrimPercent: 80, CropEnabled: true, NoUpscale: "skip"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	head, _ := os.ReadFile(filepath.Join(outputDir, webHeadName))
+	if strings.Contains(string(head), "apple-touch-icon") || !strings.Contains(string(head), "favicon-32x32.png") {
+		t.Errorf("Expected the head snippet to reference only the generated icons, got:\n%s", head)
+	}
+	manifest, _ := os.ReadFile(filepath.Join(outputDir, webManifestName))
+	if !strings.Contains(string(manifest), `"icons": []`) {
+		t.Errorf("Expected the web manifest to list no icons, got:\n%s", manifest)
+	}
+
+	config.Preset = "macos"
+	for _, image := range xcodeImages(config) {
+		if image.Size == "1024x1024" && image.Filename != "" {
+			t.Errorf("Expected the skipped 1024px slot to have no file, got %s", image.Filename)
+		}
+		if image.Size == "16x16" && image.Filename == "" {
+			t.Errorf("Expected the 16px slot to keep its file")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// colorVariants are the recolored copies --variant writes of every icon,
+// each saved as icon_<size>_<name>.png.
+var colorVariants = map[string]func(img image.Image) image.Image{
+	// Luminance with alpha kept, for disabled states and monochrome contexts
+	"grayscale": grayscaleImage,
+	// A negative, for dark UI chrome and print
+	"invert": invertImage,
+}
+
+// parseColorVariants parses a comma-separated --variant spec such as
+// "grayscale".
+func parseColorVariants(spec string) ([]string, error) {
+	var known []string
+	for name := range colorVariants {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		if _, ok := colorVariants[name]; !ok {
+			return nil, fmt.Errorf("unknown variant %q (expected %s)", name, strings.Join(known, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate variant %q", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// colorVariantTargets lists the outputs the --variant copies of config
+// produce.
+func colorVariantTargets(config Config) []plannedOutput {
+	if config.Variant == "" {
+		return nil
+	}
+	names, err := parseColorVariants(config.Variant)
+	if err != nil {
+		return nil
+	}
+
+	var targets []plannedOutput
+	for _, name := range names {
+		for _, iconSize := range outputSizes(config) {
+			target := iconTarget(config, iconSize, variantIconName(iconSize.Name, name), name)
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// generateColorVariants writes a recolored copy of every regular icon per
+// --variant.
+func generateColorVariants(sourceImg image.Image, config Config, pattern backgroundPattern, backgroundImg image.Image, layers []stackLayer, state *manifestState) error {
+	names, err := parseColorVariants(config.Variant)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		recolor := colorVariants[name]
+		for _, iconSize := range outputSizes(config) {
+			iconSize := iconSize
+			label := fmt.Sprintf("%dx%d, %s", iconSize.Size, iconSize.Size, name)
+
+			err := saveOutput(config, state, variantIconName(iconSize.Name, name), label, func() image.Image {
+				return recolor(finishIcon(prepareIcon(sourceImg, config, pattern, backgroundImg, layers, iconSize.Size), config, iconSize, nil))
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// grayscaleImage returns the luminance of img, keeping its alpha channel.
+func grayscaleImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	gray := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			y8 := luma(c)
+			gray.SetNRGBA(x, y, color.NRGBA{y8, y8, y8, c.A})
+		}
+	}
+
+	return gray
+}
+
+// luma returns the Rec. 601 luma of c, as image/color's gray model uses.
+func luma(c color.NRGBA) uint8 {
+	return uint8((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+}
+
+// invertImage returns the negative of img, inverting its color channels and
+// keeping its alpha.
+func invertImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	inverted := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			inverted.SetNRGBA(x, y, color.NRGBA{255 - c.R, 255 - c.G, 255 - c.B, c.A})
+		}
+	}
+
+	return inverted
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseColorVariants(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expectErr bool
+	}{
+		{"grayscale", "grayscale", false},
+		{"both", "grayscale,invert", false},
+		{"unknown", "sepia", true},
+		{"duplicate", "grayscale,grayscale", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseColorVariants(tt.spec)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestGrayscaleImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 128})
+
+	gray := grayscaleImage(img)
+
+	tests := []struct {
+		x      int
+		expect color.NRGBA
+	}{
+		{0, color.NRGBA{76, 76, 76, 255}},
+		{1, color.NRGBA{255, 255, 255, 128}},
+	}
+	for _, tt := range tests {
+		got := color.NRGBAModel.Convert(gray.At(tt.x, 0)).(color.NRGBA)
+		if got != tt.expect {
+			t.Errorf("Expected %v at x=%d, got %v", tt.expect, tt.x, got)
+		}
+	}
+}
+
+func TestGenerateGrayscaleVariant(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImageWithSquare(64, 32, color.RGBA{255, 0, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, Variant: "grayscale"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	for _, iconSize := range iconSizes {
+		name := variantIconName(iconSize.Name, "grayscale")
+		img, err := loadImage(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		if img.Bounds().Dx() != iconSize.Size {
+			t.Errorf("Expected %s to be %dpx, got %dpx", name, iconSize.Size, img.Bounds().Dx())
+		}
+
+		center := color.NRGBAModel.Convert(img.At(iconSize.Size/2, iconSize.Size/2)).(color.NRGBA)
+		if center != (color.NRGBA{76, 76, 76, 255}) {
+			t.Errorf("Expected the red square to turn gray in %s, got %v", name, center)
+		}
+		if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("Expected a transparent corner in %s, got alpha %d", name, a>>8)
+		}
+	}
+}
+
+func TestInvertImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 200, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 0, 0, 64})
+
+	inverted := invertImage(img)
+
+	tests := []struct {
+		x      int
+		expect color.NRGBA
+	}{
+		{0, color.NRGBA{0, 55, 255, 255}},
+		{1, color.NRGBA{255, 255, 255, 64}},
+	}
+	for _, tt := range tests {
+		got := color.NRGBAModel.Convert(inverted.At(tt.x, 0)).(color.NRGBA)
+		if got != tt.expect {
+			t.Errorf("Expected %v at x=%d, got %v", tt.expect, tt.x, got)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// warningCodes are the stable codes of the warnings a run can emit. Codes are
+// never reused, so suppressions in configuration files keep their meaning.
+var warningCodes = map[string]string{
+	"W001": "an output is larger than the source and gets upscaled",
+	"W002": "the source isn't square and gets letterboxed",
+	"W003": "an output was skipped because this build can't produce it",
+	"W004": "an output has too few opaque pixels to carry the --watermark",
+	"W005": "an optional effect was skipped to stay within the --budget",
+	"W006": "an output larger than the source was skipped for --no-upscale",
+	"W007": "a --bit-depth=16 output went through an option that works in 8 bits",
+	"W008": "the marketing icon's transparency was discarded by --flatten-marketing",
+}
+
+// warning is one issue found in a run. Target is the output it concerns, or
+// empty for the run as a whole.
+type warning struct {
+	Code    string
+	Target  string
+	Message string
+}
+
+// suppression silences the warnings with Code, only for the targets whose
+// names match the Target glob unless that is empty.
+type suppression struct {
+	Code   string
+	Target string
+}
+
+// parseSuppressions parses a comma-separated --suppress spec of codes, each
+// optionally limited to targets matching a glob, e.g. "W002,W001:icon_1024*".
+func parseSuppressions(spec string) ([]suppression, error) {
+	var suppressions []suppression
+	for _, entry := range strings.Split(spec, ",") {
+		code, target, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if _, ok := warningCodes[code]; !ok {
+			return nil, fmt.Errorf("unknown warning code %q in --suppress", code)
+		}
+		if _, err := path.Match(target, ""); err != nil {
+			return nil, fmt.Errorf("invalid target pattern %q in --suppress: %w", target, err)
+		}
+		suppressions = append(suppressions, suppression{Code: code, Target: target})
+	}
+	return suppressions, nil
+}
+
+// suppressedBy reports whether any of suppressions silences w.
+func (w warning) suppressedBy(suppressions []suppression) bool {
+	for _, s := range suppressions {
+		if s.Code != w.Code {
+			continue
+		}
+		if s.Target == "" {
+			return true
+		}
+		if matched, _ := path.Match(s.Target, w.Target); matched && w.Target != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// runWarnings lists the warnings of a run with config. They only depend on
+// the configuration and the dimensions in the source image's header, so dry
+// runs and runs with every output up to date report them too.
+func runWarnings(config Config) []warning {
+	var warnings []warning
+
+	if width, height, err := sourceDimensions(config); err == nil {
+		squared := fitMode(config) != "contain"
+		if width != height && config.Fill != "blur" && !squared {
+			warnings = append(warnings, warning{
+				Code:    "W002",
+				Message: fmt.Sprintf("source is %dx%d after cropping, so the icons get transparent bars", width, height),
+			})
+		}
+
+		resolution := sourceResolution(config, width, height)
+		for _, iconSize := range presetSizes(config) {
+			if iconSize.Size <= resolution {
+				continue
+			}
+			if config.NoUpscale == "skip" {
+				warnings = append(warnings, warning{
+					Code:    "W006",
+					Target:  iconSize.Name,
+					Message: fmt.Sprintf("skipped: needs a %dpx source, got %dx%d", iconSize.Size, width, height),
+				})
+				continue
+			}
+			warnings = append(warnings, warning{
+				Code:    "W001",
+				Target:  iconSize.Name,
+				Message: fmt.Sprintf("upscaled to %dx%d from a %dx%d source", iconSize.Size, iconSize.Size, width, height),
+			})
+		}
+	}
+
+	for _, output := range skippedOutputs(config) {
+		warnings = append(warnings, warning{
+			Code:    "W003",
+			Target:  output.Name,
+			Message: fmt.Sprintf("skipped: %s (see icongen formats)", output.Reason),
+		})
+	}
+
+	return warnings
+}
+
+// emitWarning prints w unless --suppress silences it.
+func emitWarning(config Config, w warning) {
+	var suppressions []suppression
+	if config.Suppress != "" {
+		suppressions, _ = parseSuppressions(config.Suppress)
+	}
+	if !w.suppressedBy(suppressions) {
+		printWarning(warningOutput(config), w)
+	}
+}
+
+// warningOutput returns where the warnings of a run with config go: its
+// Log, or else stderr.
+func warningOutput(config Config) io.Writer {
+	if config.Log != nil {
+		return config.Log
+	}
+	return os.Stderr
+}
+
+func printWarning(out io.Writer, w warning) {
+	if w.Target != "" {
+		fmt.Fprintf(out, "⚠️  %s %s: %s\n", w.Code, w.Target, w.Message)
+	} else {
+		fmt.Fprintf(out, "⚠️  %s %s\n", w.Code, w.Message)
+	}
+}
+
+// warnRun prints the warnings of a run with config that --suppress doesn't
+// silence, followed by how many it did.
+func warnRun(config Config) {
+	var suppressions []suppression
+	if config.Suppress != "" {
+		suppressions, _ = parseSuppressions(config.Suppress)
+	}
+
+	suppressed := 0
+	for _, w := range runWarnings(config) {
+		if w.suppressedBy(suppressions) {
+			suppressed++
+			continue
+		}
+		printWarning(warningOutput(config), w)
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(warningOutput(config), "(%d warnings suppressed)\n", suppressed)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"W001", 1, false},
+		{"W001,W002", 2, false},
+		{"W001:icon_1024*, W003:spinner.gif", 2, false},
+		{"W999", 0, true},
+		{"w001", 0, true},
+		{"W001:[", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSuppressions(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSuppressions(%q): expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("parseSuppressions(%q): expected %d suppressions, got %v", tt.spec, tt.want, got)
+		}
+	}
+}
+
+func TestWarningSuppressedBy(t *testing.T) {
+	upscaled := warning{Code: "W001", Target: "icon_1024x1024.png"}
+	nonSquare := warning{Code: "W002"}
+
+	tests := []struct {
+		name string
+		w    warning
+		spec string
+		want bool
+	}{
+		{"code", upscaled, "W001", true},
+		{"other code", upscaled, "W002", false},
+		{"matching target", upscaled, "W001:icon_1024*", true},
+		{"other target", upscaled, "W001:icon_16x16.png", false},
+		{"run warning by code", nonSquare, "W002", true},
+		{"run warning by target", nonSquare, "W002:*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suppressions, err := parseSuppressions(tt.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.spec, err)
+			}
+			if got := tt.w.suppressedBy(suppressions); got != tt.want {
+				t.Errorf("Expected suppressed %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunWarnings(t *testing.T) {
+	wide := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	config := Config{InputPath: createTempImageFile(t, wide), CropEnabled: true, TrimPercent: 80}
+
+	codes := map[string][]string{}
+	for _, w := range runWarnings(config) {
+		codes[w.Code] = append(codes[w.Code], w.Target)
+	}
+
+	if len(codes["W002"]) != 1 {
+		t.Errorf("Expected one W002 for a 480x240 source, got %v", codes["W002"])
+	}
+	// Sizes above 480px get upscaled: 256@2x, 512, 512@2x and 1024
+	if len(codes["W001"]) != 4 {
+		t.Errorf("Expected four W001 targets, got %v", codes["W001"])
+	}
+
+	config.FitMode = "cover"
+	for _, w := range runWarnings(config) {
+		if w.Code == "W002" {
+			t.Errorf("Expected no W002 with --fit=cover, got %+v", w)
+		}
+	}
+
+	square := Config{InputPath: createTempImageFile(t, createTestImage(1024, color.RGBA{255, 0, 0, 255})), TrimPercent: 80}
+	if warnings := runWarnings(square); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for an uncropped 1024px square source, got %+v", warnings)
+	}
+}
+
+func TestWarningsFollowLog(t *testing.T) {
+	// A 480x240 crop is not square, so the run warns W002 and W001
+	var log bytes.Buffer
+	wide := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	config := Config{InputPath: createTempImageFile(t, wide), CropEnabled: true, TrimPercent: 80, Suppress: "W001", Log: &log}
+
+	warnRun(config)
+	emitWarning(config, warning{Code: "W004", Target: "icon.png", Message: "too few opaque pixels to carry the watermark"})
+
+	for _, want := range []string{"W002", "(4 warnings suppressed)", "W004 icon.png"} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("Expected %q in the Log, got %q", want, log.String())
+		}
+	}
+}
+
+func TestSuppressFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icongen.yaml")
+	if err := os.WriteFile(path, []byte("suppress: \"W002,W001:icon_512x512*\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &config)
+	if err := loadConfigFile(fs, path); err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	suppressions, err := parseSuppressions(config.Suppress)
+	if err != nil {
+		t.Fatalf("Failed to parse suppressions: %v", err)
+	}
+	if !(warning{Code: "W001", Target: "icon_512x512@2x.png"}).suppressedBy(suppressions) {
+		t.Errorf("Expected the per-target suppression from the config file to apply")
+	}
+	if (warning{Code: "W001", Target: "icon_1024x1024.png"}).suppressedBy(suppressions) {
+		t.Errorf("Expected other targets to keep warning")
+	}
+}
@@ -0,0 +1,97 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"syscall/js"
+)
+
+// main exposes the generator to JavaScript as
+//
+//	icongen.generate(source: Uint8Array, options?: object)
+//
+// which returns an object mapping output file names to PNG bytes, or
+// throws. Option keys are flag names, e.g. {"trim-percent": 75}. See
+// wasm/icongen.js for a promise-based wrapper.
+func main() {
+	js.Global().Set("icongen", js.ValueOf(map[string]interface{}{
+		"generate": js.FuncOf(jsGenerate),
+	}))
+
+	// Keep the Go runtime alive to serve calls
+	select {}
+}
+
+func jsGenerate(this js.Value, args []js.Value) (result interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = js.Global().Get("Error").New(fmt.Sprint(r))
+		}
+	}()
+
+	files, err := generateFromJS(args)
+	if err != nil {
+		// Returned rather than thrown; the wrapper turns it into a rejection
+		return js.Global().Get("Error").New(err.Error())
+	}
+
+	out := js.Global().Get("Object").New()
+	for _, file := range files {
+		data := js.Global().Get("Uint8Array").New(len(file.Data))
+		js.CopyBytesToJS(data, file.Data)
+		out.Set(file.Name, data)
+	}
+	return out
+}
+
+func generateFromJS(args []js.Value) ([]renderedFile, error) {
+	if len(args) < 1 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, fmt.Errorf("generate expects the source image as a Uint8Array")
+	}
+
+	source := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(source, args[0])
+
+	var config Config
+	fs := flag.NewFlagSet("icongen", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &config)
+
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		values := make(map[string]string)
+		keys := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			values[key] = js.Global().Get("String").Invoke(args[1].Get(key)).String()
+		}
+		if err := applyOptions(fs, values); err != nil {
+			return nil, err
+		}
+	}
+	if config.MaskImage != "" {
+		return nil, fmt.Errorf("mask-image reads a file, which the WebAssembly build can't")
+	}
+	if backgroundIsImage(config) {
+		return nil, fmt.Errorf("background images are read from a file, which the WebAssembly build can't")
+	}
+	if config.Overlay != "" {
+		return nil, fmt.Errorf("overlay reads a file, which the WebAssembly build can't")
+	}
+	if config.C2PACert != "" {
+		return nil, fmt.Errorf("c2pa-cert reads a file, which the WebAssembly build can't")
+	}
+	if config.TextFont != "" {
+		return nil, fmt.Errorf("text-font reads a file, which the WebAssembly build can't")
+	}
+	if config.DarkSource != "" && config.DarkSource != darkSourceAuto {
+		return nil, fmt.Errorf("dark-source reads a file, which the WebAssembly build can't")
+	}
+	if len(layerPaths(config)) > 0 {
+		return nil, fmt.Errorf("layers with a path read a file, which the WebAssembly build can't")
+	}
+
+	return renderIconSet(source, config)
+}
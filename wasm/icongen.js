@@ -0,0 +1,51 @@
+// Browser and Node wrapper around the icongen WebAssembly build.
+//
+// Build icongen.wasm and copy Go's wasm_exec.js next to it with `make wasm`,
+// load wasm_exec.js (which defines the global Go class), then:
+//
+//   import { loadIcongen } from "./icongen.js";
+//
+//   const icongen = await loadIcongen("icongen.wasm");
+//   const files = await icongen.generate(sourceBytes, { "trim-percent": 75 });
+//   // files is a Map of output file name -> PNG bytes (Uint8Array)
+//
+// Everything runs locally; the artwork never leaves the page.
+
+export async function loadIcongen(wasm = "icongen.wasm") {
+  if (typeof globalThis.Go !== "function") {
+    throw new Error("icongen: load Go's wasm_exec.js before calling loadIcongen");
+  }
+
+  const go = new globalThis.Go();
+  const source = typeof wasm === "string" || wasm instanceof URL ? await fetchBytes(wasm) : wasm;
+  const { instance } = await WebAssembly.instantiate(source, go.importObject);
+
+  // go.run only resolves when the Go program exits, which it never does
+  go.run(instance);
+
+  const exported = globalThis.icongen;
+  if (!exported || typeof exported.generate !== "function") {
+    throw new Error("icongen: the WebAssembly module did not register itself");
+  }
+
+  return {
+    // generate renders every icon size from the source image bytes. Option
+    // keys are icongen's command-line flag names without the dashes in front.
+    async generate(sourceBytes, options = {}) {
+      const bytes = sourceBytes instanceof Uint8Array ? sourceBytes : new Uint8Array(sourceBytes);
+      const result = exported.generate(bytes, options);
+      if (result instanceof Error) {
+        throw result;
+      }
+      return new Map(Object.entries(result));
+    },
+  };
+}
+
+async function fetchBytes(url) {
+  const response = await fetch(url);
+  if (!response.ok) {
+    throw new Error(`icongen: failed to fetch ${url}: ${response.status}`);
+  }
+  return response.arrayBuffer();
+}
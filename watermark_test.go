@@ -0,0 +1,107 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{120, 60, 200, 255}}, image.Point{}, draw.Src)
+
+	marked, ok := embedWatermark(img, "ACME\x00e2f9db2486189264")
+	if !ok {
+		t.Fatalf("Expected a 64x64 opaque image to carry the watermark")
+	}
+
+	// Saving and reloading must keep the tag
+	path := filepath.Join(t.TempDir(), "marked.png")
+	if err := saveImage(marked, path); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := loadImage(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	text, err := readWatermark(loaded)
+	if err != nil {
+		t.Fatalf("Failed to read watermark: %v", err)
+	}
+	if text != "ACME\x00e2f9db2486189264" {
+		t.Errorf("Expected the embedded text back, got %q", text)
+	}
+
+	// Every channel moves by at most one level
+	for i := range img.Pix {
+		d := int(img.Pix[i]) - int(marked.(*image.RGBA).Pix[i])
+		if d < -1 || d > 1 {
+			t.Fatalf("Expected invisible changes, byte %d changed by %d", i, d)
+		}
+	}
+}
+
+func TestWatermarkNeedsOpaquePixels(t *testing.T) {
+	img := createTestImageWithSquare(64, 2, color.RGBA{255, 0, 0, 255})
+	if _, ok := embedWatermark(img, "ACME"); ok {
+		t.Errorf("Expected a mostly transparent image not to carry the watermark")
+	}
+
+	if _, err := readWatermark(createTestImage(32, color.RGBA{255, 0, 0, 255})); err == nil {
+		t.Errorf("Expected no watermark in an unmarked image")
+	}
+}
+
+func TestWatermarkDamaged(t *testing.T) {
+	img := createTestImage(64, color.RGBA{10, 20, 30, 255})
+	marked, _ := embedWatermark(img, "ACME")
+	rgba := marked.(*image.RGBA)
+	// Flip a bit inside the text
+	rgba.Pix[4*20] ^= 1
+	if _, err := readWatermark(rgba); err == nil {
+		t.Errorf("Expected a damaged watermark to be rejected")
+	}
+}
+
+func TestWatermarkMarketingSizes(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(256, color.RGBA{0, 128, 255, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, TrimPercent: 80, RadiusPercent: 20, Watermark: "ACME"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	m, err := readManifest(outputDir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if m.Generation == "" {
+		t.Fatalf("Expected the generation hash in the manifest")
+	}
+
+	for name, want := range map[string]bool{
+		"icon_1024x1024.png": true,
+		"icon_512x512.png":   true,
+		"icon_256x256.png":   false,
+		"icon_16x16.png":     false,
+	} {
+		img, err := loadImage(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		text, err := readWatermark(img)
+		if want && text != "ACME\x00"+m.Generation {
+			t.Errorf("Expected %s to carry the watermark, got %q (%v)", name, text, err)
+		}
+		if !want && err == nil {
+			t.Errorf("Expected %s to carry no watermark, got %q", name, text)
+		}
+	}
+
+	if err := validateWatermark("ACME\n"); err == nil {
+		t.Errorf("Expected non-printable watermark to fail")
+	}
+}
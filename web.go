@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	webManifestName = "site.webmanifest"
+	webHeadName     = "head.html"
+)
+
+// webManifest is the part of a web app manifest icongen writes.
+type webManifest struct {
+	Icons []webManifestIcon `json:"icons"`
+}
+
+type webManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// webIconSizes are the outputs of the web preset, by role.
+var webIconSizes = []IconSize{
+	{"favicon-16x16.png", 16},
+	{"favicon-32x32.png", 32},
+	{"apple-touch-icon.png", 180},
+	{"android-chrome-192x192.png", 192},
+	{"android-chrome-512x512.png", 512},
+}
+
+// hashedIconSizes returns sizes with a short hash of the source image and
+// options inserted before each extension, e.g. favicon-32x32.ab12cd34.png.
+// The hash changes exactly when icongen would regenerate the file, so the
+// names can be served with immutable caching. Without a readable source the
+// names are left alone.
+func hashedIconSizes(config Config, sizes []IconSize) []IconSize {
+	sourceHash, err := hashFile(config.InputPath)
+	if err != nil {
+		return sizes
+	}
+	optionsHash, err := hashOptions(config)
+	if err != nil {
+		return sizes
+	}
+
+	hashed := make([]IconSize, len(sizes))
+	for i, iconSize := range sizes {
+		sum := sha256.Sum256([]byte(sourceHash + optionsHash + iconSize.Name))
+		ext := filepath.Ext(iconSize.Name)
+		hashed[i] = IconSize{
+			Name: strings.TrimSuffix(iconSize.Name, ext) + "." + hex.EncodeToString(sum[:4]) + ext,
+			Size: iconSize.Size,
+		}
+	}
+	return hashed
+}
+
+// precompressedFormats are the --precompress formats, by file extension.
+var precompressedFormats = map[string]func(data []byte) ([]byte, error){
+	"gz": gzipBytes,
+}
+
+// parsePrecompress parses a comma-separated --precompress spec such as "gz".
+func parsePrecompress(spec string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, format := range strings.Split(spec, ",") {
+		if format == "br" {
+			return nil, fmt.Errorf("brotli precompression (br) is not supported: Go's standard library has no brotli encoder")
+		}
+		if _, ok := precompressedFormats[format]; !ok {
+			return nil, fmt.Errorf("unknown precompression format %q (expected gz)", format)
+		}
+		if !seen[format] {
+			seen[format] = true
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+// gzipBytes compresses data at the best compression level. The header
+// carries no name or timestamp, so the output only depends on data.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// webTargets lists the snippets the web preset adds to a run, followed by
+// the precompressed copies of the manifest.
+func webTargets(config Config) []plannedOutput {
+	if !hasPreset(config, "web") {
+		return nil
+	}
+	targets := []plannedOutput{
+		{Name: webManifestName, Format: "webmanifest", Variant: "web-manifest"},
+		{Name: webHeadName, Format: "html", Variant: "web-head"},
+	}
+	if config.Precompress != "" {
+		formats, _ := parsePrecompress(config.Precompress)
+		for _, format := range formats {
+			targets = append(targets, plannedOutput{Name: webManifestName + "." + format, Format: format, Variant: "web-manifest"})
+		}
+	}
+	return targets
+}
+
+// webIconNames maps the web preset's roles to the file names of this run,
+// which carry hashes with --hash-names. Roles --no-upscale skipped are left
+// out.
+func webIconNames(config Config) map[string]string {
+	sizes := webIconSizes
+	if config.HashNames {
+		sizes = hashedIconSizes(config, webIconSizes)
+	}
+
+	generated := make(map[string]bool)
+	for _, iconSize := range outputSizes(config) {
+		generated[iconSize.Name] = true
+	}
+
+	names := make(map[string]string)
+	for i, iconSize := range sizes {
+		if generated[iconSize.Name] {
+			names[webIconSizes[i].Name] = iconSize.Name
+		}
+	}
+	return names
+}
+
+// writeWebSnippets writes the web app manifest and the HTML head snippet
+// referencing the icons of this run.
+func writeWebSnippets(config Config, state *manifestState) error {
+	names := webIconNames(config)
+
+	manifest := webManifest{Icons: []webManifestIcon{}}
+	for _, role := range []string{"android-chrome-192x192.png", "android-chrome-512x512.png"} {
+		if names[role] == "" {
+			continue
+		}
+		size := strings.TrimSuffix(strings.TrimPrefix(role, "android-chrome-"), ".png")
+		manifest.Icons = append(manifest.Icons, webManifestIcon{Src: "/" + names[role], Sizes: size, Type: "image/png"})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := writeWebSnippet(config, state, webManifestName, data); err != nil {
+		return err
+	}
+
+	var head strings.Builder
+	link := func(attrs string, role string) {
+		if names[role] == "" {
+			return
+		}
+		fmt.Fprintf(&head, "<link %s href=\"/%s\">\n", attrs, html.EscapeString(names[role]))
+	}
+	favicon := func(attrs string, role string) {
+		if config.DarkSource == "" || names[role] == "" {
+			link(attrs, role)
+			return
+		}
+		// Browsers pick the favicon matching the color scheme
+		link(attrs+` media="(prefers-color-scheme: light)"`, role)
+		fmt.Fprintf(&head, "<link %s media=\"(prefers-color-scheme: dark)\" href=\"/%s\">\n", attrs, html.EscapeString(darkIconName(names[role])))
+	}
+	favicon(`rel="icon" type="image/png" sizes="32x32"`, "favicon-32x32.png")
+	favicon(`rel="icon" type="image/png" sizes="16x16"`, "favicon-16x16.png")
+	link(`rel="apple-touch-icon" sizes="180x180"`, "apple-touch-icon.png")
+	fmt.Fprintf(&head, "<link rel=\"manifest\" href=\"/%s\">\n", webManifestName)
+	if err := writeWebSnippet(config, state, webHeadName, []byte(head.String())); err != nil {
+		return err
+	}
+
+	// head.html is pasted into pages rather than served, so only the
+	// manifest gets precompressed copies
+	if config.Precompress == "" {
+		return nil
+	}
+	formats, err := parsePrecompress(config.Precompress)
+	if err != nil {
+		return err
+	}
+	for _, format := range formats {
+		compressed, err := precompressedFormats[format](data)
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %w", webManifestName, err)
+		}
+		name := webManifestName + "." + format
+		fmt.Fprintf(logOutput(config), " - %s (%d → %d bytes)\n", name, len(data), len(compressed))
+		if err := os.WriteFile(filepath.Join(config.OutputDir, name), compressed, 0644); err != nil {
+			return fmt.Errorf("failed to save %s: %w", name, err)
+		}
+		if err := state.record(name); err != nil {
+			return fmt.Errorf("failed to record %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func writeWebSnippet(config Config, state *manifestState, name string, data []byte) error {
+	fmt.Fprintf(logOutput(config), " - %s\n", name)
+	if err := os.WriteFile(filepath.Join(config.OutputDir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	if err := state.record(name); err != nil {
+		return fmt.Errorf("failed to record %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWebPreset(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "web", TrimPercent: 80}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	head, err := os.ReadFile(filepath.Join(outputDir, webHeadName))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", webHeadName, err)
+	}
+	for _, want := range []string{
+		`<link rel="icon" type="image/png" sizes="32x32" href="/favicon-32x32.png">`,
+		`<link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">`,
+		`<link rel="manifest" href="/site.webmanifest">`,
+	} {
+		if !strings.Contains(string(head), want) {
+			t.Errorf("Expected head snippet to contain %q, got:\n%s", want, head)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, webManifestName))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", webManifestName, err)
+	}
+	var manifest webManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse %s: %v", webManifestName, err)
+	}
+	if len(manifest.Icons) != 2 || manifest.Icons[1].Src != "/android-chrome-512x512.png" || manifest.Icons[1].Sizes != "512x512" {
+		t.Errorf("Unexpected manifest icons: %+v", manifest.Icons)
+	}
+}
+
+func TestHashNames(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "web", TrimPercent: 80, HashNames: true}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	hashed := regexp.MustCompile(`^favicon-32x32\.[0-9a-f]{8}\.png$`)
+	name := outputSizes(config)[1].Name
+	if !hashed.MatchString(name) {
+		t.Fatalf("Expected a hashed favicon name, got %s", name)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+		t.Errorf("Expected %s to be written: %v", name, err)
+	}
+
+	head, _ := os.ReadFile(filepath.Join(outputDir, webHeadName))
+	if !strings.Contains(string(head), `href="/`+name+`"`) {
+		t.Errorf("Expected head snippet to reference %s, got:\n%s", name, head)
+	}
+
+	// Names are stable for the same inputs and change with the options
+	if again := outputSizes(config)[1].Name; again != name {
+		t.Errorf("Expected stable hashed name, got %s then %s", name, again)
+	}
+	config.TrimPercent = 70
+	if changed := outputSizes(config)[1].Name; changed == name {
+		t.Errorf("Expected hashed name to change with the options")
+	}
+
+	if err := validateOptions(Config{TrimPercent: 80, HashNames: true}); err == nil {
+		t.Errorf("Expected --hash-names to require the web preset")
+	}
+}
+
+func TestPrecompress(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{255, 128, 0, 255}))
+	outputDir := t.TempDir()
+
+	config := Config{InputPath: inputPath, OutputDir: outputDir, Preset: "web", TrimPercent: 80, Precompress: "gz"}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(outputDir, webManifestName))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", webManifestName, err)
+	}
+	compressed, err := os.ReadFile(filepath.Join(outputDir, webManifestName+".gz"))
+	if err != nil {
+		t.Fatalf("Failed to read %s.gz: %v", webManifestName, err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Failed to open %s.gz: %v", webManifestName, err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress %s.gz: %v", webManifestName, err)
+	}
+	if !bytes.Equal(decompressed, manifest) {
+		t.Errorf("Expected %s.gz to decompress to %s", webManifestName, webManifestName)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, webHeadName+".gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected no precompressed %s, got %v", webHeadName, err)
+	}
+}
+
+func TestParsePrecompress(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{"gz", 1, false},
+		{"gz,gz", 1, false},
+		{"br", 0, true},
+		{"gz,br", 0, true},
+		{"zip", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePrecompress(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePrecompress(%q): expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("parsePrecompress(%q): expected %d formats, got %v", tt.spec, tt.want, got)
+		}
+	}
+
+	if err := validateOptions(Config{Precompress: "gz", TrimPercent: 80}); err == nil {
+		t.Errorf("Expected --precompress without the web preset to fail")
+	}
+}
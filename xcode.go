@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const xcodeContentsName = "Contents.json"
+
+// xcodeContents is the Contents.json of an Xcode asset catalog app icon set.
+type xcodeContents struct {
+	Images []xcodeImage `json:"images"`
+	Info   xcodeInfo    `json:"info"`
+}
+
+type xcodeImage struct {
+	Appearances []xcodeAppearance `json:"appearances,omitempty"`
+	Filename    string            `json:"filename,omitempty"`
+	Idiom       string            `json:"idiom"`
+	Platform    string            `json:"platform,omitempty"`
+	Scale       string            `json:"scale,omitempty"`
+	Size        string            `json:"size"`
+}
+
+// xcodeAppearance selects the home screen appearance an image is for.
+type xcodeAppearance struct {
+	Appearance string `json:"appearance"`
+	Value      string `json:"value"`
+}
+
+type xcodeInfo struct {
+	Author  string `json:"author"`
+	Version int    `json:"version"`
+}
+
+// xcodeContentsTargets lists the outputs --contents-json adds to a run.
+func xcodeContentsTargets(config Config) []plannedOutput {
+	if !config.ContentsJSON {
+		return nil
+	}
+	return []plannedOutput{{Name: xcodeContentsName, Format: "json", Variant: "xcode-contents"}}
+}
+
+// xcodeImages maps the regular icons onto asset catalog slots: the macOS
+// sizes at 1x and 2x, and the 1024px base as the single-size iOS icon,
+// followed by its dark and tinted variants with --appearances. The slots of
+// sizes --no-upscale skipped are left without a file.
+func xcodeImages(config Config) []xcodeImage {
+	generated := make(map[string]bool)
+	for _, iconSize := range outputSizes(config) {
+		generated[iconSize.Name] = true
+	}
+
+	var images []xcodeImage
+	for _, iconSize := range iconSizes {
+		filename := iconSize.Name
+		if !generated[filename] {
+			filename = ""
+		}
+		stem := strings.TrimSuffix(strings.TrimPrefix(iconSize.Name, "icon_"), ".png")
+		size, scale := stem, "1x"
+		if strings.HasSuffix(stem, "@2x") {
+			size, scale = strings.TrimSuffix(stem, "@2x"), "2x"
+		}
+
+		if size == "1024x1024" {
+			images = append(images, xcodeImage{Filename: filename, Idiom: "universal", Platform: "ios", Size: size})
+			if config.Appearances && filename != "" {
+				for _, appearance := range iconAppearances {
+					images = append(images, xcodeImage{
+						Appearances: []xcodeAppearance{{Appearance: "luminosity", Value: appearance}},
+						Filename:    appearanceIconName(iconSize.Name, appearance),
+						Idiom:       "universal",
+						Platform:    "ios",
+						Size:        size,
+					})
+				}
+			}
+			continue
+		}
+		images = append(images, xcodeImage{Filename: filename, Idiom: "mac", Scale: scale, Size: size})
+	}
+	return images
+}
+
+// writeXcodeContents writes the Contents.json that turns the output
+// directory into an Xcode app icon set.
+func writeXcodeContents(config Config, state *manifestState) error {
+	data, err := json.MarshalIndent(xcodeContents{
+		Images: xcodeImages(config),
+		Info:   xcodeInfo{Author: "icongen", Version: 1},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(logOutput(config), " - %s\n", xcodeContentsName)
+	if err := os.WriteFile(filepath.Join(config.OutputDir, xcodeContentsName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", xcodeContentsName, err)
+	}
+
+	if err := state.record(xcodeContentsName); err != nil {
+		return fmt.Errorf("failed to record %s: %w", xcodeContentsName, err)
+	}
+	return nil
+}
+
+// runXcodePhase implements "icongen xcode-phase": it prints a Run Script
+// build phase that regenerates an app icon set from its committed source,
+// or writes it to a script file with --install. Paths are relative to the
+// Xcode project directory, and generation flags are passed through.
+func runXcodePhase(args []string) error {
+	var config Config
+	flags := flag.NewFlagSet("xcode-phase", flag.ContinueOnError)
+	defineFlags(flags, &config)
+	var install string
+	flags.StringVar(&install, "install", "", "Write the script to this file, made executable, instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s xcode-phase [options] source.png path/to/AppIcon.appiconset\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Print an Xcode Run Script build phase that regenerates the app icon set from the source.\n")
+		fmt.Fprintf(flags.Output(), "Paths are relative to the project directory ($SRCROOT). Generation options are passed through.\n\n")
+		fmt.Fprintf(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("xcode-phase requires a source image and an app icon set directory")
+	}
+
+	// Pass through the generation options given explicitly. Rounded variants
+	// would be unassigned children of the icon set.
+	options := regularIconsOnly(flags, passThroughOptions(flags, "install", "contents-json", "force", "dry-run"))
+	for i, option := range options {
+		options[i] = shellQuote(option)
+	}
+
+	script := xcodePhaseScript(flags.Arg(0), flags.Arg(1), options)
+
+	if install == "" {
+		fmt.Print(script)
+		return nil
+	}
+
+	if err := os.WriteFile(install, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", install, err)
+	}
+	fmt.Printf("Wrote %s\n", install)
+	fmt.Printf("Add a Run Script build phase before \"Copy Bundle Resources\" containing:\n\n  \"${SRCROOT}/%s\"\n\n", install)
+	fmt.Printf("and list \"${SRCROOT}/%s\" under its Input Files.\n", flags.Arg(0))
+	return nil
+}
+
+// passThroughOptions returns the generation options set explicitly on flags
+// as --name=value arguments for a generated build script, leaving out input,
+// output and the named flags the script sets itself.
+func passThroughOptions(flags *flag.FlagSet, skip ...string) []string {
+	// Build scripts pass the source positionally, which --foreground replaces
+	skipped := map[string]bool{"input": true, "output": true, "foreground": true}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	var options []string
+	flags.Visit(func(f *flag.Flag) {
+		if !skipped[f.Name] {
+			options = append(options, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return options
+}
+
+// regularIconsOnly prepends --radius-percent=0 to options unless flags set a
+// radius, for build systems that only pick up the regular icons.
+func regularIconsOnly(flags *flag.FlagSet, options []string) []string {
+	radiusSet := false
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "radius-percent", "radius-px", "radius-sizes":
+			radiusSet = true
+		}
+	})
+	if radiusSet {
+		return options
+	}
+	return append([]string{"--radius-percent=0"}, options...)
+}
+
+// xcodePhaseScript returns the shell script of the build phase. icongen's
+// incremental mode leaves the icons alone unless the source or options
+// changed, so running it on every build is cheap.
+func xcodePhaseScript(source, appIconSet string, options []string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Regenerates the app icon set from its source whenever the source changes.\n")
+	b.WriteString("# Generated by icongen xcode-phase.\n")
+	b.WriteString("set -e\n\n")
+	b.WriteString("if ! command -v icongen >/dev/null 2>&1; then\n")
+	b.WriteString("  echo \"warning: icongen not installed, app icons not regenerated\"\n")
+	b.WriteString("  exit 0\n")
+	b.WriteString("fi\n\n")
+	fmt.Fprintf(&b, "icongen --contents-json --force")
+	for _, option := range options {
+		b.WriteString(" " + option)
+	}
+	fmt.Fprintf(&b, " \\\n  \"${SRCROOT}/%s\" \\\n  \"${SRCROOT}/%s\"\n", shellEscape(source), shellEscape(appIconSet))
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but safe
+// characters.
+func shellQuote(s string) string {
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=.,:/@%+#", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellEscape escapes s for use inside double quotes.
+func shellEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return r.Replace(s)
+}
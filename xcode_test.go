@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXcodeContents(t *testing.T) {
+	inputPath := createTempImageFile(t, createTestImage(64, color.RGBA{0, 128, 255, 255}))
+	outputDir := filepath.Join(t.TempDir(), "AppIcon.appiconset")
+
+	config := Config{
+		InputPath:    inputPath,
+		OutputDir:    outputDir,
+		TrimPercent:  80,
+		ContentsJSON: true,
+	}
+	if err := generateIcons(config); err != nil {
+		t.Fatalf("Failed to generate icons: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, xcodeContentsName))
+	if err != nil {
+		t.Fatalf("Expected %s: %v", xcodeContentsName, err)
+	}
+	var contents xcodeContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		t.Fatalf("Failed to parse %s: %v", xcodeContentsName, err)
+	}
+
+	if len(contents.Images) != len(iconSizes) {
+		t.Fatalf("Expected %d images, got %d", len(iconSizes), len(contents.Images))
+	}
+
+	tests := []struct {
+		filename string
+		idiom    string
+		size     string
+		scale    string
+	}{
+		{"icon_16x16.png", "mac", "16x16", "1x"},
+		{"icon_16x16@2x.png", "mac", "16x16", "2x"},
+		{"icon_512x512@2x.png", "mac", "512x512", "2x"},
+		{"icon_1024x1024.png", "universal", "1024x1024", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			for _, image := range contents.Images {
+				if image.Filename != tt.filename {
+					continue
+				}
+				if image.Idiom != tt.idiom || image.Size != tt.size || image.Scale != tt.scale {
+					t.Errorf("Expected %s %s %s, got %s %s %s", tt.idiom, tt.size, tt.scale, image.Idiom, image.Size, image.Scale)
+				}
+				return
+			}
+			t.Errorf("Expected %s in %s", tt.filename, xcodeContentsName)
+		})
+	}
+}
+
+func TestXcodePhaseScript(t *testing.T) {
+	script := xcodePhaseScript("Design/App Icon.png", "App/Assets.xcassets/AppIcon.appiconset",
+		[]string{"--radius-percent=0", shellQuote("--series=A, B")})
+
+	for _, want := range []string{
+		"#!/bin/sh",
+		"icongen --contents-json --force --radius-percent=0 '--series=A, B'",
+		`"${SRCROOT}/Design/App Icon.png"`,
+		`"${SRCROOT}/App/Assets.xcassets/AppIcon.appiconset"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+func TestRunXcodePhaseInstall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regenerate-icons.sh")
+	if err := runXcodePhase([]string{"--install", path, "--trim-percent=75", "icon.png", "AppIcon.appiconset"}); err != nil {
+		t.Fatalf("xcode-phase failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected installed script: %v", err)
+	}
+	if info.Mode()&0100 == 0 {
+		t.Errorf("Expected installed script to be executable, got mode %v", info.Mode())
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "--radius-percent=0 --trim-percent=75") {
+		t.Errorf("Expected options to be passed through, got:\n%s", data)
+	}
+}